@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/google/uuid"
+	"github.com/raystack/compass/core/backup"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/internal/config"
+	"github.com/raystack/compass/internal/middleware"
+	compassserver "github.com/raystack/compass/internal/server"
+	"github.com/raystack/compass/store"
+	"github.com/spf13/cobra"
+)
+
+func backupNamespaceCommand(cfg *config.Config) *cobra.Command {
+	var dir string
+	var withEmbeddings bool
+
+	cmd := &cobra.Command{
+		Use:   "backup <name|id>",
+		Short: "Back up a namespace to an archive directory",
+		Long: heredoc.Doc(`
+			Dump all entities and edges of a namespace, their history included,
+			together with its documents and principals, to an archive directory.
+
+			The archive holds a manifest.json and one JSON lines file per kind of
+			record. Embeddings are left out unless --embeddings is set; they can
+			be re-generated on restore instead. Connects to the database directly.
+		`),
+		Example: heredoc.Doc(`
+			$ compass namespace backup acme -o ./acme-backup
+			$ compass namespace backup acme -o ./acme-backup --embeddings
+		`),
+		Annotations: map[string]string{
+			"action:core": "true",
+		},
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			compassserver.InitLogger(cfg.LogLevel)
+			pgClient, err := store.NewClient(cfg.DB)
+			if err != nil {
+				return fmt.Errorf("connect to postgres: %w", err)
+			}
+			defer pgClient.Close()
+
+			ns, err := namespaceByNameOrID(cmd.Context(), store.NewNamespaceRepository(pgClient), args[0])
+			if err != nil {
+				return err
+			}
+			svc, err := newBackupService(pgClient)
+			if err != nil {
+				return err
+			}
+
+			ctx := middleware.BuildContextWithNamespace(cmd.Context(), ns)
+			m, err := svc.Backup(ctx, ns, dir, backup.BackupOptions{Embeddings: withEmbeddings})
+			if err != nil {
+				return err
+			}
+			c := m.Counts
+			fmt.Printf("Backed up namespace %s to %s: %d entity versions, %d edge versions, %d documents, %d principals, %d embeddings, %d entity types, %d edge types, %d aliases\n",
+				ns.Name, dir, c.Entities, c.Edges, c.Documents, c.Principals, c.Embeddings, c.EntityTypes, c.EdgeTypes, c.Aliases)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&dir, "out", "o", "", "Archive directory to write (required)")
+	cmd.Flags().BoolVar(&withEmbeddings, "embeddings", false, "Include embeddings in the archive")
+	_ = cmd.MarkFlagRequired("out")
+	return cmd
+}
+
+func restoreNamespaceCommand(cfg *config.Config) *cobra.Command {
+	var name string
+	var reembed bool
+	var batchSize int
+
+	cmd := &cobra.Command{
+		Use:   "restore <dir>",
+		Short: "Restore a namespace from an archive directory",
+		Long: heredoc.Doc(`
+			Load an archive written by 'compass namespace backup' into a namespace.
+
+			The archive is restored into the namespace it was taken from, or the
+			one named by --namespace. A missing namespace is created; an existing
+			one must hold no entities, edges, documents, type definitions or
+			aliases. Principals that already exist are kept. Nothing is written
+			unless the whole archive loads.
+
+			With --reembed, embeddings in the archive are ignored and re-generated
+			with the configured embedding provider. Connects to the database
+			directly.
+		`),
+		Example: heredoc.Doc(`
+			$ compass namespace restore ./acme-backup
+			$ compass namespace restore ./acme-backup --namespace acme_staging --reembed
+		`),
+		Annotations: map[string]string{
+			"action:core": "true",
+		},
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			compassserver.InitLogger(cfg.LogLevel)
+			dir := args[0]
+			m, err := backup.ReadManifest(dir)
+			if err != nil {
+				return err
+			}
+			if reembed && !cfg.Embedding.Enabled {
+				return fmt.Errorf("--reembed requires embedding to be enabled in config")
+			}
+			if batchSize <= 0 {
+				return fmt.Errorf("--batch-size must be positive")
+			}
+
+			pgClient, err := store.NewClient(cfg.DB)
+			if err != nil {
+				return fmt.Errorf("connect to postgres: %w", err)
+			}
+			defer pgClient.Close()
+
+			if name == "" {
+				name = m.Namespace.Name
+			}
+			ns, err := restoreTarget(cmd.Context(), store.NewNamespaceRepository(pgClient), m, name)
+			if err != nil {
+				return err
+			}
+			svc, err := newBackupService(pgClient)
+			if err != nil {
+				return err
+			}
+
+			ctx := middleware.BuildContextWithNamespace(cmd.Context(), ns)
+			c, err := svc.Restore(ctx, ns, dir, backup.RestoreOptions{SkipEmbeddings: reembed})
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Restored namespace %s from %s: %d entity versions, %d edge versions, %d documents, %d principals, %d embeddings, %d entity types, %d edge types, %d aliases\n",
+				ns.Name, dir, c.Entities, c.Edges, c.Documents, c.Principals, c.Embeddings, c.EntityTypes, c.EdgeTypes, c.Aliases)
+
+			if !reembed {
+				if !m.Embeddings {
+					fmt.Println("The archive holds no embeddings; restore with --reembed or run 'compass embed' to re-generate them")
+				}
+				return nil
+			}
+			return reembedNamespace(ctx, cfg, pgClient, ns, batchSize)
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "namespace", "", "Namespace to restore into (default: the archived namespace)")
+	cmd.Flags().BoolVar(&reembed, "reembed", false, "Re-generate embeddings instead of restoring them")
+	cmd.Flags().IntVar(&batchSize, "batch-size", 100, "Number of entities to embed per batch with --reembed")
+	return cmd
+}
+
+func newBackupService(pgClient *store.Client) (*backup.Service, error) {
+	repo, err := store.NewBackupRepository(pgClient)
+	if err != nil {
+		return nil, err
+	}
+	return backup.NewService(repo), nil
+}
+
+func namespaceByNameOrID(ctx context.Context, repo *store.NamespaceRepository, s string) (*namespace.Namespace, error) {
+	if id, err := uuid.Parse(s); err == nil {
+		return repo.GetByID(ctx, id)
+	}
+	return repo.GetByName(ctx, s)
+}
+
+// restoreTarget returns the namespace called name, creating it from the
+// archived namespace when missing. A namespace restored under its archived
+// name keeps its ID, unless the ID is taken, so that tokens naming it by ID
+// keep working.
+func restoreTarget(ctx context.Context, repo *store.NamespaceRepository, m backup.Manifest, name string) (*namespace.Namespace, error) {
+	ns, err := repo.GetByName(ctx, name)
+	if err == nil {
+		return ns, nil
+	}
+	if !errors.Is(err, namespace.ErrNotFound) {
+		return nil, err
+	}
+
+	ns = &namespace.Namespace{
+		ID:       uuid.New(),
+		Name:     name,
+		State:    m.Namespace.State,
+		Metadata: m.Namespace.Metadata,
+	}
+	if name == m.Namespace.Name {
+		if _, err := repo.GetByID(ctx, m.Namespace.ID); errors.Is(err, namespace.ErrNotFound) {
+			ns.ID = m.Namespace.ID
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if ns.State == "" {
+		ns.State = namespace.SharedState
+	}
+	if _, err := namespace.NewService(repo, nil).Create(ctx, ns); err != nil {
+		return nil, fmt.Errorf("create namespace %s: %w", name, err)
+	}
+	slog.Info("created namespace", "name", ns.Name, "id", ns.ID)
+	return ns, nil
+}
+
+// reembedNamespace generates embeddings for the current entities and the
+// documents of a namespace.
+func reembedNamespace(ctx context.Context, cfg *config.Config, pgClient *store.Client, ns *namespace.Namespace, batchSize int) error {
+	provider, err := initProvider(cfg.Embedding)
+	if err != nil {
+		return err
+	}
+	embeddingRepo, err := store.NewEmbeddingRepository(pgClient)
+	if err != nil {
+		return err
+	}
+	entityRepo, err := store.NewEntityRepository(pgClient)
+	if err != nil {
+		return err
+	}
+	docRepo, err := store.NewDocumentRepository(pgClient)
+	if err != nil {
+		return err
+	}
+	if err := embedEntities(ctx, entityRepo, embeddingRepo, provider, ns, batchSize, cfg.Embedding); err != nil {
+		return fmt.Errorf("embed entities: %w", err)
+	}
+	if err := embedDocuments(ctx, docRepo, embeddingRepo, provider, ns, batchSize, cfg.Embedding); err != nil {
+		return fmt.Errorf("embed documents: %w", err)
+	}
+	return nil
+}
@@ -1,14 +1,16 @@
 package cli
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"time"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/MakeNowJust/heredoc"
+	"github.com/raystack/compass/core/document"
 	"github.com/raystack/compass/internal/config"
 	"github.com/spf13/cobra"
 )
@@ -27,6 +29,9 @@ func documentsCommand(cfg *config.Config) *cobra.Command {
 		$ compass document upsert
 		$ compass document delete <id>
 		$ compass document entity <urn>
+		$ compass document import -f documents.jsonl
+		$ compass document export -o documents.jsonl
+		$ compass document sync ./runbooks --source runbooks
 		`),
 	}
 
@@ -36,6 +41,9 @@ func documentsCommand(cfg *config.Config) *cobra.Command {
 		upsertDocumentCommand(cfg),
 		deleteDocumentCommand(cfg),
 		documentsByEntityCommand(cfg),
+		importRecordsCommand(cfg, documentRecords),
+		exportRecordsCommand(cfg, documentRecords),
+		syncDocumentsCommand(cfg),
 	)
 
 	return cmd
@@ -61,7 +69,7 @@ func listDocumentsCommand(cfg *config.Config) *cobra.Command {
 				params += sep + "source=" + source
 			}
 
-			body, err := doDocumentRequest(cfg, "GET", url+params, nil)
+			body, err := doRequest(cfg, "GET", url+params, nil)
 			if err != nil {
 				return err
 			}
@@ -83,7 +91,7 @@ func viewDocumentCommand(cfg *config.Config) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			url := fmt.Sprintf("http://%s/v1/documents/%s", cfg.Client.Host, args[0])
 
-			body, err := doDocumentRequest(cfg, "GET", url, nil)
+			body, err := doRequest(cfg, "GET", url, nil)
 			if err != nil {
 				return err
 			}
@@ -118,7 +126,7 @@ func upsertDocumentCommand(cfg *config.Config) *cobra.Command {
 
 			url := fmt.Sprintf("http://%s/v1/documents", cfg.Client.Host)
 
-			body, err := doDocumentRequest(cfg, "POST", url, payload)
+			body, err := doRequest(cfg, "POST", url, payload)
 			if err != nil {
 				return err
 			}
@@ -137,7 +145,7 @@ func upsertDocumentCommand(cfg *config.Config) *cobra.Command {
 	cmd.Flags().StringVar(&entityURN, "entity-urn", "", "Entity URN (required)")
 	cmd.Flags().StringVar(&title, "title", "", "Document title (required)")
 	cmd.Flags().StringVar(&docBody, "body", "", "Document body (required)")
-	cmd.Flags().StringVar(&format, "format", "", "Format: markdown, plaintext")
+	cmd.Flags().StringVar(&format, "format", "", "Format: markdown, plaintext, html, confluence-storage")
 	cmd.Flags().StringVar(&source, "source", "", "Source system (e.g., confluence, github)")
 	cmd.Flags().StringVar(&sourceID, "source-id", "", "ID in source system")
 	_ = cmd.MarkFlagRequired("entity-urn")
@@ -154,7 +162,7 @@ func deleteDocumentCommand(cfg *config.Config) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			url := fmt.Sprintf("http://%s/v1/documents/%s", cfg.Client.Host, args[0])
 
-			if _, err := doDocumentRequest(cfg, "DELETE", url, nil); err != nil {
+			if _, err := doRequest(cfg, "DELETE", url, nil); err != nil {
 				return err
 			}
 
@@ -172,7 +180,7 @@ func documentsByEntityCommand(cfg *config.Config) *cobra.Command {
 		RunE: func(cmd *cobra.Command, args []string) error {
 			url := fmt.Sprintf("http://%s/v1/entities/%s/documents", cfg.Client.Host, args[0])
 
-			body, err := doDocumentRequest(cfg, "GET", url, nil)
+			body, err := doRequest(cfg, "GET", url, nil)
 			if err != nil {
 				return err
 			}
@@ -183,39 +191,144 @@ func documentsByEntityCommand(cfg *config.Config) *cobra.Command {
 	}
 }
 
-func doDocumentRequest(cfg *config.Config, method, url string, payload interface{}) ([]byte, error) {
-	var reqBody io.Reader
-	if payload != nil {
-		data, err := json.Marshal(payload)
-		if err != nil {
-			return nil, fmt.Errorf("marshal request: %w", err)
-		}
-		reqBody = bytes.NewReader(data)
-	}
+func syncDocumentsCommand(cfg *config.Config) *cobra.Command {
+	var source string
+	var prune bool
 
-	req, err := http.NewRequest(method, url, reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
+	cmd := &cobra.Command{
+		Use:   "sync <dir>",
+		Short: "Sync a directory of markdown files as documents",
+		Long: heredoc.Doc(`
+			Walk a directory of markdown files and upsert each as a document of the
+			given source. Each file starts with a YAML front matter block:
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set(cfg.Client.ServerHeaderKeyUserUUID, cfg.Client.ServerHeaderValueUserUUID)
+			  ---
+			  entity_urn: urn:bigquery:shop.orders
+			  title: Orders runbook
+			  source_id: runbooks/orders
+			  properties:
+			    owner: data-platform
+			  ---
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
+			entity_urn is required. title defaults to the first heading of the file,
+			and source_id to the file path relative to <dir>. Files without front
+			matter are skipped, and so are hidden directories such as .git.
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("read response: %w", err)
-	}
+			Unchanged documents are not rewritten. With --prune, documents of the
+			source whose files were removed are deleted.
+		`),
+		Example: heredoc.Doc(`
+			$ compass document sync ./runbooks --source runbooks
+			$ compass document sync ./runbooks --source runbooks --prune
+		`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			docs, skipped, err := readDocumentTree(args[0])
+			if err != nil {
+				return err
+			}
+			for _, path := range skipped {
+				fmt.Fprintf(os.Stderr, "skipped %s: no front matter\n", path)
+			}
 
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("server error (%d): %s", resp.StatusCode, string(body))
+			payload := map[string]interface{}{
+				"source":    source,
+				"prune":     prune,
+				"documents": docs,
+			}
+			url := fmt.Sprintf("http://%s/v1/documents/sync", cfg.Client.Host)
+			body, err := doRequest(cfg, "POST", url, payload)
+			if err != nil {
+				return err
+			}
+
+			var res struct {
+				Summary document.SyncSummary `json:"summary"`
+				Error   string               `json:"error"`
+			}
+			if err := json.Unmarshal(body, &res); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+			sm := res.Summary
+			fmt.Printf("Synced %d files: %d created, %d updated, %d unchanged, %d deleted, %d failed\n",
+				len(docs), sm.Created, sm.Updated, sm.Unchanged, sm.Deleted, len(sm.Failed))
+			for _, f := range sm.Failed {
+				fmt.Fprintf(os.Stderr, "  %s: %s\n", f.SourceID, f.Error)
+			}
+			if res.Error != "" {
+				return errors.New(res.Error)
+			}
+			if len(sm.Failed) > 0 {
+				return fmt.Errorf("%d documents failed to sync", len(sm.Failed))
+			}
+			return nil
+		},
 	}
+	cmd.Flags().StringVar(&source, "source", "", "Source the documents belong to, e.g. the repository name (required)")
+	cmd.Flags().BoolVar(&prune, "prune", false, "Delete documents of the source whose files were removed")
+	_ = cmd.MarkFlagRequired("source")
+	return cmd
+}
+
+// readDocumentTree reads the markdown files under dir. It returns the
+// documents found and the files skipped for having no front matter.
+func readDocumentTree(dir string) ([]document.Document, []string, error) {
+	var docs []document.Document
+	var skipped []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != dir && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if ext := strings.ToLower(filepath.Ext(path)); ext != ".md" && ext != ".markdown" {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
 
-	return body, nil
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		fm, body, err := document.ParseMarkdown(data)
+		if errors.Is(err, document.ErrNoFrontMatter) {
+			skipped = append(skipped, rel)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", rel, err)
+		}
+		if fm.EntityURN == "" {
+			return fmt.Errorf("%s: front matter has no entity_urn", rel)
+		}
+
+		doc := document.Document{
+			EntityURN:  fm.EntityURN,
+			Title:      fm.Title,
+			Body:       body,
+			Format:     fm.Format,
+			SourceID:   fm.SourceID,
+			Properties: fm.Properties,
+		}
+		if doc.Title == "" {
+			doc.Title = document.MarkdownTitle(body)
+		}
+		if doc.Title == "" {
+			doc.Title = strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel))
+		}
+		if doc.SourceID == "" {
+			doc.SourceID = rel
+		}
+		docs = append(docs, doc)
+		return nil
+	})
+	return docs, skipped, err
 }
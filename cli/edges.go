@@ -0,0 +1,31 @@
+package cli
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/raystack/compass/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func edgesCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "edge",
+		Aliases: []string{"edges"},
+		Short:   "Manage edges in the knowledge graph",
+		Annotations: map[string]string{
+			"group": "core",
+		},
+		Example: heredoc.Doc(`
+		$ compass edge import -f edges.jsonl
+		$ compass edge export -o edges.csv
+		$ compass edge type list
+		`),
+	}
+
+	cmd.AddCommand(
+		importRecordsCommand(cfg, edgeRecords),
+		exportRecordsCommand(cfg, edgeRecords),
+		edgeTypesCommand(cfg),
+	)
+
+	return cmd
+}
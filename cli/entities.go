@@ -1,13 +1,20 @@
 package cli
 
 import (
+	"encoding/json"
 	"fmt"
+	neturl "net/url"
+	"os"
+	"strings"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/MakeNowJust/heredoc"
+	"github.com/raystack/compass/core/entity"
 	compassv1beta1 "github.com/raystack/compass/gen/raystack/compass/v1beta1"
 	"github.com/raystack/compass/internal/client"
 	"github.com/raystack/compass/internal/config"
+	"github.com/raystack/salt/cli/printer"
 	"github.com/spf13/cobra"
 	"google.golang.org/protobuf/types/known/structpb"
 )
@@ -23,24 +30,38 @@ func entitiesCommand(cfg *config.Config) *cobra.Command {
 		Example: heredoc.Doc(`
 		$ compass entity list
 		$ compass entity view <id>
+		$ compass entity history <urn>
+		$ compass entity properties <urn>
+		$ compass entity diff <urn>
 		$ compass entity upsert
 		$ compass entity delete <urn>
+		$ compass entity restore <urn>
+		$ compass entity merge <urn> --into <urn>
 		$ compass entity search <text>
 		$ compass entity types
 		$ compass entity context <urn>
 		$ compass entity impact <urn>
+		$ compass entity import -f entities.jsonl
+		$ compass entity export -o entities.csv
 		`),
 	}
 
 	cmd.AddCommand(
 		listEntitiesCommand(cfg),
 		viewEntityCommand(cfg),
+		entityHistoryCommand(cfg),
+		entityPropertiesCommand(cfg),
+		entityDiffCommand(cfg),
 		upsertEntityCommand(cfg),
 		deleteEntityCommand(cfg),
+		restoreEntityCommand(cfg),
+		mergeEntityCommand(cfg),
 		searchEntitiesCommand(cfg),
 		entityTypesCommand(cfg),
 		entityContextCommand(cfg),
 		entityImpactCommand(cfg),
+		importRecordsCommand(cfg, entityRecords),
+		exportRecordsCommand(cfg, entityRecords),
 	)
 
 	return cmd
@@ -106,6 +127,184 @@ func viewEntityCommand(cfg *config.Config) *cobra.Command {
 	}
 }
 
+func entityHistoryCommand(cfg *config.Config) *cobra.Command {
+	var out string
+
+	cmd := &cobra.Command{
+		Use:   "history <urn>",
+		Short: "List every version of an entity",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			url := fmt.Sprintf("http://%s/v1/entities/%s/history", cfg.Client.Host, neturl.PathEscape(args[0]))
+
+			body, err := doRequest(cfg, "GET", url, nil)
+			if err != nil {
+				return err
+			}
+
+			var res struct {
+				Data []entity.Entity `json:"data"`
+			}
+			if err := json.Unmarshal(body, &res); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+
+			if out == "json" {
+				fmt.Println(prettyPrint(res.Data))
+				return nil
+			}
+
+			report := [][]string{{"VALID FROM", "VALID TO", "NAME", "SOURCE", "CHANGED BY"}}
+			for _, v := range res.Data {
+				validTo := "current"
+				if v.ValidTo != nil {
+					validTo = v.ValidTo.Format(time.RFC3339)
+				}
+				report = append(report, []string{v.ValidFrom.Format(time.RFC3339), validTo, v.Name, v.Source, v.ChangedBy})
+			}
+			printer.Table(os.Stdout, report)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&out, "out", "o", "table", "Output format: table, json")
+	return cmd
+}
+
+func entityPropertiesCommand(cfg *config.Config) *cobra.Command {
+	var out string
+
+	cmd := &cobra.Command{
+		Use:   "properties <urn>",
+		Short: "List the properties of an entity with the sources of their values",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			url := fmt.Sprintf("http://%s/v1/entities/%s/properties", cfg.Client.Host, neturl.PathEscape(args[0]))
+
+			body, err := doRequest(cfg, "GET", url, nil)
+			if err != nil {
+				return err
+			}
+
+			var res struct {
+				Data []entity.PropertyProvenance `json:"data"`
+			}
+			if err := json.Unmarshal(body, &res); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+
+			if out == "json" {
+				fmt.Println(prettyPrint(res.Data))
+				return nil
+			}
+
+			report := [][]string{{"KEY", "VALUE", "POLICY", "SOURCES"}}
+			for _, p := range res.Data {
+				report = append(report, []string{p.Key, diffValue(p.Value), string(p.Policy), strings.Join(p.Sources, ", ")})
+			}
+			printer.Table(os.Stdout, report)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&out, "out", "o", "table", "Output format: table, json")
+	return cmd
+}
+
+func entityDiffCommand(cfg *config.Config) *cobra.Command {
+	var from, to, out string
+
+	cmd := &cobra.Command{
+		Use:   "diff <urn>",
+		Short: "Show what changed between two versions of an entity",
+		Long: heredoc.Doc(`
+			Show field, property and column-level changes between two versions of an entity.
+
+			--from and --to select the versions live at those instants. By default the
+			current version is compared with the one before it.
+		`),
+		Example: heredoc.Doc(`
+			$ compass entity diff urn:bigquery:orders
+			$ compass entity diff urn:bigquery:orders --from 2026-03-01T00:00:00Z
+		`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			params := neturl.Values{}
+			for name, v := range map[string]string{"from": from, "to": to} {
+				if v == "" {
+					continue
+				}
+				if _, err := time.Parse(time.RFC3339, v); err != nil {
+					return fmt.Errorf("--%s must be an RFC 3339 timestamp", name)
+				}
+				params.Set(name, v)
+			}
+			url := fmt.Sprintf("http://%s/v1/entities/%s/diff?%s", cfg.Client.Host, neturl.PathEscape(args[0]), params.Encode())
+
+			body, err := doRequest(cfg, "GET", url, nil)
+			if err != nil {
+				return err
+			}
+
+			var d entity.EntityDiff
+			if err := json.Unmarshal(body, &d); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+
+			if out == "json" {
+				fmt.Println(prettyPrint(d))
+				return nil
+			}
+
+			fmt.Printf("%s: %s → %s\n", d.URN, d.From.ValidFrom.Format(time.RFC3339), d.To.ValidFrom.Format(time.RFC3339))
+			if d.IsEmpty() {
+				fmt.Println("No changes.")
+				return nil
+			}
+			if len(d.Changes) > 0 {
+				report := [][]string{{"PATH", "CHANGE", "FROM", "TO"}}
+				for _, c := range d.Changes {
+					report = append(report, []string{c.Path, string(c.Kind), diffValue(c.From), diffValue(c.To)})
+				}
+				fmt.Println()
+				printer.Table(os.Stdout, report)
+			}
+			if len(d.Columns) > 0 {
+				report := [][]string{{"COLUMN", "CHANGE", "ATTRIBUTE", "FROM", "TO"}}
+				for _, c := range d.Columns {
+					if len(c.Changes) == 0 {
+						report = append(report, []string{c.Name, string(c.Kind), "", "", ""})
+						continue
+					}
+					for _, attr := range c.Changes {
+						report = append(report, []string{c.Name, string(c.Kind), attr.Path, diffValue(attr.From), diffValue(attr.To)})
+					}
+				}
+				fmt.Println()
+				printer.Table(os.Stdout, report)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&from, "from", "", "RFC 3339 timestamp selecting the older version")
+	cmd.Flags().StringVar(&to, "to", "", "RFC 3339 timestamp selecting the newer version (default: current)")
+	cmd.Flags().StringVarP(&out, "out", "o", "table", "Output format: table, json")
+	return cmd
+}
+
+// diffValue renders a diff value for table output; absent values print empty.
+func diffValue(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
 func upsertEntityCommand(cfg *config.Config) *cobra.Command {
 	var urn, typ, name, desc, source string
 
@@ -170,6 +369,71 @@ func deleteEntityCommand(cfg *config.Config) *cobra.Command {
 	}
 }
 
+func restoreEntityCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <urn>",
+		Short: "Restore a deleted entity and the edges deleted with it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			url := fmt.Sprintf("http://%s/v1/entities/%s/restore", cfg.Client.Host, neturl.PathEscape(args[0]))
+			body, err := doRequest(cfg, "POST", url, nil)
+			if err != nil {
+				return err
+			}
+
+			var resp struct {
+				Entity entity.Entity `json:"entity"`
+				Edges  []entity.Edge `json:"edges"`
+			}
+			if err := json.Unmarshal(body, &resp); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+
+			fmt.Printf("Entity restored: %s (%d edges)\n", resp.Entity.URN, len(resp.Edges))
+			return nil
+		},
+	}
+}
+
+func mergeEntityCommand(cfg *config.Config) *cobra.Command {
+	var into string
+
+	cmd := &cobra.Command{
+		Use:   "merge <urn>",
+		Short: "Merge an entity into another, keeping its URN as an alias",
+		Long: heredoc.Doc(`
+			Merge an entity into another entity, e.g. after the source system
+			renamed or moved it. Its edges, documents and embeddings move to the
+			surviving entity, and reads by its URN resolve to that entity.
+		`),
+		Example: heredoc.Doc(`
+			$ compass entity merge urn:bigquery:proj:table:analytics.orders_v1 --into urn:bigquery:proj:table:analytics.orders
+		`),
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			url := fmt.Sprintf("http://%s/v1/entities/%s/merge", cfg.Client.Host, neturl.PathEscape(args[0]))
+			body, err := doRequest(cfg, "POST", url, map[string]string{"into": into})
+			if err != nil {
+				return err
+			}
+
+			var res entity.MergeResult
+			if err := json.Unmarshal(body, &res); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+
+			fmt.Printf("Merged %s into %s (%d edges, %d documents, %d embeddings, %d columns)\n",
+				res.Alias, res.Entity.URN, res.Edges, res.Documents, res.Embeddings, res.Columns)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&into, "into", "", "URN of the surviving entity")
+	_ = cmd.MarkFlagRequired("into")
+
+	return cmd
+}
+
 func searchEntitiesCommand(cfg *config.Config) *cobra.Command {
 	var types, source, mode string
 	var size uint32
@@ -208,35 +472,86 @@ func searchEntitiesCommand(cfg *config.Config) *cobra.Command {
 }
 
 func entityTypesCommand(cfg *config.Config) *cobra.Command {
-	return &cobra.Command{
+	var out string
+
+	cmd := &cobra.Command{
 		Use:   "types",
 		Short: "List all entity types with counts",
+		Long: heredoc.Doc(`
+			List the entity types in use or registered, with their entity counts
+			and, for registered types, their display name and validation mode.
+		`),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			clnt, err := createEntityClient(cmd, cfg)
+			body, err := doRequest(cfg, "GET", fmt.Sprintf("http://%s/v1/entity-types", cfg.Client.Host), nil)
 			if err != nil {
 				return err
 			}
-			
 
-			req := connect.NewRequest(&compassv1beta1.GetEntityTypesRequest{})
-			res, err := clnt.GetEntityTypes(cmd.Context(), req)
-			if err != nil {
-				return err
+			var res struct {
+				Data []entity.TypeSummary `json:"data"`
+			}
+			if err := json.Unmarshal(body, &res); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+			if out == "json" {
+				fmt.Println(prettyPrint(res.Data))
+				return nil
 			}
 
-			fmt.Println(res.Msg.GetData()); return nil
+			report := [][]string{{"TYPE", "DISPLAY NAME", "COUNT", "MODE"}}
+			for _, t := range res.Data {
+				var name, mode string
+				if t.Definition != nil {
+					name, mode = t.Definition.DisplayName, string(t.Definition.Mode)
+				}
+				report = append(report, []string{string(t.Name), name, fmt.Sprint(t.Count), mode})
+			}
+			printer.Table(os.Stdout, report)
+			return nil
 		},
 	}
+	cmd.Flags().StringVarP(&out, "out", "o", "table", "Output format: table, json")
+	return cmd
 }
 
 func entityContextCommand(cfg *config.Config) *cobra.Command {
 	var depth uint32
+	var asOf, format string
 
 	cmd := &cobra.Command{
 		Use:   "context <urn>",
 		Short: "Get full context subgraph for an entity",
-		Args:  cobra.ExactArgs(1),
+		Example: heredoc.Doc(`
+			$ compass entity context urn:bigquery:shop.orders
+			$ compass entity context urn:bigquery:shop.orders --format mermaid
+			$ compass entity context urn:bigquery:shop.orders --format dot | dot -Tsvg > context.svg
+		`),
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if format != "text" {
+				return printEntityGraph(cfg, args[0], "context", depth, asOf, format)
+			}
+			if asOf != "" {
+				var cg entity.ContextGraph
+				if err := getEntityAsOf(cfg, args[0], "context", depth, asOf, &cg); err != nil {
+					return err
+				}
+				fmt.Printf("Entity: %s (%s) as of %s\n", cg.Entity.Name, cg.Entity.Type, asOf)
+				if len(cg.Edges) > 0 {
+					fmt.Printf("\nRelationships (%d):\n", len(cg.Edges))
+					for _, e := range cg.Edges {
+						fmt.Printf("  %s —[%s]→ %s\n", e.SourceURN, e.Type, e.TargetURN)
+					}
+				}
+				if len(cg.Related) > 0 {
+					fmt.Printf("\nRelated (%d):\n", len(cg.Related))
+					for _, r := range cg.Related {
+						fmt.Printf("  %s (%s) — %s\n", r.Name, r.Type, r.URN)
+					}
+				}
+				return nil
+			}
+
 			clnt, err := createEntityClient(cmd, cfg)
 			if err != nil {
 				return err
@@ -269,17 +584,50 @@ func entityContextCommand(cfg *config.Config) *cobra.Command {
 		},
 	}
 	cmd.Flags().Uint32Var(&depth, "depth", 2, "Traversal depth")
+	cmd.Flags().StringVar(&asOf, "as-of", "", "Show the graph as it stood at this RFC 3339 timestamp")
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text, dot, mermaid or graphml")
 	return cmd
 }
 
 func entityImpactCommand(cfg *config.Config) *cobra.Command {
 	var depth uint32
+	var asOf, format, column string
 
 	cmd := &cobra.Command{
 		Use:   "impact <urn>",
 		Short: "Analyze downstream blast radius",
-		Args:  cobra.ExactArgs(1),
+		Example: heredoc.Doc(`
+			$ compass entity impact urn:bigquery:shop.orders
+			$ compass entity impact urn:bigquery:shop.orders --format mermaid
+			$ compass entity impact urn:bigquery:shop.orders --format graphml > impact.graphml
+			$ compass entity impact urn:bigquery:shop.orders --column customer_id
+		`),
+		Args: cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if column != "" {
+				args[0] = entity.ColumnURN(args[0], column)
+			}
+			if format != "text" {
+				return printEntityGraph(cfg, args[0], "impact", depth, asOf, format)
+			}
+			if asOf != "" {
+				var res struct {
+					Data []entity.Edge `json:"data"`
+				}
+				if err := getEntityAsOf(cfg, args[0], "impact", depth, asOf, &res); err != nil {
+					return err
+				}
+				if len(res.Data) == 0 {
+					fmt.Printf("No downstream dependencies found as of %s.\n", asOf)
+					return nil
+				}
+				fmt.Printf("Impact as of %s (%d edges):\n", asOf, len(res.Data))
+				for _, e := range res.Data {
+					fmt.Printf("  %s → %s\n", e.SourceURN, e.TargetURN)
+				}
+				return nil
+			}
+
 			clnt, err := createEntityClient(cmd, cfg)
 			if err != nil {
 				return err
@@ -307,9 +655,59 @@ func entityImpactCommand(cfg *config.Config) *cobra.Command {
 		},
 	}
 	cmd.Flags().Uint32Var(&depth, "depth", 3, "Traversal depth")
+	cmd.Flags().StringVar(&asOf, "as-of", "", "Show the impact as it stood at this RFC 3339 timestamp")
+	cmd.Flags().StringVar(&format, "format", "text", "Output format: text, dot, mermaid or graphml")
+	cmd.Flags().StringVar(&column, "column", "", "Analyze the impact of one column, following column lineage")
 	return cmd
 }
 
+// getEntityAsOf fetches a point-in-time entity view (context or impact) over HTTP.
+func getEntityAsOf(cfg *config.Config, urn, view string, depth uint32, asOf string, v interface{}) error {
+	if _, err := time.Parse(time.RFC3339, asOf); err != nil {
+		return fmt.Errorf("--as-of must be an RFC 3339 timestamp, e.g. 2026-03-01T09:30:00Z")
+	}
+	body, err := doRequest(cfg, "GET", entityViewURL(cfg, urn, view, depth, asOf, ""), nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	return nil
+}
+
+// printEntityGraph prints an entity view (context or impact) rendered by the
+// server as DOT, Mermaid or GraphML.
+func printEntityGraph(cfg *config.Config, urn, view string, depth uint32, asOf, format string) error {
+	f, err := entity.ParseGraphFormat(format)
+	if err != nil {
+		return err
+	}
+	if asOf != "" {
+		if _, err := time.Parse(time.RFC3339, asOf); err != nil {
+			return fmt.Errorf("--as-of must be an RFC 3339 timestamp, e.g. 2026-03-01T09:30:00Z")
+		}
+	}
+	body, err := doRequest(cfg, "GET", entityViewURL(cfg, urn, view, depth, asOf, string(f)), nil)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(body)
+	return err
+}
+
+func entityViewURL(cfg *config.Config, urn, view string, depth uint32, asOf, format string) string {
+	params := neturl.Values{}
+	params.Set("depth", fmt.Sprint(depth))
+	if asOf != "" {
+		params.Set("as_of", asOf)
+	}
+	if format != "" {
+		params.Set("format", format)
+	}
+	return fmt.Sprintf("http://%s/v1/entities/%s/%s?%s", cfg.Client.Host, neturl.PathEscape(urn), view, params.Encode())
+}
+
 func createEntityClient(cmd *cobra.Command, cfg *config.Config) (*client.Client, error) {
 	clnt, err := client.Create(cmd.Context(), cfg.Client)
 	if err != nil {
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/raystack/compass/core/batch"
+	"github.com/raystack/compass/core/dbt"
+	"github.com/raystack/compass/internal/config"
+	"github.com/spf13/cobra"
+)
+
+func importCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: "Import metadata from other tools",
+		Annotations: map[string]string{
+			"group": "core",
+		},
+		Example: heredoc.Doc(`
+		$ compass import dbt --manifest target/manifest.json --catalog target/catalog.json
+		`),
+	}
+
+	cmd.AddCommand(
+		importDBTCommand(cfg),
+	)
+
+	return cmd
+}
+
+func importDBTCommand(cfg *config.Config) *cobra.Command {
+	var manifestPath, catalogPath string
+	var dryRun bool
+
+	cmd := &cobra.Command{
+		Use:   "dbt",
+		Short: "Import models, sources, exposures and metrics from dbt artifacts",
+		Long: heredoc.Doc(`
+			Import a dbt project from the manifest.json written by every dbt command
+			and, optionally, the catalog.json written by 'dbt docs generate'.
+
+			Models, seeds, snapshots and sources become table entities keyed by their
+			warehouse relation, exposures and metrics become entities of their own,
+			dependencies become lineage edges, and model docs become documents.
+			Each batch of up to 10000 records is written in one transaction.
+		`),
+		Example: heredoc.Doc(`
+		$ compass import dbt --manifest target/manifest.json
+		$ compass import dbt --manifest target/manifest.json --catalog target/catalog.json --dry-run
+		`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			f, err := os.Open(manifestPath)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			manifest, err := dbt.ReadManifest(f)
+			if err != nil {
+				return err
+			}
+
+			var catalog *dbt.Catalog
+			if catalogPath != "" {
+				f, err := os.Open(catalogPath)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				if catalog, err = dbt.ReadCatalog(f); err != nil {
+					return err
+				}
+			}
+
+			res := dbt.Import(manifest, catalog)
+			if dryRun {
+				fmt.Printf("Would import %d entities, %d edges and %d documents\n", len(res.Entities), len(res.Edges), len(res.Documents))
+				return nil
+			}
+
+			url := fmt.Sprintf("http://%s/v1/commit", cfg.Client.Host)
+			ops := res.Operations()
+			for start := 0; start < len(ops); start += batch.MaxOperations {
+				end := min(start+batch.MaxOperations, len(ops))
+				payload := map[string]interface{}{"operations": ops[start:end]}
+				if _, err := doRequest(cfg, "POST", url, payload); err != nil {
+					return fmt.Errorf("import records %d-%d: %w", start, end-1, err)
+				}
+			}
+
+			fmt.Printf("Imported %d entities, %d edges and %d documents\n", len(res.Entities), len(res.Edges), len(res.Documents))
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&manifestPath, "manifest", "", "Path to manifest.json (required)")
+	cmd.Flags().StringVar(&catalogPath, "catalog", "", "Path to catalog.json")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print what would be imported without writing")
+	_ = cmd.MarkFlagRequired("manifest")
+	return cmd
+}
@@ -27,6 +27,8 @@ func namespacesCommand(cfg *config.Config) *cobra.Command {
 			$ compass namespace list
 			$ compass namespace view
 			$ compass namespace create
+			$ compass namespace backup <name> -o <dir>
+			$ compass namespace restore <dir>
 		`),
 	}
 
@@ -34,6 +36,8 @@ func namespacesCommand(cfg *config.Config) *cobra.Command {
 		listNamespacesCommand(cfg),
 		getNamespaceCommand(cfg),
 		createNamespaceCommand(cfg),
+		backupNamespaceCommand(cfg),
+		restoreNamespaceCommand(cfg),
 	)
 	return cmd
 }
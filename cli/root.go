@@ -57,7 +57,10 @@ func New(cliConfig *config.Config) *cobra.Command {
 		configCommand(cliConfig),
 		namespacesCommand(cliConfig),
 		entitiesCommand(cliConfig),
+		typesCommand(cliConfig),
+		edgesCommand(cliConfig),
 		documentsCommand(cliConfig),
+		importCommand(cliConfig),
 		embedCommand(cliConfig),
 		versionCmd(),
 	)
@@ -0,0 +1,610 @@
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/raystack/compass/core/document"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/internal/config"
+	"github.com/spf13/cobra"
+)
+
+const (
+	formatJSONL = "jsonl"
+	formatCSV   = "csv"
+
+	exportPageSize          = 500
+	documentCheckpointEvery = 100
+	maxImportLineBytes      = 16 << 20
+)
+
+// recordKind describes a kind of record that can be imported and exported.
+type recordKind struct {
+	name string
+	// bulkKey is the key records of this kind are wrapped in for /v1/bulk;
+	// empty for kinds written one at a time.
+	bulkKey string
+	// listPath is the endpoint pages of records are exported from.
+	listPath string
+	// columns are the CSV columns written on export.
+	columns []string
+}
+
+var (
+	entityRecords = recordKind{
+		name:     "entity",
+		bulkKey:  "entity",
+		listPath: "/v1/entities",
+		columns:  []string{"urn", "type", "name", "description", "source", "scope", "properties"},
+	}
+	edgeRecords = recordKind{
+		name:     "edge",
+		bulkKey:  "edge",
+		listPath: "/v1/edges",
+		columns:  []string{"source_urn", "target_urn", "type", "source", "scope", "properties"},
+	}
+	documentRecords = recordKind{
+		name:     "document",
+		listPath: "/v1/documents",
+		columns:  []string{"entity_urn", "title", "body", "format", "source", "source_id", "properties"},
+	}
+)
+
+var errResponseEnded = errors.New("response ended")
+
+// importSummary counts the outcome of every imported record.
+type importSummary struct {
+	Created, Updated, Unchanged, Failed int
+}
+
+func (s *importSummary) add(status entity.UpsertStatus) {
+	switch status {
+	case entity.UpsertCreated:
+		s.Created++
+	case entity.UpsertUpdated:
+		s.Updated++
+	case entity.UpsertUnchanged:
+		s.Unchanged++
+	default:
+		s.Failed++
+	}
+}
+
+func importRecordsCommand(cfg *config.Config, kind recordKind) *cobra.Command {
+	var file, format string
+	var batchSize int
+	var dryRun, resume bool
+
+	cmd := &cobra.Command{
+		Use:   "import",
+		Short: fmt.Sprintf("Import %s records from a JSONL or CSV file", kind.name),
+		Long: heredoc.Docf(`
+			Import %[1]s records from a file with one JSON object per line, or a CSV
+			file whose header names the fields. A CSV properties column holds a JSON
+			object.
+
+			Progress is saved to <file>.checkpoint as records are acknowledged. If an
+			import is interrupted, run it again with --resume to skip the records that
+			were already written. Records the server rejects are reported and counted
+			as failed; they are not retried.
+		`, kind.name),
+		Example: heredoc.Docf(`
+			$ compass %[1]s import -f %[1]s.jsonl
+			$ compass %[1]s import -f %[1]s.csv --dry-run
+			$ compass %[1]s import -f %[1]s.jsonl --resume
+		`, kind.name),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format == "" {
+				format = formatFromPath(file)
+			}
+			if format != formatJSONL && format != formatCSV {
+				return fmt.Errorf("unsupported format %q: use jsonl or csv", format)
+			}
+			if kind.bulkKey != "" && batchSize <= 0 {
+				return errors.New("batch-size must be positive")
+			}
+
+			ckpt := checkpoint{path: file + ".checkpoint"}
+			skip := 0
+			if resume {
+				n, err := ckpt.load()
+				if err != nil {
+					return err
+				}
+				skip = n
+			}
+			if dryRun {
+				ckpt.path = ""
+			}
+
+			var sum importSummary
+			var err error
+			if kind.bulkKey != "" {
+				sum, err = importBulk(cmd.Context(), cfg, kind, file, format, skip, batchSize, dryRun, ckpt)
+			} else {
+				sum, err = importDocuments(cfg, file, format, skip, dryRun, ckpt)
+			}
+
+			verb := "Imported"
+			if dryRun {
+				verb = "Dry run"
+			}
+			fmt.Printf("%s: %d created, %d updated, %d unchanged, %d failed\n", verb, sum.Created, sum.Updated, sum.Unchanged, sum.Failed)
+			if err != nil {
+				return err
+			}
+			ckpt.clear()
+			if sum.Failed > 0 {
+				return fmt.Errorf("%d records failed", sum.Failed)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "File to import (required)")
+	cmd.Flags().StringVar(&format, "format", "", "File format: jsonl or csv (default: from the file extension)")
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Report what would be created, updated or unchanged without writing")
+	cmd.Flags().BoolVar(&resume, "resume", false, "Skip the records acknowledged by an interrupted import")
+	if kind.bulkKey != "" {
+		cmd.Flags().IntVar(&batchSize, "batch-size", 500, "Records written per transaction")
+	}
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func exportRecordsCommand(cfg *config.Config, kind recordKind) *cobra.Command {
+	var out, format string
+	filters := map[string]*string{}
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: fmt.Sprintf("Export %s records as JSONL or CSV", kind.name),
+		Example: heredoc.Docf(`
+			$ compass %[1]s export -o %[1]s.jsonl
+			$ compass %[1]s export --format csv > %[1]s.csv
+		`, kind.name),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			w := io.Writer(os.Stdout)
+			if out != "" {
+				f, err := os.Create(out)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+				if format == "" {
+					format = formatFromPath(out)
+				}
+			}
+			if format == "" {
+				format = formatJSONL
+			}
+			if format != formatJSONL && format != formatCSV {
+				return fmt.Errorf("unsupported format %q: use jsonl or csv", format)
+			}
+
+			params := neturl.Values{}
+			for k, v := range filters {
+				if *v != "" {
+					params.Set(k, *v)
+				}
+			}
+			n, err := exportRecords(cfg, kind, params, format, w)
+			if err != nil {
+				return err
+			}
+			if out != "" {
+				fmt.Printf("Exported %d %s records to %s\n", n, kind.name, out)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&out, "output", "o", "", "Output file (default: stdout)")
+	cmd.Flags().StringVar(&format, "format", "", "Output format: jsonl or csv (default: from the output extension, else jsonl)")
+	switch kind.name {
+	case entityRecords.name:
+		filters["types"] = cmd.Flags().String("types", "", "Filter by types (comma-separated)")
+		filters["source"] = cmd.Flags().String("source", "", "Filter by source")
+	case edgeRecords.name:
+		filters["types"] = cmd.Flags().String("types", "", "Filter by edge types (comma-separated)")
+	case documentRecords.name:
+		filters["entity_urn"] = cmd.Flags().String("entity-urn", "", "Filter by entity URN")
+		filters["source"] = cmd.Flags().String("source", "", "Filter by source")
+	}
+	return cmd
+}
+
+// importBulk streams the records to /v1/bulk, reading the per-record results
+// while the rest of the file is still being sent.
+func importBulk(ctx context.Context, cfg *config.Config, kind recordKind, file, format string, skip, batchSize int, dryRun bool, ckpt checkpoint) (importSummary, error) {
+	var sum importSummary
+	f, err := os.Open(file)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+
+	pr, pw := io.Pipe()
+	type sendResult struct {
+		n   int
+		err error
+	}
+	sent := make(chan sendResult, 1)
+	go func() {
+		n := 0
+		bw := bufio.NewWriter(pw)
+		err := eachRecord(f, format, func(i int, rec json.RawMessage) error {
+			if i < skip {
+				return nil
+			}
+			n++
+			_, err := fmt.Fprintf(bw, `{%q:%s}`+"\n", kind.bulkKey, rec)
+			return err
+		})
+		if err == nil {
+			err = bw.Flush()
+		}
+		sent <- sendResult{n: n, err: err}
+		pw.CloseWithError(err)
+	}()
+
+	params := neturl.Values{}
+	params.Set("batch_size", strconv.Itoa(batchSize))
+	if dryRun {
+		params.Set("dry_run", "true")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, fmt.Sprintf("http://%s/v1/bulk?%s", cfg.Client.Host, params.Encode()), pr)
+	if err != nil {
+		return sum, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	req.Header.Set(cfg.Client.ServerHeaderKeyUserUUID, cfg.Client.ServerHeaderValueUserUUID)
+
+	// No client timeout: a large import streams for as long as it takes.
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return sum, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		body, _ := io.ReadAll(resp.Body)
+		return sum, fmt.Errorf("server error (%d): %s", resp.StatusCode, string(body))
+	}
+
+	acked := skip
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+	for scanner.Scan() {
+		var res entity.BulkResult
+		if err := json.Unmarshal(scanner.Bytes(), &res); err != nil {
+			continue
+		}
+		sum.add(res.Status)
+		if res.Error != "" {
+			fmt.Fprintf(os.Stderr, "record %d: %s\n", skip+res.Index+1, res.Error)
+		}
+		acked = skip + res.Index + 1
+		if (acked-skip)%batchSize == 0 {
+			ckpt.save(acked)
+		}
+	}
+	readErr := scanner.Err()
+	// Unblock the sender if the server stopped reading.
+	pr.CloseWithError(errResponseEnded)
+	res := <-sent
+	total := skip + res.n
+
+	if res.err != nil && !errors.Is(res.err, errResponseEnded) {
+		ckpt.save(acked)
+		return sum, fmt.Errorf("read %s: %w", file, res.err)
+	}
+	if readErr != nil || acked < total {
+		ckpt.save(acked)
+		if readErr == nil {
+			readErr = errors.New("response ended early")
+		}
+		return sum, fmt.Errorf("import stopped after %d of %d records (%w); rerun with --resume to continue", acked, total, readErr)
+	}
+	return sum, nil
+}
+
+// importDocuments writes the documents one at a time. Each is first compared
+// with the document stored under the same entity, source and source ID, so
+// unchanged documents are skipped rather than re-embedded.
+func importDocuments(cfg *config.Config, file, format string, skip int, dryRun bool, ckpt checkpoint) (importSummary, error) {
+	var sum importSummary
+	f, err := os.Open(file)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+
+	url := fmt.Sprintf("http://%s/v1/documents", cfg.Client.Host)
+	existing := map[string][]document.Document{}
+	acked := skip
+	err = eachRecord(f, format, func(i int, rec json.RawMessage) error {
+		if i < skip {
+			return nil
+		}
+		status, err := importDocument(cfg, url, rec, existing, dryRun)
+		if err != nil {
+			var urlErr *neturl.Error
+			if errors.As(err, &urlErr) {
+				return err
+			}
+			fmt.Fprintf(os.Stderr, "record %d: %s\n", i+1, err)
+		}
+		sum.add(status)
+		acked = i + 1
+		if (acked-skip)%documentCheckpointEvery == 0 {
+			ckpt.save(acked)
+		}
+		return nil
+	})
+	if err != nil {
+		ckpt.save(acked)
+		return sum, fmt.Errorf("import stopped after %d records (%w); rerun with --resume to continue", acked, err)
+	}
+	return sum, nil
+}
+
+func importDocument(cfg *config.Config, url string, rec json.RawMessage, existing map[string][]document.Document, dryRun bool) (entity.UpsertStatus, error) {
+	var doc document.Document
+	if err := json.Unmarshal(rec, &doc); err != nil {
+		return entity.UpsertFailed, fmt.Errorf("invalid json: %w", err)
+	}
+	if doc.EntityURN == "" || doc.Title == "" || doc.Body == "" {
+		return entity.UpsertFailed, errors.New("entity_urn, title and body are required")
+	}
+	// Convert HTML and Confluence bodies here too, so that a re-import can
+	// be compared with the stored markdown.
+	if err := document.Normalize(&doc); err != nil {
+		return entity.UpsertFailed, err
+	}
+
+	docs, ok := existing[doc.EntityURN]
+	if !ok {
+		// Keep the cache small; files are usually grouped by entity.
+		if len(existing) >= 1000 {
+			clear(existing)
+		}
+		body, err := doRequest(cfg, "GET", url+"?size=1000&entity_urn="+neturl.QueryEscape(doc.EntityURN), nil)
+		if err != nil {
+			return entity.UpsertFailed, err
+		}
+		var res struct {
+			Data []document.Document `json:"data"`
+		}
+		if err := json.Unmarshal(body, &res); err != nil {
+			return entity.UpsertFailed, fmt.Errorf("decode response: %w", err)
+		}
+		docs = res.Data
+		existing[doc.EntityURN] = docs
+	}
+
+	status := entity.UpsertCreated
+	for _, cur := range docs {
+		if cur.Source != doc.Source || cur.SourceID != doc.SourceID {
+			continue
+		}
+		status = entity.UpsertUpdated
+		if cur.Title == doc.Title && cur.Body == doc.Body && cur.Format == doc.Format && sameJSONMap(cur.Properties, doc.Properties) {
+			status = entity.UpsertUnchanged
+		}
+		break
+	}
+	if dryRun || status == entity.UpsertUnchanged {
+		return status, nil
+	}
+
+	payload := map[string]interface{}{
+		"entity_urn": doc.EntityURN,
+		"title":      doc.Title,
+		"body":       doc.Body,
+		"format":     doc.Format,
+		"source":     doc.Source,
+		"source_id":  doc.SourceID,
+		"properties": doc.Properties,
+	}
+	if _, err := doRequest(cfg, "POST", url, payload); err != nil {
+		return entity.UpsertFailed, err
+	}
+	delete(existing, doc.EntityURN)
+	return status, nil
+}
+
+// exportRecords pages through the kind's list endpoint and writes every
+// record. It returns the number of records written.
+func exportRecords(cfg *config.Config, kind recordKind, params neturl.Values, format string, w io.Writer) (int, error) {
+	var cw *csv.Writer
+	if format == formatCSV {
+		cw = csv.NewWriter(w)
+		if err := cw.Write(kind.columns); err != nil {
+			return 0, err
+		}
+	}
+
+	n := 0
+	for offset := 0; ; offset += exportPageSize {
+		params.Set("size", strconv.Itoa(exportPageSize))
+		params.Set("offset", strconv.Itoa(offset))
+		body, err := doRequest(cfg, "GET", fmt.Sprintf("http://%s%s?%s", cfg.Client.Host, kind.listPath, params.Encode()), nil)
+		if err != nil {
+			return n, err
+		}
+		var page struct {
+			Data []map[string]json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(body, &page); err != nil {
+			return n, fmt.Errorf("decode response: %w", err)
+		}
+
+		for _, rec := range page.Data {
+			if cw != nil {
+				err = cw.Write(csvRow(kind.columns, rec))
+			} else {
+				var line []byte
+				if line, err = json.Marshal(rec); err == nil {
+					_, err = fmt.Fprintf(w, "%s\n", line)
+				}
+			}
+			if err != nil {
+				return n, err
+			}
+			n++
+		}
+		if len(page.Data) < exportPageSize {
+			break
+		}
+	}
+	if cw != nil {
+		cw.Flush()
+		return n, cw.Error()
+	}
+	return n, nil
+}
+
+// eachRecord calls fn with the zero-based position and JSON of each record in
+// r. JSONL lines are passed as they are, so that the server reports malformed
+// ones against their position; CSV rows are converted to objects keyed by the
+// header.
+func eachRecord(r io.Reader, format string, fn func(int, json.RawMessage) error) error {
+	if format == formatCSV {
+		cr := csv.NewReader(r)
+		header, err := cr.Read()
+		if err != nil {
+			return fmt.Errorf("read csv header: %w", err)
+		}
+		for i := 0; ; i++ {
+			row, err := cr.Read()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("read csv: %w", err)
+			}
+			rec, err := json.Marshal(csvRecord(header, row))
+			if err != nil {
+				return err
+			}
+			if err := fn(i, rec); err != nil {
+				return err
+			}
+		}
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineBytes)
+	i := 0
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(i, append(json.RawMessage(nil), line...)); err != nil {
+			return err
+		}
+		i++
+	}
+	return scanner.Err()
+}
+
+// csvRecord converts a CSV row to a JSON object. Empty cells are left out and
+// the properties cell is decoded as JSON.
+func csvRecord(header, row []string) map[string]interface{} {
+	rec := make(map[string]interface{}, len(header))
+	for i, col := range header {
+		if i >= len(row) || row[i] == "" {
+			continue
+		}
+		col = strings.TrimSpace(col)
+		if col == "properties" && json.Valid([]byte(row[i])) {
+			rec[col] = json.RawMessage(row[i])
+			continue
+		}
+		rec[col] = row[i]
+	}
+	return rec
+}
+
+// csvRow picks the columns from a record. Strings are written as they are,
+// other values as JSON.
+func csvRow(columns []string, rec map[string]json.RawMessage) []string {
+	row := make([]string, len(columns))
+	for i, col := range columns {
+		raw, ok := rec[col]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			row[i] = s
+			continue
+		}
+		row[i] = string(raw)
+	}
+	return row
+}
+
+func formatFromPath(path string) string {
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return formatCSV
+	}
+	return formatJSONL
+}
+
+func sameJSONMap(a, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// checkpoint records how many records of a file have been acknowledged. An
+// empty path disables it.
+type checkpoint struct {
+	path string
+}
+
+func (c checkpoint) load() (int, error) {
+	data, err := os.ReadFile(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read checkpoint: %w", err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("invalid checkpoint %s: %w", c.path, err)
+	}
+	return n, nil
+}
+
+func (c checkpoint) save(n int) {
+	if c.path == "" {
+		return
+	}
+	_ = os.WriteFile(c.path, []byte(strconv.Itoa(n)+"\n"), 0o644)
+}
+
+func (c checkpoint) clear() {
+	if c.path == "" {
+		return
+	}
+	_ = os.Remove(c.path)
+}
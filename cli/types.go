@@ -0,0 +1,316 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/internal/config"
+	"github.com/raystack/salt/cli/printer"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func typesCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "type",
+		Aliases: []string{"types"},
+		Short:   "Manage entity type definitions",
+		Long: heredoc.Doc(`
+			Register entity types with a display name, an icon, and the JSON Schema
+			and required properties their entities are validated against.
+		`),
+		Annotations: map[string]string{
+			"group": "core",
+		},
+		Example: heredoc.Doc(`
+			$ compass type list
+			$ compass type view table
+			$ compass type register -f table.yaml
+			$ compass type delete table
+		`),
+	}
+
+	cmd.AddCommand(
+		listTypesCommand(cfg),
+		viewTypeCommand(cfg),
+		registerTypeCommand(cfg),
+		deleteTypeCommand(cfg),
+	)
+
+	return cmd
+}
+
+func listTypesCommand(cfg *config.Config) *cobra.Command {
+	var out string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List registered entity types",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := doRequest(cfg, "GET", fmt.Sprintf("http://%s/v1/types", cfg.Client.Host), nil)
+			if err != nil {
+				return err
+			}
+
+			var res struct {
+				Data []entity.TypeDefinition `json:"data"`
+			}
+			if err := json.Unmarshal(body, &res); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+			if out == "json" {
+				fmt.Println(prettyPrint(res.Data))
+				return nil
+			}
+
+			report := [][]string{{"TYPE", "DISPLAY NAME", "ICON", "MODE", "REQUIRED"}}
+			for _, d := range res.Data {
+				report = append(report, []string{string(d.Type), d.DisplayName, d.Icon, string(d.Mode), strings.Join(d.Required, ",")})
+			}
+			printer.Table(os.Stdout, report)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&out, "out", "o", "table", "Output format: table, json")
+	return cmd
+}
+
+func viewTypeCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "view <type>",
+		Short: "View an entity type definition",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			url := fmt.Sprintf("http://%s/v1/types/%s", cfg.Client.Host, neturl.PathEscape(args[0]))
+			body, err := doRequest(cfg, "GET", url, nil)
+			if err != nil {
+				return err
+			}
+
+			var def entity.TypeDefinition
+			if err := json.Unmarshal(body, &def); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+			fmt.Println(prettyPrint(def))
+			return nil
+		},
+	}
+}
+
+func registerTypeCommand(cfg *config.Config) *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "register",
+		Short: "Create or replace an entity type definition",
+		Long: heredoc.Doc(`
+			Register an entity type from a YAML or JSON file holding its type,
+			display_name, icon, schema, required and mode. Mode is reject (the
+			default), which fails writes of entities that do not match, or warn,
+			which writes them and logs the mismatch.
+		`),
+		Example: heredoc.Doc(`
+			$ compass type register -f table.yaml
+		`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("read definition: %w", err)
+			}
+			var def map[string]interface{}
+			if err := yaml.Unmarshal(data, &def); err != nil {
+				return fmt.Errorf("decode definition: %w", err)
+			}
+
+			body, err := doRequest(cfg, "POST", fmt.Sprintf("http://%s/v1/types", cfg.Client.Host), def)
+			if err != nil {
+				return err
+			}
+
+			var res entity.TypeDefinition
+			if err := json.Unmarshal(body, &res); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+			fmt.Printf("Registered type %s (mode: %s)\n", res.Type, res.Mode)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Definition file, YAML or JSON (required)")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func deleteTypeCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <type>",
+		Short: "Delete an entity type definition",
+		Long:  "Delete an entity type definition. Entities of the type are kept and are no longer validated.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			url := fmt.Sprintf("http://%s/v1/types/%s", cfg.Client.Host, neturl.PathEscape(args[0]))
+			if _, err := doRequest(cfg, "DELETE", url, nil); err != nil {
+				return err
+			}
+			fmt.Printf("Deleted type %s\n", args[0])
+			return nil
+		},
+	}
+}
+
+func edgeTypesCommand(cfg *config.Config) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "type",
+		Aliases: []string{"types"},
+		Short:   "Manage edge type declarations",
+		Long: heredoc.Doc(`
+			Declare edge types with the entity types they may connect, whether
+			they carry lineage, an inverse name and a cardinality.
+		`),
+		Example: heredoc.Doc(`
+			$ compass edge type list
+			$ compass edge type view owned_by
+			$ compass edge type register -f owned_by.yaml
+			$ compass edge type delete owned_by
+		`),
+	}
+
+	cmd.AddCommand(
+		listEdgeTypesCommand(cfg),
+		viewEdgeTypeCommand(cfg),
+		registerEdgeTypeCommand(cfg),
+		deleteEdgeTypeCommand(cfg),
+	)
+
+	return cmd
+}
+
+func listEdgeTypesCommand(cfg *config.Config) *cobra.Command {
+	var out string
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List declared edge types",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := doRequest(cfg, "GET", fmt.Sprintf("http://%s/v1/edge-types", cfg.Client.Host), nil)
+			if err != nil {
+				return err
+			}
+
+			var res struct {
+				Data []entity.EdgeTypeDefinition `json:"data"`
+			}
+			if err := json.Unmarshal(body, &res); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+			if out == "json" {
+				fmt.Println(prettyPrint(res.Data))
+				return nil
+			}
+
+			report := [][]string{{"TYPE", "SOURCE TYPES", "TARGET TYPES", "LINEAGE", "INVERSE", "CARDINALITY"}}
+			for _, d := range res.Data {
+				report = append(report, []string{d.Type, joinTypes(d.SourceTypes), joinTypes(d.TargetTypes),
+					fmt.Sprint(d.Lineage), d.Inverse, string(d.Cardinality)})
+			}
+			printer.Table(os.Stdout, report)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&out, "out", "o", "table", "Output format: table, json")
+	return cmd
+}
+
+func joinTypes(types []entity.Type) string {
+	s := make([]string, len(types))
+	for i, t := range types {
+		s[i] = string(t)
+	}
+	return strings.Join(s, ",")
+}
+
+func viewEdgeTypeCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "view <type>",
+		Short: "View an edge type declaration",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			url := fmt.Sprintf("http://%s/v1/edge-types/%s", cfg.Client.Host, neturl.PathEscape(args[0]))
+			body, err := doRequest(cfg, "GET", url, nil)
+			if err != nil {
+				return err
+			}
+
+			var def entity.EdgeTypeDefinition
+			if err := json.Unmarshal(body, &def); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+			fmt.Println(prettyPrint(def))
+			return nil
+		},
+	}
+}
+
+func registerEdgeTypeCommand(cfg *config.Config) *cobra.Command {
+	var file string
+
+	cmd := &cobra.Command{
+		Use:   "register",
+		Short: "Create or replace an edge type declaration",
+		Long: heredoc.Doc(`
+			Declare an edge type from a YAML or JSON file holding its type,
+			source_types, target_types, lineage, inverse and cardinality.
+			Cardinality is many_to_many (the default), many_to_one, one_to_many
+			or one_to_one.
+		`),
+		Example: heredoc.Doc(`
+			$ compass edge type register -f owned_by.yaml
+		`),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("read declaration: %w", err)
+			}
+			var def map[string]interface{}
+			if err := yaml.Unmarshal(data, &def); err != nil {
+				return fmt.Errorf("decode declaration: %w", err)
+			}
+
+			body, err := doRequest(cfg, "POST", fmt.Sprintf("http://%s/v1/edge-types", cfg.Client.Host), def)
+			if err != nil {
+				return err
+			}
+
+			var res entity.EdgeTypeDefinition
+			if err := json.Unmarshal(body, &res); err != nil {
+				return fmt.Errorf("decode response: %w", err)
+			}
+			fmt.Printf("Registered edge type %s (cardinality: %s)\n", res.Type, res.Cardinality)
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&file, "file", "f", "", "Declaration file, YAML or JSON (required)")
+	_ = cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+func deleteEdgeTypeCommand(cfg *config.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete <type>",
+		Short: "Delete an edge type declaration",
+		Long:  "Delete an edge type declaration. Edges of the type are kept, no longer checked, and followed by impact analysis.",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			url := fmt.Sprintf("http://%s/v1/edge-types/%s", cfg.Client.Host, neturl.PathEscape(args[0]))
+			if _, err := doRequest(cfg, "DELETE", url, nil); err != nil {
+				return err
+			}
+			fmt.Printf("Deleted edge type %s\n", args[0])
+			return nil
+		},
+	}
+}
@@ -0,0 +1,360 @@
+package backup
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/raystack/compass/core/document"
+	"github.com/raystack/compass/core/embedding"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+)
+
+// FormatVersion is the version of the archive layout written by this
+// package. Archives with a newer version are refused. Version 2 added entity
+// and edge type definitions and entity aliases; version 1 archives are read
+// as holding none.
+const FormatVersion = 2
+
+// Files of an archive. Each record file holds one JSON object per line.
+const (
+	ManifestFile   = "manifest.json"
+	EntitiesFile   = "entities.jsonl"
+	EdgesFile      = "edges.jsonl"
+	DocumentsFile  = "documents.jsonl"
+	PrincipalsFile = "principals.jsonl"
+	EmbeddingsFile = "embeddings.jsonl"
+
+	EntityTypesFile = "entity_types.jsonl"
+	EdgeTypesFile   = "edge_types.jsonl"
+	AliasesFile     = "aliases.jsonl"
+)
+
+var (
+	// ErrUnsupportedVersion is returned when reading an archive written in a
+	// format this version does not know.
+	ErrUnsupportedVersion = errors.New("unsupported archive format version")
+	// ErrCorrupt is returned when the records of an archive do not match its
+	// manifest.
+	ErrCorrupt = errors.New("archive is corrupt")
+)
+
+// Manifest describes an archive. It is written last, so a directory without
+// one is an incomplete backup.
+type Manifest struct {
+	FormatVersion int                 `json:"format_version"`
+	Namespace     namespace.Namespace `json:"namespace"`
+	CreatedAt     time.Time           `json:"created_at"`
+	Counts        Counts              `json:"counts"`
+	// Embeddings reports whether the archive holds embeddings.
+	Embeddings bool `json:"embeddings"`
+}
+
+// Counts is the number of records of each kind.
+type Counts struct {
+	Entities    int `json:"entities"`
+	Edges       int `json:"edges"`
+	Documents   int `json:"documents"`
+	Principals  int `json:"principals"`
+	Embeddings  int `json:"embeddings"`
+	EntityTypes int `json:"entity_types"`
+	EdgeTypes   int `json:"edge_types"`
+	Aliases     int `json:"aliases"`
+}
+
+// Principal is a principal as archived, timestamps included.
+type Principal struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`
+	Name      string         `json:"name,omitempty"`
+	Subject   string         `json:"subject"`
+	Metadata  map[string]any `json:"metadata,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// Alias is the URN of an entity merged into another, as archived.
+type Alias struct {
+	Alias     string    `json:"alias"`
+	URN       string    `json:"urn"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Sink receives the records of a namespace.
+type Sink interface {
+	WriteEntity(entity.Entity) error
+	WriteEdge(entity.Edge) error
+	WriteDocument(document.Document) error
+	WritePrincipal(Principal) error
+	WriteEmbedding(embedding.Embedding) error
+	WriteEntityType(entity.TypeDefinition) error
+	WriteEdgeType(entity.EdgeTypeDefinition) error
+	WriteAlias(Alias) error
+}
+
+// Source yields the records of a namespace, calling fn once per record.
+type Source interface {
+	Entities(fn func(entity.Entity) error) error
+	Edges(fn func(entity.Edge) error) error
+	Documents(fn func(document.Document) error) error
+	Principals(fn func(Principal) error) error
+	Embeddings(fn func(embedding.Embedding) error) error
+	EntityTypes(fn func(entity.TypeDefinition) error) error
+	EdgeTypes(fn func(entity.EdgeTypeDefinition) error) error
+	Aliases(fn func(Alias) error) error
+}
+
+// Writer writes an archive to a directory. It is a Sink.
+type Writer struct {
+	dir      string
+	manifest Manifest
+	files    map[string]*recordFile
+}
+
+type recordFile struct {
+	f   *os.File
+	buf *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewWriter creates dir, if needed, and starts an archive of ns in it. The
+// directory must not hold another archive.
+func NewWriter(dir string, ns namespace.Namespace) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, ManifestFile)); err == nil {
+		return nil, fmt.Errorf("%s already holds a backup", dir)
+	}
+	w := &Writer{
+		dir: dir,
+		manifest: Manifest{
+			FormatVersion: FormatVersion,
+			Namespace:     ns,
+		},
+		files: map[string]*recordFile{},
+	}
+	// Every record file exists, even when empty, so that a restore can tell
+	// an empty kind from a missing file.
+	for _, name := range []string{EntitiesFile, EdgesFile, DocumentsFile, PrincipalsFile, EntityTypesFile, EdgeTypesFile, AliasesFile} {
+		if _, err := w.file(name); err != nil {
+			w.abort()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+func (w *Writer) WriteEntity(e entity.Entity) error {
+	w.manifest.Counts.Entities++
+	return w.write(EntitiesFile, e)
+}
+
+func (w *Writer) WriteEdge(e entity.Edge) error {
+	w.manifest.Counts.Edges++
+	return w.write(EdgesFile, e)
+}
+
+func (w *Writer) WriteDocument(d document.Document) error {
+	w.manifest.Counts.Documents++
+	return w.write(DocumentsFile, d)
+}
+
+func (w *Writer) WritePrincipal(p Principal) error {
+	w.manifest.Counts.Principals++
+	return w.write(PrincipalsFile, p)
+}
+
+func (w *Writer) WriteEmbedding(e embedding.Embedding) error {
+	w.manifest.Counts.Embeddings++
+	w.manifest.Embeddings = true
+	return w.write(EmbeddingsFile, e)
+}
+
+func (w *Writer) WriteEntityType(d entity.TypeDefinition) error {
+	w.manifest.Counts.EntityTypes++
+	return w.write(EntityTypesFile, d)
+}
+
+func (w *Writer) WriteEdgeType(d entity.EdgeTypeDefinition) error {
+	w.manifest.Counts.EdgeTypes++
+	return w.write(EdgeTypesFile, d)
+}
+
+func (w *Writer) WriteAlias(a Alias) error {
+	w.manifest.Counts.Aliases++
+	return w.write(AliasesFile, a)
+}
+
+// Close flushes the record files and writes the manifest.
+func (w *Writer) Close(createdAt time.Time) (Manifest, error) {
+	for name, rf := range w.files {
+		if err := rf.buf.Flush(); err != nil {
+			w.abort()
+			return Manifest{}, fmt.Errorf("write %s: %w", name, err)
+		}
+		if err := rf.f.Close(); err != nil {
+			return Manifest{}, fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	w.manifest.CreatedAt = createdAt.UTC()
+	data, err := json.MarshalIndent(w.manifest, "", "  ")
+	if err != nil {
+		return Manifest{}, err
+	}
+	if err := os.WriteFile(filepath.Join(w.dir, ManifestFile), append(data, '\n'), 0o644); err != nil {
+		return Manifest{}, err
+	}
+	return w.manifest, nil
+}
+
+// abort closes the record files without writing a manifest.
+func (w *Writer) abort() {
+	for _, rf := range w.files {
+		_ = rf.f.Close()
+	}
+}
+
+func (w *Writer) write(name string, v interface{}) error {
+	rf, err := w.file(name)
+	if err != nil {
+		return err
+	}
+	if err := rf.enc.Encode(v); err != nil {
+		return fmt.Errorf("write %s: %w", name, err)
+	}
+	return nil
+}
+
+func (w *Writer) file(name string) (*recordFile, error) {
+	if rf, ok := w.files[name]; ok {
+		return rf, nil
+	}
+	f, err := os.Create(filepath.Join(w.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	buf := bufio.NewWriter(f)
+	rf := &recordFile{f: f, buf: buf, enc: json.NewEncoder(buf)}
+	w.files[name] = rf
+	return rf, nil
+}
+
+// Reader reads an archive from a directory. It is a Source, and checks that
+// each record file holds as many records as the manifest says.
+type Reader struct {
+	dir      string
+	Manifest Manifest
+}
+
+// ReadManifest reads the manifest of the archive in dir.
+func ReadManifest(dir string) (Manifest, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Manifest{}, fmt.Errorf("%w: %s has no %s", ErrCorrupt, dir, ManifestFile)
+		}
+		return Manifest{}, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return Manifest{}, fmt.Errorf("%w: decode %s: %v", ErrCorrupt, ManifestFile, err)
+	}
+	if m.FormatVersion < 1 || m.FormatVersion > FormatVersion {
+		return Manifest{}, fmt.Errorf("%w: %d", ErrUnsupportedVersion, m.FormatVersion)
+	}
+	return m, nil
+}
+
+// OpenReader opens the archive in dir.
+func OpenReader(dir string) (*Reader, error) {
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{dir: dir, Manifest: m}, nil
+}
+
+func (r *Reader) Entities(fn func(entity.Entity) error) error {
+	return readRecords(r.dir, EntitiesFile, r.Manifest.Counts.Entities, fn)
+}
+
+func (r *Reader) Edges(fn func(entity.Edge) error) error {
+	return readRecords(r.dir, EdgesFile, r.Manifest.Counts.Edges, fn)
+}
+
+func (r *Reader) Documents(fn func(document.Document) error) error {
+	return readRecords(r.dir, DocumentsFile, r.Manifest.Counts.Documents, fn)
+}
+
+func (r *Reader) Principals(fn func(Principal) error) error {
+	return readRecords(r.dir, PrincipalsFile, r.Manifest.Counts.Principals, fn)
+}
+
+func (r *Reader) Embeddings(fn func(embedding.Embedding) error) error {
+	if !r.Manifest.Embeddings {
+		return nil
+	}
+	return readRecords(r.dir, EmbeddingsFile, r.Manifest.Counts.Embeddings, fn)
+}
+
+func (r *Reader) EntityTypes(fn func(entity.TypeDefinition) error) error {
+	if r.Manifest.FormatVersion < 2 {
+		return nil
+	}
+	return readRecords(r.dir, EntityTypesFile, r.Manifest.Counts.EntityTypes, fn)
+}
+
+func (r *Reader) EdgeTypes(fn func(entity.EdgeTypeDefinition) error) error {
+	if r.Manifest.FormatVersion < 2 {
+		return nil
+	}
+	return readRecords(r.dir, EdgeTypesFile, r.Manifest.Counts.EdgeTypes, fn)
+}
+
+func (r *Reader) Aliases(fn func(Alias) error) error {
+	if r.Manifest.FormatVersion < 2 {
+		return nil
+	}
+	return readRecords(r.dir, AliasesFile, r.Manifest.Counts.Aliases, fn)
+}
+
+func readRecords[T any](dir, name string, want int, fn func(T) error) error {
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s is missing", ErrCorrupt, name)
+		}
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	n := 0
+	for {
+		var rec T
+		if err := dec.Decode(&rec); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("%w: %s record %d: %v", ErrCorrupt, name, n+1, err)
+		}
+		n++
+		if n > want {
+			break
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+	if n != want {
+		return fmt.Errorf("%w: %s holds %d records, manifest says %d", ErrCorrupt, name, n, want)
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+// Package backup dumps a namespace to a portable archive and loads it back.
+//
+// An archive is a directory holding a manifest and one JSON lines file per
+// kind of record: entities and edges with their full history, documents,
+// principals, entity and edge type definitions, entity aliases and,
+// optionally, embeddings. Records keep their URNs, history
+// and timestamps; row IDs are reassigned on restore so that an archive can be
+// restored next to the namespace it was taken from.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+// ErrNotEmpty is returned when restoring into a namespace that already holds
+// entities, edges, documents, type definitions or aliases.
+var ErrNotEmpty = errors.New("namespace is not empty")
+
+// Repository reads and writes the raw rows of a namespace.
+type Repository interface {
+	// Dump streams every row of the namespace to sink. Embeddings are only
+	// read when withEmbeddings is set.
+	Dump(ctx context.Context, ns *namespace.Namespace, sink Sink, withEmbeddings bool) error
+	// Load writes every record of src to the namespace in one transaction.
+	// Embeddings are only loaded when withEmbeddings is set.
+	Load(ctx context.Context, ns *namespace.Namespace, src Source, withEmbeddings bool) (Counts, error)
+	// IsEmpty reports whether the namespace holds no entities, edges,
+	// documents, type definitions or aliases.
+	IsEmpty(ctx context.Context, ns *namespace.Namespace) (bool, error)
+}
+
+// BackupOptions configures a backup.
+type BackupOptions struct {
+	// Embeddings includes the embedding index in the archive. Without it, a
+	// restore has to re-generate embeddings.
+	Embeddings bool
+}
+
+// RestoreOptions configures a restore.
+type RestoreOptions struct {
+	// SkipEmbeddings leaves out the embeddings of the archive, for callers
+	// that re-generate them after the restore.
+	SkipEmbeddings bool
+}
+
+// Service backs up and restores namespaces.
+type Service struct {
+	repo Repository
+	now  func() time.Time
+}
+
+// NewService creates a backup service.
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo, now: time.Now}
+}
+
+// Backup writes an archive of the namespace to dir and returns its manifest.
+func (s *Service) Backup(ctx context.Context, ns *namespace.Namespace, dir string, opts BackupOptions) (Manifest, error) {
+	w, err := NewWriter(dir, *ns)
+	if err != nil {
+		return Manifest{}, err
+	}
+	if err := s.repo.Dump(ctx, ns, w, opts.Embeddings); err != nil {
+		w.abort()
+		return Manifest{}, fmt.Errorf("dump namespace %s: %w", ns.Name, err)
+	}
+	return w.Close(s.now())
+}
+
+// Restore loads the archive in dir into the namespace, which must be empty.
+// Nothing is written unless the whole archive loads.
+func (s *Service) Restore(ctx context.Context, ns *namespace.Namespace, dir string, opts RestoreOptions) (Counts, error) {
+	r, err := OpenReader(dir)
+	if err != nil {
+		return Counts{}, err
+	}
+	empty, err := s.repo.IsEmpty(ctx, ns)
+	if err != nil {
+		return Counts{}, err
+	}
+	if !empty {
+		return Counts{}, fmt.Errorf("%w: %s", ErrNotEmpty, ns.Name)
+	}
+	counts, err := s.repo.Load(ctx, ns, r, !opts.SkipEmbeddings)
+	if err != nil {
+		return Counts{}, fmt.Errorf("restore namespace %s: %w", ns.Name, err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,330 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/raystack/compass/core/document"
+	"github.com/raystack/compass/core/embedding"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+)
+
+// mockRepo holds the rows of a single namespace in memory.
+type mockRepo struct {
+	entities   []entity.Entity
+	edges      []entity.Edge
+	documents  []document.Document
+	principals []Principal
+	embeddings []embedding.Embedding
+	types      []entity.TypeDefinition
+	edgeTypes  []entity.EdgeTypeDefinition
+	aliases    []Alias
+	loaded     *mockRepo
+}
+
+func (m *mockRepo) Dump(_ context.Context, _ *namespace.Namespace, sink Sink, withEmbeddings bool) error {
+	for _, e := range m.entities {
+		if err := sink.WriteEntity(e); err != nil {
+			return err
+		}
+	}
+	for _, e := range m.edges {
+		if err := sink.WriteEdge(e); err != nil {
+			return err
+		}
+	}
+	for _, d := range m.documents {
+		if err := sink.WriteDocument(d); err != nil {
+			return err
+		}
+	}
+	for _, p := range m.principals {
+		if err := sink.WritePrincipal(p); err != nil {
+			return err
+		}
+	}
+	for _, d := range m.types {
+		if err := sink.WriteEntityType(d); err != nil {
+			return err
+		}
+	}
+	for _, d := range m.edgeTypes {
+		if err := sink.WriteEdgeType(d); err != nil {
+			return err
+		}
+	}
+	for _, a := range m.aliases {
+		if err := sink.WriteAlias(a); err != nil {
+			return err
+		}
+	}
+	if withEmbeddings {
+		for _, e := range m.embeddings {
+			if err := sink.WriteEmbedding(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *mockRepo) Load(_ context.Context, _ *namespace.Namespace, src Source, withEmbeddings bool) (Counts, error) {
+	got := &mockRepo{}
+	if err := src.Entities(func(e entity.Entity) error { got.entities = append(got.entities, e); return nil }); err != nil {
+		return Counts{}, err
+	}
+	if err := src.Edges(func(e entity.Edge) error { got.edges = append(got.edges, e); return nil }); err != nil {
+		return Counts{}, err
+	}
+	if err := src.Documents(func(d document.Document) error { got.documents = append(got.documents, d); return nil }); err != nil {
+		return Counts{}, err
+	}
+	if err := src.Principals(func(p Principal) error { got.principals = append(got.principals, p); return nil }); err != nil {
+		return Counts{}, err
+	}
+	if err := src.EntityTypes(func(d entity.TypeDefinition) error { got.types = append(got.types, d); return nil }); err != nil {
+		return Counts{}, err
+	}
+	if err := src.EdgeTypes(func(d entity.EdgeTypeDefinition) error { got.edgeTypes = append(got.edgeTypes, d); return nil }); err != nil {
+		return Counts{}, err
+	}
+	if err := src.Aliases(func(a Alias) error { got.aliases = append(got.aliases, a); return nil }); err != nil {
+		return Counts{}, err
+	}
+	if withEmbeddings {
+		if err := src.Embeddings(func(e embedding.Embedding) error { got.embeddings = append(got.embeddings, e); return nil }); err != nil {
+			return Counts{}, err
+		}
+	}
+	m.loaded = got
+	return Counts{
+		Entities:    len(got.entities),
+		Edges:       len(got.edges),
+		Documents:   len(got.documents),
+		Principals:  len(got.principals),
+		Embeddings:  len(got.embeddings),
+		EntityTypes: len(got.types),
+		EdgeTypes:   len(got.edgeTypes),
+		Aliases:     len(got.aliases),
+	}, nil
+}
+
+func (m *mockRepo) IsEmpty(context.Context, *namespace.Namespace) (bool, error) {
+	return len(m.entities) == 0 && len(m.edges) == 0 && len(m.documents) == 0 &&
+		len(m.types) == 0 && len(m.edgeTypes) == 0 && len(m.aliases) == 0, nil
+}
+
+func sampleRepo() *mockRepo {
+	t0 := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+	return &mockRepo{
+		entities: []entity.Entity{
+			{ID: "e1", URN: "urn:a", Type: entity.TypeTable, Name: "a", ChangedBy: "alice", ValidFrom: t0, ValidTo: &t1, CreatedAt: t0, UpdatedAt: t0},
+			{ID: "e2", URN: "urn:a", Type: entity.TypeTable, Name: "a2", Properties: map[string]interface{}{"k": "v"}, ValidFrom: t1, CreatedAt: t0, UpdatedAt: t1},
+			{ID: "e3", URN: "urn:b", Type: entity.TypeJob, Name: "b", Scope: "team", ValidFrom: t0, CreatedAt: t0, UpdatedAt: t0},
+		},
+		edges: []entity.Edge{
+			{ID: "g1", SourceURN: "urn:b", TargetURN: "urn:a", Type: "writes", ValidFrom: t0, CreatedAt: t0},
+		},
+		documents: []document.Document{
+			{ID: "d1", EntityURN: "urn:a", Title: "Runbook", Body: "# Runbook", Format: "markdown", CreatedAt: t0, UpdatedAt: t1},
+		},
+		principals: []Principal{
+			{ID: "p1", Type: "user", Name: "Alice", Subject: "alice", CreatedAt: t0, UpdatedAt: t0},
+		},
+		embeddings: []embedding.Embedding{
+			{ID: "m1", EntityURN: "urn:a", ContentID: "d1", ContentType: "document", Content: "Runbook", Vector: []float32{0.5, -1}, CreatedAt: t1},
+		},
+		types: []entity.TypeDefinition{
+			{Type: entity.TypeTable, DisplayName: "Table", Required: []string{"owner"}, Mode: entity.TypeMode("reject"), CreatedAt: t0, UpdatedAt: t0},
+		},
+		edgeTypes: []entity.EdgeTypeDefinition{
+			{Type: "writes", SourceTypes: []entity.Type{entity.TypeJob}, Lineage: true, Cardinality: entity.CardinalityManyToMany, CreatedAt: t0, UpdatedAt: t0},
+		},
+		aliases: []Alias{
+			{Alias: "urn:old", URN: "urn:a", CreatedAt: t1},
+		},
+	}
+}
+
+var tenant = &namespace.Namespace{Name: "tenant", State: namespace.SharedState}
+
+func TestService_BackupRestore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "archive")
+	src := sampleRepo()
+	svc := NewService(src)
+	svc.now = func() time.Time { return time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC) }
+
+	m, err := svc.Backup(context.Background(), tenant, dir, BackupOptions{Embeddings: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Counts{Entities: 3, Edges: 1, Documents: 1, Principals: 1, Embeddings: 1, EntityTypes: 1, EdgeTypes: 1, Aliases: 1}
+	if m.Counts != want || !m.Embeddings || m.FormatVersion != FormatVersion || m.Namespace.Name != "tenant" {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+
+	dst := &mockRepo{}
+	counts, err := NewService(dst).Restore(context.Background(), tenant, dir, RestoreOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts != want {
+		t.Errorf("expected %+v restored, got %+v", want, counts)
+	}
+	got := dst.loaded
+	if !got.entities[0].ValidTo.Equal(*src.entities[0].ValidTo) || got.entities[0].ChangedBy != "alice" {
+		t.Errorf("expected history to round trip, got %+v", got.entities[0])
+	}
+	if got.entities[1].Properties["k"] != "v" || got.entities[2].Scope != "team" {
+		t.Errorf("expected properties and scope to round trip, got %+v", got.entities[1:])
+	}
+	if got.principals[0].Subject != "alice" || !got.principals[0].CreatedAt.Equal(src.principals[0].CreatedAt) {
+		t.Errorf("unexpected principal %+v", got.principals[0])
+	}
+	if e := got.embeddings[0]; e.ContentID != "d1" || len(e.Vector) != 2 || e.Vector[1] != -1 {
+		t.Errorf("unexpected embedding %+v", e)
+	}
+	if d := got.types[0]; d.DisplayName != "Table" || len(d.Required) != 1 || d.Mode != "reject" {
+		t.Errorf("unexpected entity type %+v", d)
+	}
+	if d := got.edgeTypes[0]; !d.Lineage || len(d.SourceTypes) != 1 || d.Cardinality != entity.CardinalityManyToMany {
+		t.Errorf("unexpected edge type %+v", d)
+	}
+	if a := got.aliases[0]; a.Alias != "urn:old" || a.URN != "urn:a" || !a.CreatedAt.Equal(src.aliases[0].CreatedAt) {
+		t.Errorf("unexpected alias %+v", a)
+	}
+}
+
+func TestService_RestoreVersion1(t *testing.T) {
+	dir := t.TempDir()
+	src := sampleRepo()
+	src.types, src.edgeTypes, src.aliases = nil, nil, nil
+	if _, err := NewService(src).Backup(context.Background(), tenant, dir, BackupOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// A version 1 archive has neither the files nor the counts of types and
+	// aliases.
+	for _, name := range []string{EntityTypesFile, EdgeTypesFile, AliasesFile} {
+		if err := os.Remove(filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data, []byte(`"format_version": 2`), []byte(`"format_version": 1`), 1)
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dst := &mockRepo{}
+	counts, err := NewService(dst).Restore(context.Background(), tenant, dir, RestoreOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts.Entities != 3 || counts.EntityTypes != 0 || counts.Aliases != 0 {
+		t.Errorf("unexpected counts %+v", counts)
+	}
+}
+
+func TestService_RestoreSkipEmbeddings(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewService(sampleRepo()).Backup(context.Background(), tenant, dir, BackupOptions{Embeddings: true}); err != nil {
+		t.Fatal(err)
+	}
+	dst := &mockRepo{}
+	counts, err := NewService(dst).Restore(context.Background(), tenant, dir, RestoreOptions{SkipEmbeddings: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counts.Embeddings != 0 || counts.Entities != 3 {
+		t.Errorf("expected embeddings to be skipped, got %+v", counts)
+	}
+}
+
+func TestService_BackupWithoutEmbeddings(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewService(sampleRepo()).Backup(context.Background(), tenant, dir, BackupOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Embeddings || m.Counts.Embeddings != 0 {
+		t.Errorf("expected no embeddings, got %+v", m)
+	}
+	if _, err := os.Stat(filepath.Join(dir, EmbeddingsFile)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected no embeddings file, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, EdgesFile)); err != nil {
+		t.Errorf("expected edges file, got %v", err)
+	}
+}
+
+func TestService_BackupRefusesExistingArchive(t *testing.T) {
+	dir := t.TempDir()
+	svc := NewService(sampleRepo())
+	if _, err := svc.Backup(context.Background(), tenant, dir, BackupOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Backup(context.Background(), tenant, dir, BackupOptions{}); err == nil {
+		t.Error("expected error writing over an archive")
+	}
+}
+
+func TestService_RestoreErrors(t *testing.T) {
+	backupTo := func(t *testing.T) string {
+		dir := t.TempDir()
+		if _, err := NewService(sampleRepo()).Backup(context.Background(), tenant, dir, BackupOptions{}); err != nil {
+			t.Fatal(err)
+		}
+		return dir
+	}
+
+	t.Run("not empty", func(t *testing.T) {
+		_, err := NewService(sampleRepo()).Restore(context.Background(), tenant, backupTo(t), RestoreOptions{})
+		if !errors.Is(err, ErrNotEmpty) {
+			t.Errorf("expected ErrNotEmpty, got %v", err)
+		}
+	})
+
+	t.Run("missing manifest", func(t *testing.T) {
+		_, err := NewService(&mockRepo{}).Restore(context.Background(), tenant, t.TempDir(), RestoreOptions{})
+		if !errors.Is(err, ErrCorrupt) {
+			t.Errorf("expected ErrCorrupt, got %v", err)
+		}
+	})
+
+	t.Run("newer version", func(t *testing.T) {
+		dir := backupTo(t)
+		if err := os.WriteFile(filepath.Join(dir, ManifestFile), []byte(`{"format_version": 99}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err := NewService(&mockRepo{}).Restore(context.Background(), tenant, dir, RestoreOptions{})
+		if !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("expected ErrUnsupportedVersion, got %v", err)
+		}
+	})
+
+	t.Run("truncated file", func(t *testing.T) {
+		dir := backupTo(t)
+		path := filepath.Join(dir, EntitiesFile)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data[:len(data)/2], 0o644); err != nil {
+			t.Fatal(err)
+		}
+		_, err = NewService(&mockRepo{}).Restore(context.Background(), tenant, dir, RestoreOptions{})
+		if !errors.Is(err, ErrCorrupt) {
+			t.Errorf("expected ErrCorrupt, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,130 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/raystack/compass/core/document"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+)
+
+// ErrInvalid is returned for a batch that cannot be applied as written.
+var ErrInvalid = errors.New("invalid batch")
+
+// MaxOperations caps the size of one batch; a batch holds its locks until
+// it commits.
+const MaxOperations = 10000
+
+// Action names the kind of an operation.
+type Action string
+
+const (
+	ActionUpsertEntity   Action = "upsert_entity"
+	ActionDeleteEntity   Action = "delete_entity"
+	ActionUpsertEdge     Action = "upsert_edge"
+	ActionDeleteEdge     Action = "delete_edge"
+	ActionUpsertDocument Action = "upsert_document"
+)
+
+// EdgeRef identifies an edge to delete.
+type EdgeRef struct {
+	SourceURN string `json:"source_urn"`
+	TargetURN string `json:"target_urn"`
+	Type      string `json:"type"`
+}
+
+// EntityRef identifies an entity to delete.
+type EntityRef struct {
+	URN string `json:"urn"`
+}
+
+// Operation is one write of a batch. Exactly one field is set.
+type Operation struct {
+	UpsertEntity   *entity.Entity     `json:"upsert_entity,omitempty"`
+	DeleteEntity   *EntityRef         `json:"delete_entity,omitempty"`
+	UpsertEdge     *entity.Edge       `json:"upsert_edge,omitempty"`
+	DeleteEdge     *EdgeRef           `json:"delete_edge,omitempty"`
+	UpsertDocument *document.Document `json:"upsert_document,omitempty"`
+}
+
+// Action returns the kind of the operation, or "" when it does not hold
+// exactly one write.
+func (op Operation) Action() Action {
+	var action Action
+	n := 0
+	if op.UpsertEntity != nil {
+		action, n = ActionUpsertEntity, n+1
+	}
+	if op.DeleteEntity != nil {
+		action, n = ActionDeleteEntity, n+1
+	}
+	if op.UpsertEdge != nil {
+		action, n = ActionUpsertEdge, n+1
+	}
+	if op.DeleteEdge != nil {
+		action, n = ActionDeleteEdge, n+1
+	}
+	if op.UpsertDocument != nil {
+		action, n = ActionUpsertDocument, n+1
+	}
+	if n != 1 {
+		return ""
+	}
+	return action
+}
+
+// Result is the outcome of one applied operation. ID is the written
+// entity, edge or document version; deletes carry no ID.
+type Result struct {
+	Index  int    `json:"index"`
+	Action Action `json:"action"`
+	ID     string `json:"id,omitempty"`
+}
+
+// OpError reports the operation that made a batch fail.
+type OpError struct {
+	Index  int
+	Action Action
+	Err    error
+}
+
+func (e *OpError) Error() string {
+	return fmt.Sprintf("operation %d (%s): %v", e.Index, e.Action, e.Err)
+}
+
+func (e *OpError) Unwrap() error { return e.Err }
+
+// Clock hands out the timestamps of a batch's operations. Each one is at
+// least a microsecond, the resolution Postgres keeps, after the one before.
+// Repeated writes of the same key in one batch thus become successive
+// versions rather than colliding, and every operation's versions close at a
+// time of their own.
+type Clock struct {
+	now  func() time.Time
+	last time.Time
+}
+
+// NewClock returns a clock reading the current time from now.
+func NewClock(now func() time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Next returns the timestamp of the next operation.
+func (c *Clock) Next() time.Time {
+	t := c.now().UTC().Truncate(time.Microsecond)
+	if !t.After(c.last) {
+		t = c.last.Add(time.Microsecond)
+	}
+	c.last = t
+	return t
+}
+
+// Repository applies batches.
+type Repository interface {
+	// Commit applies the operations in order in a single transaction. If
+	// any of them fails, none is applied and the error is an *OpError.
+	Commit(ctx context.Context, ns *namespace.Namespace, ops []Operation) ([]Result, error)
+}
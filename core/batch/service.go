@@ -0,0 +1,215 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/raystack/compass/core/document"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/principal"
+)
+
+// EmbeddingPipeline enqueues committed entities and documents for async
+// embedding.
+type EmbeddingPipeline interface {
+	EnqueueEntity(ctx context.Context, ns *namespace.Namespace, ent *entity.Entity) error
+	EnqueueDocument(ctx context.Context, ns *namespace.Namespace, doc *document.Document) error
+}
+
+// ColumnExpander derives the column entities written or deleted along with
+// an entity. Implemented by entity.Service.
+type ColumnExpander interface {
+	ExpandColumns(ctx context.Context, ns *namespace.Namespace, ent entity.Entity) (entity.ColumnExpansion, error)
+	CurrentColumns(ctx context.Context, ns *namespace.Namespace, urn string) ([]string, error)
+}
+
+// TypeChecker validates an entity against the definition of its type.
+// Implemented by entity.Service.
+type TypeChecker interface {
+	CheckType(ctx context.Context, ns *namespace.Namespace, ent entity.Entity) (string, error)
+}
+
+// Service validates and commits batches.
+type Service struct {
+	repo     Repository
+	pipeline EmbeddingPipeline
+	columns  ColumnExpander
+	types    TypeChecker
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// WithPipeline enables async embedding of committed entities and documents.
+func (s *Service) WithPipeline(p EmbeddingPipeline) {
+	s.pipeline = p
+}
+
+// WithColumnExpander writes and deletes the column entities of the entities
+// in a batch, in the same transaction.
+func (s *Service) WithColumnExpander(x ColumnExpander) {
+	s.columns = x
+}
+
+// WithTypeChecker validates the entities of a batch against their type
+// definitions. A batch with an entity its type rejects is not written.
+func (s *Service) WithTypeChecker(c TypeChecker) {
+	s.types = c
+}
+
+// Commit applies all operations atomically, in order. The whole batch is
+// validated before anything is written.
+func (s *Service) Commit(ctx context.Context, ns *namespace.Namespace, ops []Operation) ([]Result, error) {
+	if len(ops) == 0 {
+		return nil, fmt.Errorf("%w: no operations", ErrInvalid)
+	}
+	if len(ops) > MaxOperations {
+		return nil, fmt.Errorf("%w: at most %d operations per batch", ErrInvalid, MaxOperations)
+	}
+	changedBy := principal.FromContext(ctx).Subject
+	for i, op := range ops {
+		if err := validate(op); err != nil {
+			return nil, &OpError{Index: i, Action: op.Action(), Err: fmt.Errorf("%w: %s", ErrInvalid, err)}
+		}
+		if op.UpsertEntity != nil {
+			op.UpsertEntity.ChangedBy = changedBy
+			op.UpsertEntity.PropertySources = nil
+		}
+		if op.UpsertEntity != nil && s.types != nil {
+			if _, err := s.types.CheckType(ctx, ns, *op.UpsertEntity); err != nil {
+				if errors.Is(err, entity.ErrTypeViolation) {
+					err = fmt.Errorf("%w: %s", ErrInvalid, err)
+				}
+				return nil, &OpError{Index: i, Action: op.Action(), Err: err}
+			}
+		}
+		if op.UpsertDocument != nil {
+			if err := document.Normalize(op.UpsertDocument); err != nil {
+				return nil, &OpError{Index: i, Action: op.Action(), Err: fmt.Errorf("%w: %s", ErrInvalid, err)}
+			}
+		}
+	}
+
+	write, owners, err := s.expandColumns(ctx, ns, ops)
+	if err != nil {
+		return nil, err
+	}
+	results, err := s.repo.Commit(ctx, ns, write)
+	if err != nil {
+		var opErr *OpError
+		if errors.As(err, &opErr) && owners != nil {
+			opErr.Index = owners[opErr.Index]
+			opErr.Action = ops[opErr.Index].Action()
+		}
+		return nil, err
+	}
+	if owners != nil {
+		results = ownResults(results, owners)
+	}
+
+	if s.pipeline != nil {
+		for _, op := range ops {
+			switch {
+			case op.UpsertEntity != nil:
+				_ = s.pipeline.EnqueueEntity(ctx, ns, op.UpsertEntity)
+			case op.UpsertDocument != nil:
+				_ = s.pipeline.EnqueueDocument(ctx, ns, op.UpsertDocument)
+			}
+		}
+	}
+	return results, nil
+}
+
+// expandColumns returns ops with the column writes of each entity operation
+// following it, and for each operation written the index of the operation in
+// ops it belongs to. owners is nil when nothing was added.
+func (s *Service) expandColumns(ctx context.Context, ns *namespace.Namespace, ops []Operation) ([]Operation, []int, error) {
+	if s.columns == nil {
+		return ops, nil, nil
+	}
+	var write []Operation
+	var owners []int
+	for i, op := range ops {
+		write, owners = append(write, op), append(owners, i)
+
+		var removed []string
+		switch {
+		case op.UpsertEntity != nil:
+			exp, err := s.columns.ExpandColumns(ctx, ns, *op.UpsertEntity)
+			if err != nil {
+				return nil, nil, &OpError{Index: i, Action: op.Action(), Err: err}
+			}
+			for j := range exp.Columns {
+				write, owners = append(write, Operation{UpsertEntity: &exp.Columns[j]}), append(owners, i)
+			}
+			for j := range exp.Edges {
+				write, owners = append(write, Operation{UpsertEdge: &exp.Edges[j]}), append(owners, i)
+			}
+			removed = exp.Removed
+		case op.DeleteEntity != nil:
+			cols, err := s.columns.CurrentColumns(ctx, ns, op.DeleteEntity.URN)
+			if err != nil {
+				return nil, nil, &OpError{Index: i, Action: op.Action(), Err: err}
+			}
+			removed = cols
+		}
+		for _, urn := range removed {
+			write, owners = append(write, Operation{DeleteEntity: &EntityRef{URN: urn}}), append(owners, i)
+		}
+	}
+	if len(write) == len(ops) {
+		return ops, nil, nil
+	}
+	return write, owners, nil
+}
+
+// ownResults keeps the results of the operations that were requested,
+// dropping those of the column writes added to them.
+func ownResults(results []Result, owners []int) []Result {
+	own := make([]Result, 0, len(results))
+	for i, res := range results {
+		if i > 0 && owners[i] == owners[i-1] {
+			continue
+		}
+		res.Index = owners[i]
+		own = append(own, res)
+	}
+	return own
+}
+
+func validate(op Operation) error {
+	switch op.Action() {
+	case ActionUpsertEntity:
+		e := op.UpsertEntity
+		if e.URN == "" || e.Type == "" || e.Name == "" {
+			return errors.New("entity urn, type and name are required")
+		}
+	case ActionDeleteEntity:
+		if op.DeleteEntity.URN == "" {
+			return errors.New("urn is required")
+		}
+	case ActionUpsertEdge:
+		e := op.UpsertEdge
+		if e.SourceURN == "" || e.TargetURN == "" || e.Type == "" {
+			return errors.New("edge source_urn, target_urn and type are required")
+		}
+		return entity.ValidateEdge(*e)
+	case ActionDeleteEdge:
+		e := op.DeleteEdge
+		if e.SourceURN == "" || e.TargetURN == "" || e.Type == "" {
+			return errors.New("edge source_urn, target_urn and type are required")
+		}
+	case ActionUpsertDocument:
+		d := op.UpsertDocument
+		if d.EntityURN == "" || d.Title == "" || d.Body == "" {
+			return errors.New("entity_urn, title, and body are required")
+		}
+	default:
+		return fmt.Errorf("operation must hold exactly one of %s, %s, %s, %s, %s",
+			ActionUpsertEntity, ActionDeleteEntity, ActionUpsertEdge, ActionDeleteEdge, ActionUpsertDocument)
+	}
+	return nil
+}
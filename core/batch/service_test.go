@@ -0,0 +1,281 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/raystack/compass/core/document"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/principal"
+)
+
+type mockRepo struct {
+	committed [][]Operation
+	err       error
+}
+
+func (m *mockRepo) Commit(_ context.Context, _ *namespace.Namespace, ops []Operation) ([]Result, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.committed = append(m.committed, ops)
+	results := make([]Result, len(ops))
+	for i, op := range ops {
+		results[i] = Result{Index: i, Action: op.Action()}
+	}
+	return results, nil
+}
+
+type mockPipeline struct {
+	entities, documents int
+}
+
+func (p *mockPipeline) EnqueueEntity(context.Context, *namespace.Namespace, *entity.Entity) error {
+	p.entities++
+	return nil
+}
+
+func (p *mockPipeline) EnqueueDocument(context.Context, *namespace.Namespace, *document.Document) error {
+	p.documents++
+	return nil
+}
+
+func TestOperation_Action(t *testing.T) {
+	tests := []struct {
+		op   Operation
+		want Action
+	}{
+		{Operation{UpsertEntity: &entity.Entity{}}, ActionUpsertEntity},
+		{Operation{DeleteEntity: &EntityRef{}}, ActionDeleteEntity},
+		{Operation{UpsertEdge: &entity.Edge{}}, ActionUpsertEdge},
+		{Operation{DeleteEdge: &EdgeRef{}}, ActionDeleteEdge},
+		{Operation{UpsertDocument: &document.Document{}}, ActionUpsertDocument},
+		{Operation{}, ""},
+		{Operation{UpsertEntity: &entity.Entity{}, DeleteEdge: &EdgeRef{}}, ""},
+	}
+	for _, tt := range tests {
+		if got := tt.op.Action(); got != tt.want {
+			t.Errorf("expected %q, got %q", tt.want, got)
+		}
+	}
+}
+
+func TestService_Commit(t *testing.T) {
+	repo := &mockRepo{}
+	pipeline := &mockPipeline{}
+	svc := NewService(repo)
+	svc.WithPipeline(pipeline)
+	ctx := principal.NewContext(context.Background(), principal.Principal{Subject: "alice@example.com"})
+
+	ent := &entity.Entity{URN: "urn:a", Type: entity.TypeTable, Name: "a", ChangedBy: "mallory"}
+	doc := &document.Document{EntityURN: "urn:a", Title: "Runbook", Body: "..."}
+	results, err := svc.Commit(ctx, namespace.DefaultNamespace, []Operation{
+		{UpsertEntity: ent},
+		{UpsertEdge: &entity.Edge{SourceURN: "urn:a", TargetURN: "urn:b", Type: "lineage"}},
+		{UpsertDocument: doc},
+		{DeleteEntity: &EntityRef{URN: "urn:old"}},
+	})
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if len(results) != 4 || results[2].Action != ActionUpsertDocument {
+		t.Errorf("unexpected results: %+v", results)
+	}
+	if ent.ChangedBy != "alice@example.com" {
+		t.Errorf("expected changed_by from principal, got %q", ent.ChangedBy)
+	}
+	if doc.Format != "markdown" {
+		t.Errorf("expected default document format, got %q", doc.Format)
+	}
+	if pipeline.entities != 1 || pipeline.documents != 1 {
+		t.Errorf("expected one entity and one document enqueued, got %+v", pipeline)
+	}
+}
+
+func TestService_Commit_RepeatedKey(t *testing.T) {
+	repo := &mockRepo{}
+	edge := func() *entity.Edge { return &entity.Edge{SourceURN: "urn:a", TargetURN: "urn:b", Type: "lineage"} }
+	ops := []Operation{
+		{UpsertEntity: &entity.Entity{URN: "urn:a", Type: "table", Name: "a"}},
+		{UpsertEntity: &entity.Entity{URN: "urn:a", Type: "table", Name: "a", Description: "second"}},
+		{UpsertEdge: edge()},
+		{DeleteEdge: &EdgeRef{SourceURN: "urn:a", TargetURN: "urn:b", Type: "lineage"}},
+		{UpsertEdge: edge()},
+	}
+	results, err := NewService(repo).Commit(context.Background(), namespace.DefaultNamespace, ops)
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	if len(results) != len(ops) || len(repo.committed) != 1 || len(repo.committed[0]) != len(ops) {
+		t.Fatalf("expected every repeated write to be applied in order, got %+v", results)
+	}
+
+	// The repository writes each operation at the clock's next timestamp;
+	// operations faster than the clock still get distinct ones.
+	frozen := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewClock(func() time.Time { return frozen })
+	var prev time.Time
+	for i := range ops {
+		now := clock.Next()
+		if i > 0 && !now.After(prev) {
+			t.Fatalf("operation %d: expected a timestamp after %s, got %s", i, prev, now)
+		}
+		prev = now
+	}
+	if later := frozen.Add(time.Second); !NewClock(func() time.Time { return later }).Next().Equal(later) {
+		t.Error("expected the clock to follow the current time")
+	}
+}
+
+func TestService_Commit_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		ops   []Operation
+		index int
+	}{
+		{"empty", nil, -1},
+		{"no write", []Operation{{UpsertEntity: &entity.Entity{URN: "urn:a", Type: "table", Name: "a"}}, {}}, 1},
+		{"entity without name", []Operation{{UpsertEntity: &entity.Entity{URN: "urn:a", Type: "table"}}}, 0},
+		{"edge without type", []Operation{{DeleteEdge: &EdgeRef{SourceURN: "urn:a", TargetURN: "urn:b"}}}, 0},
+		{"document without body", []Operation{{UpsertDocument: &document.Document{EntityURN: "urn:a", Title: "t"}}}, 0},
+		{"document in unknown format", []Operation{{UpsertEntity: &entity.Entity{URN: "urn:a", Type: "table", Name: "a"}}, {UpsertDocument: &document.Document{EntityURN: "urn:a", Title: "t", Body: "b", Format: "docx"}}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockRepo{}
+			_, err := NewService(repo).Commit(context.Background(), namespace.DefaultNamespace, tt.ops)
+			if !errors.Is(err, ErrInvalid) {
+				t.Fatalf("expected ErrInvalid, got %v", err)
+			}
+			var opErr *OpError
+			if tt.index >= 0 && (!errors.As(err, &opErr) || opErr.Index != tt.index) {
+				t.Errorf("expected failing index %d, got %v", tt.index, err)
+			}
+			if len(repo.committed) != 0 {
+				t.Error("expected nothing committed")
+			}
+		})
+	}
+}
+
+// mockTypeChecker rejects entities without an owner property.
+type mockTypeChecker struct{}
+
+func (mockTypeChecker) CheckType(_ context.Context, _ *namespace.Namespace, ent entity.Entity) (string, error) {
+	if ent.Properties["owner"] == nil {
+		return "", fmt.Errorf("%w: %s: property \"owner\" is required", entity.ErrTypeViolation, ent.URN)
+	}
+	return "", nil
+}
+
+func TestService_Commit_TypeViolation(t *testing.T) {
+	repo := &mockRepo{}
+	svc := NewService(repo)
+	svc.WithTypeChecker(mockTypeChecker{})
+
+	_, err := svc.Commit(context.Background(), namespace.DefaultNamespace, []Operation{
+		{UpsertEntity: &entity.Entity{URN: "urn:a", Type: "table", Name: "a", Properties: map[string]interface{}{"owner": "x"}}},
+		{UpsertEntity: &entity.Entity{URN: "urn:b", Type: "table", Name: "b"}},
+	})
+	var opErr *OpError
+	if !errors.Is(err, ErrInvalid) || !errors.As(err, &opErr) || opErr.Index != 1 {
+		t.Fatalf("expected ErrInvalid at index 1, got %v", err)
+	}
+	if len(repo.committed) != 0 {
+		t.Error("expected nothing committed")
+	}
+}
+
+func TestService_Commit_RepositoryError(t *testing.T) {
+	pipeline := &mockPipeline{}
+	svc := NewService(&mockRepo{err: &OpError{Index: 1, Action: ActionUpsertEdge, Err: errors.New("boom")}})
+	svc.WithPipeline(pipeline)
+
+	_, err := svc.Commit(context.Background(), namespace.DefaultNamespace, []Operation{
+		{UpsertEntity: &entity.Entity{URN: "urn:a", Type: "table", Name: "a"}},
+		{UpsertEdge: &entity.Edge{SourceURN: "urn:a", TargetURN: "urn:b", Type: "lineage"}},
+	})
+	var opErr *OpError
+	if !errors.As(err, &opErr) || opErr.Index != 1 {
+		t.Fatalf("expected OpError at index 1, got %v", err)
+	}
+	if pipeline.entities != 0 {
+		t.Error("expected nothing enqueued for a rolled back batch")
+	}
+}
+
+type mockExpander struct{}
+
+func (mockExpander) ExpandColumns(_ context.Context, _ *namespace.Namespace, ent entity.Entity) (entity.ColumnExpansion, error) {
+	if ent.Type != entity.TypeTable {
+		return entity.ColumnExpansion{}, nil
+	}
+	cols, edges := entity.ColumnEntities(ent)
+	return entity.ColumnExpansion{Columns: cols, Edges: edges, Removed: []string{ent.URN + "#dropped"}}, nil
+}
+
+func (mockExpander) CurrentColumns(_ context.Context, _ *namespace.Namespace, urn string) ([]string, error) {
+	return []string{urn + "#id"}, nil
+}
+
+func TestService_Commit_ColumnEntities(t *testing.T) {
+	repo := &mockRepo{}
+	pipeline := &mockPipeline{}
+	svc := NewService(repo)
+	svc.WithPipeline(pipeline)
+	svc.WithColumnExpander(mockExpander{})
+
+	table := &entity.Entity{URN: "urn:orders", Type: entity.TypeTable, Name: "orders", Properties: map[string]interface{}{
+		"columns": []interface{}{map[string]interface{}{"name": "id", "type": "INT64"}},
+	}}
+	results, err := svc.Commit(context.Background(), namespace.DefaultNamespace, []Operation{
+		{UpsertEntity: table},
+		{UpsertEdge: &entity.Edge{SourceURN: "urn:revenue#id", TargetURN: "urn:orders#id", Type: entity.EdgeDerivedFrom}},
+		{DeleteEntity: &EntityRef{URN: "urn:old"}},
+	})
+	if err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+
+	var actions []Action
+	for _, op := range repo.committed[0] {
+		actions = append(actions, op.Action())
+	}
+	want := []Action{ActionUpsertEntity, ActionUpsertEntity, ActionUpsertEdge, ActionDeleteEntity, ActionUpsertEdge, ActionDeleteEntity, ActionDeleteEntity}
+	if !slices.Equal(actions, want) {
+		t.Errorf("expected committed actions %v, got %v", want, actions)
+	}
+	if len(results) != 3 || results[1].Index != 1 || results[1].Action != ActionUpsertEdge || results[2].Index != 2 {
+		t.Errorf("expected results of the requested operations only, got %+v", results)
+	}
+	if pipeline.entities != 1 {
+		t.Errorf("expected column entities not to be embedded, got %d entities enqueued", pipeline.entities)
+	}
+
+	_, err = svc.Commit(context.Background(), namespace.DefaultNamespace, []Operation{
+		{UpsertEdge: &entity.Edge{SourceURN: "urn:revenue", TargetURN: "urn:orders#id", Type: entity.EdgeDerivedFrom}},
+	})
+	if !errors.Is(err, ErrInvalid) {
+		t.Errorf("expected ErrInvalid for a derived_from edge between tables, got %v", err)
+	}
+}
+
+func TestService_Commit_ColumnEntities_OpError(t *testing.T) {
+	svc := NewService(&mockRepo{err: &OpError{Index: 4, Action: ActionUpsertEntity, Err: errors.New("boom")}})
+	svc.WithColumnExpander(mockExpander{})
+
+	_, err := svc.Commit(context.Background(), namespace.DefaultNamespace, []Operation{
+		{UpsertEntity: &entity.Entity{URN: "urn:orders", Type: entity.TypeTable, Name: "orders"}},
+		{DeleteEntity: &EntityRef{URN: "urn:old"}},
+		{UpsertEntity: &entity.Entity{URN: "urn:job", Type: entity.TypeJob, Name: "job"}},
+	})
+	var opErr *OpError
+	if !errors.As(err, &opErr) || opErr.Index != 2 || opErr.Action != ActionUpsertEntity {
+		t.Errorf("expected OpError at requested index 2, got %v", err)
+	}
+}
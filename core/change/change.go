@@ -0,0 +1,69 @@
+package change
+
+import (
+	"context"
+	"time"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+// Kind is the kind of object a change record describes.
+type Kind string
+
+const (
+	KindEntity   Kind = "entity"
+	KindEdge     Kind = "edge"
+	KindDocument Kind = "document"
+	// KindSchema records a schema change an entity upsert made. Its op is
+	// the level of the change and its payload the impact report.
+	KindSchema Kind = "schema"
+)
+
+// Op is the mutation a change record describes.
+type Op string
+
+const (
+	OpCreated Op = "created"
+	OpUpdated Op = "updated"
+	OpDeleted Op = "deleted"
+	// OpAdditive and OpBreaking are the ops of schema records.
+	OpAdditive Op = "additive"
+	OpBreaking Op = "breaking"
+)
+
+// Change is one record of the change feed. Records are written in the same
+// transaction as the mutation they describe, and Seq increases monotonically
+// within a namespace in commit order.
+//
+// Key identifies the object: the URN for entities and schema changes,
+// "source|type|target" for edges and the document ID for documents. Type is
+// the entity or edge type.
+type Change struct {
+	Seq         int64                  `json:"seq"`
+	NamespaceID string                 `json:"namespace_id"`
+	Kind        Kind                   `json:"kind"`
+	Op          Op                     `json:"op"`
+	Key         string                 `json:"key"`
+	Type        string                 `json:"type,omitempty"`
+	Payload     map[string]interface{} `json:"payload,omitempty"`
+	ChangedBy   string                 `json:"changed_by,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
+
+// EdgeKey builds the change key for an edge.
+func EdgeKey(sourceURN, edgeType, targetURN string) string {
+	return sourceURN + "|" + edgeType + "|" + targetURN
+}
+
+// Filter for reading the change feed.
+type Filter struct {
+	SinceSeq int64 // only records with seq > SinceSeq
+	Kinds    []Kind
+	Types    []string
+	Limit    int
+}
+
+// Repository defines storage operations for the change feed.
+type Repository interface {
+	List(ctx context.Context, ns *namespace.Namespace, filter Filter) ([]Change, error)
+}
@@ -0,0 +1,77 @@
+package change
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+const (
+	defaultLimit        = 100
+	maxLimit            = 1000
+	defaultPollInterval = time.Second
+)
+
+// Service reads the change feed.
+type Service struct {
+	repo         Repository
+	pollInterval time.Duration
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo, pollInterval: defaultPollInterval}
+}
+
+// WithPollInterval sets how often Watch checks for new records once it has
+// caught up.
+func (s *Service) WithPollInterval(d time.Duration) {
+	if d > 0 {
+		s.pollInterval = d
+	}
+}
+
+// List returns up to filter.Limit records after filter.SinceSeq, oldest first.
+func (s *Service) List(ctx context.Context, ns *namespace.Namespace, flt Filter) ([]Change, error) {
+	if flt.Limit <= 0 {
+		flt.Limit = defaultLimit
+	}
+	if flt.Limit > maxLimit {
+		flt.Limit = maxLimit
+	}
+	changes, err := s.repo.List(ctx, ns, flt)
+	if err != nil {
+		return nil, fmt.Errorf("list changes: %w", err)
+	}
+	return changes, nil
+}
+
+// Watch calls fn for every record after filter.SinceSeq, in order, and keeps
+// following the feed until ctx is done or fn returns an error.
+func (s *Service) Watch(ctx context.Context, ns *namespace.Namespace, flt Filter, fn func(Change) error) error {
+	if flt.Limit <= 0 || flt.Limit > maxLimit {
+		flt.Limit = maxLimit
+	}
+	for {
+		changes, err := s.List(ctx, ns, flt)
+		if err != nil {
+			return err
+		}
+		for _, c := range changes {
+			if err := fn(c); err != nil {
+				return err
+			}
+			flt.SinceSeq = c.Seq
+		}
+		if len(changes) == flt.Limit {
+			continue // more records are waiting
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.pollInterval):
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package change
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+type mockRepo struct {
+	changes   []Change
+	lastLimit int
+}
+
+func (m *mockRepo) List(_ context.Context, _ *namespace.Namespace, flt Filter) ([]Change, error) {
+	m.lastLimit = flt.Limit
+	kinds := map[Kind]bool{}
+	for _, k := range flt.Kinds {
+		kinds[k] = true
+	}
+	var result []Change
+	for _, c := range m.changes {
+		if c.Seq <= flt.SinceSeq || (len(kinds) > 0 && !kinds[c.Kind]) {
+			continue
+		}
+		result = append(result, c)
+		if len(result) == flt.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+func TestService_List(t *testing.T) {
+	repo := &mockRepo{changes: []Change{
+		{Seq: 1, Kind: KindEntity, Op: OpCreated, Key: "urn:a"},
+		{Seq: 2, Kind: KindEdge, Op: OpCreated, Key: EdgeKey("urn:a", "feeds", "urn:b")},
+		{Seq: 3, Kind: KindEntity, Op: OpUpdated, Key: "urn:a"},
+	}}
+	svc := NewService(repo)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	got, err := svc.List(ctx, ns, Filter{SinceSeq: 1, Kinds: []Kind{KindEntity}})
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(got) != 1 || got[0].Seq != 3 {
+		t.Errorf("expected only seq 3, got %+v", got)
+	}
+	if repo.lastLimit != defaultLimit {
+		t.Errorf("expected default limit %d, got %d", defaultLimit, repo.lastLimit)
+	}
+
+	_, _ = svc.List(ctx, ns, Filter{Limit: 5000})
+	if repo.lastLimit != maxLimit {
+		t.Errorf("expected limit capped at %d, got %d", maxLimit, repo.lastLimit)
+	}
+}
+
+func TestService_Watch(t *testing.T) {
+	repo := &mockRepo{changes: []Change{
+		{Seq: 1, Kind: KindEntity, Key: "urn:a"},
+		{Seq: 2, Kind: KindEntity, Key: "urn:b"},
+	}}
+	svc := NewService(repo)
+	svc.WithPollInterval(time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	errStop := errors.New("stop")
+	var seen []int64
+	err := svc.Watch(ctx, namespace.DefaultNamespace, Filter{}, func(c Change) error {
+		seen = append(seen, c.Seq)
+		if c.Seq == 2 {
+			// a record committed while watching is picked up on the next poll
+			repo.changes = append(repo.changes, Change{Seq: 3, Kind: KindDocument, Key: "doc-1"})
+		}
+		if c.Seq == 3 {
+			return errStop
+		}
+		return nil
+	})
+	if !errors.Is(err, errStop) {
+		t.Fatalf("expected watch to stop with callback error, got %v", err)
+	}
+	if len(seen) != 3 || seen[0] != 1 || seen[1] != 2 || seen[2] != 3 {
+		t.Errorf("expected seqs [1 2 3] in order, got %v", seen)
+	}
+}
+
+func TestService_Watch_ContextCancelled(t *testing.T) {
+	svc := NewService(&mockRepo{})
+	svc.WithPollInterval(time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	err := svc.Watch(ctx, namespace.DefaultNamespace, Filter{}, func(Change) error { return nil })
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
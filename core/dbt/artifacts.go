@@ -0,0 +1,163 @@
+package dbt
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Manifest is the subset of dbt's manifest.json the importer reads. Fields
+// are shared by manifest schema versions v7 onwards.
+type Manifest struct {
+	Metadata  ManifestMetadata    `json:"metadata"`
+	Nodes     map[string]Node     `json:"nodes"`
+	Sources   map[string]Source   `json:"sources"`
+	Exposures map[string]Exposure `json:"exposures"`
+	Metrics   map[string]Metric   `json:"metrics"`
+}
+
+// ManifestMetadata describes the project and warehouse a manifest was built for.
+type ManifestMetadata struct {
+	DBTVersion  string `json:"dbt_version"`
+	ProjectName string `json:"project_name"`
+	AdapterType string `json:"adapter_type"`
+}
+
+// Node is a model, seed, snapshot, test or other graph node.
+type Node struct {
+	UniqueID         string                 `json:"unique_id"`
+	ResourceType     string                 `json:"resource_type"`
+	PackageName      string                 `json:"package_name"`
+	Name             string                 `json:"name"`
+	Alias            string                 `json:"alias"`
+	Database         string                 `json:"database"`
+	Schema           string                 `json:"schema"`
+	Description      string                 `json:"description"`
+	Columns          map[string]Column      `json:"columns"`
+	DependsOn        DependsOn              `json:"depends_on"`
+	Config           NodeConfig             `json:"config"`
+	Tags             []string               `json:"tags"`
+	Meta             map[string]interface{} `json:"meta"`
+	OriginalFilePath string                 `json:"original_file_path"`
+}
+
+// NodeConfig holds the node configuration the importer uses.
+type NodeConfig struct {
+	Materialized string `json:"materialized"`
+}
+
+// Source is a table declared in a sources block.
+type Source struct {
+	UniqueID          string                 `json:"unique_id"`
+	PackageName       string                 `json:"package_name"`
+	SourceName        string                 `json:"source_name"`
+	Name              string                 `json:"name"`
+	Identifier        string                 `json:"identifier"`
+	Database          string                 `json:"database"`
+	Schema            string                 `json:"schema"`
+	Description       string                 `json:"description"`
+	SourceDescription string                 `json:"source_description"`
+	Loader            string                 `json:"loader"`
+	Columns           map[string]Column      `json:"columns"`
+	Tags              []string               `json:"tags"`
+	Meta              map[string]interface{} `json:"meta"`
+	OriginalFilePath  string                 `json:"original_file_path"`
+}
+
+// Exposure is a downstream use of the project, such as a dashboard.
+type Exposure struct {
+	UniqueID    string                 `json:"unique_id"`
+	PackageName string                 `json:"package_name"`
+	Name        string                 `json:"name"`
+	Label       string                 `json:"label"`
+	Type        string                 `json:"type"`
+	Description string                 `json:"description"`
+	URL         string                 `json:"url"`
+	Maturity    string                 `json:"maturity"`
+	Owner       ExposureOwner          `json:"owner"`
+	DependsOn   DependsOn              `json:"depends_on"`
+	Tags        []string               `json:"tags"`
+	Meta        map[string]interface{} `json:"meta"`
+}
+
+// ExposureOwner is the owner of an exposure.
+type ExposureOwner struct {
+	Name  string `json:"name,omitempty"`
+	Email string `json:"email,omitempty"`
+}
+
+// Metric is a metric defined in the project.
+type Metric struct {
+	UniqueID    string                 `json:"unique_id"`
+	PackageName string                 `json:"package_name"`
+	Name        string                 `json:"name"`
+	Label       string                 `json:"label"`
+	Type        string                 `json:"type"`
+	Description string                 `json:"description"`
+	DependsOn   DependsOn              `json:"depends_on"`
+	Tags        []string               `json:"tags"`
+	Meta        map[string]interface{} `json:"meta"`
+}
+
+// Column is a documented column of a node or source.
+type Column struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	DataType    string                 `json:"data_type"`
+	Tags        []string               `json:"tags"`
+	Meta        map[string]interface{} `json:"meta"`
+}
+
+// DependsOn lists the unique IDs of the nodes a node is built from.
+type DependsOn struct {
+	Nodes []string `json:"nodes"`
+}
+
+// Catalog is the subset of dbt's catalog.json the importer reads: the
+// relations and column types found in the warehouse by `dbt docs generate`.
+type Catalog struct {
+	Nodes   map[string]CatalogTable `json:"nodes"`
+	Sources map[string]CatalogTable `json:"sources"`
+}
+
+// CatalogTable is a relation as found in the warehouse.
+type CatalogTable struct {
+	Metadata CatalogMetadata          `json:"metadata"`
+	Columns  map[string]CatalogColumn `json:"columns"`
+}
+
+// CatalogMetadata describes a relation in the warehouse.
+type CatalogMetadata struct {
+	Type     string `json:"type"`
+	Database string `json:"database"`
+	Schema   string `json:"schema"`
+	Name     string `json:"name"`
+	Comment  string `json:"comment"`
+	Owner    string `json:"owner"`
+}
+
+// CatalogColumn is a column as found in the warehouse.
+type CatalogColumn struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Index   int    `json:"index"`
+	Comment string `json:"comment"`
+}
+
+// ReadManifest decodes a manifest.json.
+func ReadManifest(r io.Reader) (*Manifest, error) {
+	var m Manifest
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// ReadCatalog decodes a catalog.json.
+func ReadCatalog(r io.Reader) (*Catalog, error) {
+	var c Catalog
+	if err := json.NewDecoder(r).Decode(&c); err != nil {
+		return nil, fmt.Errorf("decode catalog: %w", err)
+	}
+	return &c, nil
+}
@@ -0,0 +1,352 @@
+// Package dbt maps dbt project artifacts onto the knowledge graph.
+package dbt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/raystack/compass/core/batch"
+	"github.com/raystack/compass/core/document"
+	"github.com/raystack/compass/core/entity"
+)
+
+const (
+	// SourceSystem is the source of every entity, edge and document imported
+	// from dbt.
+	SourceSystem = "dbt"
+	// EdgeLineage points from a node to each node built from it, following
+	// the data flow, so impact analysis on a node reaches its dependents.
+	EdgeLineage = "lineage"
+	// PropertyKey holds the dbt identity and configuration of imported entities.
+	PropertyKey = "dbt"
+)
+
+// relationTypes are the node resource types materialized in the warehouse.
+var relationTypes = map[string]bool{
+	"model":    true,
+	"seed":     true,
+	"snapshot": true,
+}
+
+// exposureTypes maps dbt exposure types to entity types. Other exposure types
+// keep the type "exposure".
+var exposureTypes = map[string]entity.Type{
+	"dashboard":   entity.TypeDashboard,
+	"application": entity.TypeApplication,
+	"ml":          entity.TypeModel,
+}
+
+// Result is the graph built from a dbt project.
+type Result struct {
+	Entities  []entity.Entity
+	Edges     []entity.Edge
+	Documents []document.Document
+}
+
+// Import maps the models, seeds, snapshots, sources, exposures and metrics of
+// a manifest to entities, their dependencies to lineage edges, and the
+// docs of each model to a document. Models, seeds, snapshots and sources are
+// tables, keyed by the URN of their warehouse relation so that they line up
+// with entities written by warehouse extractors. The catalog is optional; when
+// given, it adds column types and fills in descriptions from warehouse
+// comments.
+func Import(m *Manifest, c *Catalog) *Result {
+	if c == nil {
+		c = &Catalog{}
+	}
+	adapter := strings.ToLower(m.Metadata.AdapterType)
+	res := &Result{}
+	urns := map[string]string{}
+	deps := map[string][]string{}
+
+	for _, id := range sortedKeys(m.Nodes) {
+		n := m.Nodes[id]
+		if !relationTypes[n.ResourceType] {
+			continue
+		}
+		cat := c.Nodes[id]
+		name := n.Alias
+		if name == "" {
+			name = n.Name
+		}
+		urn := relationURN(adapter, n.Database, n.Schema, name)
+		if n.Config.Materialized == "ephemeral" || urn == "" {
+			urn = dbtURN(id)
+		}
+		info := dbtInfo(id, n.ResourceType, n.PackageName, n.OriginalFilePath, n.Tags, n.Meta)
+		if n.Config.Materialized != "" {
+			info["materialized"] = n.Config.Materialized
+		}
+		props := map[string]interface{}{PropertyKey: info}
+		cols := columns(n.Columns, cat.Columns)
+		if len(cols) > 0 {
+			props["columns"] = cols
+		}
+		ent := entity.Entity{
+			URN:         urn,
+			Type:        entity.TypeTable,
+			Name:        n.Name,
+			Description: firstNonEmpty(n.Description, cat.Metadata.Comment),
+			Source:      SourceSystem,
+			Properties:  props,
+		}
+		res.Entities = append(res.Entities, ent)
+		urns[id] = urn
+		deps[id] = n.DependsOn.Nodes
+
+		if n.ResourceType == "model" && ent.Description != "" {
+			res.Documents = append(res.Documents, modelDocument(id, ent, cols))
+		}
+	}
+
+	for _, id := range sortedKeys(m.Sources) {
+		s := m.Sources[id]
+		cat := c.Sources[id]
+		name := s.Identifier
+		if name == "" {
+			name = s.Name
+		}
+		urn := relationURN(adapter, s.Database, s.Schema, name)
+		if urn == "" {
+			urn = dbtURN(id)
+		}
+		info := dbtInfo(id, "source", s.PackageName, s.OriginalFilePath, s.Tags, s.Meta)
+		info["source_name"] = s.SourceName
+		if s.Loader != "" {
+			info["loader"] = s.Loader
+		}
+		props := map[string]interface{}{PropertyKey: info}
+		if cols := columns(s.Columns, cat.Columns); len(cols) > 0 {
+			props["columns"] = cols
+		}
+		res.Entities = append(res.Entities, entity.Entity{
+			URN:         urn,
+			Type:        entity.TypeTable,
+			Name:        s.SourceName + "." + s.Name,
+			Description: firstNonEmpty(s.Description, cat.Metadata.Comment, s.SourceDescription),
+			Source:      SourceSystem,
+			Properties:  props,
+		})
+		urns[id] = urn
+	}
+
+	for _, id := range sortedKeys(m.Exposures) {
+		e := m.Exposures[id]
+		typ, ok := exposureTypes[e.Type]
+		if !ok {
+			typ = entity.Type("exposure")
+		}
+		info := dbtInfo(id, "exposure", e.PackageName, "", e.Tags, e.Meta)
+		for k, v := range map[string]string{"exposure_type": e.Type, "url": e.URL, "maturity": e.Maturity} {
+			if v != "" {
+				info[k] = v
+			}
+		}
+		props := map[string]interface{}{PropertyKey: info}
+		if e.Owner != (ExposureOwner{}) {
+			props["owner"] = map[string]interface{}{"name": e.Owner.Name, "email": e.Owner.Email}
+		}
+		res.Entities = append(res.Entities, entity.Entity{
+			URN:         dbtURN(id),
+			Type:        typ,
+			Name:        firstNonEmpty(e.Label, e.Name),
+			Description: e.Description,
+			Source:      SourceSystem,
+			Properties:  props,
+		})
+		urns[id] = dbtURN(id)
+		deps[id] = e.DependsOn.Nodes
+	}
+
+	for _, id := range sortedKeys(m.Metrics) {
+		mt := m.Metrics[id]
+		info := dbtInfo(id, "metric", mt.PackageName, "", mt.Tags, mt.Meta)
+		if mt.Type != "" {
+			info["metric_type"] = mt.Type
+		}
+		props := map[string]interface{}{PropertyKey: info}
+		res.Entities = append(res.Entities, entity.Entity{
+			URN:         dbtURN(id),
+			Type:        entity.TypeMetric,
+			Name:        firstNonEmpty(mt.Label, mt.Name),
+			Description: mt.Description,
+			Source:      SourceSystem,
+			Properties:  props,
+		})
+		urns[id] = dbtURN(id)
+		deps[id] = mt.DependsOn.Nodes
+	}
+
+	// Dependencies on nodes that are not imported, such as macros or
+	// semantic models, are dropped.
+	for _, id := range sortedKeys(deps) {
+		seen := map[string]bool{}
+		for _, dep := range deps[id] {
+			parent, ok := urns[dep]
+			if !ok || seen[parent] || parent == urns[id] {
+				continue
+			}
+			seen[parent] = true
+			res.Edges = append(res.Edges, entity.Edge{
+				SourceURN: parent,
+				TargetURN: urns[id],
+				Type:      EdgeLineage,
+				Source:    SourceSystem,
+			})
+		}
+	}
+	return res
+}
+
+// Operations returns the result as batch operations: entities first, so that
+// edges and documents refer to entities that exist.
+func (r *Result) Operations() []batch.Operation {
+	ops := make([]batch.Operation, 0, len(r.Entities)+len(r.Edges)+len(r.Documents))
+	for i := range r.Entities {
+		ops = append(ops, batch.Operation{UpsertEntity: &r.Entities[i]})
+	}
+	for i := range r.Edges {
+		ops = append(ops, batch.Operation{UpsertEdge: &r.Edges[i]})
+	}
+	for i := range r.Documents {
+		ops = append(ops, batch.Operation{UpsertDocument: &r.Documents[i]})
+	}
+	return ops
+}
+
+// relationURN is the URN of a warehouse relation, in the form warehouse
+// extractors use: urn:<adapter>:<database>.<schema>.<name>.
+func relationURN(adapter, database, schema, name string) string {
+	if adapter == "" || schema == "" || name == "" {
+		return ""
+	}
+	parts := []string{schema, name}
+	if database != "" {
+		parts = append([]string{database}, parts...)
+	}
+	return "urn:" + adapter + ":" + strings.Join(parts, ".")
+}
+
+func dbtURN(uniqueID string) string {
+	return "urn:dbt:" + uniqueID
+}
+
+// dbtInfo is the dbt identity of an entity, kept under its PropertyKey property.
+func dbtInfo(uniqueID, resourceType, pkg, path string, tags []string, meta map[string]interface{}) map[string]interface{} {
+	p := map[string]interface{}{
+		"unique_id":     uniqueID,
+		"resource_type": resourceType,
+	}
+	if pkg != "" {
+		p["package_name"] = pkg
+	}
+	if path != "" {
+		p["path"] = path
+	}
+	if len(tags) > 0 {
+		p["tags"] = tags
+	}
+	if len(meta) > 0 {
+		p["meta"] = meta
+	}
+	return p
+}
+
+// columns merges documented columns with the columns found in the warehouse.
+// Warehouse columns come first, in their ordinal order, followed by documented
+// columns missing from the warehouse. Names are matched case-insensitively as
+// warehouses differ in how they case identifiers.
+func columns(documented map[string]Column, found map[string]CatalogColumn) []interface{} {
+	docs := make(map[string]Column, len(documented))
+	for _, col := range documented {
+		docs[strings.ToLower(col.Name)] = col
+	}
+
+	catalog := make([]CatalogColumn, 0, len(found))
+	for _, col := range found {
+		catalog = append(catalog, col)
+	}
+	sort.Slice(catalog, func(i, j int) bool { return catalog[i].Index < catalog[j].Index })
+
+	var cols []interface{}
+	for _, cc := range catalog {
+		key := strings.ToLower(cc.Name)
+		doc, ok := docs[key]
+		delete(docs, key)
+		name := cc.Name
+		if ok {
+			name = doc.Name
+		}
+		cols = append(cols, column(name, cc.Type, firstNonEmpty(doc.Description, cc.Comment), doc))
+	}
+	for _, key := range sortedKeys(docs) {
+		doc := docs[key]
+		cols = append(cols, column(doc.Name, doc.DataType, doc.Description, doc))
+	}
+	return cols
+}
+
+func column(name, typ, description string, doc Column) map[string]interface{} {
+	col := map[string]interface{}{"name": name}
+	if typ != "" {
+		col["type"] = typ
+	}
+	if description != "" {
+		col["description"] = description
+	}
+	if len(doc.Tags) > 0 {
+		col["tags"] = doc.Tags
+	}
+	if len(doc.Meta) > 0 {
+		col["meta"] = doc.Meta
+	}
+	return col
+}
+
+// modelDocument renders the docs of a model, its description and column
+// descriptions, as a markdown document.
+func modelDocument(uniqueID string, ent entity.Entity, cols []interface{}) document.Document {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n%s\n", ent.Name, strings.TrimSpace(ent.Description))
+	if len(cols) > 0 {
+		b.WriteString("\n## Columns\n\n| Column | Type | Description |\n|--------|------|-------------|\n")
+		for _, c := range cols {
+			col := c.(map[string]interface{})
+			typ, _ := col["type"].(string)
+			desc, _ := col["description"].(string)
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", col["name"], typ, tableCell(desc))
+		}
+	}
+	return document.Document{
+		EntityURN: ent.URN,
+		Title:     ent.Name,
+		Body:      b.String(),
+		Format:    "markdown",
+		Source:    SourceSystem,
+		SourceID:  uniqueID,
+	}
+}
+
+func tableCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
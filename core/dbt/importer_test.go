@@ -0,0 +1,223 @@
+package dbt
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/raystack/compass/core/batch"
+	"github.com/raystack/compass/core/entity"
+)
+
+const sampleManifest = `{
+  "metadata": {"dbt_version": "1.8.0", "project_name": "shop", "adapter_type": "bigquery"},
+  "nodes": {
+    "model.shop.orders": {
+      "unique_id": "model.shop.orders", "resource_type": "model", "package_name": "shop",
+      "name": "orders", "alias": "orders", "database": "warehouse", "schema": "analytics",
+      "description": "One row per order.",
+      "columns": {
+        "order_id": {"name": "order_id", "description": "Primary key"},
+        "status": {"name": "status", "description": "Order | fulfilment status"}
+      },
+      "depends_on": {"nodes": ["model.shop.stg_orders", "source.shop.raw.orders", "macro.dbt.star"]},
+      "config": {"materialized": "table"},
+      "original_file_path": "models/orders.sql"
+    },
+    "model.shop.stg_orders": {
+      "unique_id": "model.shop.stg_orders", "resource_type": "model", "package_name": "shop",
+      "name": "stg_orders", "database": "warehouse", "schema": "staging",
+      "depends_on": {"nodes": ["source.shop.raw.orders"]},
+      "config": {"materialized": "ephemeral"}
+    },
+    "test.shop.not_null_orders_order_id": {
+      "unique_id": "test.shop.not_null_orders_order_id", "resource_type": "test",
+      "name": "not_null_orders_order_id",
+      "depends_on": {"nodes": ["model.shop.orders"]}
+    }
+  },
+  "sources": {
+    "source.shop.raw.orders": {
+      "unique_id": "source.shop.raw.orders", "source_name": "raw", "name": "orders",
+      "identifier": "orders_v2", "database": "lake", "schema": "raw",
+      "source_description": "Replicated from the shop database", "loader": "fivetran"
+    }
+  },
+  "exposures": {
+    "exposure.shop.revenue": {
+      "unique_id": "exposure.shop.revenue", "name": "revenue", "label": "Revenue", "type": "dashboard",
+      "url": "https://bi.example.com/revenue", "owner": {"name": "Finance", "email": "finance@example.com"},
+      "depends_on": {"nodes": ["model.shop.orders"]}
+    }
+  },
+  "metrics": {
+    "metric.shop.order_count": {
+      "unique_id": "metric.shop.order_count", "name": "order_count", "type": "simple",
+      "description": "Number of orders",
+      "depends_on": {"nodes": ["semantic_model.shop.orders"]}
+    }
+  }
+}`
+
+const sampleCatalog = `{
+  "nodes": {
+    "model.shop.orders": {
+      "metadata": {"type": "table", "database": "warehouse", "schema": "analytics", "name": "orders"},
+      "columns": {
+        "STATUS": {"name": "STATUS", "type": "STRING", "index": 2},
+        "ORDER_ID": {"name": "ORDER_ID", "type": "INT64", "index": 1},
+        "CREATED_AT": {"name": "CREATED_AT", "type": "TIMESTAMP", "index": 3, "comment": "Creation time"}
+      }
+    }
+  },
+  "sources": {}
+}`
+
+func readSample(t *testing.T) *Result {
+	t.Helper()
+	m, err := ReadManifest(strings.NewReader(sampleManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := ReadCatalog(strings.NewReader(sampleCatalog))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Import(m, c)
+}
+
+func entityByURN(t *testing.T, res *Result, urn string) entity.Entity {
+	t.Helper()
+	for _, e := range res.Entities {
+		if e.URN == urn {
+			return e
+		}
+	}
+	t.Fatalf("entity %s not imported", urn)
+	return entity.Entity{}
+}
+
+func TestImport_Entities(t *testing.T) {
+	res := readSample(t)
+	if len(res.Entities) != 5 {
+		t.Fatalf("expected 5 entities, got %d", len(res.Entities))
+	}
+
+	orders := entityByURN(t, res, "urn:bigquery:warehouse.analytics.orders")
+	if orders.Type != entity.TypeTable || orders.Source != SourceSystem || orders.Description != "One row per order." {
+		t.Errorf("unexpected model entity: %+v", orders)
+	}
+	info := orders.Properties[PropertyKey].(map[string]interface{})
+	if info["unique_id"] != "model.shop.orders" || info["materialized"] != "table" {
+		t.Errorf("unexpected dbt properties: %v", info)
+	}
+
+	cols := orders.Properties["columns"].([]interface{})
+	if len(cols) != 3 {
+		t.Fatalf("expected 3 columns, got %v", cols)
+	}
+	first := cols[0].(map[string]interface{})
+	if first["name"] != "order_id" || first["type"] != "INT64" || first["description"] != "Primary key" {
+		t.Errorf("expected documented name with warehouse type, got %v", first)
+	}
+	if last := cols[2].(map[string]interface{}); last["description"] != "Creation time" {
+		t.Errorf("expected warehouse comment as description, got %v", last)
+	}
+
+	entityByURN(t, res, "urn:dbt:model.shop.stg_orders")
+	src := entityByURN(t, res, "urn:bigquery:lake.raw.orders_v2")
+	if src.Name != "raw.orders" || src.Description != "Replicated from the shop database" {
+		t.Errorf("unexpected source entity: %+v", src)
+	}
+	if exp := entityByURN(t, res, "urn:dbt:exposure.shop.revenue"); exp.Type != entity.TypeDashboard || exp.Name != "Revenue" {
+		t.Errorf("unexpected exposure entity: %+v", exp)
+	}
+	if mt := entityByURN(t, res, "urn:dbt:metric.shop.order_count"); mt.Type != entity.TypeMetric {
+		t.Errorf("unexpected metric entity: %+v", mt)
+	}
+}
+
+func TestImport_Edges(t *testing.T) {
+	res := readSample(t)
+
+	got := map[string]bool{}
+	for _, e := range res.Edges {
+		if e.Type != EdgeLineage {
+			t.Errorf("unexpected edge type %q", e.Type)
+		}
+		got[e.SourceURN+" -> "+e.TargetURN] = true
+	}
+	want := []string{
+		"urn:dbt:model.shop.stg_orders -> urn:bigquery:warehouse.analytics.orders",
+		"urn:bigquery:lake.raw.orders_v2 -> urn:bigquery:warehouse.analytics.orders",
+		"urn:bigquery:lake.raw.orders_v2 -> urn:dbt:model.shop.stg_orders",
+		"urn:bigquery:warehouse.analytics.orders -> urn:dbt:exposure.shop.revenue",
+	}
+	if len(res.Edges) != len(want) {
+		t.Errorf("expected %d edges, got %v", len(want), got)
+	}
+	for _, w := range want {
+		if !got[w] {
+			t.Errorf("missing edge %s", w)
+		}
+	}
+}
+
+func TestImport_Impact(t *testing.T) {
+	res := readSample(t)
+
+	// Impact analysis walks edges from source to target.
+	downstream := func(urn string) []string {
+		seen := map[string]bool{urn: true}
+		var reached []string
+		for queue := []string{urn}; len(queue) > 0; queue = queue[1:] {
+			for _, e := range res.Edges {
+				if e.SourceURN == queue[0] && !seen[e.TargetURN] {
+					seen[e.TargetURN] = true
+					reached = append(reached, e.TargetURN)
+					queue = append(queue, e.TargetURN)
+				}
+			}
+		}
+		sort.Strings(reached)
+		return reached
+	}
+
+	want := []string{
+		"urn:bigquery:warehouse.analytics.orders",
+		"urn:dbt:exposure.shop.revenue",
+		"urn:dbt:model.shop.stg_orders",
+	}
+	if got := downstream("urn:bigquery:lake.raw.orders_v2"); !slices.Equal(got, want) {
+		t.Errorf("expected the source to impact %v, got %v", want, got)
+	}
+	if got := downstream("urn:dbt:exposure.shop.revenue"); len(got) != 0 {
+		t.Errorf("expected nothing downstream of the exposure, got %v", got)
+	}
+}
+
+func TestImport_Documents(t *testing.T) {
+	res := readSample(t)
+	if len(res.Documents) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(res.Documents))
+	}
+	doc := res.Documents[0]
+	if doc.EntityURN != "urn:bigquery:warehouse.analytics.orders" || doc.SourceID != "model.shop.orders" || doc.Source != SourceSystem {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+	if !strings.Contains(doc.Body, "| status | STRING | Order \\| fulfilment status |") {
+		t.Errorf("expected column table in body, got:\n%s", doc.Body)
+	}
+}
+
+func TestResult_Operations(t *testing.T) {
+	res := readSample(t)
+	ops := res.Operations()
+	if len(ops) != len(res.Entities)+len(res.Edges)+len(res.Documents) {
+		t.Fatalf("unexpected operation count %d", len(ops))
+	}
+	if ops[0].Action() != batch.ActionUpsertEntity || ops[len(ops)-1].Action() != batch.ActionUpsertDocument {
+		t.Errorf("expected entities first and documents last")
+	}
+}
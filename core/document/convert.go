@@ -0,0 +1,542 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Document formats. Documents in FormatHTML or FormatConfluenceStorage are
+// converted to FormatMarkdown when they are ingested.
+const (
+	FormatMarkdown          = "markdown"
+	FormatPlaintext         = "plaintext"
+	FormatHTML              = "html"
+	FormatConfluenceStorage = "confluence-storage"
+)
+
+// OriginalProperty is the property that keeps the body and format of a
+// document as it was ingested, before conversion to markdown.
+const OriginalProperty = "original"
+
+// ErrInvalidFormat is returned for a document in a format Compass cannot
+// store, or whose body cannot be converted to markdown.
+var ErrInvalidFormat = errors.New("invalid document format")
+
+// Normalize prepares a document for storage. An empty format defaults to
+// markdown. HTML and Confluence storage bodies are converted to markdown, so
+// that chunking can find their headings, and the original body and format are
+// kept under the OriginalProperty property.
+func Normalize(doc *Document) error {
+	switch doc.Format {
+	case "":
+		doc.Format = FormatMarkdown
+		return nil
+	case FormatMarkdown, FormatPlaintext:
+		return nil
+	case FormatHTML, FormatConfluenceStorage:
+	default:
+		return fmt.Errorf("%w %q: use markdown, plaintext, html or confluence-storage", ErrInvalidFormat, doc.Format)
+	}
+
+	var (
+		body string
+		err  error
+	)
+	if doc.Format == FormatHTML {
+		body, err = HTMLToMarkdown(doc.Body)
+	} else {
+		body, err = ConfluenceToMarkdown(doc.Body)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: convert %s body: %v", ErrInvalidFormat, doc.Format, err)
+	}
+	if body == "" {
+		return fmt.Errorf("%w: %s body has no content", ErrInvalidFormat, doc.Format)
+	}
+
+	props := make(map[string]interface{}, len(doc.Properties)+1)
+	for k, v := range doc.Properties {
+		props[k] = v
+	}
+	props[OriginalProperty] = map[string]interface{}{
+		"format": doc.Format,
+		"body":   doc.Body,
+	}
+	doc.Properties = props
+	doc.Body = body
+	doc.Format = FormatMarkdown
+	return nil
+}
+
+// HTMLToMarkdown converts an HTML page or fragment to markdown. Headings,
+// paragraphs, lists, links, images, code, quotes and tables are kept;
+// scripts, styles and other markup are dropped.
+func HTMLToMarkdown(s string) (string, error) {
+	root, err := html.Parse(strings.NewReader(s))
+	if err != nil {
+		return "", err
+	}
+	return renderMarkdown(root), nil
+}
+
+var (
+	cdataPattern = regexp.MustCompile(`(?s)<!\[CDATA\[(.*?)\]\]>`)
+	// Confluence elements are XML; the HTML parser does not know them and
+	// would ignore the slash of an empty element.
+	emptyElementPattern = regexp.MustCompile(`<((?:ac|ri):[A-Za-z-]+)([^<>]*?)/>`)
+)
+
+// ConfluenceToMarkdown converts a page in Confluence storage format, the
+// XHTML that Confluence exports, to markdown. Besides HTML it understands
+// code, panel and task list macros, page links and attached images.
+func ConfluenceToMarkdown(s string) (string, error) {
+	s = cdataPattern.ReplaceAllStringFunc(s, func(m string) string {
+		return html.EscapeString(cdataPattern.FindStringSubmatch(m)[1])
+	})
+	s = emptyElementPattern.ReplaceAllString(s, "<$1$2></$1>")
+	return HTMLToMarkdown(s)
+}
+
+func renderMarkdown(root *html.Node) string {
+	blocks := renderBlocks(root)
+	if len(blocks) == 0 {
+		return ""
+	}
+	return strings.Join(blocks, "\n\n") + "\n"
+}
+
+var blockElements = map[string]bool{
+	"html": true, "head": true, "body": true, "p": true, "div": true, "section": true,
+	"article": true, "main": true, "header": true, "footer": true, "nav": true, "aside": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"pre": true, "blockquote": true, "hr": true, "table": true, "figure": true,
+	"script": true, "style": true, "title": true, "noscript": true, "template": true,
+	"ac:structured-macro": true, "ac:rich-text-body": true, "ac:task-list": true,
+	"ac:layout": true, "ac:layout-section": true, "ac:layout-cell": true,
+}
+
+func isBlock(n *html.Node) bool {
+	return n.Type == html.ElementNode && blockElements[n.Data]
+}
+
+// renderBlocks renders the children of n as markdown blocks. Runs of inline
+// children become a paragraph.
+func renderBlocks(n *html.Node) []string {
+	var blocks []string
+	var para strings.Builder
+	flush := func() {
+		if p := cleanParagraph(para.String()); p != "" {
+			blocks = append(blocks, p)
+		}
+		para.Reset()
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if isBlock(c) {
+			flush()
+			blocks = append(blocks, renderBlock(c)...)
+			continue
+		}
+		para.WriteString(renderInline(c))
+	}
+	flush()
+	return blocks
+}
+
+func renderBlock(n *html.Node) []string {
+	switch n.Data {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		text := strings.TrimSpace(collapseSpace(textContent(n)))
+		if text == "" {
+			return nil
+		}
+		return []string{strings.Repeat("#", int(n.Data[1]-'0')) + " " + text}
+	case "ul", "ol":
+		if list := renderList(n); list != "" {
+			return []string{list}
+		}
+		return nil
+	case "pre":
+		return []string{fence(textContent(n), codeLanguage(n))}
+	case "blockquote":
+		return quote(renderBlocks(n))
+	case "hr":
+		return []string{"---"}
+	case "table":
+		if table := renderTable(n); table != "" {
+			return []string{table}
+		}
+		return nil
+	case "ac:structured-macro":
+		return renderMacro(n)
+	case "ac:task-list":
+		if list := renderTaskList(n); list != "" {
+			return []string{list}
+		}
+		return nil
+	case "head", "script", "style", "title", "noscript", "template":
+		return nil
+	default:
+		return renderBlocks(n)
+	}
+}
+
+func renderInline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return collapseSpace(n.Data)
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if isBlock(n) {
+		return " " + strings.Join(renderBlock(n), " ") + " "
+	}
+
+	switch n.Data {
+	case "br":
+		return "\n"
+	case "strong", "b":
+		return wrap("**", inlineChildren(n))
+	case "em", "i":
+		return wrap("*", inlineChildren(n))
+	case "s", "del", "strike":
+		return wrap("~~", inlineChildren(n))
+	case "code", "tt", "kbd":
+		text := collapseSpace(textContent(n))
+		if strings.TrimSpace(text) == "" {
+			return text
+		}
+		return wrap("`", text)
+	case "a":
+		text := strings.TrimSpace(inlineChildren(n))
+		href := attr(n, "href")
+		if href == "" || strings.HasPrefix(href, "javascript:") {
+			return text
+		}
+		if text == "" {
+			text = href
+		}
+		return "[" + text + "](" + href + ")"
+	case "img":
+		src := attr(n, "src")
+		if src == "" {
+			return ""
+		}
+		return "![" + attr(n, "alt") + "](" + src + ")"
+	case "ac:link":
+		return renderConfluenceLink(n)
+	case "ac:image":
+		return renderConfluenceImage(n)
+	case "ac:parameter", "ac:emoticon", "ac:placeholder":
+		return ""
+	default:
+		return inlineChildren(n)
+	}
+}
+
+func inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(renderInline(c))
+	}
+	return b.String()
+}
+
+// renderList renders a list, indenting nested blocks of an item under its
+// marker.
+func renderList(n *html.Node) string {
+	var items []string
+	num := 1
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.Data != "li" {
+			continue
+		}
+		marker := "- "
+		if n.Data == "ol" {
+			marker = fmt.Sprintf("%d. ", num)
+			num++
+		}
+		items = append(items, listItem(marker, renderBlocks(li)))
+	}
+	return strings.Join(items, "\n")
+}
+
+func renderTaskList(n *html.Node) string {
+	var items []string
+	for task := n.FirstChild; task != nil; task = task.NextSibling {
+		if task.Type != html.ElementNode || task.Data != "ac:task" {
+			continue
+		}
+		marker := "- [ ] "
+		if status := child(task, "ac:task-status"); status != nil && strings.TrimSpace(textContent(status)) == "complete" {
+			marker = "- [x] "
+		}
+		var blocks []string
+		if body := child(task, "ac:task-body"); body != nil {
+			blocks = renderBlocks(body)
+		}
+		items = append(items, listItem(marker, blocks))
+	}
+	return strings.Join(items, "\n")
+}
+
+func listItem(marker string, blocks []string) string {
+	indent := strings.Repeat(" ", len(marker))
+	text := strings.Join(blocks, "\n")
+	lines := strings.Split(text, "\n")
+	for i := 1; i < len(lines); i++ {
+		if lines[i] != "" {
+			lines[i] = indent + lines[i]
+		}
+	}
+	return marker + strings.Join(lines, "\n")
+}
+
+// renderTable renders a table as a markdown table with its first row as the
+// header.
+func renderTable(n *html.Node) string {
+	var rows [][]string
+	width := 0
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.Data {
+			case "tr":
+				var row []string
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type == html.ElementNode && (cell.Data == "td" || cell.Data == "th") {
+						row = append(row, tableCell(cell))
+					}
+				}
+				rows = append(rows, row)
+				width = max(width, len(row))
+			case "thead", "tbody", "tfoot":
+				walk(c)
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 || width == 0 {
+		return ""
+	}
+
+	lines := make([]string, 0, len(rows)+1)
+	for i, row := range rows {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		lines = append(lines, "| "+strings.Join(row, " | ")+" |")
+		if i == 0 {
+			lines = append(lines, "|"+strings.Repeat(" --- |", width))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func tableCell(n *html.Node) string {
+	text := strings.Join(renderBlocks(n), " ")
+	text = strings.ReplaceAll(text, "\n", " ")
+	return strings.ReplaceAll(text, "|", `\|`)
+}
+
+// macroLabels are the Confluence panel macros rendered as labelled quotes.
+var macroLabels = map[string]string{
+	"info":    "Info",
+	"note":    "Note",
+	"tip":     "Tip",
+	"warning": "Warning",
+}
+
+func renderMacro(n *html.Node) []string {
+	name := attr(n, "ac:name")
+	switch name {
+	case "code", "noformat":
+		body := child(n, "ac:plain-text-body")
+		if body == nil {
+			return nil
+		}
+		return []string{fence(textContent(body), macroParameter(n, "language"))}
+	}
+
+	var blocks []string
+	if body := child(n, "ac:rich-text-body"); body != nil {
+		blocks = renderBlocks(body)
+	} else if body := child(n, "ac:plain-text-body"); body != nil {
+		if text := strings.TrimSpace(textContent(body)); text != "" {
+			blocks = []string{text}
+		}
+	}
+	title := collapseSpace(macroParameter(n, "title"))
+
+	if label, ok := macroLabels[name]; ok || name == "panel" {
+		heading := label
+		if title != "" && heading != "" {
+			heading += ": " + title
+		} else if title != "" {
+			heading = title
+		}
+		if heading != "" {
+			blocks = append([]string{"**" + heading + "**"}, blocks...)
+		}
+		return quote(blocks)
+	}
+	if title != "" && len(blocks) > 0 {
+		blocks = append([]string{"**" + title + "**"}, blocks...)
+	}
+	return blocks
+}
+
+func macroParameter(n *html.Node, name string) string {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == "ac:parameter" && attr(c, "ac:name") == name {
+			return strings.TrimSpace(textContent(c))
+		}
+	}
+	return ""
+}
+
+// renderConfluenceLink renders a link to a page, attachment or URL as its
+// text. Page links are kept as text since their URLs are not known.
+func renderConfluenceLink(n *html.Node) string {
+	for _, body := range []string{"ac:plain-text-link-body", "ac:link-body"} {
+		if c := child(n, body); c != nil {
+			if text := strings.TrimSpace(inlineChildren(c)); text != "" {
+				return text
+			}
+		}
+	}
+	if c := child(n, "ri:page"); c != nil {
+		return attr(c, "ri:content-title")
+	}
+	if c := child(n, "ri:attachment"); c != nil {
+		return attr(c, "ri:filename")
+	}
+	if c := child(n, "ri:url"); c != nil {
+		return attr(c, "ri:value")
+	}
+	return ""
+}
+
+func renderConfluenceImage(n *html.Node) string {
+	var src string
+	if c := child(n, "ri:attachment"); c != nil {
+		src = attr(c, "ri:filename")
+	} else if c := child(n, "ri:url"); c != nil {
+		src = attr(c, "ri:value")
+	}
+	if src == "" {
+		return ""
+	}
+	return "![" + attr(n, "ac:alt") + "](" + src + ")"
+}
+
+func quote(blocks []string) []string {
+	if len(blocks) == 0 {
+		return nil
+	}
+	lines := strings.Split(strings.Join(blocks, "\n\n"), "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = ">"
+		} else {
+			lines[i] = "> " + line
+		}
+	}
+	return []string{strings.Join(lines, "\n")}
+}
+
+// fence renders code as a fenced block, using a longer fence when the code
+// holds one.
+func fence(code, lang string) string {
+	code = strings.TrimPrefix(code, "\n")
+	code = strings.TrimRight(code, "\n ")
+	marker := "```"
+	for strings.Contains(code, marker) {
+		marker += "`"
+	}
+	return marker + lang + "\n" + code + "\n" + marker
+}
+
+// codeLanguage returns the language of a pre block from a "language-" or
+// "lang-" class on it or its code element.
+func codeLanguage(n *html.Node) string {
+	nodes := []*html.Node{n}
+	if c := child(n, "code"); c != nil {
+		nodes = append(nodes, c)
+	}
+	for _, node := range nodes {
+		for _, class := range strings.Fields(attr(node, "class")) {
+			for _, prefix := range []string{"language-", "lang-"} {
+				if strings.HasPrefix(class, prefix) {
+					return strings.TrimPrefix(class, prefix)
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func wrap(marker, s string) string {
+	inner := strings.TrimSpace(s)
+	if inner == "" {
+		return s
+	}
+	lead := s[:len(s)-len(strings.TrimLeft(s, " \n"))]
+	trail := s[len(strings.TrimRight(s, " \n")):]
+	return lead + marker + inner + marker + trail
+}
+
+// cleanParagraph trims the lines of a run of inline content and drops blank
+// ones.
+func cleanParagraph(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+var spacePattern = regexp.MustCompile(`[ \t\r\n\f]+`)
+
+func collapseSpace(s string) string {
+	return spacePattern.ReplaceAllString(s, " ")
+}
+
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+func child(n *html.Node, name string) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && c.Data == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
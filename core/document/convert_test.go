@@ -0,0 +1,167 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/raystack/compass/core/chunking"
+	"github.com/raystack/compass/core/namespace"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "page",
+			html: `<html><head><title>Orders</title><style>p { color: red }</style></head><body>
+				<h1>Orders</h1>
+				<p>Restart the <strong>orders </strong>job, see <a href="https://wiki/deploys">deploys</a>.<br>Then wait.</p>
+				<script>track()</script>
+			</body></html>`,
+			want: "# Orders\n\nRestart the **orders** job, see [deploys](https://wiki/deploys).\nThen wait.\n",
+		},
+		{
+			name: "lists",
+			html: `<ol><li>Drain<ul><li>run <code>kubectl drain</code></li></ul></li><li><p>Restart</p></li></ol>`,
+			want: "1. Drain\n   - run `kubectl drain`\n2. Restart\n",
+		},
+		{
+			name: "code",
+			html: "<pre><code class=\"language-sql\">SELECT *\n  FROM orders;\n</code></pre>",
+			want: "```sql\nSELECT *\n  FROM orders;\n```\n",
+		},
+		{
+			name: "table",
+			html: `<table><thead><tr><th>Env</th><th>Host</th></tr></thead><tbody><tr><td>prod</td><td>a|b</td></tr><tr><td>dev</td></tr></tbody></table>`,
+			want: "| Env | Host |\n| --- | --- |\n| prod | a\\|b |\n| dev |  |\n",
+		},
+		{
+			name: "quote",
+			html: `<blockquote><p>One</p><p>Two</p></blockquote><hr><p><em>done</em></p>`,
+			want: "> One\n>\n> Two\n\n---\n\n*done*\n",
+		},
+		{
+			name: "empty",
+			html: `<div> </div>`,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HTMLToMarkdown(tt.html)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("expected\n%q\ngot\n%q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestConfluenceToMarkdown(t *testing.T) {
+	page := `<h1>Runbook</h1>
+<ac:structured-macro ac:name="toc" />
+<p>Owned by data platform, see <ac:link><ri:page ri:content-title="Deploys" /></ac:link> and
+<ac:link><ri:page ri:content-title="On-call" /><ac:plain-text-link-body><![CDATA[the rota]]></ac:plain-text-link-body></ac:link>.</p>
+<ac:structured-macro ac:name="warning"><ac:parameter ac:name="title">Paging</ac:parameter><ac:rich-text-body><p>Page before restarting.</p></ac:rich-text-body></ac:structured-macro>
+<h2>Restart</h2>
+<ac:structured-macro ac:name="code"><ac:parameter ac:name="language">bash</ac:parameter><ac:plain-text-body><![CDATA[kubectl rollout restart deploy/orders && echo "<ok>"]]></ac:plain-text-body></ac:structured-macro>
+<ac:task-list><ac:task><ac:task-id>1</ac:task-id><ac:task-status>complete</ac:task-status><ac:task-body>Check dashboards</ac:task-body></ac:task><ac:task><ac:task-id>2</ac:task-id><ac:task-status>incomplete</ac:task-status><ac:task-body>Post in channel</ac:task-body></ac:task></ac:task-list>
+<p><ac:image ac:alt="architecture"><ri:attachment ri:filename="arch.png" /></ac:image></p>`
+
+	want := "# Runbook\n\n" +
+		"Owned by data platform, see Deploys and the rota.\n\n" +
+		"> **Warning: Paging**\n>\n> Page before restarting.\n\n" +
+		"## Restart\n\n" +
+		"```bash\nkubectl rollout restart deploy/orders && echo \"<ok>\"\n```\n\n" +
+		"- [x] Check dashboards\n- [ ] Post in channel\n\n" +
+		"![architecture](arch.png)\n"
+
+	got, err := ConfluenceToMarkdown(page)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+
+	chunks := chunking.SplitDocument("Runbook", got, chunking.Options{})
+	var headings []string
+	for _, c := range chunks {
+		headings = append(headings, c.Heading)
+	}
+	if len(chunks) < 2 || chunks[len(chunks)-1].Heading != "Restart" {
+		t.Errorf("expected chunks split on headings, got %q", headings)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	doc := &Document{
+		Title:      "Runbook",
+		Body:       "<h2>Restart</h2><p>Run the job.</p>",
+		Format:     FormatHTML,
+		Properties: map[string]interface{}{"space": "DATA"},
+	}
+	if err := Normalize(doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Format != FormatMarkdown || doc.Body != "## Restart\n\nRun the job.\n" {
+		t.Errorf("unexpected document %q: %q", doc.Format, doc.Body)
+	}
+	original, ok := doc.Properties[OriginalProperty].(map[string]interface{})
+	if !ok || original["format"] != FormatHTML || original["body"] != "<h2>Restart</h2><p>Run the job.</p>" {
+		t.Errorf("expected original kept in properties, got %v", doc.Properties)
+	}
+	if doc.Properties["space"] != "DATA" {
+		t.Errorf("expected other properties kept, got %v", doc.Properties)
+	}
+
+	for _, format := range []string{"", FormatMarkdown, FormatPlaintext} {
+		doc := &Document{Body: "<b>as is</b>", Format: format}
+		if err := Normalize(doc); err != nil {
+			t.Fatal(err)
+		}
+		if doc.Body != "<b>as is</b>" || doc.Properties != nil {
+			t.Errorf("expected %q document unchanged, got %+v", format, doc)
+		}
+	}
+
+	for _, doc := range []*Document{
+		{Body: "text", Format: "docx"},
+		{Body: "<script>x()</script>", Format: FormatHTML},
+	} {
+		if err := Normalize(doc); !errors.Is(err, ErrInvalidFormat) {
+			t.Errorf("expected ErrInvalidFormat for %+v, got %v", doc, err)
+		}
+	}
+}
+
+func TestService_Upsert_ConvertsConfluence(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	id, err := svc.Upsert(ctx, ns, &Document{
+		EntityURN: "urn:table:orders",
+		Title:     "Runbook",
+		Body:      `<h1>Runbook</h1><ac:structured-macro ac:name="info"><ac:rich-text-body><p>Ask in #data.</p></ac:rich-text-body></ac:structured-macro>`,
+		Format:    FormatConfluenceStorage,
+	})
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	got, _ := svc.GetByID(ctx, id)
+	if got.Format != FormatMarkdown || got.Body != "# Runbook\n\n> **Info**\n>\n> Ask in #data.\n" {
+		t.Errorf("unexpected stored document %q: %q", got.Format, got.Body)
+	}
+
+	if _, err := svc.Upsert(ctx, ns, &Document{EntityURN: "urn:table:orders", Title: "Doc", Body: "b", Format: "docx"}); !errors.Is(err, ErrInvalidFormat) {
+		t.Errorf("expected ErrInvalidFormat, got %v", err)
+	}
+}
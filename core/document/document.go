@@ -16,7 +16,7 @@ type Document struct {
 	EntityURN   string                 `json:"entity_urn"`
 	Title       string                 `json:"title"`
 	Body        string                 `json:"body"`
-	Format      string                 `json:"format,omitempty"`    // "markdown", "plaintext"
+	Format      string                 `json:"format,omitempty"`    // "markdown", "plaintext"; "html" and "confluence-storage" are converted to markdown
 	Source      string                 `json:"source,omitempty"`    // "confluence", "github", "manual"
 	SourceID    string                 `json:"source_id,omitempty"` // original doc ID for dedup
 	Properties  map[string]interface{} `json:"properties,omitempty"`
@@ -0,0 +1,71 @@
+package document
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNoFrontMatter is returned for a markdown file without a front matter
+// block.
+var ErrNoFrontMatter = errors.New("no front matter")
+
+// FrontMatter is the YAML block at the top of a markdown file that describes
+// the document the file holds.
+type FrontMatter struct {
+	EntityURN  string                 `yaml:"entity_urn"`
+	Title      string                 `yaml:"title"`
+	SourceID   string                 `yaml:"source_id"`
+	Format     string                 `yaml:"format"`
+	Properties map[string]interface{} `yaml:"properties"`
+}
+
+// ParseMarkdown splits a markdown file into its front matter, delimited by
+// "---" lines at the top of the file, and its body.
+func ParseMarkdown(data []byte) (FrontMatter, string, error) {
+	var fm FrontMatter
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if !strings.HasPrefix(text, "---\n") {
+		return fm, "", ErrNoFrontMatter
+	}
+	rest := text[len("---\n"):]
+
+	var header, body string
+	switch {
+	case strings.HasPrefix(rest, "---\n"):
+		body = rest[len("---\n"):]
+	case rest == "---":
+	case strings.Contains(rest, "\n---\n"):
+		end := strings.Index(rest, "\n---\n")
+		header, body = rest[:end], rest[end+len("\n---\n"):]
+	case strings.HasSuffix(rest, "\n---"):
+		header = strings.TrimSuffix(rest, "\n---")
+	default:
+		return fm, "", errors.New("front matter is not closed")
+	}
+	if err := yaml.Unmarshal([]byte(header), &fm); err != nil {
+		return fm, "", fmt.Errorf("decode front matter: %w", err)
+	}
+	return fm, strings.TrimLeft(body, "\n"), nil
+}
+
+// MarkdownTitle returns the text of the first level-one heading of a
+// markdown body, or "" when there is none.
+func MarkdownTitle(body string) string {
+	inFence := false
+	for _, line := range strings.Split(body, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if !inFence && strings.HasPrefix(trimmed, "# ") {
+			return strings.TrimSpace(strings.TrimRight(trimmed[2:], "#"))
+		}
+	}
+	return ""
+}
@@ -0,0 +1,62 @@
+package document
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseMarkdown(t *testing.T) {
+	data := "---\r\nentity_urn: urn:bigquery:shop.orders\r\ntitle: Orders runbook\r\nproperties:\r\n  owner: data-platform\r\n  tier: 1\r\n---\r\n\r\n# Orders\r\n\r\nRestart the job.\r\n"
+	fm, body, err := ParseMarkdown([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm.EntityURN != "urn:bigquery:shop.orders" || fm.Title != "Orders runbook" || fm.SourceID != "" {
+		t.Errorf("unexpected front matter %+v", fm)
+	}
+	if fm.Properties["owner"] != "data-platform" || fm.Properties["tier"] != 1 {
+		t.Errorf("unexpected properties %v", fm.Properties)
+	}
+	if body != "# Orders\n\nRestart the job.\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+}
+
+func TestParseMarkdown_Edges(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		body    string
+		wantErr error
+	}{
+		{"no front matter", "# Title\n", "", ErrNoFrontMatter},
+		{"empty front matter", "---\n---\nbody\n", "body\n", nil},
+		{"closed at end of file", "---\ntitle: x\n---", "", nil},
+		{"horizontal rule in body", "---\ntitle: x\n---\nabove\n---\nbelow\n", "above\n---\nbelow\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, body, err := ParseMarkdown([]byte(tt.data))
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if body != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, body)
+			}
+		})
+	}
+
+	if _, _, err := ParseMarkdown([]byte("---\ntitle: x\nbody\n")); err == nil {
+		t.Error("expected error for unclosed front matter")
+	}
+}
+
+func TestMarkdownTitle(t *testing.T) {
+	body := "Intro\n\n```sh\n# not a heading\n```\n\n## Sub\n\n# Real Title #\n"
+	if got := MarkdownTitle(body); got != "Real Title" {
+		t.Errorf("expected Real Title, got %q", got)
+	}
+	if got := MarkdownTitle("no heading"); got != "" {
+		t.Errorf("expected no title, got %q", got)
+	}
+}
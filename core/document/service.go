@@ -27,9 +27,11 @@ func (s *Service) WithPipeline(p EmbeddingPipeline) {
 	s.pipeline = p
 }
 
+// Upsert stores a document, converting HTML and Confluence storage bodies to
+// markdown first.
 func (s *Service) Upsert(ctx context.Context, ns *namespace.Namespace, doc *Document) (string, error) {
-	if doc.Format == "" {
-		doc.Format = "markdown"
+	if err := Normalize(doc); err != nil {
+		return "", err
 	}
 	id, err := s.repo.Upsert(ctx, ns, doc)
 	if err != nil {
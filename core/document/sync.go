@@ -0,0 +1,142 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+// ErrSyncIncomplete is returned when some documents of a sync failed. Nothing
+// is pruned then, since a document that failed to write would look removed.
+var ErrSyncIncomplete = errors.New("some documents failed to sync; nothing was deleted")
+
+// syncPageSize is the page size used to list the documents of a source.
+const syncPageSize = 500
+
+// SyncSummary reports the changes a sync made.
+type SyncSummary struct {
+	Source    string        `json:"source"`
+	Created   int           `json:"created"`
+	Updated   int           `json:"updated"`
+	Unchanged int           `json:"unchanged"`
+	Deleted   int           `json:"deleted"`
+	Failed    []SyncFailure `json:"failed,omitempty"`
+}
+
+// SyncFailure is a document a sync could not write.
+type SyncFailure struct {
+	EntityURN string `json:"entity_urn"`
+	SourceID  string `json:"source_id"`
+	Error     string `json:"error"`
+}
+
+// Sync makes the documents of source match docs, a complete set identified
+// by entity URN and source ID. Documents whose content changed are upserted
+// and unchanged ones are left alone, so they are not embedded again. With
+// prune, documents of source missing from docs are deleted, unless a document
+// failed, in which case ErrSyncIncomplete is returned with the summary.
+func (s *Service) Sync(ctx context.Context, ns *namespace.Namespace, source string, docs []Document, prune bool) (SyncSummary, error) {
+	summary := SyncSummary{Source: source}
+	if source == "" {
+		return summary, errors.New("source is required")
+	}
+
+	existing, err := s.sourceDocuments(ctx, ns, source)
+	if err != nil {
+		return summary, err
+	}
+
+	synced := map[string]bool{}
+	for i := range docs {
+		doc := &docs[i]
+		doc.Source = source
+		fail := func(msg string) {
+			summary.Failed = append(summary.Failed, SyncFailure{EntityURN: doc.EntityURN, SourceID: doc.SourceID, Error: msg})
+		}
+		if doc.EntityURN == "" || doc.Title == "" || doc.Body == "" || doc.SourceID == "" {
+			fail("entity_urn, title, body and source_id are required")
+			continue
+		}
+		if err := Normalize(doc); err != nil {
+			fail(err.Error())
+			continue
+		}
+		key := syncKey(doc.EntityURN, doc.SourceID)
+		if synced[key] {
+			fail("duplicate entity_urn and source_id")
+			continue
+		}
+		synced[key] = true
+
+		prev, ok := existing[key]
+		if ok && sameDocumentContent(prev, *doc) {
+			summary.Unchanged++
+			continue
+		}
+		if _, err := s.Upsert(ctx, ns, doc); err != nil {
+			fail(err.Error())
+			continue
+		}
+		if ok {
+			summary.Updated++
+		} else {
+			summary.Created++
+		}
+	}
+
+	if !prune {
+		return summary, nil
+	}
+	if len(summary.Failed) > 0 {
+		return summary, ErrSyncIncomplete
+	}
+	for key, doc := range existing {
+		if synced[key] {
+			continue
+		}
+		if err := s.repo.Delete(ctx, ns, doc.ID); err != nil {
+			return summary, fmt.Errorf("delete document %s: %w", doc.ID, err)
+		}
+		summary.Deleted++
+	}
+	return summary, nil
+}
+
+// sourceDocuments returns the documents of source keyed by entity URN and
+// source ID.
+func (s *Service) sourceDocuments(ctx context.Context, ns *namespace.Namespace, source string) (map[string]Document, error) {
+	docs := map[string]Document{}
+	for offset := 0; ; offset += syncPageSize {
+		page, err := s.repo.GetAll(ctx, ns, Filter{Source: source, Size: syncPageSize, Offset: offset})
+		if err != nil {
+			return nil, fmt.Errorf("list documents of %s: %w", source, err)
+		}
+		for _, d := range page {
+			if d.Source == source {
+				docs[syncKey(d.EntityURN, d.SourceID)] = d
+			}
+		}
+		if len(page) < syncPageSize {
+			return docs, nil
+		}
+	}
+}
+
+func syncKey(entityURN, sourceID string) string {
+	return entityURN + "\x00" + sourceID
+}
+
+// sameDocumentContent reports whether doc carries the content of the stored
+// document prev.
+func sameDocumentContent(prev, doc Document) bool {
+	if prev.Title != doc.Title || prev.Body != doc.Body || prev.Format != doc.Format {
+		return false
+	}
+	if len(prev.Properties) == 0 && len(doc.Properties) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(prev.Properties, doc.Properties)
+}
@@ -0,0 +1,125 @@
+package document
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+func runbook(urn, sourceID, title, body string) Document {
+	return Document{EntityURN: urn, SourceID: sourceID, Title: title, Body: body}
+}
+
+func TestService_Sync(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	if _, err := svc.Upsert(ctx, ns, &Document{EntityURN: "urn:c", Title: "Wiki", Body: "x", Source: "confluence", SourceID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	docs := []Document{
+		runbook("urn:a", "a.md", "A", "first"),
+		runbook("urn:b", "b.md", "B", "first"),
+	}
+	summary, err := svc.Sync(ctx, ns, "runbooks", docs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Created != 2 || summary.Updated != 0 || summary.Deleted != 0 {
+		t.Errorf("unexpected first sync %+v", summary)
+	}
+	if d := repo.documents["urn:a/A"]; d.Source != "runbooks" || d.Format != "markdown" {
+		t.Errorf("expected source and default format to be set, got %+v", d)
+	}
+
+	// b.md changed, a.md is unchanged and c.md is new.
+	docs = []Document{
+		runbook("urn:a", "a.md", "A", "first"),
+		runbook("urn:b", "b.md", "B", "second"),
+		runbook("urn:c", "c.md", "C", "first"),
+	}
+	summary, err = svc.Sync(ctx, ns, "runbooks", docs, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Created != 1 || summary.Updated != 1 || summary.Unchanged != 1 {
+		t.Errorf("unexpected second sync %+v", summary)
+	}
+
+	// a.md was removed.
+	docs = []Document{
+		runbook("urn:b", "b.md", "B", "second"),
+		runbook("urn:c", "c.md", "C", "first"),
+	}
+	summary, err = svc.Sync(ctx, ns, "runbooks", docs, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Deleted != 1 || summary.Unchanged != 2 {
+		t.Errorf("unexpected pruning sync %+v", summary)
+	}
+	if _, ok := repo.documents["urn:a/A"]; ok {
+		t.Error("expected removed file to be pruned")
+	}
+	if _, ok := repo.documents["urn:c/Wiki"]; !ok {
+		t.Error("expected documents of other sources to be kept")
+	}
+}
+
+func TestService_Sync_FailuresSkipPrune(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	if _, err := svc.Sync(ctx, ns, "runbooks", []Document{runbook("urn:a", "a.md", "A", "body")}, true); err != nil {
+		t.Fatal(err)
+	}
+
+	docs := []Document{
+		runbook("urn:b", "b.md", "B", ""),
+		runbook("urn:c", "c.md", "C", "body"),
+		runbook("urn:c", "c.md", "C", "body"),
+	}
+	summary, err := svc.Sync(ctx, ns, "runbooks", docs, true)
+	if !errors.Is(err, ErrSyncIncomplete) {
+		t.Fatalf("expected ErrSyncIncomplete, got %v", err)
+	}
+	if len(summary.Failed) != 2 || summary.Created != 1 || summary.Deleted != 0 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+	if _, ok := repo.documents["urn:a/A"]; !ok {
+		t.Error("expected nothing to be pruned after a failure")
+	}
+
+	if _, err := svc.Sync(ctx, ns, "", nil, false); err == nil {
+		t.Error("expected error without source")
+	}
+}
+
+func TestService_Sync_ConvertedUnchanged(t *testing.T) {
+	svc := NewService(newMockRepo())
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	page := func() []Document {
+		doc := runbook("urn:a", "123", "Orders", "<h1>Orders</h1><p>Restart the job.</p>")
+		doc.Format = FormatConfluenceStorage
+		return []Document{doc}
+	}
+	if _, err := svc.Sync(ctx, ns, "confluence", page(), false); err != nil {
+		t.Fatal(err)
+	}
+	summary, err := svc.Sync(ctx, ns, "confluence", page(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Unchanged != 1 || summary.Updated != 0 {
+		t.Errorf("expected converted page to be unchanged, got %+v", summary)
+	}
+}
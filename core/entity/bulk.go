@@ -0,0 +1,248 @@
+package entity
+
+import (
+	"context"
+	"errors"
+
+	"github.com/raystack/compass/core/change"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/principal"
+)
+
+// UpsertStatus is the outcome of writing one record.
+type UpsertStatus string
+
+const (
+	UpsertCreated   UpsertStatus = "created"
+	UpsertUpdated   UpsertStatus = "updated"
+	UpsertUnchanged UpsertStatus = "unchanged"
+	UpsertFailed    UpsertStatus = "error"
+)
+
+// UpsertOutcome reports what a batched upsert did with one entity.
+type UpsertOutcome struct {
+	Status UpsertStatus
+	// Previous is the version an update replaced.
+	Previous *Entity
+}
+
+// BulkRepository writes many entities or edges with a handful of statements.
+// Keys must be unique within a batch; outcomes are returned in input order.
+type BulkRepository interface {
+	// UpsertEntities writes the entities in one transaction. Entities whose
+	// type is in columns are written as UpsertWithColumns writes them.
+	UpsertEntities(ctx context.Context, ns *namespace.Namespace, ents []*Entity, columns map[Type]bool) ([]UpsertOutcome, error)
+	UpsertEdges(ctx context.Context, ns *namespace.Namespace, edges []*Edge) ([]UpsertStatus, error)
+	// CheckEntities and CheckEdges report the status the upserts would
+	// return, without writing.
+	CheckEntities(ctx context.Context, ns *namespace.Namespace, ents []*Entity) ([]UpsertStatus, error)
+	CheckEdges(ctx context.Context, ns *namespace.Namespace, edges []*Edge) ([]UpsertStatus, error)
+	// Prune soft-deletes the current entities and edges of the source and
+	// scope whose URNs or edge keys are not kept.
+	Prune(ctx context.Context, ns *namespace.Namespace, source, scope string, keepEntities, keepEdges map[string]bool) (PruneResult, error)
+}
+
+// BulkRecord is one record of a bulk write: either an entity or an edge.
+type BulkRecord struct {
+	Entity *Entity `json:"entity,omitempty"`
+	Edge   *Edge   `json:"edge,omitempty"`
+}
+
+// BulkResult is the outcome of one bulk record. Index is the record's
+// position in the request.
+type BulkResult struct {
+	Index  int          `json:"index"`
+	Kind   string       `json:"kind"`
+	Key    string       `json:"key,omitempty"`
+	ID     string       `json:"id,omitempty"`
+	Status UpsertStatus `json:"status"`
+	Error  string       `json:"error,omitempty"`
+	// Warning reports how an entity written anyway departs from the
+	// definition of its type.
+	Warning string `json:"warning,omitempty"`
+	// SchemaChange reports how an update changed the entity's schema, with
+	// the downstream entities a breaking change reaches.
+	SchemaChange *SchemaImpact `json:"schema_change,omitempty"`
+}
+
+var (
+	errBulkRecord = errors.New("record must hold exactly one of entity or edge")
+	errBulkEntity = errors.New("entity urn, type and name are required")
+	errBulkEdge   = errors.New("edge source_urn, target_urn and type are required")
+)
+
+// BulkUpsert writes a batch of entity and edge records. Invalid records fail
+// on their own; a storage error fails every record of the chunk it hit.
+// Repeated keys are written in order, each in its own chunk, so the last
+// record wins as it would with one call per record.
+func (s *Service) BulkUpsert(ctx context.Context, ns *namespace.Namespace, offset int, records []BulkRecord) []BulkResult {
+	return s.bulkWrite(ctx, ns, offset, records, false)
+}
+
+// BulkCheck reports what BulkUpsert would do with each record, without
+// writing. Records are compared with the stored state only, so a key
+// repeated in the batch is reported as if it were written once.
+func (s *Service) BulkCheck(ctx context.Context, ns *namespace.Namespace, offset int, records []BulkRecord) []BulkResult {
+	return s.bulkWrite(ctx, ns, offset, records, true)
+}
+
+func (s *Service) bulkWrite(ctx context.Context, ns *namespace.Namespace, offset int, records []BulkRecord, dryRun bool) []BulkResult {
+	results := make([]BulkResult, len(records))
+	types := s.newTypeChecker(ns)
+	var ents, edges []int
+	for i, rec := range records {
+		results[i] = BulkResult{Index: offset + i, Status: UpsertFailed}
+		switch {
+		case (rec.Entity == nil) == (rec.Edge == nil):
+			results[i].Error = errBulkRecord.Error()
+		case rec.Entity != nil:
+			rec.Entity.PropertySources = nil
+			results[i].Kind, results[i].Key = "entity", rec.Entity.URN
+			if rec.Entity.URN == "" || rec.Entity.Type == "" || rec.Entity.Name == "" {
+				results[i].Error = errBulkEntity.Error()
+				continue
+			}
+			// Canonicalise before chunking, so two spellings of one URN are
+			// never written in the same statement.
+			if err := rec.Entity.CanonicalizeURN(ns); err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			results[i].Key = rec.Entity.URN
+			warning, err := types.check(ctx, *rec.Entity)
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			results[i].Warning = warning
+			ents = append(ents, i)
+		default:
+			e := rec.Edge
+			results[i].Kind, results[i].Key = "edge", change.EdgeKey(e.SourceURN, e.Type, e.TargetURN)
+			if e.SourceURN == "" || e.TargetURN == "" || e.Type == "" {
+				results[i].Error = errBulkEdge.Error()
+				continue
+			}
+			if err := e.CanonicalizeURNs(ns); err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			results[i].Key = change.EdgeKey(e.SourceURN, e.Type, e.TargetURN)
+			if err := ValidateEdge(*e); err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			edges = append(edges, i)
+		}
+	}
+	if s.bulk == nil {
+		for _, i := range append(ents, edges...) {
+			results[i].Error = "bulk writes are not enabled"
+		}
+		return results
+	}
+
+	changedBy := principal.FromContext(ctx).Subject
+	for _, chunk := range uniqueChunks(ents, func(i int) string { return records[i].Entity.URN }) {
+		batch := make([]*Entity, len(chunk))
+		for j, i := range chunk {
+			batch[j] = records[i].Entity
+			batch[j].ChangedBy = changedBy
+		}
+		if dryRun {
+			statuses, err := s.bulk.CheckEntities(ctx, ns, batch)
+			setStatuses(results, chunk, statuses, err, func(j int) string { return batch[j].ID })
+			continue
+		}
+		outcomes, err := s.bulk.UpsertEntities(ctx, ns, batch, s.columnTypes)
+		for j, i := range chunk {
+			if err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			results[i].ID, results[i].Status = batch[j].ID, outcomes[j].Status
+			results[i].SchemaChange = s.afterUpsert(ctx, ns, batch[j], outcomes[j])
+		}
+	}
+
+	// Edge declarations are checked here rather than by the bulk repository,
+	// so a violation fails its own record instead of its chunk. Entities are
+	// written first, so their types are known.
+	declared := s.newEdgeChecker(ns)
+	for _, chunk := range uniqueChunks(edges, func(i int) string { return results[i].Key }) {
+		var batch []*Edge
+		var allowed []int
+		for _, i := range chunk {
+			if err := declared.check(ctx, *records[i].Edge); err != nil {
+				results[i].Error = err.Error()
+				continue
+			}
+			batch, allowed = append(batch, records[i].Edge), append(allowed, i)
+		}
+		if len(batch) == 0 {
+			continue
+		}
+		chunk = allowed
+		upsert := s.bulk.UpsertEdges
+		if dryRun {
+			upsert = s.bulk.CheckEdges
+		}
+		statuses, err := upsert(ctx, ns, batch)
+		setStatuses(results, chunk, statuses, err, func(j int) string { return batch[j].ID })
+	}
+	return results
+}
+
+// setStatuses fills in the results of a chunk from its statuses, or fails
+// every record of the chunk with err.
+func setStatuses(results []BulkResult, chunk []int, statuses []UpsertStatus, err error, id func(int) string) {
+	for j, i := range chunk {
+		if err != nil {
+			results[i].Error = err.Error()
+			continue
+		}
+		results[i].ID, results[i].Status = id(j), statuses[j]
+	}
+}
+
+// afterUpsert runs the side effects Upsert has for a written entity and
+// returns the schema change an update made, if any.
+func (s *Service) afterUpsert(ctx context.Context, ns *namespace.Namespace, ent *Entity, outcome UpsertOutcome) *SchemaImpact {
+	if outcome.Status == UpsertUnchanged {
+		return nil
+	}
+	if s.pipeline != nil {
+		_ = s.pipeline.EnqueueEntity(ctx, ns, ent)
+	}
+	if outcome.Previous == nil {
+		return nil
+	}
+	impact, err := s.schemaImpact(ctx, ns, *outcome.Previous, *ent)
+	if err != nil || impact.Level == SchemaUnchanged {
+		return nil
+	}
+	if s.schema != nil {
+		s.schema.NotifySchemaChange(ctx, ns, impact)
+	}
+	return impact
+}
+
+// uniqueChunks splits indexes into consecutive runs with no repeated key.
+func uniqueChunks(indexes []int, key func(int) string) [][]int {
+	var chunks [][]int
+	var cur []int
+	seen := map[string]bool{}
+	for _, i := range indexes {
+		k := key(i)
+		if seen[k] {
+			chunks = append(chunks, cur)
+			cur, seen = nil, map[string]bool{}
+		}
+		seen[k] = true
+		cur = append(cur, i)
+	}
+	if len(cur) > 0 {
+		chunks = append(chunks, cur)
+	}
+	return chunks
+}
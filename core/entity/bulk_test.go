@@ -0,0 +1,225 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/principal"
+)
+
+// mockBulkRepo records the batches it receives and reports every entity as
+// created, except URNs listed in existing, which it reports as updated.
+type mockBulkRepo struct {
+	entityBatches [][]string
+	edgeBatches   [][]string
+	existing      map[string]Entity
+	failEdges     bool
+	pruned        []string
+}
+
+func (m *mockBulkRepo) UpsertEntities(_ context.Context, _ *namespace.Namespace, ents []*Entity, _ map[Type]bool) ([]UpsertOutcome, error) {
+	var urns []string
+	outcomes := make([]UpsertOutcome, len(ents))
+	for i, e := range ents {
+		urns = append(urns, e.URN)
+		e.ID = "id-" + e.URN
+		outcomes[i] = UpsertOutcome{Status: UpsertCreated}
+		if prev, ok := m.existing[e.URN]; ok {
+			outcomes[i] = UpsertOutcome{Status: UpsertUpdated, Previous: &prev}
+		}
+	}
+	m.entityBatches = append(m.entityBatches, urns)
+	return outcomes, nil
+}
+
+func (m *mockBulkRepo) UpsertEdges(_ context.Context, _ *namespace.Namespace, edges []*Edge) ([]UpsertStatus, error) {
+	if m.failEdges {
+		return nil, errors.New("connection reset")
+	}
+	var keys []string
+	statuses := make([]UpsertStatus, len(edges))
+	for i, e := range edges {
+		keys = append(keys, e.SourceURN+">"+e.TargetURN)
+		statuses[i] = UpsertUnchanged
+	}
+	m.edgeBatches = append(m.edgeBatches, keys)
+	return statuses, nil
+}
+
+func (m *mockBulkRepo) CheckEntities(_ context.Context, _ *namespace.Namespace, ents []*Entity) ([]UpsertStatus, error) {
+	statuses := make([]UpsertStatus, len(ents))
+	for i, e := range ents {
+		statuses[i] = UpsertCreated
+		if _, ok := m.existing[e.URN]; ok {
+			statuses[i] = UpsertUpdated
+		}
+	}
+	return statuses, nil
+}
+
+func (m *mockBulkRepo) CheckEdges(_ context.Context, _ *namespace.Namespace, edges []*Edge) ([]UpsertStatus, error) {
+	if m.failEdges {
+		return nil, errors.New("connection reset")
+	}
+	statuses := make([]UpsertStatus, len(edges))
+	for i := range edges {
+		statuses[i] = UpsertUnchanged
+	}
+	return statuses, nil
+}
+
+func (m *mockBulkRepo) Prune(_ context.Context, _ *namespace.Namespace, source, scope string, keepEntities, keepEdges map[string]bool) (PruneResult, error) {
+	m.pruned = append(m.pruned, source+"/"+scope)
+	return PruneResult{Entities: 3 - len(keepEntities), Edges: 1}, nil
+}
+
+func TestService_BulkUpsert(t *testing.T) {
+	bulk := &mockBulkRepo{}
+	svc := NewService(newMockRepo(), nil, nil)
+	svc.WithBulkRepository(bulk)
+	ctx := principal.NewContext(context.Background(), principal.Principal{Subject: "extractor"})
+
+	a := &Entity{URN: "urn:a", Type: TypeTable, Name: "a", ChangedBy: "mallory"}
+	records := []BulkRecord{
+		{Entity: a},
+		{Edge: &Edge{SourceURN: "urn:a", TargetURN: "urn:b", Type: "lineage"}},
+		{Entity: &Entity{URN: "urn:b", Type: TypeTable}},
+		{},
+		{Entity: &Entity{URN: "urn:a", Type: TypeTable, Name: "a2"}},
+	}
+	results := svc.BulkUpsert(ctx, namespace.DefaultNamespace, 10, records)
+
+	if len(results) != len(records) {
+		t.Fatalf("expected %d results, got %d", len(records), len(results))
+	}
+	for i, res := range results {
+		if res.Index != 10+i {
+			t.Errorf("result %d: expected index %d, got %d", i, 10+i, res.Index)
+		}
+	}
+	if results[0].Status != UpsertCreated || results[0].ID != "id-urn:a" || results[0].Kind != "entity" {
+		t.Errorf("unexpected entity result: %+v", results[0])
+	}
+	if results[1].Status != UpsertUnchanged || results[1].Key != "urn:a|lineage|urn:b" {
+		t.Errorf("unexpected edge result: %+v", results[1])
+	}
+	if results[2].Status != UpsertFailed || results[2].Error == "" {
+		t.Errorf("expected entity without name to fail, got %+v", results[2])
+	}
+	if results[3].Status != UpsertFailed || results[3].Error == "" {
+		t.Errorf("expected empty record to fail, got %+v", results[3])
+	}
+	if a.ChangedBy != "extractor" {
+		t.Errorf("expected changed_by from principal, got %q", a.ChangedBy)
+	}
+
+	// The repeated URN goes into a second batch so that it is written last.
+	if len(bulk.entityBatches) != 2 || len(bulk.entityBatches[0]) != 1 || bulk.entityBatches[1][0] != "urn:a" {
+		t.Errorf("expected repeated URN in its own batch, got %v", bulk.entityBatches)
+	}
+}
+
+func TestService_BulkUpsert_StorageError(t *testing.T) {
+	bulk := &mockBulkRepo{failEdges: true}
+	svc := NewService(newMockRepo(), nil, nil)
+	svc.WithBulkRepository(bulk)
+
+	results := svc.BulkUpsert(context.Background(), namespace.DefaultNamespace, 0, []BulkRecord{
+		{Entity: &Entity{URN: "urn:a", Type: TypeTable, Name: "a"}},
+		{Edge: &Edge{SourceURN: "urn:a", TargetURN: "urn:b", Type: "lineage"}},
+		{Edge: &Edge{SourceURN: "urn:b", TargetURN: "urn:c", Type: "lineage"}},
+	})
+	if results[0].Status != UpsertCreated {
+		t.Errorf("expected entity to be written, got %+v", results[0])
+	}
+	for _, res := range results[1:] {
+		if res.Status != UpsertFailed || res.Error != "connection reset" {
+			t.Errorf("expected edge batch to fail, got %+v", res)
+		}
+	}
+}
+
+func TestService_BulkUpsert_NotifiesSchemaChanges(t *testing.T) {
+	prev := Entity{URN: "urn:a", Type: TypeTable, Name: "a", Properties: map[string]interface{}{
+		"columns": []interface{}{map[string]interface{}{"name": "id", "type": "int"}},
+	}}
+	bulk := &mockBulkRepo{existing: map[string]Entity{"urn:a": prev}}
+	notifier := &recordingNotifier{}
+	svc := NewService(newMockRepo(), nil, nil)
+	svc.WithBulkRepository(bulk)
+	svc.WithSchemaChangeNotifier(notifier)
+
+	results := svc.BulkUpsert(context.Background(), namespace.DefaultNamespace, 0, []BulkRecord{
+		{Entity: &Entity{URN: "urn:a", Type: TypeTable, Name: "a", Properties: map[string]interface{}{"columns": []interface{}{}}}},
+		{Entity: &Entity{URN: "urn:new", Type: TypeTable, Name: "new"}},
+	})
+	if len(notifier.impacts) != 1 || notifier.impacts[0].Level != SchemaBreaking {
+		t.Errorf("expected one breaking schema change, got %+v", notifier.impacts)
+	}
+	if sc := results[0].SchemaChange; sc == nil || sc.Level != SchemaBreaking || sc.Type != TypeTable {
+		t.Errorf("expected the result to carry the breaking change, got %+v", sc)
+	}
+	if results[1].SchemaChange != nil {
+		t.Errorf("expected no schema change for a new entity, got %+v", results[1].SchemaChange)
+	}
+}
+
+func TestService_BulkCheck(t *testing.T) {
+	prev := Entity{URN: "urn:a", Type: TypeTable, Name: "a"}
+	bulk := &mockBulkRepo{existing: map[string]Entity{"urn:a": prev}}
+	notifier := &recordingNotifier{}
+	svc := NewService(newMockRepo(), nil, nil)
+	svc.WithBulkRepository(bulk)
+	svc.WithSchemaChangeNotifier(notifier)
+
+	results := svc.BulkCheck(context.Background(), namespace.DefaultNamespace, 10, []BulkRecord{
+		{Entity: &Entity{URN: "urn:a", Type: TypeTable, Name: "a", Properties: map[string]interface{}{"columns": []interface{}{}}}},
+		{Entity: &Entity{URN: "urn:b", Type: TypeTable, Name: "b"}},
+		{Edge: &Edge{SourceURN: "urn:a", TargetURN: "urn:b", Type: "lineage"}},
+		{Entity: &Entity{URN: "urn:c"}},
+	})
+
+	want := []UpsertStatus{UpsertUpdated, UpsertCreated, UpsertUnchanged, UpsertFailed}
+	for i, res := range results {
+		if res.Index != 10+i || res.Status != want[i] {
+			t.Errorf("record %d: expected %s at index %d, got %+v", i, want[i], 10+i, res)
+		}
+	}
+	if len(bulk.entityBatches) != 0 || len(bulk.edgeBatches) != 0 {
+		t.Errorf("expected nothing to be written, got %v and %v", bulk.entityBatches, bulk.edgeBatches)
+	}
+	if len(notifier.impacts) != 0 {
+		t.Errorf("expected no schema notifications, got %+v", notifier.impacts)
+	}
+}
+
+func TestService_BulkUpsert_CanonicalizesURNs(t *testing.T) {
+	bulk := &mockBulkRepo{}
+	svc := NewService(newMockRepo(), nil, nil)
+	svc.WithBulkRepository(bulk)
+	ns := &namespace.Namespace{Name: "tenant", Metadata: map[string]interface{}{
+		"urn": map[string]interface{}{"sources": []interface{}{"bigquery"}},
+	}}
+
+	results := svc.BulkUpsert(context.Background(), ns, 0, []BulkRecord{
+		{Entity: &Entity{URN: "urn:bigquery:Proj.ds.t", Type: TypeTable, Name: "t"}},
+		{Entity: &Entity{URN: " urn:bigquery:proj.ds.t", Type: TypeTable, Name: "t"}},
+		{Edge: &Edge{SourceURN: "urn:BigQuery:proj.ds.t", TargetURN: "urn:bigquery:proj.ds.u", Type: "lineage"}},
+		{Entity: &Entity{URN: "urn:mysql:db.t", Type: TypeTable, Name: "t"}},
+	})
+
+	if results[0].Key != "urn:bigquery:proj.ds.t" || results[1].Key != results[0].Key {
+		t.Errorf("expected both spellings to be keyed canonically, got %q and %q", results[0].Key, results[1].Key)
+	}
+	if len(bulk.entityBatches) != 2 {
+		t.Errorf("expected the two spellings to be written in separate batches, got %v", bulk.entityBatches)
+	}
+	if len(bulk.edgeBatches) != 1 || bulk.edgeBatches[0][0] != "urn:bigquery:proj.ds.t>urn:bigquery:proj.ds.u" {
+		t.Errorf("expected edge endpoints to be canonicalised, got %v", bulk.edgeBatches)
+	}
+	if results[3].Status != UpsertFailed || results[3].Error == "" {
+		t.Errorf("expected urn of an unlisted source to fail, got %+v", results[3])
+	}
+}
@@ -0,0 +1,183 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+// TypeColumn is the type of the column entities derived from a table-like
+// entity's properties.columns.
+const TypeColumn Type = "column"
+
+// Edge types of column entities. A has_column edge runs from an entity to
+// each of its columns. A derived_from edge runs from a column to a column it
+// is computed from, so its target is upstream of its source.
+const (
+	EdgeHasColumn   = "has_column"
+	EdgeDerivedFrom = "derived_from"
+)
+
+// columnSeparator joins the URN of an entity and the name of one of its
+// columns into the column's URN.
+const columnSeparator = "#"
+
+// ErrNotColumn is returned for a derived_from edge whose ends are not both
+// column URNs.
+var ErrNotColumn = errors.New("derived_from edges must link two column urns")
+
+// ColumnURN returns the URN of a column of the entity entityURN.
+func ColumnURN(entityURN, column string) string {
+	return entityURN + columnSeparator + column
+}
+
+// ParseColumnURN splits a column URN into the URN of its entity and the
+// column name.
+func ParseColumnURN(urn string) (entityURN, column string, ok bool) {
+	entityURN, column, ok = strings.Cut(urn, columnSeparator)
+	if !ok || entityURN == "" || column == "" {
+		return "", "", false
+	}
+	return entityURN, column, true
+}
+
+// ColumnEntities derives a column entity for each entry of ent's
+// properties.columns, and the has_column edges linking ent to them. A column's
+// description becomes the entity description; its other attributes, such as
+// its type, become the entity properties. Columns take ent's source and scope.
+func ColumnEntities(ent Entity) ([]Entity, []Edge) {
+	cols, ok := namedMaps(ent.Properties["columns"])
+	if !ok {
+		return nil, nil
+	}
+
+	var (
+		columns []Entity
+		edges   []Edge
+	)
+	seen := map[string]bool{}
+	for _, c := range cols {
+		name := columnName(c)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		props := make(map[string]interface{}, len(c))
+		for k, v := range c {
+			if k != "name" && k != "description" {
+				props[k] = v
+			}
+		}
+		if len(props) == 0 {
+			props = nil
+		}
+		desc, _ := c["description"].(string)
+		urn := ColumnURN(ent.URN, name)
+		columns = append(columns, Entity{
+			URN:         urn,
+			Type:        TypeColumn,
+			Name:        name,
+			Description: desc,
+			Properties:  props,
+			Source:      ent.Source,
+			Scope:       ent.Scope,
+			ChangedBy:   ent.ChangedBy,
+		})
+		edges = append(edges, Edge{
+			SourceURN: ent.URN,
+			TargetURN: urn,
+			Type:      EdgeHasColumn,
+			Source:    ent.Source,
+			Scope:     ent.Scope,
+		})
+	}
+	return columns, edges
+}
+
+// ValidateEdge checks the edge types with a fixed shape: derived_from edges
+// must link two column URNs.
+func ValidateEdge(e Edge) error {
+	if e.Type != EdgeDerivedFrom {
+		return nil
+	}
+	if _, _, ok := ParseColumnURN(e.SourceURN); !ok {
+		return fmt.Errorf("%w: %q is not a column urn", ErrNotColumn, e.SourceURN)
+	}
+	if _, _, ok := ParseColumnURN(e.TargetURN); !ok {
+		return fmt.Errorf("%w: %q is not a column urn", ErrNotColumn, e.TargetURN)
+	}
+	return nil
+}
+
+// ColumnExpansion is what writing an entity does to its column entities.
+type ColumnExpansion struct {
+	Columns []Entity
+	Edges   []Edge
+	// Removed are the URNs of current column entities the entity no longer
+	// has.
+	Removed []string
+}
+
+// expandsColumns reports whether entities of type t get column entities.
+func (s *Service) expandsColumns(t Type) bool {
+	return s.columnTypes[t]
+}
+
+// ExpandColumns returns the column entities and has_column edges of ent, and
+// the columns it dropped, when column entities are enabled for its type.
+func (s *Service) ExpandColumns(ctx context.Context, ns *namespace.Namespace, ent Entity) (ColumnExpansion, error) {
+	if !s.expandsColumns(ent.Type) {
+		return ColumnExpansion{}, nil
+	}
+	current, err := s.currentColumns(ctx, ns, ent.URN)
+	if err != nil {
+		return ColumnExpansion{}, err
+	}
+	return PlanColumns(ent, current), nil
+}
+
+// PlanColumns returns the column entities and has_column edges of ent, and
+// the URNs of the current columns, given as current, it no longer has.
+func PlanColumns(ent Entity, current []string) ColumnExpansion {
+	var exp ColumnExpansion
+	exp.Columns, exp.Edges = ColumnEntities(ent)
+	kept := make(map[string]bool, len(exp.Columns))
+	for _, c := range exp.Columns {
+		kept[c.URN] = true
+	}
+	for _, urn := range current {
+		if !kept[urn] {
+			exp.Removed = append(exp.Removed, urn)
+		}
+	}
+	return exp
+}
+
+// currentColumns returns the URNs of the current columns of urn.
+func (s *Service) currentColumns(ctx context.Context, ns *namespace.Namespace, urn string) ([]string, error) {
+	if s.edges == nil {
+		return nil, nil
+	}
+	edges, err := s.edges.GetBySource(ctx, ns, urn, EdgeFilter{Types: []string{EdgeHasColumn}, Current: true})
+	if err != nil {
+		return nil, fmt.Errorf("get columns of %s: %w", urn, err)
+	}
+	columns := make([]string, len(edges))
+	for i, e := range edges {
+		columns[i] = e.TargetURN
+	}
+	return columns, nil
+}
+
+// CurrentColumns returns the URNs of the current column entities of urn, or
+// none when column entities are disabled.
+func (s *Service) CurrentColumns(ctx context.Context, ns *namespace.Namespace, urn string) ([]string, error) {
+	if len(s.columnTypes) == 0 {
+		return nil, nil
+	}
+	return s.currentColumns(ctx, ns, urn)
+}
@@ -0,0 +1,142 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+func TestParseColumnURN(t *testing.T) {
+	entityURN, column, ok := ParseColumnURN(ColumnURN("urn:bigquery:shop.orders", "customer_id"))
+	if !ok || entityURN != "urn:bigquery:shop.orders" || column != "customer_id" {
+		t.Errorf("unexpected split %q %q %v", entityURN, column, ok)
+	}
+	for _, urn := range []string{"urn:bigquery:shop.orders", "urn:bigquery:shop.orders#", "#customer_id"} {
+		if _, _, ok := ParseColumnURN(urn); ok {
+			t.Errorf("expected %q not to be a column urn", urn)
+		}
+	}
+}
+
+func TestColumnEntities(t *testing.T) {
+	ent := Entity{
+		URN: "urn:bigquery:shop.orders", Type: TypeTable, Name: "orders", Source: "bigquery", Scope: "prod",
+		Properties: map[string]interface{}{
+			"columns": []interface{}{
+				map[string]interface{}{"name": "id", "type": "INT64", "description": "Order ID"},
+				map[string]interface{}{"name": "customer_id", "type": "INT64"},
+				map[string]interface{}{"name": "id", "type": "STRING"},
+			},
+		},
+	}
+	columns, edges := ColumnEntities(ent)
+	if len(columns) != 2 || len(edges) != 2 {
+		t.Fatalf("expected 2 columns and edges, got %d and %d", len(columns), len(edges))
+	}
+	id := columns[0]
+	if id.URN != "urn:bigquery:shop.orders#id" || id.Type != TypeColumn || id.Name != "id" || id.Description != "Order ID" {
+		t.Errorf("unexpected column %+v", id)
+	}
+	if id.Properties["type"] != "INT64" || id.Properties["name"] != nil || id.Properties["description"] != nil {
+		t.Errorf("unexpected column properties %v", id.Properties)
+	}
+	if id.Source != "bigquery" || id.Scope != "prod" {
+		t.Errorf("expected column to take source and scope, got %q %q", id.Source, id.Scope)
+	}
+	if e := edges[1]; e.SourceURN != ent.URN || e.TargetURN != "urn:bigquery:shop.orders#customer_id" || e.Type != EdgeHasColumn {
+		t.Errorf("unexpected edge %+v", e)
+	}
+
+	if columns, _ := ColumnEntities(Entity{URN: "urn:x", Properties: map[string]interface{}{"columns": "id"}}); columns != nil {
+		t.Errorf("expected no columns, got %v", columns)
+	}
+}
+
+func TestValidateEdge(t *testing.T) {
+	if err := ValidateEdge(Edge{SourceURN: "urn:a#x", TargetURN: "urn:b#y", Type: EdgeDerivedFrom}); err != nil {
+		t.Errorf("expected column edge to be valid, got %v", err)
+	}
+	if err := ValidateEdge(Edge{SourceURN: "urn:a", TargetURN: "urn:b", Type: "lineage"}); err != nil {
+		t.Errorf("expected entity edge to be valid, got %v", err)
+	}
+	if err := ValidateEdge(Edge{SourceURN: "urn:a#x", TargetURN: "urn:b", Type: EdgeDerivedFrom}); !errors.Is(err, ErrNotColumn) {
+		t.Errorf("expected ErrNotColumn, got %v", err)
+	}
+}
+
+func TestPlanColumns(t *testing.T) {
+	table := Entity{URN: "urn:orders", Type: TypeTable, Name: "orders", Properties: map[string]interface{}{
+		"columns": []interface{}{
+			map[string]interface{}{"name": "id", "type": "STRING"},
+			map[string]interface{}{"name": "email", "type": "STRING"},
+		},
+	}}
+
+	exp := PlanColumns(table, []string{"urn:orders#id", "urn:orders#name"})
+	if len(exp.Columns) != 2 || exp.Columns[0].URN != "urn:orders#id" || exp.Columns[1].URN != "urn:orders#email" {
+		t.Errorf("expected the id and email columns, got %+v", exp.Columns)
+	}
+	if len(exp.Edges) != 2 || exp.Edges[0].Type != EdgeHasColumn || exp.Edges[0].SourceURN != "urn:orders" {
+		t.Errorf("expected has_column edges, got %+v", exp.Edges)
+	}
+	// Dropping a column deletes its entity.
+	if !reflect.DeepEqual(exp.Removed, []string{"urn:orders#name"}) {
+		t.Errorf("expected the dropped column to be removed, got %v", exp.Removed)
+	}
+
+	// An entity without columns drops all of them.
+	exp = PlanColumns(Entity{URN: "urn:orders", Type: TypeTable}, []string{"urn:orders#id"})
+	if len(exp.Columns) != 0 || !reflect.DeepEqual(exp.Removed, []string{"urn:orders#id"}) {
+		t.Errorf("expected every column to be removed, got %+v", exp)
+	}
+}
+
+func TestService_Upsert_ColumnEntities(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, &mockEdgeRepo{}, nil)
+	svc.WithColumnEntities(TypeTable)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	columns := map[string]interface{}{"columns": []interface{}{map[string]interface{}{"name": "id", "type": "STRING"}}}
+	if _, err := svc.Upsert(ctx, ns, &Entity{URN: "urn:orders", Type: TypeTable, Name: "orders", Properties: columns}); err != nil {
+		t.Fatal(err)
+	}
+	// Other types are left alone.
+	if _, err := svc.Upsert(ctx, ns, &Entity{URN: "urn:job", Type: TypeJob, Name: "job", Properties: columns}); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(repo.withColumns, []string{"urn:orders"}) {
+		t.Errorf("expected only the table to be written with its columns, got %v", repo.withColumns)
+	}
+}
+
+func TestService_GetImpact_Column(t *testing.T) {
+	derived := []Edge{{SourceURN: "urn:revenue#customer_id", TargetURN: "urn:orders#customer_id", Type: EdgeDerivedFrom}}
+	edges := &mockEdgeRepo{upstreamEdges: derived, downstreamEdges: []Edge{{SourceURN: "urn:orders", TargetURN: "urn:revenue", Type: "lineage"}}}
+	svc := NewService(newMockRepo(), edges, nil)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	// Without column entities a column URN is an ordinary URN.
+	got, err := svc.GetImpact(ctx, ns, "urn:orders#customer_id", 0)
+	if err != nil || len(got) != 1 || got[0].Type != "lineage" {
+		t.Errorf("expected entity impact, got %+v, %v", got, err)
+	}
+
+	svc.WithColumnEntities(TypeTable)
+	got, err = svc.GetImpact(ctx, ns, "urn:orders#customer_id", 0)
+	if err != nil || len(got) != 1 || got[0].SourceURN != "urn:revenue#customer_id" {
+		t.Errorf("expected column impact, got %+v, %v", got, err)
+	}
+	if f := edges.lastUpstreamFilter; len(f.Types) != 1 || f.Types[0] != EdgeDerivedFrom {
+		t.Errorf("expected traversal of derived_from edges, got %+v", f)
+	}
+
+	if got, _ := svc.GetImpact(ctx, ns, "urn:orders", 0); len(got) != 1 || got[0].Type != "lineage" {
+		t.Errorf("expected entity impact for a table, got %+v", got)
+	}
+}
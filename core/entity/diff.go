@@ -0,0 +1,301 @@
+package entity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrVersionNotFound is returned when no version of an entity matches a diff bound.
+var ErrVersionNotFound = errors.New("entity version not found")
+
+// ChangeKind describes how a value differs between two versions.
+type ChangeKind string
+
+const (
+	ChangeAdded   ChangeKind = "added"
+	ChangeRemoved ChangeKind = "removed"
+	ChangeChanged ChangeKind = "changed"
+)
+
+// FieldChange is a single changed value. Path is dotted, e.g. "name" or
+// "properties.owner.team".
+type FieldChange struct {
+	Path string      `json:"path"`
+	Kind ChangeKind  `json:"kind"`
+	From interface{} `json:"from,omitempty"`
+	To   interface{} `json:"to,omitempty"`
+}
+
+// ColumnChange is a change to one entry of properties.columns, matched by name.
+// Changes lists attribute-level differences for changed columns.
+type ColumnChange struct {
+	Name    string                 `json:"name"`
+	Kind    ChangeKind             `json:"kind"`
+	From    map[string]interface{} `json:"from,omitempty"`
+	To      map[string]interface{} `json:"to,omitempty"`
+	Changes []FieldChange          `json:"changes,omitempty"`
+}
+
+// VersionRef identifies one side of a diff.
+type VersionRef struct {
+	ValidFrom time.Time  `json:"valid_from"`
+	ValidTo   *time.Time `json:"valid_to,omitempty"`
+	Source    string     `json:"source,omitempty"`
+	ChangedBy string     `json:"changed_by,omitempty"`
+}
+
+// EntityDiff is the structured difference between two versions of an entity.
+type EntityDiff struct {
+	URN     string         `json:"urn"`
+	From    VersionRef     `json:"from"`
+	To      VersionRef     `json:"to"`
+	Changes []FieldChange  `json:"changes"`
+	Columns []ColumnChange `json:"columns,omitempty"`
+}
+
+// IsEmpty reports whether the two versions carry the same content.
+func (d *EntityDiff) IsEmpty() bool {
+	return len(d.Changes) == 0 && len(d.Columns) == 0
+}
+
+// DiffEntities compares two versions of an entity. Top-level fields and
+// nested properties are reported as FieldChanges; when both versions carry a
+// properties.columns list, columns are compared by name instead.
+func DiffEntities(from, to Entity) *EntityDiff {
+	d := &EntityDiff{
+		URN:     to.URN,
+		From:    versionRef(from),
+		To:      versionRef(to),
+		Changes: []FieldChange{},
+	}
+
+	for _, f := range []struct {
+		path     string
+		from, to string
+	}{
+		{"type", string(from.Type), string(to.Type)},
+		{"name", from.Name, to.Name},
+		{"description", from.Description, to.Description},
+		{"source", from.Source, to.Source},
+		{"scope", from.Scope, to.Scope},
+	} {
+		if f.from != f.to {
+			d.Changes = append(d.Changes, stringChange(f.path, f.from, f.to))
+		}
+	}
+
+	fromCols, fromOK := columnList(from.Properties)
+	toCols, toOK := columnList(to.Properties)
+	skip := map[string]bool{}
+	if fromOK && toOK {
+		skip["properties.columns"] = true
+		d.Columns = diffColumns(fromCols, toCols)
+	}
+	diffValues("properties", asMap(from.Properties), asMap(to.Properties), skip, &d.Changes)
+
+	return d
+}
+
+func versionRef(e Entity) VersionRef {
+	return VersionRef{ValidFrom: e.ValidFrom, ValidTo: e.ValidTo, Source: e.Source, ChangedBy: e.ChangedBy}
+}
+
+func stringChange(path, from, to string) FieldChange {
+	switch {
+	case from == "":
+		return FieldChange{Path: path, Kind: ChangeAdded, To: to}
+	case to == "":
+		return FieldChange{Path: path, Kind: ChangeRemoved, From: from}
+	default:
+		return FieldChange{Path: path, Kind: ChangeChanged, From: from, To: to}
+	}
+}
+
+// diffValues appends changes between two arbitrary JSON-like values. Maps are
+// walked key by key; everything else is compared as a leaf.
+func diffValues(path string, from, to interface{}, skip map[string]bool, out *[]FieldChange) {
+	if skip[path] {
+		return
+	}
+	fm, fromIsMap := asMapOK(from)
+	tm, toIsMap := asMapOK(to)
+	if fromIsMap && toIsMap {
+		for _, k := range unionKeys(fm, tm) {
+			fv, inFrom := fm[k]
+			tv, inTo := tm[k]
+			child := joinPath(path, k)
+			switch {
+			case skip[child]:
+			case !inFrom:
+				*out = append(*out, FieldChange{Path: child, Kind: ChangeAdded, To: tv})
+			case !inTo:
+				*out = append(*out, FieldChange{Path: child, Kind: ChangeRemoved, From: fv})
+			default:
+				diffValues(child, fv, tv, skip, out)
+			}
+		}
+		return
+	}
+	if !sameValue(from, to) {
+		*out = append(*out, FieldChange{Path: path, Kind: ChangeChanged, From: from, To: to})
+	}
+}
+
+func diffColumns(from, to []map[string]interface{}) []ColumnChange {
+	fromByName := make(map[string]map[string]interface{}, len(from))
+	for _, c := range from {
+		fromByName[columnName(c)] = c
+	}
+	toByName := make(map[string]map[string]interface{}, len(to))
+	for _, c := range to {
+		toByName[columnName(c)] = c
+	}
+
+	var changes []ColumnChange
+	for _, c := range from {
+		name := columnName(c)
+		next, ok := toByName[name]
+		if !ok {
+			changes = append(changes, ColumnChange{Name: name, Kind: ChangeRemoved, From: c})
+			continue
+		}
+		var attrs []FieldChange
+		diffValues("", c, next, nil, &attrs)
+		if len(attrs) == 0 {
+			continue
+		}
+		changes = append(changes, ColumnChange{Name: name, Kind: ChangeChanged, From: c, To: next, Changes: attrs})
+	}
+	for _, c := range to {
+		if _, ok := fromByName[columnName(c)]; !ok {
+			changes = append(changes, ColumnChange{Name: columnName(c), Kind: ChangeAdded, To: c})
+		}
+	}
+	return changes
+}
+
+// columnList extracts properties.columns as name-keyed maps, in order.
+// It reports false when the property is missing or not a list of named maps.
+func columnList(props map[string]interface{}) ([]map[string]interface{}, bool) {
+	raw, ok := props["columns"]
+	if !ok {
+		return nil, false
+	}
+	return namedMaps(raw)
+}
+
+// namedMaps reports whether v is a list of maps that all carry a string name.
+func namedMaps(v interface{}) ([]map[string]interface{}, bool) {
+	var items []interface{}
+	switch v := v.(type) {
+	case []interface{}:
+		items = v
+	case []map[string]interface{}:
+		for _, m := range v {
+			items = append(items, m)
+		}
+	default:
+		return nil, false
+	}
+
+	cols := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok || columnName(m) == "" {
+			return nil, false
+		}
+		cols = append(cols, m)
+	}
+	return cols, true
+}
+
+func joinPath(parent, key string) string {
+	if parent == "" {
+		return key
+	}
+	return parent + "." + key
+}
+
+func columnName(c map[string]interface{}) string {
+	name, _ := c["name"].(string)
+	return name
+}
+
+func asMap(m map[string]interface{}) map[string]interface{} {
+	if m == nil {
+		return map[string]interface{}{}
+	}
+	return m
+}
+
+func asMapOK(v interface{}) (map[string]interface{}, bool) {
+	m, ok := v.(map[string]interface{})
+	return m, ok
+}
+
+func unionKeys(a, b map[string]interface{}) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sameValue compares leaves by their JSON encoding so that numbers decoded
+// from JSONB (float64) compare equal to ints supplied by callers.
+func sameValue(a, b interface{}) bool {
+	ja, errA := json.Marshal(a)
+	jb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return bytes.Equal(ja, jb)
+}
+
+// selectVersions picks the two versions to compare from a newest-first
+// history. A zero to selects the current version; a zero from selects the
+// version immediately preceding to.
+func selectVersions(history []Entity, from, to time.Time) (Entity, Entity, error) {
+	toIdx := 0
+	if !to.IsZero() {
+		toIdx = versionIndexAt(history, to)
+		if toIdx < 0 {
+			return Entity{}, Entity{}, fmt.Errorf("%w: no version at %s", ErrVersionNotFound, to.Format(time.RFC3339))
+		}
+	}
+
+	fromIdx := toIdx + 1
+	if !from.IsZero() {
+		fromIdx = versionIndexAt(history, from)
+		if fromIdx < 0 {
+			return Entity{}, Entity{}, fmt.Errorf("%w: no version at %s", ErrVersionNotFound, from.Format(time.RFC3339))
+		}
+	} else if fromIdx >= len(history) {
+		return Entity{}, Entity{}, fmt.Errorf("%w: no version before %s", ErrVersionNotFound, history[toIdx].ValidFrom.Format(time.RFC3339))
+	}
+
+	return history[fromIdx], history[toIdx], nil
+}
+
+// versionIndexAt returns the index of the version live at t, or -1.
+func versionIndexAt(history []Entity, t time.Time) int {
+	for i, v := range history {
+		if !v.ValidFrom.After(t) {
+			if v.ValidTo != nil && !v.ValidTo.After(t) {
+				return -1
+			}
+			return i
+		}
+	}
+	return -1
+}
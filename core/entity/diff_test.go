@@ -0,0 +1,152 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+func TestDiffEntities_FieldsAndProperties(t *testing.T) {
+	from := Entity{
+		URN: "urn:table:orders", Type: TypeTable, Name: "orders", Description: "Orders",
+		Properties: map[string]interface{}{
+			"owner":     map[string]interface{}{"team": "payments", "email": "pay@example.com"},
+			"retention": float64(30),
+			"legacy":    true,
+		},
+	}
+	to := Entity{
+		URN: "urn:table:orders", Type: TypeTable, Name: "orders", Description: "Customer orders",
+		Properties: map[string]interface{}{
+			"owner":     map[string]interface{}{"team": "checkout", "email": "pay@example.com"},
+			"retention": 30,
+			"tier":      "gold",
+		},
+	}
+
+	d := DiffEntities(from, to)
+
+	want := map[string]ChangeKind{
+		"description":           ChangeChanged,
+		"properties.legacy":     ChangeRemoved,
+		"properties.owner.team": ChangeChanged,
+		"properties.tier":       ChangeAdded,
+	}
+	if len(d.Changes) != len(want) {
+		t.Fatalf("expected %d changes, got %d: %+v", len(want), len(d.Changes), d.Changes)
+	}
+	for _, c := range d.Changes {
+		kind, ok := want[c.Path]
+		if !ok {
+			t.Errorf("unexpected change at %q", c.Path)
+			continue
+		}
+		if c.Kind != kind {
+			t.Errorf("%s: expected %s, got %s", c.Path, kind, c.Kind)
+		}
+	}
+}
+
+func TestDiffEntities_Columns(t *testing.T) {
+	from := Entity{Properties: map[string]interface{}{
+		"columns": []interface{}{
+			map[string]interface{}{"name": "id", "type": "INT64"},
+			map[string]interface{}{"name": "amount", "type": "FLOAT64"},
+			map[string]interface{}{"name": "note", "type": "STRING"},
+		},
+	}}
+	to := Entity{Properties: map[string]interface{}{
+		"columns": []map[string]interface{}{
+			{"name": "id", "type": "INT64"},
+			{"name": "amount", "type": "NUMERIC", "description": "Order total"},
+			{"name": "currency", "type": "STRING"},
+		},
+	}}
+
+	d := DiffEntities(from, to)
+
+	if len(d.Changes) != 0 {
+		t.Errorf("expected columns to be reported only at column level, got %+v", d.Changes)
+	}
+	got := map[string]ColumnChange{}
+	for _, c := range d.Columns {
+		got[c.Name] = c
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 column changes, got %+v", d.Columns)
+	}
+	if got["note"].Kind != ChangeRemoved {
+		t.Errorf("expected note removed, got %s", got["note"].Kind)
+	}
+	if got["currency"].Kind != ChangeAdded {
+		t.Errorf("expected currency added, got %s", got["currency"].Kind)
+	}
+	amount := got["amount"]
+	if amount.Kind != ChangeChanged || len(amount.Changes) != 2 {
+		t.Fatalf("expected amount changed in 2 attributes, got %+v", amount)
+	}
+	if amount.Changes[0].Path != "description" || amount.Changes[1].Path != "type" {
+		t.Errorf("unexpected attribute paths: %+v", amount.Changes)
+	}
+}
+
+func TestDiffEntities_Identical(t *testing.T) {
+	e := Entity{Name: "x", Properties: map[string]interface{}{"a": 1}}
+	if d := DiffEntities(e, e); !d.IsEmpty() {
+		t.Errorf("expected empty diff, got %+v", d)
+	}
+}
+
+func TestService_DiffEntity(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, nil, nil)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	_, _ = svc.Upsert(ctx, ns, &Entity{URN: "urn:x", Type: TypeTable, Name: "v1"})
+	first := time.Now()
+	time.Sleep(time.Millisecond)
+	_, _ = svc.Upsert(ctx, ns, &Entity{URN: "urn:x", Type: TypeTable, Name: "v2"})
+	time.Sleep(time.Millisecond)
+	_, _ = svc.Upsert(ctx, ns, &Entity{URN: "urn:x", Type: TypeTable, Name: "v3"})
+
+	// Defaults: current version against its predecessor.
+	d, err := svc.DiffEntity(ctx, ns, "urn:x", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("DiffEntity failed: %v", err)
+	}
+	if len(d.Changes) != 1 || d.Changes[0].From != "v2" || d.Changes[0].To != "v3" {
+		t.Errorf("expected v2 -> v3, got %+v", d.Changes)
+	}
+
+	// Explicit from.
+	d, err = svc.DiffEntity(ctx, ns, "urn:x", first, time.Time{})
+	if err != nil {
+		t.Fatalf("DiffEntity failed: %v", err)
+	}
+	if len(d.Changes) != 1 || d.Changes[0].From != "v1" || d.Changes[0].To != "v3" {
+		t.Errorf("expected v1 -> v3, got %+v", d.Changes)
+	}
+
+	// Before the entity existed.
+	_, err = svc.DiffEntity(ctx, ns, "urn:x", first.Add(-time.Hour), time.Time{})
+	if !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("expected ErrVersionNotFound, got %v", err)
+	}
+}
+
+func TestService_DiffEntity_SingleVersion(t *testing.T) {
+	svc := NewService(newMockRepo(), nil, nil)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	_, _ = svc.Upsert(ctx, ns, &Entity{URN: "urn:x", Type: TypeTable, Name: "v1"})
+
+	_, err := svc.DiffEntity(ctx, ns, "urn:x", time.Time{}, time.Time{})
+	if !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("expected ErrVersionNotFound, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/urn"
 )
 
 // Edge is a typed, directed, temporal relationship between two entities.
@@ -18,13 +19,31 @@ type Edge struct {
 	ValidFrom   time.Time              `json:"valid_from"`
 	ValidTo     *time.Time             `json:"valid_to,omitempty"`
 	Source      string                 `json:"source,omitempty"`
+	Scope       string                 `json:"scope,omitempty"`
 	CreatedAt   time.Time              `json:"created_at"`
 }
 
+// CanonicalizeURNs replaces the endpoint URNs with their canonical forms
+// under the URN rules of ns; see the urn package.
+func (e *Edge) CanonicalizeURNs(ns *namespace.Namespace) error {
+	source, err := urn.Canonical(ns, e.SourceURN)
+	if err != nil {
+		return err
+	}
+	target, err := urn.Canonical(ns, e.TargetURN)
+	if err != nil {
+		return err
+	}
+	e.SourceURN, e.TargetURN = source, target
+	return nil
+}
+
 // EdgeFilter for querying edges.
 type EdgeFilter struct {
-	Types   []string
-	Current bool // only current edges (valid_to IS NULL)
+	Types        []string   // edge types to return or traverse; empty means all
+	ExcludeTypes []string   // edge types to leave out, applied after Types
+	Current      bool       // only current edges (valid_to IS NULL)
+	AsOf         *time.Time // edges live at this instant; takes precedence over Current
 }
 
 // EdgeRepository defines storage operations for edges.
@@ -32,9 +51,9 @@ type EdgeRepository interface {
 	Upsert(ctx context.Context, ns *namespace.Namespace, e *Edge) error
 	GetBySource(ctx context.Context, ns *namespace.Namespace, urn string, filter EdgeFilter) ([]Edge, error)
 	GetByTarget(ctx context.Context, ns *namespace.Namespace, urn string, filter EdgeFilter) ([]Edge, error)
-	GetDownstream(ctx context.Context, ns *namespace.Namespace, urn string, depth int) ([]Edge, error)
-	GetUpstream(ctx context.Context, ns *namespace.Namespace, urn string, depth int) ([]Edge, error)
-	GetBidirectional(ctx context.Context, ns *namespace.Namespace, urn string, depth int) ([]Edge, error)
+	GetDownstream(ctx context.Context, ns *namespace.Namespace, urn string, depth int, filter EdgeFilter) ([]Edge, error)
+	GetUpstream(ctx context.Context, ns *namespace.Namespace, urn string, depth int, filter EdgeFilter) ([]Edge, error)
+	GetBidirectional(ctx context.Context, ns *namespace.Namespace, urn string, depth int, filter EdgeFilter) ([]Edge, error)
 	Delete(ctx context.Context, ns *namespace.Namespace, sourceURN, targetURN, edgeType string) error
 	DeleteByURN(ctx context.Context, ns *namespace.Namespace, urn string) error
 }
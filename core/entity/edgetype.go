@@ -0,0 +1,268 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+var (
+	ErrEdgeTypeNotFound = errors.New("edge type not registered")
+	// ErrInvalidEdgeTypeDefinition is returned when an edge type registration
+	// fails validation.
+	ErrInvalidEdgeTypeDefinition = errors.New("invalid edge type definition")
+	// ErrEdgeConstraint is returned for an edge its type declaration does not
+	// allow.
+	ErrEdgeConstraint = errors.New("edge violates its type declaration")
+)
+
+// Cardinality limits the number of current edges of one type an entity may
+// have.
+type Cardinality string
+
+const (
+	// CardinalityManyToMany sets no limit. It is the default.
+	CardinalityManyToMany Cardinality = "many_to_many"
+	// CardinalityManyToOne allows each source one edge of the type, e.g. an
+	// asset is owned_by one team.
+	CardinalityManyToOne Cardinality = "many_to_one"
+	// CardinalityOneToMany allows each target one edge of the type.
+	CardinalityOneToMany Cardinality = "one_to_many"
+	// CardinalityOneToOne allows each source and each target one edge of the
+	// type.
+	CardinalityOneToOne Cardinality = "one_to_one"
+)
+
+// EdgeTypeDefinition declares an edge type of a namespace. SourceTypes and
+// TargetTypes list the entity types the edge may start and end at; empty
+// allows any. Lineage marks edges that carry data from source to target,
+// which impact analysis follows; other declared edges, such as ownership,
+// are associations it skips. Inverse names the edge read from its target,
+// e.g. "owns" for owned_by.
+type EdgeTypeDefinition struct {
+	Type        string      `json:"type"`
+	SourceTypes []Type      `json:"source_types,omitempty"`
+	TargetTypes []Type      `json:"target_types,omitempty"`
+	Lineage     bool        `json:"lineage"`
+	Inverse     string      `json:"inverse,omitempty"`
+	Cardinality Cardinality `json:"cardinality"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+// EdgeEndpoints is what checking an edge against its declaration needs to
+// know about the graph around it.
+type EdgeEndpoints struct {
+	// SourceType and TargetType are the types of the current entities at the
+	// ends of the edge, or empty when an entity does not exist yet.
+	SourceType Type
+	TargetType Type
+	// SourceEdges counts the current edges of the type from the source to
+	// other targets, and TargetEdges those into the target from other sources.
+	SourceEdges int
+	TargetEdges int
+}
+
+func (d EdgeTypeDefinition) validate() error {
+	if d.Type == "" {
+		return fmt.Errorf("%w: type is required", ErrInvalidEdgeTypeDefinition)
+	}
+	switch d.Cardinality {
+	case CardinalityManyToMany, CardinalityManyToOne, CardinalityOneToMany, CardinalityOneToOne:
+	default:
+		return fmt.Errorf("%w: cardinality must be %s, %s, %s or %s", ErrInvalidEdgeTypeDefinition,
+			CardinalityManyToMany, CardinalityManyToOne, CardinalityOneToMany, CardinalityOneToOne)
+	}
+	for _, t := range append(slices.Clone(d.SourceTypes), d.TargetTypes...) {
+		if !t.IsValid() {
+			return fmt.Errorf("%w: entity types must not be empty", ErrInvalidEdgeTypeDefinition)
+		}
+	}
+	return nil
+}
+
+// OneSource reports whether a target may have only one edge of the type.
+func (d EdgeTypeDefinition) OneSource() bool {
+	return d.Cardinality == CardinalityOneToMany || d.Cardinality == CardinalityOneToOne
+}
+
+// OneTarget reports whether a source may have only one edge of the type.
+func (d EdgeTypeDefinition) OneTarget() bool {
+	return d.Cardinality == CardinalityManyToOne || d.Cardinality == CardinalityOneToOne
+}
+
+// CheckEdge returns ErrEdgeConstraint when the declaration does not allow e.
+// Ends whose entity does not exist yet are not type-checked.
+func (d EdgeTypeDefinition) CheckEdge(e Edge, ends EdgeEndpoints) error {
+	if ends.SourceType != "" && len(d.SourceTypes) > 0 && !slices.Contains(d.SourceTypes, ends.SourceType) {
+		return fmt.Errorf("%w: %s edges cannot start at %s %s", ErrEdgeConstraint, d.Type, ends.SourceType, e.SourceURN)
+	}
+	if ends.TargetType != "" && len(d.TargetTypes) > 0 && !slices.Contains(d.TargetTypes, ends.TargetType) {
+		return fmt.Errorf("%w: %s edges cannot end at %s %s", ErrEdgeConstraint, d.Type, ends.TargetType, e.TargetURN)
+	}
+	if d.OneTarget() && ends.SourceEdges > 0 {
+		return fmt.Errorf("%w: %s already has a %s edge", ErrEdgeConstraint, e.SourceURN, d.Type)
+	}
+	if d.OneSource() && ends.TargetEdges > 0 {
+		return fmt.Errorf("%w: %s already has an incoming %s edge", ErrEdgeConstraint, e.TargetURN, d.Type)
+	}
+	return nil
+}
+
+// RegisterEdgeType creates or replaces the declaration of an edge type. The
+// cardinality defaults to many_to_many. Existing edges are not re-checked.
+func (s *Service) RegisterEdgeType(ctx context.Context, ns *namespace.Namespace, def *EdgeTypeDefinition) error {
+	if s.types == nil {
+		return errors.New("type registry is not enabled")
+	}
+	if def.Cardinality == "" {
+		def.Cardinality = CardinalityManyToMany
+	}
+	if err := def.validate(); err != nil {
+		return err
+	}
+	if err := s.types.UpsertEdgeType(ctx, ns, def); err != nil {
+		return fmt.Errorf("register edge type %s: %w", def.Type, err)
+	}
+	return nil
+}
+
+// GetEdgeType returns the declaration of a registered edge type.
+func (s *Service) GetEdgeType(ctx context.Context, ns *namespace.Namespace, edgeType string) (EdgeTypeDefinition, error) {
+	if s.types == nil {
+		return EdgeTypeDefinition{}, ErrEdgeTypeNotFound
+	}
+	return s.types.GetEdgeType(ctx, ns, edgeType)
+}
+
+// ListEdgeTypes returns the registered edge type declarations.
+func (s *Service) ListEdgeTypes(ctx context.Context, ns *namespace.Namespace) ([]EdgeTypeDefinition, error) {
+	if s.types == nil {
+		return nil, nil
+	}
+	return s.types.ListEdgeTypes(ctx, ns)
+}
+
+// DeleteEdgeType removes the declaration of an edge type. Edges of the type
+// are kept, no longer checked, and followed by impact analysis again.
+func (s *Service) DeleteEdgeType(ctx context.Context, ns *namespace.Namespace, edgeType string) error {
+	if s.types == nil {
+		return ErrEdgeTypeNotFound
+	}
+	return s.types.DeleteEdgeType(ctx, ns, edgeType)
+}
+
+// impactFilter is the filter impact analysis traverses with: every edge type
+// except the ones declared as associations.
+func (s *Service) impactFilter(ctx context.Context, ns *namespace.Namespace, asOf *time.Time) (EdgeFilter, error) {
+	filter := EdgeFilter{AsOf: asOf}
+	defs, err := s.ListEdgeTypes(ctx, ns)
+	if err != nil {
+		return filter, fmt.Errorf("list edge types: %w", err)
+	}
+	for _, d := range defs {
+		if !d.Lineage {
+			filter.ExcludeTypes = append(filter.ExcludeTypes, d.Type)
+		}
+	}
+	return filter, nil
+}
+
+// edgeChecker checks the edges of one bulk write against their declarations.
+// Edges accepted earlier in the write count towards cardinality, so a
+// violation within one batch is caught before the batch is written. The bulk
+// repository checks each edge again in its write transaction, against edges
+// other writers added since.
+type edgeChecker struct {
+	s        *Service
+	ns       *namespace.Namespace
+	defs     map[string]*EdgeTypeDefinition
+	accepted map[string]map[string]bool
+}
+
+func (s *Service) newEdgeChecker(ns *namespace.Namespace) *edgeChecker {
+	return &edgeChecker{s: s, ns: ns, defs: map[string]*EdgeTypeDefinition{}, accepted: map[string]map[string]bool{}}
+}
+
+func (c *edgeChecker) check(ctx context.Context, e Edge) error {
+	if c.s.types == nil {
+		return nil
+	}
+	def, ok := c.defs[e.Type]
+	if !ok {
+		d, err := c.s.types.GetEdgeType(ctx, c.ns, e.Type)
+		switch {
+		case err == nil:
+			def = &d
+		case !errors.Is(err, ErrEdgeTypeNotFound):
+			return fmt.Errorf("get edge type %s: %w", e.Type, err)
+		}
+		c.defs[e.Type] = def
+	}
+	if def == nil {
+		return nil
+	}
+
+	var ends EdgeEndpoints
+	if src, err := c.s.repo.GetByURN(ctx, c.ns, e.SourceURN); err == nil {
+		ends.SourceType = src.Type
+	}
+	if tgt, err := c.s.repo.GetByURN(ctx, c.ns, e.TargetURN); err == nil {
+		ends.TargetType = tgt.Type
+	}
+	fromKey, toKey := "out\x00"+e.Type+"\x00"+e.SourceURN, "in\x00"+e.Type+"\x00"+e.TargetURN
+	filter := EdgeFilter{Types: []string{e.Type}, Current: true}
+	var err error
+	if def.OneTarget() {
+		ends.SourceEdges, err = c.others(fromKey, e.TargetURN, func() ([]Edge, error) {
+			return c.s.edges.GetBySource(ctx, c.ns, e.SourceURN, filter)
+		}, func(x Edge) string { return x.TargetURN })
+		if err != nil {
+			return err
+		}
+	}
+	if def.OneSource() {
+		ends.TargetEdges, err = c.others(toKey, e.SourceURN, func() ([]Edge, error) {
+			return c.s.edges.GetByTarget(ctx, c.ns, e.TargetURN, filter)
+		}, func(x Edge) string { return x.SourceURN })
+		if err != nil {
+			return err
+		}
+	}
+	if err := def.CheckEdge(e, ends); err != nil {
+		return err
+	}
+
+	for _, k := range []string{fromKey, toKey} {
+		if c.accepted[k] == nil {
+			c.accepted[k] = map[string]bool{}
+		}
+	}
+	c.accepted[fromKey][e.TargetURN] = true
+	c.accepted[toKey][e.SourceURN] = true
+	return nil
+}
+
+// others counts the far ends of the current edges returned by current and
+// of the edges accepted under key, except self.
+func (c *edgeChecker) others(key, self string, current func() ([]Edge, error), end func(Edge) string) (int, error) {
+	ends := map[string]bool{}
+	if c.s.edges != nil {
+		edges, err := current()
+		if err != nil {
+			return 0, fmt.Errorf("get current edges: %w", err)
+		}
+		for _, x := range edges {
+			ends[end(x)] = true
+		}
+	}
+	for u := range c.accepted[key] {
+		ends[u] = true
+	}
+	delete(ends, self)
+	return len(ends), nil
+}
@@ -0,0 +1,137 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+var ownedBy = EdgeTypeDefinition{
+	Type:        "owned_by",
+	SourceTypes: []Type{TypeTable, TypeDashboard},
+	TargetTypes: []Type{"team"},
+	Inverse:     "owns",
+	Cardinality: CardinalityManyToOne,
+}
+
+func TestEdgeTypeDefinition_CheckEdge(t *testing.T) {
+	e := Edge{SourceURN: "urn:t", TargetURN: "urn:team", Type: "owned_by"}
+	tests := []struct {
+		name string
+		def  EdgeTypeDefinition
+		ends EdgeEndpoints
+		ok   bool
+	}{
+		{"allowed", ownedBy, EdgeEndpoints{SourceType: TypeTable, TargetType: "team"}, true},
+		{"unknown ends", ownedBy, EdgeEndpoints{}, true},
+		{"wrong source type", ownedBy, EdgeEndpoints{SourceType: TypeJob, TargetType: "team"}, false},
+		{"wrong target type", ownedBy, EdgeEndpoints{SourceType: TypeTable, TargetType: TypeTable}, false},
+		{"second target", ownedBy, EdgeEndpoints{SourceType: TypeTable, SourceEdges: 1}, false},
+		{"second source", ownedBy, EdgeEndpoints{SourceType: TypeTable, TargetEdges: 3}, true},
+		{"one to one", EdgeTypeDefinition{Type: "owned_by", Cardinality: CardinalityOneToOne}, EdgeEndpoints{TargetEdges: 1}, false},
+		{"many to many", EdgeTypeDefinition{Type: "owned_by", Cardinality: CardinalityManyToMany}, EdgeEndpoints{SourceEdges: 2, TargetEdges: 2}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.def.CheckEdge(e, tt.ends)
+			if tt.ok && err != nil {
+				t.Errorf("expected edge to be allowed, got %v", err)
+			}
+			if !tt.ok && !errors.Is(err, ErrEdgeConstraint) {
+				t.Errorf("expected ErrEdgeConstraint, got %v", err)
+			}
+		})
+	}
+}
+
+func TestService_RegisterEdgeType(t *testing.T) {
+	svc := NewService(newMockRepo(), nil, nil)
+	types := newMockTypeRepo()
+	svc.WithTypeRegistry(types)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	if err := svc.RegisterEdgeType(ctx, ns, &EdgeTypeDefinition{Type: "reads", Lineage: true}); err != nil {
+		t.Fatalf("RegisterEdgeType failed: %v", err)
+	}
+	if types.edgeTypes["reads"].Cardinality != CardinalityManyToMany {
+		t.Errorf("expected cardinality to default to many_to_many, got %q", types.edgeTypes["reads"].Cardinality)
+	}
+
+	invalid := []*EdgeTypeDefinition{
+		{Lineage: true},
+		{Type: "owned_by", Cardinality: "one"},
+		{Type: "owned_by", SourceTypes: []Type{""}},
+	}
+	for _, d := range invalid {
+		if err := svc.RegisterEdgeType(ctx, ns, d); !errors.Is(err, ErrInvalidEdgeTypeDefinition) {
+			t.Errorf("expected ErrInvalidEdgeTypeDefinition for %+v, got %v", d, err)
+		}
+	}
+}
+
+func TestService_GetImpact_SkipsAssociations(t *testing.T) {
+	edges := &mockEdgeRepo{}
+	svc := NewService(newMockRepo(), edges, nil)
+	types := newMockTypeRepo()
+	types.edgeTypes["owned_by"] = ownedBy
+	types.edgeTypes["reads"] = EdgeTypeDefinition{Type: "reads", Lineage: true, Cardinality: CardinalityManyToMany}
+	svc.WithTypeRegistry(types)
+
+	if _, err := svc.GetImpact(context.Background(), namespace.DefaultNamespace, "urn:a", 2); err != nil {
+		t.Fatalf("GetImpact failed: %v", err)
+	}
+	if got := edges.lastDownstreamFilter.ExcludeTypes; !slices.Equal(got, []string{"owned_by"}) {
+		t.Errorf("expected association edges to be excluded, got %v", got)
+	}
+	if len(edges.lastDownstreamFilter.Types) != 0 {
+		t.Errorf("expected undeclared edge types to be traversed, got %v", edges.lastDownstreamFilter.Types)
+	}
+}
+
+func TestService_BulkUpsert_EdgeDeclarations(t *testing.T) {
+	repo := newMockRepo()
+	edges := &mockEdgeRepo{edges: []Edge{{SourceURN: "urn:dash", TargetURN: "urn:team:a", Type: "owned_by"}}}
+	bulk := &mockBulkRepo{}
+	svc := NewService(repo, edges, nil)
+	svc.WithBulkRepository(bulk)
+	types := newMockTypeRepo()
+	types.edgeTypes["owned_by"] = ownedBy
+	svc.WithTypeRegistry(types)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	for _, ent := range []*Entity{
+		{URN: "urn:t", Type: TypeTable, Name: "t"},
+		{URN: "urn:job", Type: TypeJob, Name: "job"},
+		{URN: "urn:dash", Type: TypeDashboard, Name: "dash"},
+		{URN: "urn:team:a", Type: "team", Name: "a"},
+		{URN: "urn:team:b", Type: "team", Name: "b"},
+	} {
+		_, _ = repo.Upsert(ctx, ns, ent)
+	}
+
+	records := []BulkRecord{
+		{Edge: &Edge{SourceURN: "urn:t", TargetURN: "urn:team:a", Type: "owned_by"}},
+		{Edge: &Edge{SourceURN: "urn:t", TargetURN: "urn:team:b", Type: "owned_by"}},
+		{Edge: &Edge{SourceURN: "urn:job", TargetURN: "urn:team:a", Type: "owned_by"}},
+		{Edge: &Edge{SourceURN: "urn:dash", TargetURN: "urn:team:b", Type: "owned_by"}},
+		{Edge: &Edge{SourceURN: "urn:dash", TargetURN: "urn:team:a", Type: "owned_by"}},
+		{Edge: &Edge{SourceURN: "urn:job", TargetURN: "urn:t", Type: "reads"}},
+	}
+	results := svc.BulkUpsert(ctx, ns, 0, records)
+
+	want := []bool{true, false, false, false, true, true}
+	for i, ok := range want {
+		failed := results[i].Status == UpsertFailed
+		if failed == ok {
+			t.Errorf("record %d: expected ok=%v, got %+v", i, ok, results[i])
+		}
+	}
+	if len(bulk.edgeBatches) != 1 || len(bulk.edgeBatches[0]) != 3 {
+		t.Errorf("expected only the allowed edges to be written, got %v", bulk.edgeBatches)
+	}
+}
@@ -2,11 +2,16 @@ package entity
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/urn"
 )
 
+// ErrNotDeleted is returned when restoring an entity that has a current version.
+var ErrNotDeleted = errors.New("entity is not deleted")
+
 // Type is an open type system — any non-empty string is valid.
 type Type string
 
@@ -38,25 +43,64 @@ type Entity struct {
 	Name        string                 `json:"name"`
 	Description string                 `json:"description,omitempty"`
 	Properties  map[string]interface{} `json:"properties,omitempty"`
-	Source      string                 `json:"source,omitempty"`
-	ValidFrom   time.Time              `json:"valid_from"`
-	ValidTo     *time.Time             `json:"valid_to,omitempty"`
-	CreatedAt   time.Time              `json:"created_at"`
-	UpdatedAt   time.Time              `json:"updated_at"`
+	// PropertySources holds the properties each source wrote; Properties is
+	// their merge under the namespace's property policy.
+	PropertySources PropertySources `json:"property_sources,omitempty"`
+	Source          string          `json:"source,omitempty"`
+	Scope           string          `json:"scope,omitempty"`
+	ChangedBy       string          `json:"changed_by,omitempty"`
+	ValidFrom       time.Time       `json:"valid_from"`
+	ValidTo         *time.Time      `json:"valid_to,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
 }
 
 // IsCurrent returns true if this entity represents current state.
 func (e Entity) IsCurrent() bool { return e.ValidTo == nil }
 
+// CanonicalizeURN replaces the URN with its canonical form under the URN
+// rules of ns; see the urn package.
+func (e *Entity) CanonicalizeURN(ns *namespace.Namespace) error {
+	c, err := urn.Canonical(ns, e.URN)
+	if err != nil {
+		return err
+	}
+	e.URN = c
+	return nil
+}
+
 // Repository defines storage operations for entities.
 type Repository interface {
 	Upsert(ctx context.Context, ns *namespace.Namespace, ent *Entity) (string, error)
+	// UpsertWithColumns writes the entity as Upsert does and, in the same
+	// transaction, the column entities and has_column edges of the merged
+	// entity, deleting the current columns it no longer has; see PlanColumns.
+	UpsertWithColumns(ctx context.Context, ns *namespace.Namespace, ent *Entity) (string, error)
 	GetByURN(ctx context.Context, ns *namespace.Namespace, urn string) (Entity, error)
+	GetByURNAsOf(ctx context.Context, ns *namespace.Namespace, urn string, asOf time.Time) (Entity, error)
+	// GetByID returns the current version of the entity that has a version
+	// with the ID.
 	GetByID(ctx context.Context, id string) (Entity, error)
+	GetHistory(ctx context.Context, ns *namespace.Namespace, urn string) ([]Entity, error)
 	GetAll(ctx context.Context, ns *namespace.Namespace, filter Filter) ([]Entity, error)
 	GetCount(ctx context.Context, ns *namespace.Namespace, filter Filter) (int, error)
 	GetTypes(ctx context.Context, ns *namespace.Namespace) (map[Type]int, error)
+	// Delete soft-deletes the entity and, in the same transaction, the edges
+	// touching it and its column entities, closing all of them at the same
+	// instant.
 	Delete(ctx context.Context, ns *namespace.Namespace, urn string) error
+	// Restore brings back a deleted entity as a new version with the content
+	// of its latest closed version, together with the edges closed by the same
+	// delete that their type declaration still allows, and its column
+	// entities, in one transaction. It returns ErrNotDeleted when the entity
+	// has a current version.
+	Restore(ctx context.Context, ns *namespace.Namespace, urn string) (Entity, []Edge, error)
+	// Merge moves the current edges, documents and embeddings of the entity
+	// from to the entity into, deletes from and records its URN as an alias
+	// of into, and does the same for each column entity of from and the
+	// column of into of the same name, in one transaction. Columns into does
+	// not have are deleted.
+	Merge(ctx context.Context, ns *namespace.Namespace, from, into string) (MergeResult, error)
 }
 
 // Filter for querying entities.
@@ -66,4 +110,5 @@ type Filter struct {
 	Size   int
 	Offset int
 	Query  string
+	AsOf   *time.Time // entities live at this instant instead of current state
 }
@@ -0,0 +1,46 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/urn"
+)
+
+// ErrInvalidMerge is returned for a merge of an entity into itself.
+var ErrInvalidMerge = errors.New("invalid merge")
+
+// MergeResult is what merging one entity into another moved.
+type MergeResult struct {
+	// Entity is the surviving entity.
+	Entity Entity `json:"entity"`
+	// Alias is the URN of the merged entity, which now resolves to Entity.
+	Alias      string `json:"alias"`
+	Edges      int    `json:"edges"`
+	Documents  int    `json:"documents"`
+	Embeddings int    `json:"embeddings"`
+	// Columns counts the column entities merged into columns of the same
+	// name. Columns the surviving entity does not have are deleted.
+	Columns int `json:"columns"`
+}
+
+// MergeEntities merges the entity from into the entity into, for a source
+// system that renamed or moved it. The edges, documents and document
+// embeddings of from are re-pointed at into, from is deleted, and its URN
+// becomes an alias: GetByURN, graph traversal and search given the alias
+// resolve to into. Column lineage moves to the column of into of the same
+// name; columns into does not have are deleted, as a column dropped from
+// properties.columns is. The content of into is kept as it is.
+func (s *Service) MergeEntities(ctx context.Context, ns *namespace.Namespace, from, into string) (MergeResult, error) {
+	from, into = urn.Lookup(ns, from), urn.Lookup(ns, into)
+	if from == into {
+		return MergeResult{}, fmt.Errorf("%w: cannot merge %s into itself", ErrInvalidMerge, from)
+	}
+	res, err := s.repo.Merge(ctx, ns, from, into)
+	if err != nil {
+		return MergeResult{}, fmt.Errorf("merge %s into %s: %w", from, into, err)
+	}
+	return res, nil
+}
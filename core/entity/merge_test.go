@@ -0,0 +1,38 @@
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+func TestService_MergeEntities(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, &mockEdgeRepo{}, nil)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	_, _ = repo.Upsert(ctx, ns, &Entity{URN: "urn:bigquery:old", Type: TypeTable, Name: "old"})
+	_, _ = repo.Upsert(ctx, ns, &Entity{URN: "urn:bigquery:new", Type: TypeTable, Name: "new"})
+
+	if _, err := svc.MergeEntities(ctx, ns, "urn:bigquery:new", " urn:BigQuery:NEW "); !errors.Is(err, ErrInvalidMerge) {
+		t.Errorf("expected ErrInvalidMerge for a merge into itself, got %v", err)
+	}
+	if _, err := svc.MergeEntities(ctx, ns, "urn:bigquery:missing", "urn:bigquery:new"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for a missing entity, got %v", err)
+	}
+
+	res, err := svc.MergeEntities(ctx, ns, "urn:bigquery:OLD", "urn:bigquery:new")
+	if err != nil {
+		t.Fatalf("MergeEntities failed: %v", err)
+	}
+	if res.Entity.URN != "urn:bigquery:new" || res.Alias != "urn:bigquery:old" {
+		t.Errorf("expected old merged into new, got %+v", res)
+	}
+	if _, ok := repo.entities["urn:bigquery:old"]; ok {
+		t.Error("expected merged entity to be removed")
+	}
+}
@@ -0,0 +1,412 @@
+package entity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/urn"
+)
+
+// PropertyPolicyKey is the namespace metadata key holding the property merge
+// policy.
+const PropertyPolicyKey = "property_merge"
+
+// ManualSource is the source the properties of writes that name no source
+// are recorded under, typically people editing through the API or UI.
+const ManualSource = "manual"
+
+// ErrInvalidPropertyPolicy is returned for a namespace whose property merge
+// policy cannot be read.
+var ErrInvalidPropertyPolicy = errors.New("invalid property merge policy")
+
+// MergePolicy is how the values several sources wrote for one property are
+// merged into the entity's property.
+type MergePolicy string
+
+const (
+	// MergeLatest takes the value changed most recently. It is the default.
+	MergeLatest MergePolicy = "latest"
+	// MergePriority takes the value of the first source in the priority list
+	// that wrote the property, or the latest value when no listed source did.
+	MergePriority MergePolicy = "priority"
+	// MergeUnion combines the values of every source into one list without
+	// duplicates, in priority order. A value that is not a list counts as a
+	// list of itself.
+	MergeUnion MergePolicy = "union"
+)
+
+// PropertyValue is the value one source wrote for a property.
+type PropertyValue struct {
+	Value interface{} `json:"value"`
+	// UpdatedAt is when the source last changed the value.
+	UpdatedAt time.Time `json:"updated_at"`
+	// Applied reports whether the value went into the entity's property.
+	Applied bool `json:"applied"`
+}
+
+// PropertySources holds, for each source that wrote an entity, the
+// properties it wrote.
+type PropertySources map[string]map[string]PropertyValue
+
+// KeyPolicy is the merge policy of one property. Priority lists sources,
+// highest first.
+type KeyPolicy struct {
+	Policy   MergePolicy `json:"policy"`
+	Priority []string    `json:"priority,omitempty"`
+}
+
+// PropertyPolicy is how a namespace merges the properties its sources write,
+// read from its metadata:
+//
+//	"property_merge": {
+//	  "policy": "latest",
+//	  "priority": ["manual", "dbt", "bigquery"],
+//	  "keys": {"owner": {"policy": "priority", "priority": ["manual"]}, "tags": "union"}
+//	}
+//
+// Keys without a policy of their own use the namespace policy and priority.
+type PropertyPolicy struct {
+	Default KeyPolicy
+	Keys    map[string]KeyPolicy
+}
+
+// PropertyPolicyFromNamespace reads the property merge policy from namespace
+// metadata.
+func PropertyPolicyFromNamespace(ns *namespace.Namespace) (PropertyPolicy, error) {
+	p := PropertyPolicy{Default: KeyPolicy{Policy: MergeLatest}}
+	raw, ok := ns.Metadata[PropertyPolicyKey]
+	if !ok || raw == nil {
+		return p, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return PropertyPolicy{}, fmt.Errorf("%w: metadata.%s must be an object", ErrInvalidPropertyPolicy, PropertyPolicyKey)
+	}
+	var err error
+	if p.Default, err = keyPolicy(m, p.Default, PropertyPolicyKey); err != nil {
+		return PropertyPolicy{}, err
+	}
+
+	keys, ok := m["keys"].(map[string]interface{})
+	if !ok && m["keys"] != nil {
+		return PropertyPolicy{}, fmt.Errorf("%w: metadata.%s.keys must be an object", ErrInvalidPropertyPolicy, PropertyPolicyKey)
+	}
+	p.Keys = make(map[string]KeyPolicy, len(keys))
+	for key, v := range keys {
+		path := PropertyPolicyKey + ".keys." + key
+		switch v := v.(type) {
+		case string:
+			p.Keys[key], err = keyPolicy(map[string]interface{}{"policy": v}, p.Default, path)
+		case map[string]interface{}:
+			p.Keys[key], err = keyPolicy(v, p.Default, path)
+		default:
+			err = fmt.Errorf("%w: metadata.%s must be a policy or an object", ErrInvalidPropertyPolicy, path)
+		}
+		if err != nil {
+			return PropertyPolicy{}, err
+		}
+	}
+	return p, nil
+}
+
+// keyPolicy reads a policy and priority list, defaulting to def. Metadata
+// decoded from JSON carries lists as []interface{}.
+func keyPolicy(m map[string]interface{}, def KeyPolicy, path string) (KeyPolicy, error) {
+	kp := def
+	if v, ok := m["policy"]; ok && v != nil {
+		s, _ := v.(string)
+		switch MergePolicy(s) {
+		case MergeLatest, MergePriority, MergeUnion:
+			kp.Policy = MergePolicy(s)
+		default:
+			return KeyPolicy{}, fmt.Errorf("%w: metadata.%s.policy must be %s, %s or %s",
+				ErrInvalidPropertyPolicy, path, MergeLatest, MergePriority, MergeUnion)
+		}
+	}
+	if v, ok := m["priority"]; ok && v != nil {
+		var items []interface{}
+		switch v := v.(type) {
+		case []interface{}:
+			items = v
+		case []string:
+			for _, s := range v {
+				items = append(items, s)
+			}
+		default:
+			return KeyPolicy{}, fmt.Errorf("%w: metadata.%s.priority must be a list of sources", ErrInvalidPropertyPolicy, path)
+		}
+		kp.Priority = make([]string, 0, len(items))
+		for _, item := range items {
+			s, ok := item.(string)
+			if !ok || s == "" {
+				return KeyPolicy{}, fmt.Errorf("%w: metadata.%s.priority must be a list of sources", ErrInvalidPropertyPolicy, path)
+			}
+			kp.Priority = append(kp.Priority, s)
+		}
+	}
+	return kp, nil
+}
+
+// For returns the policy of a property.
+func (p PropertyPolicy) For(key string) KeyPolicy {
+	if kp, ok := p.Keys[key]; ok {
+		return kp
+	}
+	return p.Default
+}
+
+// Merge resolves the properties of an entity from what its sources wrote. It
+// returns the properties and a copy of the sources with the values that went
+// into them marked as applied.
+func (p PropertyPolicy) Merge(sources PropertySources) (map[string]interface{}, PropertySources) {
+	merged := make(PropertySources, len(sources))
+	writers := map[string][]string{}
+	for src, values := range sources {
+		merged[src] = make(map[string]PropertyValue, len(values))
+		for key, v := range values {
+			v.Applied = false
+			merged[src][key] = v
+			writers[key] = append(writers[key], src)
+		}
+	}
+
+	props := make(map[string]interface{}, len(writers))
+	for key, srcs := range writers {
+		kp := p.For(key)
+		kp.order(key, srcs, merged)
+		if kp.Policy != MergeUnion {
+			v := merged[srcs[0]][key]
+			props[key] = v.Value
+			v.Applied = true
+			merged[srcs[0]][key] = v
+			continue
+		}
+
+		var list []interface{}
+		seen := map[string]bool{}
+		for _, src := range srcs {
+			v := merged[src][key]
+			for _, item := range asList(v.Value) {
+				if b, err := json.Marshal(item); err == nil {
+					if seen[string(b)] {
+						continue
+					}
+					seen[string(b)] = true
+				}
+				list = append(list, item)
+			}
+			v.Applied = true
+			merged[src][key] = v
+		}
+		props[key] = list
+	}
+	return props, merged
+}
+
+// order sorts the sources that wrote key, the one whose value wins first:
+// by priority unless the policy is latest, then by the time the value
+// changed, latest first, then by name.
+func (kp KeyPolicy) order(key string, srcs []string, sources PropertySources) {
+	rank := func(src string) int {
+		if kp.Policy == MergeLatest {
+			return 0
+		}
+		for i, s := range kp.Priority {
+			if s == src {
+				return i
+			}
+		}
+		return len(kp.Priority)
+	}
+	sort.Slice(srcs, func(i, j int) bool {
+		if ri, rj := rank(srcs[i]), rank(srcs[j]); ri != rj {
+			return ri < rj
+		}
+		ti, tj := sources[srcs[i]][key].UpdatedAt, sources[srcs[j]][key].UpdatedAt
+		if !ti.Equal(tj) {
+			return ti.After(tj)
+		}
+		return srcs[i] < srcs[j]
+	})
+}
+
+func asList(v interface{}) []interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		return list
+	}
+	return []interface{}{v}
+}
+
+// PropertySource returns the source the properties of e are recorded under.
+func (e Entity) PropertySource() string {
+	if e.Source == "" {
+		return ManualSource
+	}
+	return e.Source
+}
+
+// currentSources returns a copy of the property sources of the current
+// version cur, nil for a new entity. A version written before sources were
+// tracked is attributed as a whole to its source.
+func currentSources(cur *Entity) PropertySources {
+	sources := PropertySources{}
+	if cur == nil {
+		return sources
+	}
+	if len(cur.PropertySources) == 0 {
+		if len(cur.Properties) > 0 {
+			values := make(map[string]PropertyValue, len(cur.Properties))
+			for key, v := range cur.Properties {
+				values[key] = PropertyValue{Value: v, UpdatedAt: cur.ValidFrom}
+			}
+			sources[cur.PropertySource()] = values
+		}
+		return sources
+	}
+	for src, values := range cur.PropertySources {
+		sources[src] = make(map[string]PropertyValue, len(values))
+		for key, v := range values {
+			sources[src][key] = v
+		}
+	}
+	return sources
+}
+
+// MergeProperties records the properties of e as what its source writes at
+// now, replacing what that source wrote for the current version cur, nil for
+// a new entity, and sets them to the merge under the property policy of ns
+// of every source's properties. Values the source writes unchanged keep the
+// time they last changed. The property sources e carries are ignored, so a
+// version read back and written again is merged from its properties, and a
+// writer cannot attribute values to other sources.
+func (e *Entity) MergeProperties(ns *namespace.Namespace, cur *Entity, now time.Time) error {
+	sources := currentSources(cur)
+	src := e.PropertySource()
+	previous := sources[src]
+	values := make(map[string]PropertyValue, len(e.Properties))
+	for key, v := range e.Properties {
+		if prev, ok := previous[key]; ok && sameValue(prev.Value, v) {
+			values[key] = PropertyValue{Value: prev.Value, UpdatedAt: prev.UpdatedAt}
+			continue
+		}
+		values[key] = PropertyValue{Value: v, UpdatedAt: now}
+	}
+	delete(sources, src)
+	if len(values) > 0 {
+		sources[src] = values
+	}
+	return e.mergeSources(ns, sources)
+}
+
+// RestoreProperties sets the properties of e, a version that carries its
+// property sources, such as a deleted version being restored, to the merge
+// of those sources under the property policy of ns. A version written before
+// sources were tracked keeps its properties.
+func (e *Entity) RestoreProperties(ns *namespace.Namespace) error {
+	if len(e.PropertySources) == 0 {
+		return nil
+	}
+	return e.mergeSources(ns, e.PropertySources)
+}
+
+func (e *Entity) mergeSources(ns *namespace.Namespace, sources PropertySources) error {
+	policy, err := PropertyPolicyFromNamespace(ns)
+	if err != nil {
+		return err
+	}
+	e.Properties, e.PropertySources = policy.Merge(sources)
+	if len(e.PropertySources) == 0 {
+		e.PropertySources = nil
+	}
+	return nil
+}
+
+// currentVersion returns the current version of the entity u names, or nil
+// when there is none. The entity a merged URN resolves to is not the current
+// version of that URN.
+func (s *Service) currentVersion(ctx context.Context, ns *namespace.Namespace, u string) *Entity {
+	cur, err := s.repo.GetByURN(ctx, ns, u)
+	if err != nil || cur.URN != urn.Lookup(ns, u) {
+		return nil
+	}
+	return &cur
+}
+
+// withMergedProperties returns ent with the properties writing it over cur
+// would leave it with, for checks made before the write. The write itself
+// merges again, against the version current then.
+func withMergedProperties(ns *namespace.Namespace, ent Entity, cur *Entity) Entity {
+	merged := ent
+	if err := merged.MergeProperties(ns, cur, time.Now().UTC()); err != nil {
+		return ent
+	}
+	return merged
+}
+
+// PropertyProvenance is one property of an entity with the values its
+// sources wrote for it.
+type PropertyProvenance struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Policy MergePolicy `json:"policy"`
+	// Sources are the sources whose values went into Value.
+	Sources []string `json:"sources"`
+	// Values are the values of every source that wrote the property.
+	Values map[string]PropertyValue `json:"values"`
+}
+
+// GetPropertyProvenance returns the properties of the current version of an
+// entity, sorted by key, each with the sources that contributed its value.
+func (s *Service) GetPropertyProvenance(ctx context.Context, ns *namespace.Namespace, urn string) ([]PropertyProvenance, error) {
+	ent, err := s.repo.GetByURN(ctx, ns, urn)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := PropertyPolicyFromNamespace(ns)
+	if err != nil {
+		return nil, err
+	}
+	sources := currentSources(&ent)
+	if len(ent.PropertySources) == 0 {
+		// Untracked versions took every value from their one source.
+		for _, values := range sources {
+			for key, v := range values {
+				v.Applied = true
+				values[key] = v
+			}
+		}
+	}
+
+	result := make([]PropertyProvenance, 0, len(ent.Properties))
+	for key, value := range ent.Properties {
+		p := PropertyProvenance{Key: key, Value: value, Policy: policy.For(key).Policy, Values: map[string]PropertyValue{}}
+		for src, values := range sources {
+			v, ok := values[key]
+			if !ok {
+				continue
+			}
+			p.Values[src] = v
+			if v.Applied {
+				p.Sources = append(p.Sources, src)
+			}
+		}
+		sort.Strings(p.Sources)
+		result = append(result, p)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+	return result, nil
+}
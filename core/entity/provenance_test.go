@@ -0,0 +1,254 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+func policyNamespace(policy map[string]interface{}) *namespace.Namespace {
+	return &namespace.Namespace{Name: "test", Metadata: map[string]interface{}{PropertyPolicyKey: policy}}
+}
+
+func TestPropertyPolicyFromNamespace(t *testing.T) {
+	p, err := PropertyPolicyFromNamespace(namespace.DefaultNamespace)
+	if err != nil {
+		t.Fatalf("PropertyPolicyFromNamespace failed: %v", err)
+	}
+	if p.For("owner").Policy != MergeLatest {
+		t.Errorf("expected latest by default, got %+v", p.For("owner"))
+	}
+
+	p, err = PropertyPolicyFromNamespace(policyNamespace(map[string]interface{}{
+		"policy":   "priority",
+		"priority": []interface{}{"manual", "dbt"},
+		"keys": map[string]interface{}{
+			"tags":  "union",
+			"owner": map[string]interface{}{"priority": []interface{}{"hr"}},
+		},
+	}))
+	if err != nil {
+		t.Fatalf("PropertyPolicyFromNamespace failed: %v", err)
+	}
+	want := map[string]KeyPolicy{
+		"description": {Policy: MergePriority, Priority: []string{"manual", "dbt"}},
+		"tags":        {Policy: MergeUnion, Priority: []string{"manual", "dbt"}},
+		"owner":       {Policy: MergePriority, Priority: []string{"hr"}},
+	}
+	for key, w := range want {
+		if got := p.For(key); !reflect.DeepEqual(got, w) {
+			t.Errorf("%s: expected %+v, got %+v", key, w, got)
+		}
+	}
+
+	invalid := []map[string]interface{}{
+		{"policy": "oldest"},
+		{"priority": "manual"},
+		{"keys": []interface{}{"tags"}},
+		{"keys": map[string]interface{}{"tags": 1}},
+	}
+	for _, m := range invalid {
+		if _, err := PropertyPolicyFromNamespace(policyNamespace(m)); !errors.Is(err, ErrInvalidPropertyPolicy) {
+			t.Errorf("expected ErrInvalidPropertyPolicy for %v, got %v", m, err)
+		}
+	}
+}
+
+func TestEntity_MergeProperties(t *testing.T) {
+	ns := policyNamespace(map[string]interface{}{
+		"priority": []interface{}{"manual", "dbt", "bigquery"},
+		"keys": map[string]interface{}{
+			"owner": "priority",
+			"tags":  "union",
+		},
+	})
+	t1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	t2, t3, t4 := t1.Add(time.Hour), t1.Add(2*time.Hour), t1.Add(3*time.Hour)
+
+	write := func(cur *Entity, source string, props map[string]interface{}, now time.Time) *Entity {
+		t.Helper()
+		ent := &Entity{URN: "urn:orders", Source: source, Properties: props}
+		if err := ent.MergeProperties(ns, cur, now); err != nil {
+			t.Fatalf("MergeProperties failed: %v", err)
+		}
+		return ent
+	}
+
+	ent := write(nil, "bigquery", map[string]interface{}{"rows": 10, "owner": "bq-admin", "tags": []interface{}{"pii"}}, t1)
+	ent = write(ent, "dbt", map[string]interface{}{"description": "orders", "tags": []interface{}{"finance", "pii"}}, t2)
+	ent = write(ent, "", map[string]interface{}{"owner": "data-eng", "rows": 12}, t3)
+
+	want := map[string]interface{}{
+		"rows":        12,
+		"owner":       "data-eng",
+		"description": "orders",
+		"tags":        []interface{}{"finance", "pii"},
+	}
+	if !reflect.DeepEqual(ent.Properties, want) {
+		t.Errorf("expected %v, got %v", want, ent.Properties)
+	}
+	applied := func(src, key string) bool { return ent.PropertySources[src][key].Applied }
+	if !applied(ManualSource, "owner") || applied("bigquery", "owner") {
+		t.Errorf("expected the manual owner to be applied, got %+v", ent.PropertySources)
+	}
+	if !applied(ManualSource, "rows") || applied("bigquery", "rows") {
+		t.Errorf("expected the latest rows to be applied, got %+v", ent.PropertySources)
+	}
+	if !applied("bigquery", "tags") || !applied("dbt", "tags") {
+		t.Errorf("expected every tags value to be applied, got %+v", ent.PropertySources)
+	}
+
+	// Rewriting a value unchanged keeps its time, so it does not become the
+	// latest; dropping a key removes only that source's value.
+	ent = write(ent, "bigquery", map[string]interface{}{"rows": 10, "owner": "bq-admin"}, t4)
+	if got := ent.PropertySources["bigquery"]["rows"].UpdatedAt; !got.Equal(t1) {
+		t.Errorf("expected unchanged value to keep its time, got %s", got)
+	}
+	if ent.Properties["rows"] != 12 {
+		t.Errorf("expected rows to stay 12, got %v", ent.Properties["rows"])
+	}
+	if _, ok := ent.PropertySources["bigquery"]["tags"]; ok {
+		t.Error("expected dropped tags to be removed from bigquery")
+	}
+	if !reflect.DeepEqual(ent.Properties["tags"], []interface{}{"finance", "pii"}) {
+		t.Errorf("expected dbt tags to remain, got %v", ent.Properties["tags"])
+	}
+}
+
+func TestEntity_MergeProperties_Untracked(t *testing.T) {
+	t1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	cur := &Entity{URN: "urn:orders", Source: "bigquery", ValidFrom: t1,
+		Properties: map[string]interface{}{"rows": 10, "owner": "bq-admin"}}
+
+	ent := &Entity{URN: "urn:orders", Source: "dbt", Properties: map[string]interface{}{"description": "orders"}}
+	if err := ent.MergeProperties(namespace.DefaultNamespace, cur, t1.Add(time.Hour)); err != nil {
+		t.Fatalf("MergeProperties failed: %v", err)
+	}
+	if len(ent.Properties) != 3 {
+		t.Errorf("expected the untracked properties to be kept, got %v", ent.Properties)
+	}
+	if v := ent.PropertySources["bigquery"]["rows"]; !v.Applied || !v.UpdatedAt.Equal(t1) {
+		t.Errorf("expected untracked properties attributed to bigquery, got %+v", v)
+	}
+
+	// A restored version that carries its sources is merged from them.
+	restored := Entity{URN: "urn:orders", Source: "dbt", PropertySources: ent.PropertySources}
+	if err := restored.RestoreProperties(namespace.DefaultNamespace); err != nil {
+		t.Fatalf("RestoreProperties failed: %v", err)
+	}
+	if !reflect.DeepEqual(restored.Properties, ent.Properties) {
+		t.Errorf("expected %v, got %v", ent.Properties, restored.Properties)
+	}
+
+	// Sources a written version carries are ignored.
+	forged := Entity{URN: "urn:orders", Source: "dbt", Properties: map[string]interface{}{"description": "orders"},
+		PropertySources: PropertySources{"hr": {"owner": {Value: "hr-team", UpdatedAt: t1}}}}
+	if err := forged.MergeProperties(namespace.DefaultNamespace, nil, t1.Add(2*time.Hour)); err != nil {
+		t.Fatalf("MergeProperties failed: %v", err)
+	}
+	if _, ok := forged.PropertySources["hr"]; ok || forged.Properties["owner"] != nil {
+		t.Errorf("expected the forged source to be dropped, got %+v", forged.PropertySources)
+	}
+}
+
+// mergingRepo merges the properties of an upserted entity with those of its
+// current version, as the store does.
+type mergingRepo struct {
+	*mockRepo
+}
+
+func (m mergingRepo) Upsert(ctx context.Context, ns *namespace.Namespace, ent *Entity) (string, error) {
+	var cur *Entity
+	if c, ok := m.entities[ent.URN]; ok {
+		cur = &c
+	}
+	if err := ent.MergeProperties(ns, cur, time.Now()); err != nil {
+		return "", err
+	}
+	return m.mockRepo.Upsert(ctx, ns, ent)
+}
+
+func TestService_Upsert_ReadBack(t *testing.T) {
+	repo := mergingRepo{newMockRepo()}
+	svc := NewService(repo, nil, nil)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	if _, err := svc.Upsert(ctx, ns, &Entity{URN: "urn:orders", Type: TypeTable, Name: "orders", Source: "bigquery",
+		Properties: map[string]interface{}{"owner": "bq-admin", "rows": 10}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A client reads the entity, edits a property and writes it back.
+	read, err := svc.GetByURN(ctx, ns, "urn:orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	read.Properties = maps.Clone(read.Properties)
+	read.Properties["owner"] = "data-eng"
+	read.PropertySources = PropertySources{"hr": {"tier": {Value: "gold"}}}
+	if _, err := svc.Upsert(ctx, ns, &read); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := svc.GetByURN(ctx, ns, "urn:orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Properties["owner"] != "data-eng" || got.Properties["rows"] != 10 {
+		t.Errorf("expected the edit to be written, got %v", got.Properties)
+	}
+	if _, ok := got.PropertySources["hr"]; ok || got.Properties["tier"] != nil {
+		t.Errorf("expected the sources the client sent to be ignored, got %+v", got.PropertySources)
+	}
+}
+
+func TestService_GetPropertyProvenance(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, nil, nil)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	ent := &Entity{URN: "urn:orders", Type: TypeTable, Name: "orders", Source: "bigquery", Properties: map[string]interface{}{"owner": "bq-admin"}}
+	_ = ent.MergeProperties(ns, nil, time.Now())
+	manual := &Entity{URN: "urn:orders", Type: TypeTable, Name: "orders", Properties: map[string]interface{}{"owner": "data-eng", "tier": "gold"}}
+	_ = manual.MergeProperties(ns, ent, time.Now().Add(time.Second))
+	_, _ = repo.Upsert(ctx, ns, manual)
+
+	props, err := svc.GetPropertyProvenance(ctx, ns, "urn:orders")
+	if err != nil {
+		t.Fatalf("GetPropertyProvenance failed: %v", err)
+	}
+	if len(props) != 2 || props[0].Key != "owner" || props[1].Key != "tier" {
+		t.Fatalf("expected owner and tier, got %+v", props)
+	}
+	owner := props[0]
+	if owner.Value != "data-eng" || !reflect.DeepEqual(owner.Sources, []string{ManualSource}) || len(owner.Values) != 2 {
+		t.Errorf("expected owner from manual with both values, got %+v", owner)
+	}
+
+	if _, err := svc.GetPropertyProvenance(ctx, ns, "urn:missing"); err == nil {
+		t.Error("expected an error for a missing entity")
+	}
+}
+
+func TestService_Upsert_TypeCheckMergedProperties(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, nil, nil)
+	svc.WithTypeRegistry(newMockTypeRepo(tableDefinition))
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	if _, err := svc.Upsert(ctx, ns, &Entity{URN: "urn:t", Type: TypeTable, Name: "t", Properties: map[string]interface{}{"owner": "data-eng"}}); err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	// Another source leaves out the owner the first one wrote.
+	if _, err := svc.Upsert(ctx, ns, &Entity{URN: "urn:t", Type: TypeTable, Name: "t", Source: "bigquery", Properties: map[string]interface{}{"row_count": 10}}); err != nil {
+		t.Errorf("expected the merged entity to match its type, got %v", err)
+	}
+}
@@ -0,0 +1,193 @@
+package entity
+
+import (
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// GraphFormat is a text format a subgraph can be rendered in.
+type GraphFormat string
+
+const (
+	GraphFormatDOT     GraphFormat = "dot"
+	GraphFormatMermaid GraphFormat = "mermaid"
+	GraphFormatGraphML GraphFormat = "graphml"
+)
+
+// ErrUnknownGraphFormat is returned for a graph format that cannot be rendered.
+var ErrUnknownGraphFormat = errors.New("unknown graph format")
+
+// ParseGraphFormat parses a graph format name, case-insensitively.
+func ParseGraphFormat(s string) (GraphFormat, error) {
+	switch f := GraphFormat(strings.ToLower(strings.TrimSpace(s))); f {
+	case GraphFormatDOT, GraphFormatMermaid, GraphFormatGraphML:
+		return f, nil
+	}
+	return "", fmt.Errorf("%w %q: use dot, mermaid or graphml", ErrUnknownGraphFormat, s)
+}
+
+// ContentType is the media type of a rendered graph.
+func (f GraphFormat) ContentType() string {
+	switch f {
+	case GraphFormatDOT:
+		return "text/vnd.graphviz; charset=utf-8"
+	case GraphFormatGraphML:
+		return "application/graphml+xml; charset=utf-8"
+	default:
+		return "text/plain; charset=utf-8"
+	}
+}
+
+// Render writes the subgraph in the given format. Nodes are labelled with the
+// name and type of their entity, or with their URN when the entity is not part
+// of the graph; edges are labelled with their type. The root entity is
+// highlighted.
+func (cg *ContextGraph) Render(w io.Writer, format GraphFormat) error {
+	g := newRenderGraph(cg)
+	bw := bufio.NewWriter(w)
+	switch format {
+	case GraphFormatDOT:
+		g.writeDOT(bw)
+	case GraphFormatMermaid:
+		g.writeMermaid(bw)
+	case GraphFormatGraphML:
+		g.writeGraphML(bw)
+	default:
+		return fmt.Errorf("%w %q", ErrUnknownGraphFormat, format)
+	}
+	return bw.Flush()
+}
+
+type renderNode struct {
+	urn    string
+	entity *Entity
+}
+
+func (n renderNode) label() string {
+	if n.entity == nil {
+		return n.urn
+	}
+	name := n.entity.Name
+	if name == "" {
+		name = n.urn
+	}
+	return name + "\n(" + n.entity.Type.String() + ")"
+}
+
+// renderGraph is a subgraph with its nodes in order of first appearance, the
+// root first, and its edges without duplicates.
+type renderGraph struct {
+	nodes []renderNode
+	index map[string]int
+	edges []Edge
+}
+
+func newRenderGraph(cg *ContextGraph) *renderGraph {
+	g := &renderGraph{index: map[string]int{}}
+	entities := map[string]*Entity{cg.Entity.URN: &cg.Entity}
+	for i := range cg.Related {
+		entities[cg.Related[i].URN] = &cg.Related[i]
+	}
+	add := func(urn string) {
+		if _, ok := g.index[urn]; ok {
+			return
+		}
+		g.index[urn] = len(g.nodes)
+		g.nodes = append(g.nodes, renderNode{urn: urn, entity: entities[urn]})
+	}
+
+	add(cg.Entity.URN)
+	seen := map[string]bool{}
+	for _, e := range cg.Edges {
+		key := e.SourceURN + "\x00" + e.TargetURN + "\x00" + e.Type
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		add(e.SourceURN)
+		add(e.TargetURN)
+		g.edges = append(g.edges, e)
+	}
+	for _, rel := range cg.Related {
+		add(rel.URN)
+	}
+	return g
+}
+
+func (g *renderGraph) writeDOT(w *bufio.Writer) {
+	w.WriteString("digraph compass {\n")
+	w.WriteString("  rankdir=LR;\n")
+	w.WriteString("  node [shape=box, style=rounded];\n")
+	for i, n := range g.nodes {
+		attrs := "label=" + dotQuote(n.label())
+		if i == 0 {
+			attrs += ", penwidth=2"
+		}
+		fmt.Fprintf(w, "  %s [%s];\n", dotQuote(n.urn), attrs)
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(w, "  %s -> %s [label=%s];\n", dotQuote(e.SourceURN), dotQuote(e.TargetURN), dotQuote(e.Type))
+	}
+	w.WriteString("}\n")
+}
+
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func (g *renderGraph) writeMermaid(w *bufio.Writer) {
+	w.WriteString("flowchart LR\n")
+	for i, n := range g.nodes {
+		fmt.Fprintf(w, "  n%d[%s]\n", i, mermaidQuote(n.label()))
+	}
+	for _, e := range g.edges {
+		fmt.Fprintf(w, "  n%d -->|%s| n%d\n", g.index[e.SourceURN], mermaidQuote(e.Type), g.index[e.TargetURN])
+	}
+	w.WriteString("  classDef root stroke-width:3px\n")
+	w.WriteString("  class n0 root\n")
+}
+
+// mermaidQuote quotes a label, using Mermaid's entity codes for characters
+// that would end it.
+func mermaidQuote(s string) string {
+	r := strings.NewReplacer(`"`, "#quot;", "\n", "<br/>")
+	return `"` + r.Replace(s) + `"`
+}
+
+func (g *renderGraph) writeGraphML(w *bufio.Writer) {
+	w.WriteString(xml.Header)
+	w.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	w.WriteString(`  <key id="name" for="node" attr.name="name" attr.type="string"/>` + "\n")
+	w.WriteString(`  <key id="type" for="node" attr.name="type" attr.type="string"/>` + "\n")
+	w.WriteString(`  <key id="root" for="node" attr.name="root" attr.type="boolean"><default>false</default></key>` + "\n")
+	w.WriteString(`  <key id="label" for="edge" attr.name="label" attr.type="string"/>` + "\n")
+	w.WriteString(`  <graph id="compass" edgedefault="directed">` + "\n")
+	for i, n := range g.nodes {
+		fmt.Fprintf(w, `    <node id="%s">`+"\n", xmlEscape(n.urn))
+		if n.entity != nil {
+			fmt.Fprintf(w, `      <data key="name">%s</data>`+"\n", xmlEscape(n.entity.Name))
+			fmt.Fprintf(w, `      <data key="type">%s</data>`+"\n", xmlEscape(n.entity.Type.String()))
+		}
+		if i == 0 {
+			w.WriteString(`      <data key="root">true</data>` + "\n")
+		}
+		w.WriteString("    </node>\n")
+	}
+	for i, e := range g.edges {
+		fmt.Fprintf(w, `    <edge id="e%d" source="%s" target="%s">`+"\n", i, xmlEscape(e.SourceURN), xmlEscape(e.TargetURN))
+		fmt.Fprintf(w, `      <data key="label">%s</data>`+"\n", xmlEscape(e.Type))
+		w.WriteString("    </edge>\n")
+	}
+	w.WriteString("  </graph>\n</graphml>\n")
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,112 @@
+package entity
+
+import (
+	"encoding/xml"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func sampleGraph() *ContextGraph {
+	return &ContextGraph{
+		Entity: Entity{URN: "urn:bigquery:shop.orders", Type: TypeTable, Name: `orders "v2"`},
+		Edges: []Edge{
+			{SourceURN: "urn:optimus:load_orders", TargetURN: "urn:bigquery:shop.orders", Type: "writes"},
+			{SourceURN: "urn:bigquery:shop.orders", TargetURN: "urn:metabase:revenue", Type: "feeds"},
+			{SourceURN: "urn:optimus:load_orders", TargetURN: "urn:bigquery:shop.orders", Type: "writes"},
+		},
+		Related: []Entity{
+			{URN: "urn:optimus:load_orders", Type: TypeJob, Name: "load_orders"},
+		},
+	}
+}
+
+func render(t *testing.T, cg *ContextGraph, f GraphFormat) string {
+	t.Helper()
+	var b strings.Builder
+	if err := cg.Render(&b, f); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+func TestParseGraphFormat(t *testing.T) {
+	for in, want := range map[string]GraphFormat{"dot": GraphFormatDOT, "Mermaid": GraphFormatMermaid, " graphml ": GraphFormatGraphML} {
+		if got, err := ParseGraphFormat(in); err != nil || got != want {
+			t.Errorf("ParseGraphFormat(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := ParseGraphFormat("svg"); !errors.Is(err, ErrUnknownGraphFormat) {
+		t.Errorf("expected ErrUnknownGraphFormat, got %v", err)
+	}
+}
+
+func TestContextGraph_RenderDOT(t *testing.T) {
+	out := render(t, sampleGraph(), GraphFormatDOT)
+	for _, want := range []string{
+		`digraph compass {`,
+		`"urn:bigquery:shop.orders" [label="orders \"v2\"\n(table)", penwidth=2];`,
+		`"urn:optimus:load_orders" [label="load_orders\n(job)"];`,
+		`"urn:metabase:revenue" [label="urn:metabase:revenue"];`,
+		`"urn:optimus:load_orders" -> "urn:bigquery:shop.orders" [label="writes"];`,
+		`"urn:bigquery:shop.orders" -> "urn:metabase:revenue" [label="feeds"];`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "->") != 2 {
+		t.Errorf("expected duplicate edges to be dropped:\n%s", out)
+	}
+}
+
+func TestContextGraph_RenderMermaid(t *testing.T) {
+	out := render(t, sampleGraph(), GraphFormatMermaid)
+	for _, want := range []string{
+		"flowchart LR\n",
+		`n0["orders #quot;v2#quot;<br/>(table)"]`,
+		`n1["load_orders<br/>(job)"]`,
+		`n2["urn:metabase:revenue"]`,
+		`n1 -->|"writes"| n0`,
+		`n0 -->|"feeds"| n2`,
+		"class n0 root",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in:\n%s", want, out)
+		}
+	}
+}
+
+func TestContextGraph_RenderGraphML(t *testing.T) {
+	out := render(t, sampleGraph(), GraphFormatGraphML)
+
+	var doc struct {
+		Graph struct {
+			Nodes []struct {
+				ID   string `xml:"id,attr"`
+				Data []struct {
+					Key   string `xml:"key,attr"`
+					Value string `xml:",chardata"`
+				} `xml:"data"`
+			} `xml:"node"`
+			Edges []struct {
+				Source string `xml:"source,attr"`
+				Target string `xml:"target,attr"`
+				Label  string `xml:"data"`
+			} `xml:"edge"`
+		} `xml:"graph"`
+	}
+	if err := xml.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("invalid GraphML: %v\n%s", err, out)
+	}
+	if len(doc.Graph.Nodes) != 3 || len(doc.Graph.Edges) != 2 {
+		t.Fatalf("expected 3 nodes and 2 edges, got:\n%s", out)
+	}
+	root := doc.Graph.Nodes[0]
+	if root.ID != "urn:bigquery:shop.orders" || root.Data[0].Value != `orders "v2"` || root.Data[2].Value != "true" {
+		t.Errorf("unexpected root node %+v", root)
+	}
+	if e := doc.Graph.Edges[0]; e.Source != "urn:optimus:load_orders" || e.Label != "writes" {
+		t.Errorf("unexpected edge %+v", e)
+	}
+}
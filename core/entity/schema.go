@@ -0,0 +1,191 @@
+package entity
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/urn"
+)
+
+// SchemaChangeLevel summarises how an upsert changed an entity's schema.
+type SchemaChangeLevel string
+
+const (
+	SchemaUnchanged SchemaChangeLevel = "none"
+	SchemaAdditive  SchemaChangeLevel = "additive"
+	SchemaBreaking  SchemaChangeLevel = "breaking"
+)
+
+// SchemaChange is a change to one column of a column-like structure. Path is
+// the dotted property path of the structure, e.g. "columns" or "schema.fields".
+type SchemaChange struct {
+	Path     string     `json:"path"`
+	Column   string     `json:"column"`
+	Kind     ChangeKind `json:"kind"`
+	FromType string     `json:"from_type,omitempty"`
+	ToType   string     `json:"to_type,omitempty"`
+	Breaking bool       `json:"breaking"`
+}
+
+// AffectedEntity is a downstream entity reached from a schema change.
+type AffectedEntity struct {
+	URN  string `json:"urn"`
+	Type Type   `json:"type,omitempty"`
+	Name string `json:"name,omitempty"`
+}
+
+// SchemaImpact reports a schema change and, for breaking changes, the
+// downstream entities it reaches.
+type SchemaImpact struct {
+	URN      string            `json:"urn"`
+	Type     Type              `json:"type,omitempty"`
+	Level    SchemaChangeLevel `json:"level"`
+	Changes  []SchemaChange    `json:"changes,omitempty"`
+	Edges    []Edge            `json:"edges,omitempty"`
+	Affected []AffectedEntity  `json:"affected,omitempty"`
+}
+
+// SchemaChangeNotifier receives the impact report of upserts that change an
+// entity's schema.
+type SchemaChangeNotifier interface {
+	NotifySchemaChange(ctx context.Context, ns *namespace.Namespace, impact *SchemaImpact)
+}
+
+// ClassifySchemaChange compares the column-like structures of two entity
+// versions. Removing a column or changing its type is breaking; adding a
+// column is additive. Other column attributes are not part of the schema.
+func ClassifySchemaChange(from, to Entity) (SchemaChangeLevel, []SchemaChange) {
+	before := schemaStructures(from.Properties)
+	after := schemaStructures(to.Properties)
+
+	var paths []string
+	for p := range before {
+		paths = append(paths, p)
+	}
+	for p := range after {
+		if _, ok := before[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	level := SchemaUnchanged
+	var changes []SchemaChange
+	for _, path := range paths {
+		for _, cc := range diffColumns(before[path], after[path]) {
+			sc := SchemaChange{Path: path, Column: cc.Name, Kind: cc.Kind}
+			sc.FromType, _ = cc.From["type"].(string)
+			sc.ToType, _ = cc.To["type"].(string)
+			switch cc.Kind {
+			case ChangeRemoved:
+				sc.Breaking = true
+			case ChangeChanged:
+				if sc.FromType == sc.ToType {
+					continue
+				}
+				sc.Breaking = true
+			}
+			changes = append(changes, sc)
+			if sc.Breaking {
+				level = SchemaBreaking
+			} else if level == SchemaUnchanged {
+				level = SchemaAdditive
+			}
+		}
+	}
+	return level, changes
+}
+
+// schemaStructures finds every list of name/type maps in props, keyed by
+// dotted path. These are the same column-like shapes the chunking serializer
+// renders as "name (type)".
+func schemaStructures(props map[string]interface{}) map[string][]map[string]interface{} {
+	found := map[string][]map[string]interface{}{}
+	var walk func(path string, m map[string]interface{})
+	walk = func(path string, m map[string]interface{}) {
+		for k, v := range m {
+			child := joinPath(path, k)
+			if nested, ok := v.(map[string]interface{}); ok {
+				walk(child, nested)
+				continue
+			}
+			if cols, ok := columnStructure(v); ok {
+				found[child] = cols
+			}
+		}
+	}
+	walk("", props)
+	return found
+}
+
+// columnStructure reports whether v is a non-empty list of maps that all carry
+// string name and type keys.
+func columnStructure(v interface{}) ([]map[string]interface{}, bool) {
+	cols, ok := namedMaps(v)
+	if !ok || len(cols) == 0 {
+		return nil, false
+	}
+	for _, c := range cols {
+		if _, ok := c["type"].(string); !ok {
+			return nil, false
+		}
+	}
+	return cols, true
+}
+
+// CheckSchemaChange reports how upserting ent would change the schema of the
+// current version, without writing anything. The properties of ent are
+// merged with those other sources wrote first, as the upsert would. A new
+// entity is compared against an empty schema, so all its columns are
+// additive.
+func (s *Service) CheckSchemaChange(ctx context.Context, ns *namespace.Namespace, ent Entity) (*SchemaImpact, error) {
+	current, err := s.repo.GetByURN(ctx, ns, ent.URN)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("get entity: %w", err)
+	}
+	var cur *Entity
+	if err == nil && current.URN == urn.Lookup(ns, ent.URN) {
+		cur = &current
+	} else {
+		current = Entity{}
+	}
+	return s.schemaImpact(ctx, ns, current, withMergedProperties(ns, ent, cur))
+}
+
+// schemaImpact classifies the change from one version to the next and, when
+// it is breaking, walks downstream lineage to list the affected entities.
+func (s *Service) schemaImpact(ctx context.Context, ns *namespace.Namespace, from, to Entity) (*SchemaImpact, error) {
+	level, changes := ClassifySchemaChange(from, to)
+	impact := &SchemaImpact{URN: to.URN, Type: to.Type, Level: level, Changes: changes}
+	if level != SchemaBreaking || s.edges == nil {
+		return impact, nil
+	}
+
+	filter, err := s.impactFilter(ctx, ns, nil)
+	if err != nil {
+		return nil, err
+	}
+	edges, err := s.edges.GetDownstream(ctx, ns, to.URN, 3, filter)
+	if err != nil {
+		return nil, fmt.Errorf("get downstream: %w", err)
+	}
+	impact.Edges = edges
+
+	seen := map[string]bool{to.URN: true}
+	for _, e := range edges {
+		if seen[e.TargetURN] {
+			continue
+		}
+		seen[e.TargetURN] = true
+		affected := AffectedEntity{URN: e.TargetURN}
+		if ent, err := s.repo.GetByURN(ctx, ns, e.TargetURN); err == nil {
+			affected.Type, affected.Name = ent.Type, ent.Name
+		}
+		impact.Affected = append(impact.Affected, affected)
+	}
+	return impact, nil
+}
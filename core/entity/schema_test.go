@@ -0,0 +1,192 @@
+package entity
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+func cols(pairs ...string) []interface{} {
+	var out []interface{}
+	for i := 0; i+1 < len(pairs); i += 2 {
+		out = append(out, map[string]interface{}{"name": pairs[i], "type": pairs[i+1]})
+	}
+	return out
+}
+
+func TestClassifySchemaChange(t *testing.T) {
+	base := Entity{Properties: map[string]interface{}{"columns": cols("id", "INT64", "amount", "FLOAT64")}}
+
+	tests := []struct {
+		name     string
+		to       map[string]interface{}
+		level    SchemaChangeLevel
+		nChanges int
+	}{
+		{
+			name:  "unchanged",
+			to:    map[string]interface{}{"columns": cols("id", "INT64", "amount", "FLOAT64")},
+			level: SchemaUnchanged,
+		},
+		{
+			name: "description only",
+			to: map[string]interface{}{"columns": []interface{}{
+				map[string]interface{}{"name": "id", "type": "INT64", "description": "primary key"},
+				map[string]interface{}{"name": "amount", "type": "FLOAT64"},
+			}},
+			level: SchemaUnchanged,
+		},
+		{
+			name:     "column added",
+			to:       map[string]interface{}{"columns": cols("id", "INT64", "amount", "FLOAT64", "currency", "STRING")},
+			level:    SchemaAdditive,
+			nChanges: 1,
+		},
+		{
+			name:     "column removed",
+			to:       map[string]interface{}{"columns": cols("id", "INT64")},
+			level:    SchemaBreaking,
+			nChanges: 1,
+		},
+		{
+			name:     "type changed and column added",
+			to:       map[string]interface{}{"columns": cols("id", "STRING", "amount", "FLOAT64", "currency", "STRING")},
+			level:    SchemaBreaking,
+			nChanges: 2,
+		},
+		{
+			name:     "structure dropped",
+			to:       map[string]interface{}{},
+			level:    SchemaBreaking,
+			nChanges: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			level, changes := ClassifySchemaChange(base, Entity{Properties: tt.to})
+			if level != tt.level {
+				t.Errorf("expected level %s, got %s", tt.level, level)
+			}
+			if len(changes) != tt.nChanges {
+				t.Errorf("expected %d changes, got %d: %+v", tt.nChanges, len(changes), changes)
+			}
+		})
+	}
+}
+
+func TestClassifySchemaChange_NestedStructure(t *testing.T) {
+	from := Entity{Properties: map[string]interface{}{
+		"schema": map[string]interface{}{"fields": cols("event_id", "STRING", "ts", "TIMESTAMP")},
+		"tags":   []interface{}{"pii", "gold"},
+	}}
+	to := Entity{Properties: map[string]interface{}{
+		"schema": map[string]interface{}{"fields": cols("event_id", "STRING")},
+		"tags":   []interface{}{"pii"},
+	}}
+
+	level, changes := ClassifySchemaChange(from, to)
+	if level != SchemaBreaking {
+		t.Fatalf("expected breaking, got %s", level)
+	}
+	if len(changes) != 1 || changes[0].Path != "schema.fields" || changes[0].Column != "ts" || changes[0].Kind != ChangeRemoved {
+		t.Errorf("unexpected changes: %+v", changes)
+	}
+}
+
+type recordingNotifier struct {
+	impacts []*SchemaImpact
+}
+
+func (n *recordingNotifier) NotifySchemaChange(_ context.Context, _ *namespace.Namespace, impact *SchemaImpact) {
+	n.impacts = append(n.impacts, impact)
+}
+
+func TestService_Upsert_NotifiesBreakingSchemaChange(t *testing.T) {
+	repo := newMockRepo()
+	edges := &mockEdgeRepo{
+		downstreamEdges: []Edge{
+			{SourceURN: "urn:table:orders", TargetURN: "urn:dashboard:revenue", Type: "feeds"},
+			{SourceURN: "urn:dashboard:revenue", TargetURN: "urn:dashboard:exec", Type: "feeds"},
+		},
+	}
+	notifier := &recordingNotifier{}
+	svc := NewService(repo, edges, nil)
+	svc.WithSchemaChangeNotifier(notifier)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	_, _ = svc.Upsert(ctx, ns, &Entity{URN: "urn:dashboard:revenue", Type: TypeDashboard, Name: "Revenue"})
+	_, _ = svc.Upsert(ctx, ns, &Entity{URN: "urn:table:orders", Type: TypeTable, Name: "orders",
+		Properties: map[string]interface{}{"columns": cols("id", "INT64", "amount", "FLOAT64")}})
+	if len(notifier.impacts) != 0 {
+		t.Fatalf("expected no notification for new entities, got %d", len(notifier.impacts))
+	}
+
+	_, err := svc.Upsert(ctx, ns, &Entity{URN: "urn:table:orders", Type: TypeTable, Name: "orders",
+		Properties: map[string]interface{}{"columns": cols("id", "INT64")}})
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+	if len(notifier.impacts) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(notifier.impacts))
+	}
+
+	impact := notifier.impacts[0]
+	if impact.Level != SchemaBreaking {
+		t.Errorf("expected breaking, got %s", impact.Level)
+	}
+	if len(impact.Affected) != 2 {
+		t.Fatalf("expected 2 affected entities, got %+v", impact.Affected)
+	}
+	if impact.Affected[0].URN != "urn:dashboard:revenue" || impact.Affected[0].Type != TypeDashboard {
+		t.Errorf("expected affected entity to be resolved, got %+v", impact.Affected[0])
+	}
+}
+
+func TestService_CheckSchemaChange_MergedProperties(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, &mockEdgeRepo{}, nil)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	cur := &Entity{URN: "urn:table:orders", Type: TypeTable, Name: "orders", Source: "bigquery",
+		Properties: map[string]interface{}{"columns": cols("id", "INT64")}}
+	_ = cur.MergeProperties(ns, nil, time.Now())
+	_, _ = repo.Upsert(ctx, ns, cur)
+
+	// Another source leaves the columns out; they stay merged in.
+	impact, err := svc.CheckSchemaChange(ctx, ns, Entity{URN: "urn:table:orders", Source: "dbt",
+		Properties: map[string]interface{}{"description": "orders"}})
+	if err != nil {
+		t.Fatalf("CheckSchemaChange failed: %v", err)
+	}
+	if impact.Level != SchemaUnchanged {
+		t.Errorf("expected no change when another source omits the columns, got %+v", impact)
+	}
+
+	impact, err = svc.CheckSchemaChange(ctx, ns, Entity{URN: "urn:table:orders", Source: "bigquery",
+		Properties: map[string]interface{}{}})
+	if err != nil {
+		t.Fatalf("CheckSchemaChange failed: %v", err)
+	}
+	if impact.Level != SchemaBreaking {
+		t.Errorf("expected breaking when the owning source drops the columns, got %+v", impact)
+	}
+}
+
+func TestService_CheckSchemaChange_NewEntity(t *testing.T) {
+	svc := NewService(newMockRepo(), &mockEdgeRepo{}, nil)
+
+	impact, err := svc.CheckSchemaChange(context.Background(), namespace.DefaultNamespace, Entity{
+		URN: "urn:table:new", Properties: map[string]interface{}{"columns": cols("id", "INT64")},
+	})
+	if err != nil {
+		t.Fatalf("CheckSchemaChange failed: %v", err)
+	}
+	if impact.Level != SchemaAdditive {
+		t.Errorf("expected additive for a new entity, got %s", impact.Level)
+	}
+}
@@ -2,10 +2,13 @@ package entity
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/principal"
 )
 
 // HybridSearcher is an interface for hybrid search (keyword + semantic).
@@ -27,6 +30,11 @@ type Service struct {
 	hybrid   HybridSearcher
 	pipeline EmbeddingPipeline
 	docs     DocumentFetcher
+	schema   SchemaChangeNotifier
+	bulk     BulkRepository
+	types    TypeRepository
+
+	columnTypes map[Type]bool
 }
 
 func NewService(repo Repository, edges EdgeRepository, search SearchRepository) *Service {
@@ -48,8 +56,56 @@ func (s *Service) WithDocumentFetcher(d DocumentFetcher) {
 	s.docs = d
 }
 
+// WithSchemaChangeNotifier enables schema change detection on entity upsert.
+func (s *Service) WithSchemaChangeNotifier(n SchemaChangeNotifier) {
+	s.schema = n
+}
+
+// WithBulkRepository enables BulkUpsert.
+func (s *Service) WithBulkRepository(b BulkRepository) {
+	s.bulk = b
+}
+
+// WithTypeRegistry enables entity type definitions and edge type
+// declarations. Written entities are validated against the definition of
+// their type, and impact analysis skips edges declared as associations.
+func (s *Service) WithTypeRegistry(r TypeRepository) {
+	s.types = r
+}
+
+// WithColumnEntities expands the properties.columns of entities of the given
+// types into column entities, and lets GetImpact follow derived_from edges
+// from a column.
+func (s *Service) WithColumnEntities(types ...Type) {
+	s.columnTypes = make(map[Type]bool, len(types))
+	for _, t := range types {
+		s.columnTypes[t] = true
+	}
+}
+
+// Upsert writes an entity, recording the principal of ctx as the one who
+// changed it. Its properties are merged with those other
+// sources wrote; see Entity.MergeProperties. When its type is registered,
+// the merged entity is checked against the type definition first; see
+// CheckType. Property sources are derived from what was written, so those ent
+// carries are dropped.
 func (s *Service) Upsert(ctx context.Context, ns *namespace.Namespace, ent *Entity) (string, error) {
-	id, err := s.repo.Upsert(ctx, ns, ent)
+	ent.ChangedBy = principal.FromContext(ctx).Subject
+	ent.PropertySources = nil
+	if _, err := s.CheckType(ctx, ns, *ent); err != nil {
+		return "", err
+	}
+
+	var previous *Entity
+	if s.schema != nil {
+		previous = s.currentVersion(ctx, ns, ent.URN)
+	}
+
+	upsert := s.repo.Upsert
+	if s.expandsColumns(ent.Type) {
+		upsert = s.repo.UpsertWithColumns
+	}
+	id, err := upsert(ctx, ns, ent)
 	if err != nil {
 		return "", fmt.Errorf("upsert entity: %w", err)
 	}
@@ -59,6 +115,12 @@ func (s *Service) Upsert(ctx context.Context, ns *namespace.Namespace, ent *Enti
 		_ = s.pipeline.EnqueueEntity(ctx, ns, ent)
 	}
 
+	if previous != nil {
+		if impact, err := s.schemaImpact(ctx, ns, *previous, *ent); err == nil && impact.Level != SchemaUnchanged {
+			s.schema.NotifySchemaChange(ctx, ns, impact)
+		}
+	}
+
 	return id, nil
 }
 
@@ -66,6 +128,46 @@ func (s *Service) GetByURN(ctx context.Context, ns *namespace.Namespace, urn str
 	return s.repo.GetByURN(ctx, ns, urn)
 }
 
+// GetByURNAsOf returns the version of an entity that was live at asOf.
+func (s *Service) GetByURNAsOf(ctx context.Context, ns *namespace.Namespace, urn string, asOf time.Time) (Entity, error) {
+	return s.repo.GetByURNAsOf(ctx, ns, urn, asOf)
+}
+
+// getByURN resolves the current entity, or the version live at asOf when set.
+func (s *Service) getByURN(ctx context.Context, ns *namespace.Namespace, urn string, asOf *time.Time) (Entity, error) {
+	if asOf != nil {
+		return s.repo.GetByURNAsOf(ctx, ns, urn, *asOf)
+	}
+	return s.repo.GetByURN(ctx, ns, urn)
+}
+
+// GetHistory returns every version of an entity, newest first.
+func (s *Service) GetHistory(ctx context.Context, ns *namespace.Namespace, urn string) ([]Entity, error) {
+	versions, err := s.repo.GetHistory(ctx, ns, urn)
+	if err != nil {
+		return nil, fmt.Errorf("get entity history: %w", err)
+	}
+	if len(versions) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	return versions, nil
+}
+
+// DiffEntity compares two versions of an entity. from and to select the
+// versions live at those instants; a zero to means the current version and a
+// zero from means the version immediately before to.
+func (s *Service) DiffEntity(ctx context.Context, ns *namespace.Namespace, urn string, from, to time.Time) (*EntityDiff, error) {
+	history, err := s.GetHistory(ctx, ns, urn)
+	if err != nil {
+		return nil, err
+	}
+	older, newer, err := selectVersions(history, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return DiffEntities(older, newer), nil
+}
+
 func (s *Service) GetByID(ctx context.Context, id string) (Entity, error) {
 	return s.repo.GetByID(ctx, id)
 }
@@ -86,16 +188,26 @@ func (s *Service) GetTypes(ctx context.Context, ns *namespace.Namespace) (map[Ty
 	return s.repo.GetTypes(ctx, ns)
 }
 
+// Delete soft-deletes an entity together with its edges and column entities.
 func (s *Service) Delete(ctx context.Context, ns *namespace.Namespace, urn string) error {
 	if err := s.repo.Delete(ctx, ns, urn); err != nil {
 		return fmt.Errorf("delete entity: %w", err)
 	}
-	if s.edges != nil {
-		_ = s.edges.DeleteByURN(ctx, ns, urn)
-	}
 	return nil
 }
 
+// RestoreEntity undoes a delete: the entity's latest version and the edges
+// removed along with it become current again, and so do its columns with
+// their own lineage. Edges their type declaration no longer allows stay
+// deleted. History keeps the deleted gap.
+func (s *Service) RestoreEntity(ctx context.Context, ns *namespace.Namespace, urn string) (Entity, []Edge, error) {
+	ent, edges, err := s.repo.Restore(ctx, ns, urn)
+	if err != nil {
+		return Entity{}, nil, fmt.Errorf("restore entity: %w", err)
+	}
+	return ent, edges, nil
+}
+
 func (s *Service) Search(ctx context.Context, cfg SearchConfig) ([]SearchResult, error) {
 	if s.hybrid != nil && (cfg.Mode == SearchModeSemantic || cfg.Mode == SearchModeHybrid) {
 		return s.hybrid.Search(ctx, cfg)
@@ -118,10 +230,21 @@ const maxContextDepth = 5
 
 // GetContext assembles a context subgraph around an entity.
 func (s *Service) GetContext(ctx context.Context, ns *namespace.Namespace, urn string, depth int) (*ContextGraph, error) {
-	ent, err := s.repo.GetByURN(ctx, ns, urn)
+	return s.getContext(ctx, ns, urn, depth, nil)
+}
+
+// GetContextAsOf assembles the context subgraph as it stood at asOf.
+func (s *Service) GetContextAsOf(ctx context.Context, ns *namespace.Namespace, urn string, depth int, asOf time.Time) (*ContextGraph, error) {
+	return s.getContext(ctx, ns, urn, depth, &asOf)
+}
+
+func (s *Service) getContext(ctx context.Context, ns *namespace.Namespace, urn string, depth int, asOf *time.Time) (*ContextGraph, error) {
+	ent, err := s.getByURN(ctx, ns, urn, asOf)
 	if err != nil {
 		return nil, fmt.Errorf("get entity: %w", err)
 	}
+	// A merged URN resolves to the entity it was merged into.
+	urn = ent.URN
 
 	cg := &ContextGraph{Entity: ent}
 
@@ -133,37 +256,91 @@ func (s *Service) GetContext(ctx context.Context, ns *namespace.Namespace, urn s
 			depth = maxContextDepth
 		}
 
-		cg.Edges, err = s.edges.GetBidirectional(ctx, ns, urn, depth)
+		cg.Edges, err = s.edges.GetBidirectional(ctx, ns, urn, depth, EdgeFilter{AsOf: asOf})
 		if err != nil {
 			return nil, fmt.Errorf("get context edges: %w", err)
 		}
+		cg.Related = s.relatedEntities(ctx, ns, urn, cg.Edges, asOf)
+	}
 
-		seen := map[string]bool{urn: true}
-		for _, e := range cg.Edges {
-			for _, candidate := range []string{e.SourceURN, e.TargetURN} {
-				if seen[candidate] {
-					continue
-				}
-				seen[candidate] = true
-				if rel, err := s.repo.GetByURN(ctx, ns, candidate); err == nil {
-					cg.Related = append(cg.Related, rel)
-				}
+	return cg, nil
+}
+
+// relatedEntities looks up the entities at the ends of the edges, other than
+// the entity urn itself. Entities that cannot be found are left out.
+func (s *Service) relatedEntities(ctx context.Context, ns *namespace.Namespace, urn string, edges []Edge, asOf *time.Time) []Entity {
+	var related []Entity
+	seen := map[string]bool{urn: true}
+	for _, e := range edges {
+		for _, candidate := range []string{e.SourceURN, e.TargetURN} {
+			if seen[candidate] {
+				continue
+			}
+			seen[candidate] = true
+			if rel, err := s.getByURN(ctx, ns, candidate, asOf); err == nil {
+				related = append(related, rel)
 			}
 		}
 	}
-
-	return cg, nil
+	return related
 }
 
 // GetImpact returns downstream entities affected by changes to the given entity.
+// With column entities enabled, the impact of a column URN is the columns
+// derived from it, following derived_from edges from their targets.
 func (s *Service) GetImpact(ctx context.Context, ns *namespace.Namespace, urn string, depth int) ([]Edge, error) {
+	return s.getImpact(ctx, ns, urn, depth, nil)
+}
+
+// GetImpactAsOf returns the downstream edges as they stood at asOf.
+func (s *Service) GetImpactAsOf(ctx context.Context, ns *namespace.Namespace, urn string, depth int, asOf time.Time) ([]Edge, error) {
+	return s.getImpact(ctx, ns, urn, depth, &asOf)
+}
+
+func (s *Service) getImpact(ctx context.Context, ns *namespace.Namespace, urn string, depth int, asOf *time.Time) ([]Edge, error) {
 	if s.edges == nil {
 		return nil, nil
 	}
 	if depth <= 0 {
 		depth = 3
 	}
-	return s.edges.GetDownstream(ctx, ns, urn, depth)
+	if _, _, ok := ParseColumnURN(urn); ok && len(s.columnTypes) > 0 {
+		return s.edges.GetUpstream(ctx, ns, urn, depth, EdgeFilter{AsOf: asOf, Types: []string{EdgeDerivedFrom}})
+	}
+	filter, err := s.impactFilter(ctx, ns, asOf)
+	if err != nil {
+		return nil, err
+	}
+	return s.edges.GetDownstream(ctx, ns, urn, depth, filter)
+}
+
+// GetImpactGraph returns the downstream edges of an entity together with the
+// entity and the entities the edges reach, for rendering.
+func (s *Service) GetImpactGraph(ctx context.Context, ns *namespace.Namespace, urn string, depth int) (*ContextGraph, error) {
+	return s.getImpactGraph(ctx, ns, urn, depth, nil)
+}
+
+// GetImpactGraphAsOf returns the impact graph as it stood at asOf.
+func (s *Service) GetImpactGraphAsOf(ctx context.Context, ns *namespace.Namespace, urn string, depth int, asOf time.Time) (*ContextGraph, error) {
+	return s.getImpactGraph(ctx, ns, urn, depth, &asOf)
+}
+
+func (s *Service) getImpactGraph(ctx context.Context, ns *namespace.Namespace, urn string, depth int, asOf *time.Time) (*ContextGraph, error) {
+	ent, err := s.getByURN(ctx, ns, urn, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("get entity: %w", err)
+	}
+	// A merged URN resolves to the entity it was merged into.
+	urn = ent.URN
+	edges, err := s.getImpact(ctx, ns, urn, depth, asOf)
+	if err != nil {
+		return nil, fmt.Errorf("get impact edges: %w", err)
+	}
+	return &ContextGraph{
+		Entity:  ent,
+		Edges:   edges,
+		Related: s.relatedEntities(ctx, ns, urn, edges, asOf),
+	}, nil
 }
 
 // ContextGraph is the assembled context subgraph for an entity.
@@ -217,7 +394,7 @@ func (s *Service) AssembleContext(ctx context.Context, ns *namespace.Namespace,
 
 	if s.edges != nil {
 		for _, seed := range seeds {
-			edges, err := s.edges.GetBidirectional(ctx, ns, seed.URN, req.Depth)
+			edges, err := s.edges.GetBidirectional(ctx, ns, seed.URN, req.Depth, EdgeFilter{})
 			if err != nil {
 				continue
 			}
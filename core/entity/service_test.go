@@ -3,19 +3,25 @@ package entity
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"slices"
 	"testing"
 	"time"
 
 	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/principal"
 )
 
 // mockRepo is a simple in-memory entity repository for testing.
 type mockRepo struct {
 	entities map[string]Entity
+	history  map[string][]Entity
+	// withColumns lists the URNs written with UpsertWithColumns.
+	withColumns []string
 }
 
 func newMockRepo() *mockRepo {
-	return &mockRepo{entities: make(map[string]Entity)}
+	return &mockRepo{entities: make(map[string]Entity), history: make(map[string][]Entity)}
 }
 
 func (m *mockRepo) Upsert(_ context.Context, _ *namespace.Namespace, ent *Entity) (string, error) {
@@ -23,10 +29,21 @@ func (m *mockRepo) Upsert(_ context.Context, _ *namespace.Namespace, ent *Entity
 	ent.ID = id
 	ent.CreatedAt = time.Now()
 	ent.UpdatedAt = time.Now()
+	ent.ValidFrom = ent.UpdatedAt
 	m.entities[id] = *ent
+	m.history[ent.URN] = append([]Entity{*ent}, m.history[ent.URN]...)
 	return id, nil
 }
 
+func (m *mockRepo) UpsertWithColumns(ctx context.Context, ns *namespace.Namespace, ent *Entity) (string, error) {
+	m.withColumns = append(m.withColumns, ent.URN)
+	return m.Upsert(ctx, ns, ent)
+}
+
+func (m *mockRepo) GetHistory(_ context.Context, _ *namespace.Namespace, urn string) ([]Entity, error) {
+	return m.history[urn], nil
+}
+
 func (m *mockRepo) GetByURN(_ context.Context, _ *namespace.Namespace, urn string) (Entity, error) {
 	if e, ok := m.entities[urn]; ok {
 		return e, nil
@@ -34,6 +51,15 @@ func (m *mockRepo) GetByURN(_ context.Context, _ *namespace.Namespace, urn strin
 	return Entity{}, sql.ErrNoRows
 }
 
+func (m *mockRepo) GetByURNAsOf(_ context.Context, _ *namespace.Namespace, urn string, asOf time.Time) (Entity, error) {
+	for _, e := range m.history[urn] {
+		if !e.ValidFrom.After(asOf) {
+			return e, nil
+		}
+	}
+	return Entity{}, sql.ErrNoRows
+}
+
 func (m *mockRepo) GetByID(_ context.Context, id string) (Entity, error) {
 	for _, e := range m.entities {
 		if e.ID == id {
@@ -71,6 +97,30 @@ func (m *mockRepo) Delete(_ context.Context, _ *namespace.Namespace, urn string)
 	return nil
 }
 
+func (m *mockRepo) Restore(ctx context.Context, ns *namespace.Namespace, urn string) (Entity, []Edge, error) {
+	if _, ok := m.entities[urn]; ok {
+		return Entity{}, nil, ErrNotDeleted
+	}
+	if len(m.history[urn]) == 0 {
+		return Entity{}, nil, sql.ErrNoRows
+	}
+	ent := m.history[urn][0]
+	_, _ = m.Upsert(ctx, ns, &ent)
+	return ent, nil, nil
+}
+
+func (m *mockRepo) Merge(_ context.Context, _ *namespace.Namespace, from, into string) (MergeResult, error) {
+	if _, ok := m.entities[from]; !ok {
+		return MergeResult{}, sql.ErrNoRows
+	}
+	target, ok := m.entities[into]
+	if !ok {
+		return MergeResult{}, sql.ErrNoRows
+	}
+	delete(m.entities, from)
+	return MergeResult{Entity: target, Alias: from}, nil
+}
+
 func TestService_UpsertAndGet(t *testing.T) {
 	repo := newMockRepo()
 	svc := NewService(repo, nil, nil)
@@ -104,6 +154,37 @@ func TestService_UpsertAndGet(t *testing.T) {
 	}
 }
 
+func TestService_GetHistory(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, nil, nil)
+	ns := namespace.DefaultNamespace
+	ctx := principal.NewContext(context.Background(), principal.Principal{Subject: "alice@example.com"})
+
+	_, _ = svc.Upsert(ctx, ns, &Entity{URN: "urn:a", Type: TypeTable, Name: "a", Source: "bigquery"})
+	_, _ = svc.Upsert(ctx, ns, &Entity{URN: "urn:a", Type: TypeTable, Name: "a_renamed", Source: "dbt", ChangedBy: "mallory"})
+
+	versions, err := svc.GetHistory(ctx, ns, "urn:a")
+	if err != nil {
+		t.Fatalf("GetHistory failed: %v", err)
+	}
+	if len(versions) != 2 {
+		t.Fatalf("expected 2 versions, got %d", len(versions))
+	}
+	if versions[0].Name != "a_renamed" || versions[0].Source != "dbt" {
+		t.Errorf("expected newest version first, got %q from %q", versions[0].Name, versions[0].Source)
+	}
+	if versions[1].ChangedBy != "alice@example.com" {
+		t.Errorf("expected changed_by from principal, got %q", versions[1].ChangedBy)
+	}
+	if versions[0].ChangedBy != "alice@example.com" {
+		t.Errorf("expected changed_by from the request to be ignored, got %q", versions[0].ChangedBy)
+	}
+
+	if _, err := svc.GetHistory(ctx, ns, "urn:missing"); err == nil {
+		t.Error("expected error for unknown URN, got nil")
+	}
+}
+
 func TestService_Delete(t *testing.T) {
 	repo := newMockRepo()
 	svc := NewService(repo, nil, nil)
@@ -123,6 +204,34 @@ func TestService_Delete(t *testing.T) {
 	}
 }
 
+func TestService_RestoreEntity(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, nil, nil)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	if _, _, err := svc.RestoreEntity(ctx, ns, "urn:x"); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("expected sql.ErrNoRows for unknown entity, got %v", err)
+	}
+
+	_, _ = svc.Upsert(ctx, ns, &Entity{URN: "urn:x", Type: TypeJob, Name: "x"})
+	if _, _, err := svc.RestoreEntity(ctx, ns, "urn:x"); !errors.Is(err, ErrNotDeleted) {
+		t.Errorf("expected ErrNotDeleted for current entity, got %v", err)
+	}
+
+	_ = svc.Delete(ctx, ns, "urn:x")
+	ent, _, err := svc.RestoreEntity(ctx, ns, "urn:x")
+	if err != nil {
+		t.Fatalf("RestoreEntity failed: %v", err)
+	}
+	if ent.Name != "x" {
+		t.Errorf("expected restored name x, got %q", ent.Name)
+	}
+	if _, err := svc.GetByURN(ctx, ns, "urn:x"); err != nil {
+		t.Errorf("expected entity to be current after restore, got %v", err)
+	}
+}
+
 func TestService_GetAll(t *testing.T) {
 	repo := newMockRepo()
 	svc := NewService(repo, nil, nil)
@@ -168,9 +277,12 @@ func TestService_GetTypes(t *testing.T) {
 
 // mockEdgeRepo is a simple in-memory edge repository for testing.
 type mockEdgeRepo struct {
-	edges               []Edge
-	downstreamEdges     []Edge
-	lastDownstreamDepth int
+	edges                []Edge
+	downstreamEdges      []Edge
+	lastDownstreamDepth  int
+	lastDownstreamFilter EdgeFilter
+	upstreamEdges        []Edge
+	lastUpstreamFilter   EdgeFilter
 }
 
 func (m *mockEdgeRepo) Upsert(_ context.Context, _ *namespace.Namespace, e *Edge) error {
@@ -178,36 +290,38 @@ func (m *mockEdgeRepo) Upsert(_ context.Context, _ *namespace.Namespace, e *Edge
 	return nil
 }
 
-func (m *mockEdgeRepo) GetBySource(_ context.Context, _ *namespace.Namespace, urn string, _ EdgeFilter) ([]Edge, error) {
+func (m *mockEdgeRepo) GetBySource(_ context.Context, _ *namespace.Namespace, urn string, filter EdgeFilter) ([]Edge, error) {
 	var result []Edge
 	for _, e := range m.edges {
-		if e.SourceURN == urn {
+		if e.SourceURN == urn && (len(filter.Types) == 0 || slices.Contains(filter.Types, e.Type)) {
 			result = append(result, e)
 		}
 	}
 	return result, nil
 }
 
-func (m *mockEdgeRepo) GetByTarget(_ context.Context, _ *namespace.Namespace, urn string, _ EdgeFilter) ([]Edge, error) {
+func (m *mockEdgeRepo) GetByTarget(_ context.Context, _ *namespace.Namespace, urn string, filter EdgeFilter) ([]Edge, error) {
 	var result []Edge
 	for _, e := range m.edges {
-		if e.TargetURN == urn {
+		if e.TargetURN == urn && (len(filter.Types) == 0 || slices.Contains(filter.Types, e.Type)) {
 			result = append(result, e)
 		}
 	}
 	return result, nil
 }
 
-func (m *mockEdgeRepo) GetDownstream(_ context.Context, _ *namespace.Namespace, _ string, depth int) ([]Edge, error) {
+func (m *mockEdgeRepo) GetDownstream(_ context.Context, _ *namespace.Namespace, _ string, depth int, filter EdgeFilter) ([]Edge, error) {
 	m.lastDownstreamDepth = depth
+	m.lastDownstreamFilter = filter
 	return m.downstreamEdges, nil
 }
 
-func (m *mockEdgeRepo) GetUpstream(_ context.Context, _ *namespace.Namespace, _ string, _ int) ([]Edge, error) {
-	return nil, nil
+func (m *mockEdgeRepo) GetUpstream(_ context.Context, _ *namespace.Namespace, _ string, _ int, filter EdgeFilter) ([]Edge, error) {
+	m.lastUpstreamFilter = filter
+	return m.upstreamEdges, nil
 }
 
-func (m *mockEdgeRepo) GetBidirectional(_ context.Context, _ *namespace.Namespace, urn string, depth int) ([]Edge, error) {
+func (m *mockEdgeRepo) GetBidirectional(_ context.Context, _ *namespace.Namespace, urn string, depth int, _ EdgeFilter) ([]Edge, error) {
 	// BFS traversal up to depth hops in both directions.
 	type frontier struct {
 		urn   string
@@ -455,6 +569,86 @@ func TestService_GetImpact(t *testing.T) {
 	}
 }
 
+func TestService_GetImpactAsOf(t *testing.T) {
+	edges := &mockEdgeRepo{}
+	svc := NewService(newMockRepo(), edges, nil)
+	asOf := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, err := svc.GetImpactAsOf(context.Background(), namespace.DefaultNamespace, "urn:a", 2, asOf); err != nil {
+		t.Fatalf("GetImpactAsOf failed: %v", err)
+	}
+	if edges.lastDownstreamFilter.AsOf == nil || !edges.lastDownstreamFilter.AsOf.Equal(asOf) {
+		t.Errorf("expected as_of %v to reach the edge repository, got %v", asOf, edges.lastDownstreamFilter.AsOf)
+	}
+
+	if _, err := svc.GetImpact(context.Background(), namespace.DefaultNamespace, "urn:a", 2); err != nil {
+		t.Fatalf("GetImpact failed: %v", err)
+	}
+	if edges.lastDownstreamFilter.AsOf != nil {
+		t.Errorf("expected current-state traversal without as_of, got %v", edges.lastDownstreamFilter.AsOf)
+	}
+}
+
+func TestService_GetImpactGraph(t *testing.T) {
+	repo := newMockRepo()
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+	for _, e := range []Entity{
+		{URN: "urn:a", Type: TypeTable, Name: "a"},
+		{URN: "urn:b", Type: TypeTable, Name: "b"},
+	} {
+		e := e
+		repo.Upsert(ctx, ns, &e)
+	}
+	edges := &mockEdgeRepo{
+		downstreamEdges: []Edge{
+			{SourceURN: "urn:a", TargetURN: "urn:b", Type: "derived_from"},
+			{SourceURN: "urn:b", TargetURN: "urn:gone", Type: "derived_from"},
+		},
+	}
+	svc := NewService(repo, edges, nil)
+
+	cg, err := svc.GetImpactGraph(ctx, ns, "urn:a", 0)
+	if err != nil {
+		t.Fatalf("GetImpactGraph failed: %v", err)
+	}
+	if cg.Entity.URN != "urn:a" || len(cg.Edges) != 2 {
+		t.Errorf("unexpected impact graph %+v", cg)
+	}
+	if len(cg.Related) != 1 || cg.Related[0].URN != "urn:b" {
+		t.Errorf("expected only found entities as related, got %+v", cg.Related)
+	}
+
+	if _, err := svc.GetImpactGraph(ctx, ns, "urn:missing", 0); err == nil {
+		t.Error("expected error for missing root entity")
+	}
+}
+
+func TestService_GetContextAsOf(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, &mockEdgeRepo{}, nil)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	_, _ = svc.Upsert(ctx, ns, &Entity{URN: "urn:table:orders", Type: TypeTable, Name: "orders_v1"})
+	between := time.Now()
+	time.Sleep(time.Millisecond)
+	_, _ = svc.Upsert(ctx, ns, &Entity{URN: "urn:table:orders", Type: TypeTable, Name: "orders_v2"})
+
+	cg, err := svc.GetContextAsOf(ctx, ns, "urn:table:orders", 1, between)
+	if err != nil {
+		t.Fatalf("GetContextAsOf failed: %v", err)
+	}
+	if cg.Entity.Name != "orders_v1" {
+		t.Errorf("expected version live at as_of (orders_v1), got %q", cg.Entity.Name)
+	}
+
+	_, err = svc.GetContextAsOf(ctx, ns, "urn:table:orders", 1, between.Add(-time.Hour))
+	if err == nil {
+		t.Error("expected error for as_of before the entity existed")
+	}
+}
+
 func TestService_GetImpact_NilEdges(t *testing.T) {
 	svc := NewService(newMockRepo(), nil, nil)
 	ctx := context.Background()
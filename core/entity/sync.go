@@ -0,0 +1,175 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/raystack/compass/core/change"
+	"github.com/raystack/compass/core/namespace"
+)
+
+// ErrSyncIncomplete is returned when a snapshot had failed records. Nothing is
+// pruned then, since a record that failed to write would look deleted.
+var ErrSyncIncomplete = errors.New("snapshot has failed records; nothing was deleted")
+
+// PruneResult counts what a prune soft-deleted.
+type PruneResult struct {
+	Entities int
+	Edges    int
+}
+
+// SyncCounts tallies the records of one kind in a sync.
+type SyncCounts struct {
+	Created   int `json:"created"`
+	Updated   int `json:"updated"`
+	Unchanged int `json:"unchanged"`
+	Deleted   int `json:"deleted"`
+	Failed    int `json:"failed"`
+}
+
+func (c *SyncCounts) add(status UpsertStatus) {
+	switch status {
+	case UpsertCreated:
+		c.Created++
+	case UpsertUpdated:
+		c.Updated++
+	case UpsertUnchanged:
+		c.Unchanged++
+	default:
+		c.Failed++
+	}
+}
+
+// SyncSummary reports the changes a sync made.
+type SyncSummary struct {
+	Source   string     `json:"source"`
+	Scope    string     `json:"scope"`
+	Entities SyncCounts `json:"entities"`
+	Edges    SyncCounts `json:"edges"`
+	// Invalid counts records holding neither an entity nor an edge.
+	Invalid int `json:"invalid"`
+}
+
+// Sync reconciles a complete snapshot of one source and scope. Records are
+// written as they arrive; Finish then soft-deletes the current entities and
+// edges of that source and scope the snapshot did not contain.
+type Sync struct {
+	service  *Service
+	ns       *namespace.Namespace
+	entities map[string]bool
+	edges    map[string]bool
+	offset   int
+	summary  SyncSummary
+}
+
+// StartSync begins reconciling a snapshot of source within scope. An empty
+// scope covers the records of the source written without one.
+func (s *Service) StartSync(ns *namespace.Namespace, source, scope string) (*Sync, error) {
+	if source == "" {
+		return nil, errors.New("source is required")
+	}
+	if s.bulk == nil {
+		return nil, errors.New("bulk writes are not enabled")
+	}
+	return &Sync{
+		service:  s,
+		ns:       ns,
+		entities: map[string]bool{},
+		edges:    map[string]bool{},
+		summary:  SyncSummary{Source: source, Scope: scope},
+	}, nil
+}
+
+// Write upserts the next records of the snapshot. Records take the sync's
+// scope, and its source unless they name another one, which fails them.
+func (y *Sync) Write(ctx context.Context, records []BulkRecord) []BulkResult {
+	source, scope := y.summary.Source, y.summary.Scope
+	foreign := map[int]string{}
+	for i, rec := range records {
+		switch {
+		case rec.Entity != nil && rec.Edge == nil:
+			if rec.Entity.Source == "" {
+				rec.Entity.Source = source
+			}
+			rec.Entity.Scope = scope
+			if rec.Entity.Source != source {
+				foreign[i] = rec.Entity.Source
+			}
+		case rec.Edge != nil && rec.Entity == nil:
+			if rec.Edge.Source == "" {
+				rec.Edge.Source = source
+			}
+			rec.Edge.Scope = scope
+			if rec.Edge.Source != source {
+				foreign[i] = rec.Edge.Source
+			}
+		}
+	}
+	write := records
+	if len(foreign) > 0 {
+		write = make([]BulkRecord, len(records))
+		copy(write, records)
+		for i := range foreign {
+			write[i] = BulkRecord{}
+		}
+	}
+
+	results := y.service.BulkUpsert(ctx, y.ns, y.offset, write)
+	y.offset += len(records)
+	for i := range results {
+		res := &results[i]
+		switch rec := records[i]; {
+		case rec.Entity != nil:
+			res.Kind, res.Key = "entity", rec.Entity.URN
+			y.entities[rec.Entity.URN] = true
+			y.keepColumns(*rec.Entity)
+		case rec.Edge != nil:
+			res.Kind, res.Key = "edge", change.EdgeKey(rec.Edge.SourceURN, rec.Edge.Type, rec.Edge.TargetURN)
+			y.edges[res.Key] = true
+		}
+		if src, ok := foreign[i]; ok {
+			res.Error = fmt.Sprintf("record source %q does not match sync source %q", src, source)
+		}
+		switch res.Kind {
+		case "entity":
+			y.summary.Entities.add(res.Status)
+		case "edge":
+			y.summary.Edges.add(res.Status)
+		default:
+			y.summary.Invalid++
+		}
+	}
+	return results
+}
+
+// keepColumns keeps the column entities and has_column edges derived from
+// ent, which the snapshot holds implicitly.
+func (y *Sync) keepColumns(ent Entity) {
+	if !y.service.expandsColumns(ent.Type) {
+		return
+	}
+	columns, edges := ColumnEntities(ent)
+	for _, c := range columns {
+		y.entities[c.URN] = true
+	}
+	for _, e := range edges {
+		y.edges[change.EdgeKey(e.SourceURN, e.Type, e.TargetURN)] = true
+	}
+}
+
+// Finish prunes what the snapshot did not contain and returns the summary.
+// It returns ErrSyncIncomplete, with the summary of what was written, when
+// any record failed.
+func (y *Sync) Finish(ctx context.Context) (SyncSummary, error) {
+	if y.summary.Entities.Failed > 0 || y.summary.Edges.Failed > 0 || y.summary.Invalid > 0 {
+		return y.summary, ErrSyncIncomplete
+	}
+	pruned, err := y.service.bulk.Prune(ctx, y.ns, y.summary.Source, y.summary.Scope, y.entities, y.edges)
+	if err != nil {
+		return y.summary, fmt.Errorf("prune snapshot: %w", err)
+	}
+	y.summary.Entities.Deleted = pruned.Entities
+	y.summary.Edges.Deleted = pruned.Edges
+	return y.summary, nil
+}
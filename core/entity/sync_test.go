@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+func TestService_Sync(t *testing.T) {
+	bulk := &mockBulkRepo{existing: map[string]Entity{"urn:b": {URN: "urn:b"}}}
+	svc := NewService(newMockRepo(), nil, nil)
+	svc.WithBulkRepository(bulk)
+	ctx := context.Background()
+
+	if _, err := svc.StartSync(namespace.DefaultNamespace, "", "project-x"); err == nil {
+		t.Fatal("expected error without a source")
+	}
+
+	s, err := svc.StartSync(namespace.DefaultNamespace, "bigquery", "project-x")
+	if err != nil {
+		t.Fatalf("StartSync failed: %v", err)
+	}
+	a := &Entity{URN: "urn:a", Type: TypeTable, Name: "a"}
+	edge := &Edge{SourceURN: "urn:a", TargetURN: "urn:b", Type: "lineage"}
+	results := s.Write(ctx, []BulkRecord{{Entity: a}, {Edge: edge}})
+	results = append(results, s.Write(ctx, []BulkRecord{{Entity: &Entity{URN: "urn:b", Type: TypeTable, Name: "b"}}})...)
+
+	if a.Source != "bigquery" || a.Scope != "project-x" || edge.Source != "bigquery" || edge.Scope != "project-x" {
+		t.Errorf("expected records stamped with source and scope, got %+v and %+v", a, edge)
+	}
+	if results[2].Index != 2 {
+		t.Errorf("expected indexes to continue across writes, got %d", results[2].Index)
+	}
+
+	summary, err := s.Finish(ctx)
+	if err != nil {
+		t.Fatalf("Finish failed: %v", err)
+	}
+	want := SyncSummary{
+		Source:   "bigquery",
+		Scope:    "project-x",
+		Entities: SyncCounts{Created: 1, Updated: 1, Deleted: 1},
+		Edges:    SyncCounts{Unchanged: 1, Deleted: 1},
+	}
+	if summary != want {
+		t.Errorf("expected %+v, got %+v", want, summary)
+	}
+	if len(bulk.pruned) != 1 || bulk.pruned[0] != "bigquery/project-x" {
+		t.Errorf("expected one prune of bigquery/project-x, got %v", bulk.pruned)
+	}
+}
+
+func TestService_Sync_FailedRecordsSkipPrune(t *testing.T) {
+	bulk := &mockBulkRepo{}
+	svc := NewService(newMockRepo(), nil, nil)
+	svc.WithBulkRepository(bulk)
+	ctx := context.Background()
+
+	s, _ := svc.StartSync(namespace.DefaultNamespace, "bigquery", "")
+	results := s.Write(ctx, []BulkRecord{
+		{Entity: &Entity{URN: "urn:a", Type: TypeTable, Name: "a"}},
+		{Entity: &Entity{URN: "urn:d", Type: TypeDashboard, Name: "d", Source: "metabase"}},
+	})
+	if results[1].Status != UpsertFailed || results[1].Kind != "entity" || results[1].Key != "urn:d" {
+		t.Errorf("expected record of another source to fail, got %+v", results[1])
+	}
+
+	summary, err := s.Finish(ctx)
+	if !errors.Is(err, ErrSyncIncomplete) {
+		t.Fatalf("expected ErrSyncIncomplete, got %v", err)
+	}
+	if summary.Entities.Created != 1 || summary.Entities.Failed != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if len(bulk.pruned) != 0 {
+		t.Error("expected nothing pruned")
+	}
+}
@@ -0,0 +1,290 @@
+package entity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/raystack/compass/core/namespace"
+)
+
+var (
+	ErrTypeNotFound = errors.New("entity type not registered")
+	// ErrInvalidTypeDefinition is returned when a type registration fails
+	// validation.
+	ErrInvalidTypeDefinition = errors.New("invalid entity type definition")
+	// ErrTypeViolation is returned for an entity that does not match the
+	// definition of its type, when the type rejects such entities.
+	ErrTypeViolation = errors.New("entity does not match its type definition")
+)
+
+// TypeMode is what writing an entity that does not match its type definition
+// does.
+type TypeMode string
+
+const (
+	// TypeModeReject fails the write.
+	TypeModeReject TypeMode = "reject"
+	// TypeModeWarn writes the entity and logs the mismatch.
+	TypeModeWarn TypeMode = "warn"
+)
+
+// TypeDefinition describes the entities of one type in a namespace. Schema
+// is a JSON Schema the entity properties must match; Required names the
+// properties that must be set, which is a shorthand for the common case of
+// a schema with only required keys.
+type TypeDefinition struct {
+	Type        Type                   `json:"type"`
+	DisplayName string                 `json:"display_name,omitempty"`
+	Icon        string                 `json:"icon,omitempty"`
+	Schema      map[string]interface{} `json:"schema,omitempty"`
+	Required    []string               `json:"required,omitempty"`
+	Mode        TypeMode               `json:"mode"`
+	CreatedAt   time.Time              `json:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at"`
+}
+
+// TypeSummary is an entity type with the number of current entities of that
+// type and, when the type is registered, its definition.
+type TypeSummary struct {
+	Name       Type            `json:"name"`
+	Count      int             `json:"count"`
+	Definition *TypeDefinition `json:"definition,omitempty"`
+}
+
+// TypeRepository defines storage operations for entity type definitions and
+// edge type declarations.
+type TypeRepository interface {
+	UpsertType(ctx context.Context, ns *namespace.Namespace, def *TypeDefinition) error
+	GetType(ctx context.Context, ns *namespace.Namespace, t Type) (TypeDefinition, error)
+	ListTypes(ctx context.Context, ns *namespace.Namespace) ([]TypeDefinition, error)
+	DeleteType(ctx context.Context, ns *namespace.Namespace, t Type) error
+	UpsertEdgeType(ctx context.Context, ns *namespace.Namespace, def *EdgeTypeDefinition) error
+	GetEdgeType(ctx context.Context, ns *namespace.Namespace, edgeType string) (EdgeTypeDefinition, error)
+	ListEdgeTypes(ctx context.Context, ns *namespace.Namespace) ([]EdgeTypeDefinition, error)
+	DeleteEdgeType(ctx context.Context, ns *namespace.Namespace, edgeType string) error
+}
+
+// compile checks the definition and returns the resolved schema of its
+// properties, or nil when it has none.
+func (d TypeDefinition) compile() (*jsonschema.Resolved, error) {
+	if !d.Type.IsValid() {
+		return nil, fmt.Errorf("%w: type is required", ErrInvalidTypeDefinition)
+	}
+	switch d.Mode {
+	case TypeModeReject, TypeModeWarn:
+	default:
+		return nil, fmt.Errorf("%w: mode must be %s or %s", ErrInvalidTypeDefinition, TypeModeReject, TypeModeWarn)
+	}
+	for _, key := range d.Required {
+		if key == "" {
+			return nil, fmt.Errorf("%w: required property names must not be empty", ErrInvalidTypeDefinition)
+		}
+	}
+	if len(d.Schema) == 0 {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(d.Schema)
+	if err != nil {
+		return nil, fmt.Errorf("%w: schema: %s", ErrInvalidTypeDefinition, err)
+	}
+	var schema jsonschema.Schema
+	if err := json.Unmarshal(raw, &schema); err != nil {
+		return nil, fmt.Errorf("%w: schema: %s", ErrInvalidTypeDefinition, err)
+	}
+	resolved, err := schema.Resolve(nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: schema: %s", ErrInvalidTypeDefinition, err)
+	}
+	return resolved, nil
+}
+
+// Check reports how ent departs from the definition: required properties
+// that are missing or empty, and the first mismatch with the schema.
+func (d TypeDefinition) Check(ent Entity) ([]string, error) {
+	resolved, err := d.compile()
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for _, key := range d.Required {
+		if isEmptyProperty(ent.Properties[key]) {
+			problems = append(problems, fmt.Sprintf("property %q is required", key))
+		}
+	}
+	if resolved != nil {
+		props := ent.Properties
+		if props == nil {
+			props = map[string]interface{}{}
+		}
+		if err := resolved.Validate(normalizeJSON(props)); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems, nil
+}
+
+func isEmptyProperty(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	}
+	return false
+}
+
+// normalizeJSON round-trips properties through JSON, so values built in Go,
+// such as ints or typed slices, are validated as the JSON they are stored as.
+func normalizeJSON(props map[string]interface{}) interface{} {
+	raw, err := json.Marshal(props)
+	if err != nil {
+		return props
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return props
+	}
+	return v
+}
+
+// RegisterType creates or replaces the definition of an entity type. The
+// mode defaults to reject.
+func (s *Service) RegisterType(ctx context.Context, ns *namespace.Namespace, def *TypeDefinition) error {
+	if s.types == nil {
+		return errors.New("type registry is not enabled")
+	}
+	if def.Mode == "" {
+		def.Mode = TypeModeReject
+	}
+	if _, err := def.compile(); err != nil {
+		return err
+	}
+	if err := s.types.UpsertType(ctx, ns, def); err != nil {
+		return fmt.Errorf("register type %s: %w", def.Type, err)
+	}
+	return nil
+}
+
+// GetType returns the definition of a registered entity type.
+func (s *Service) GetType(ctx context.Context, ns *namespace.Namespace, t Type) (TypeDefinition, error) {
+	if s.types == nil {
+		return TypeDefinition{}, ErrTypeNotFound
+	}
+	return s.types.GetType(ctx, ns, t)
+}
+
+// ListTypes returns the registered entity type definitions.
+func (s *Service) ListTypes(ctx context.Context, ns *namespace.Namespace) ([]TypeDefinition, error) {
+	if s.types == nil {
+		return nil, nil
+	}
+	return s.types.ListTypes(ctx, ns)
+}
+
+// DeleteType removes the definition of an entity type. Entities of the type
+// are kept and no longer validated.
+func (s *Service) DeleteType(ctx context.Context, ns *namespace.Namespace, t Type) error {
+	if s.types == nil {
+		return ErrTypeNotFound
+	}
+	return s.types.DeleteType(ctx, ns, t)
+}
+
+// GetEntityTypes returns every type that has current entities or is
+// registered, with its entity count and definition, sorted by name.
+func (s *Service) GetEntityTypes(ctx context.Context, ns *namespace.Namespace) ([]TypeSummary, error) {
+	counts, err := s.repo.GetTypes(ctx, ns)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := s.ListTypes(ctx, ns)
+	if err != nil {
+		return nil, fmt.Errorf("list type definitions: %w", err)
+	}
+
+	byName := make(map[Type]*TypeSummary, len(counts)+len(defs))
+	for t, n := range counts {
+		byName[t] = &TypeSummary{Name: t, Count: n}
+	}
+	for i := range defs {
+		sum, ok := byName[defs[i].Type]
+		if !ok {
+			sum = &TypeSummary{Name: defs[i].Type}
+			byName[defs[i].Type] = sum
+		}
+		sum.Definition = &defs[i]
+	}
+
+	result := make([]TypeSummary, 0, len(byName))
+	for _, sum := range byName {
+		result = append(result, *sum)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// CheckType validates ent against the definition of its type, if the type is
+// registered, after merging its properties with those other sources wrote. A
+// mismatch is returned as ErrTypeViolation when the type
+// rejects such entities; otherwise it is logged and returned as a warning.
+func (s *Service) CheckType(ctx context.Context, ns *namespace.Namespace, ent Entity) (string, error) {
+	return s.newTypeChecker(ns).check(ctx, ent)
+}
+
+func checkDefinition(ctx context.Context, def TypeDefinition, ent Entity) (string, error) {
+	problems, err := def.Check(ent)
+	if err != nil {
+		return "", err
+	}
+	if len(problems) == 0 {
+		return "", nil
+	}
+	msg := strings.Join(problems, "; ")
+	if def.Mode == TypeModeWarn {
+		slog.WarnContext(ctx, "entity does not match its type definition", "urn", ent.URN, "type", ent.Type, "problems", msg)
+		return msg, nil
+	}
+	return "", fmt.Errorf("%w: %s: %s", ErrTypeViolation, ent.URN, msg)
+}
+
+// typeChecker validates the entities of one bulk write, looking up each type
+// definition once.
+type typeChecker struct {
+	s    *Service
+	ns   *namespace.Namespace
+	defs map[Type]*TypeDefinition
+}
+
+func (s *Service) newTypeChecker(ns *namespace.Namespace) *typeChecker {
+	return &typeChecker{s: s, ns: ns, defs: map[Type]*TypeDefinition{}}
+}
+
+func (c *typeChecker) check(ctx context.Context, ent Entity) (string, error) {
+	if c.s.types == nil {
+		return "", nil
+	}
+	def, ok := c.defs[ent.Type]
+	if !ok {
+		d, err := c.s.types.GetType(ctx, c.ns, ent.Type)
+		switch {
+		case err == nil:
+			def = &d
+		case !errors.Is(err, ErrTypeNotFound):
+			return "", fmt.Errorf("get type %s: %w", ent.Type, err)
+		}
+		c.defs[ent.Type] = def
+	}
+	if def == nil {
+		return "", nil
+	}
+	ent = withMergedProperties(c.ns, ent, c.s.currentVersion(ctx, c.ns, ent.URN))
+	return checkDefinition(ctx, *def, ent)
+}
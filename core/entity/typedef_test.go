@@ -0,0 +1,254 @@
+package entity
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+// mockTypeRepo is an in-memory type registry that counts entity type
+// lookups.
+type mockTypeRepo struct {
+	defs      map[Type]TypeDefinition
+	edgeTypes map[string]EdgeTypeDefinition
+	lookups   int
+}
+
+func newMockTypeRepo(defs ...TypeDefinition) *mockTypeRepo {
+	m := &mockTypeRepo{defs: map[Type]TypeDefinition{}, edgeTypes: map[string]EdgeTypeDefinition{}}
+	for _, d := range defs {
+		m.defs[d.Type] = d
+	}
+	return m
+}
+
+func (m *mockTypeRepo) UpsertType(_ context.Context, _ *namespace.Namespace, def *TypeDefinition) error {
+	m.defs[def.Type] = *def
+	return nil
+}
+
+func (m *mockTypeRepo) GetType(_ context.Context, _ *namespace.Namespace, t Type) (TypeDefinition, error) {
+	m.lookups++
+	if d, ok := m.defs[t]; ok {
+		return d, nil
+	}
+	return TypeDefinition{}, ErrTypeNotFound
+}
+
+func (m *mockTypeRepo) ListTypes(_ context.Context, _ *namespace.Namespace) ([]TypeDefinition, error) {
+	var defs []TypeDefinition
+	for _, d := range m.defs {
+		defs = append(defs, d)
+	}
+	return defs, nil
+}
+
+func (m *mockTypeRepo) DeleteType(_ context.Context, _ *namespace.Namespace, t Type) error {
+	if _, ok := m.defs[t]; !ok {
+		return ErrTypeNotFound
+	}
+	delete(m.defs, t)
+	return nil
+}
+
+func (m *mockTypeRepo) UpsertEdgeType(_ context.Context, _ *namespace.Namespace, def *EdgeTypeDefinition) error {
+	m.edgeTypes[def.Type] = *def
+	return nil
+}
+
+func (m *mockTypeRepo) GetEdgeType(_ context.Context, _ *namespace.Namespace, edgeType string) (EdgeTypeDefinition, error) {
+	if d, ok := m.edgeTypes[edgeType]; ok {
+		return d, nil
+	}
+	return EdgeTypeDefinition{}, ErrEdgeTypeNotFound
+}
+
+func (m *mockTypeRepo) ListEdgeTypes(_ context.Context, _ *namespace.Namespace) ([]EdgeTypeDefinition, error) {
+	var defs []EdgeTypeDefinition
+	for _, d := range m.edgeTypes {
+		defs = append(defs, d)
+	}
+	return defs, nil
+}
+
+func (m *mockTypeRepo) DeleteEdgeType(_ context.Context, _ *namespace.Namespace, edgeType string) error {
+	if _, ok := m.edgeTypes[edgeType]; !ok {
+		return ErrEdgeTypeNotFound
+	}
+	delete(m.edgeTypes, edgeType)
+	return nil
+}
+
+var tableDefinition = TypeDefinition{
+	Type:        TypeTable,
+	DisplayName: "Table",
+	Schema: map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"row_count": map[string]interface{}{"type": "integer", "minimum": 0},
+			"tier":      map[string]interface{}{"enum": []interface{}{"gold", "silver", "bronze"}},
+		},
+	},
+	Required: []string{"owner"},
+	Mode:     TypeModeReject,
+}
+
+func TestTypeDefinition_Check(t *testing.T) {
+	tests := []struct {
+		name     string
+		props    map[string]interface{}
+		problems int
+	}{
+		{"valid", map[string]interface{}{"owner": "data-eng", "row_count": 10, "tier": "gold"}, 0},
+		{"missing required", map[string]interface{}{"row_count": 10}, 1},
+		{"empty required", map[string]interface{}{"owner": " "}, 1},
+		{"schema mismatch", map[string]interface{}{"owner": "data-eng", "row_count": -1}, 1},
+		{"both", map[string]interface{}{"tier": "platinum"}, 2},
+		{"no properties", nil, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problems, err := tableDefinition.Check(Entity{URN: "urn:t", Type: TypeTable, Properties: tt.props})
+			if err != nil {
+				t.Fatalf("Check failed: %v", err)
+			}
+			if len(problems) != tt.problems {
+				t.Errorf("expected %d problems, got %v", tt.problems, problems)
+			}
+		})
+	}
+}
+
+func TestService_RegisterType(t *testing.T) {
+	svc := NewService(newMockRepo(), nil, nil)
+	types := newMockTypeRepo()
+	svc.WithTypeRegistry(types)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	def := &TypeDefinition{Type: TypeTable, Required: []string{"owner"}}
+	if err := svc.RegisterType(ctx, ns, def); err != nil {
+		t.Fatalf("RegisterType failed: %v", err)
+	}
+	if types.defs[TypeTable].Mode != TypeModeReject {
+		t.Errorf("expected mode to default to reject, got %q", types.defs[TypeTable].Mode)
+	}
+
+	invalid := []*TypeDefinition{
+		{Required: []string{"owner"}},
+		{Type: TypeTable, Mode: "ignore"},
+		{Type: TypeTable, Schema: map[string]interface{}{"type": 12}},
+	}
+	for _, d := range invalid {
+		if err := svc.RegisterType(ctx, ns, d); !errors.Is(err, ErrInvalidTypeDefinition) {
+			t.Errorf("expected ErrInvalidTypeDefinition for %+v, got %v", d, err)
+		}
+	}
+}
+
+func TestService_Upsert_TypeViolation(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, nil, nil)
+	svc.WithTypeRegistry(newMockTypeRepo(tableDefinition))
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	_, err := svc.Upsert(ctx, ns, &Entity{URN: "urn:t", Type: TypeTable, Name: "t"})
+	if !errors.Is(err, ErrTypeViolation) {
+		t.Fatalf("expected ErrTypeViolation, got %v", err)
+	}
+	if _, ok := repo.entities["urn:t"]; ok {
+		t.Error("expected rejected entity not to be written")
+	}
+
+	if _, err := svc.Upsert(ctx, ns, &Entity{URN: "urn:t", Type: TypeTable, Name: "t", Properties: map[string]interface{}{"owner": "data-eng"}}); err != nil {
+		t.Fatalf("expected valid entity to be written, got %v", err)
+	}
+	if _, err := svc.Upsert(ctx, ns, &Entity{URN: "urn:j", Type: TypeJob, Name: "j"}); err != nil {
+		t.Fatalf("expected entity of unregistered type to be written, got %v", err)
+	}
+}
+
+func TestService_Upsert_TypeWarning(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, nil, nil)
+	def := tableDefinition
+	def.Mode = TypeModeWarn
+	svc.WithTypeRegistry(newMockTypeRepo(def))
+
+	if _, err := svc.Upsert(context.Background(), namespace.DefaultNamespace, &Entity{URN: "urn:t", Type: TypeTable, Name: "t"}); err != nil {
+		t.Fatalf("expected entity to be written with a warning, got %v", err)
+	}
+	if _, ok := repo.entities["urn:t"]; !ok {
+		t.Error("expected entity to be written")
+	}
+}
+
+func TestService_BulkUpsert_TypeDefinitions(t *testing.T) {
+	bulk := &mockBulkRepo{}
+	svc := NewService(newMockRepo(), nil, nil)
+	svc.WithBulkRepository(bulk)
+	warn := TypeDefinition{Type: TypeJob, Required: []string{"schedule"}, Mode: TypeModeWarn}
+	types := newMockTypeRepo(tableDefinition, warn)
+	svc.WithTypeRegistry(types)
+
+	records := []BulkRecord{
+		{Entity: &Entity{URN: "urn:a", Type: TypeTable, Name: "a"}},
+		{Entity: &Entity{URN: "urn:b", Type: TypeTable, Name: "b", Properties: map[string]interface{}{"owner": "x"}}},
+		{Entity: &Entity{URN: "urn:c", Type: TypeJob, Name: "c"}},
+	}
+	results := svc.BulkUpsert(context.Background(), namespace.DefaultNamespace, 0, records)
+
+	if results[0].Status != UpsertFailed || results[0].Error == "" {
+		t.Errorf("expected rejected entity to fail, got %+v", results[0])
+	}
+	if results[1].Status != UpsertCreated || results[1].Warning != "" {
+		t.Errorf("expected valid entity to be created, got %+v", results[1])
+	}
+	if results[2].Status != UpsertCreated || results[2].Warning == "" {
+		t.Errorf("expected entity to be created with a warning, got %+v", results[2])
+	}
+	if types.lookups != 2 {
+		t.Errorf("expected one lookup per type, got %d", types.lookups)
+	}
+}
+
+func TestService_GetEntityTypes(t *testing.T) {
+	repo := newMockRepo()
+	svc := NewService(repo, nil, nil)
+	svc.WithTypeRegistry(newMockTypeRepo(tableDefinition, TypeDefinition{Type: TypeDashboard, DisplayName: "Dashboard", Mode: TypeModeWarn}))
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	_, _ = repo.Upsert(ctx, ns, &Entity{URN: "urn:a", Type: TypeTable, Name: "a"})
+	_, _ = repo.Upsert(ctx, ns, &Entity{URN: "urn:b", Type: TypeTable, Name: "b"})
+	_, _ = repo.Upsert(ctx, ns, &Entity{URN: "urn:c", Type: TypeJob, Name: "c"})
+
+	types, err := svc.GetEntityTypes(ctx, ns)
+	if err != nil {
+		t.Fatalf("GetEntityTypes failed: %v", err)
+	}
+	if len(types) != 3 {
+		t.Fatalf("expected 3 types, got %+v", types)
+	}
+	want := []struct {
+		name       Type
+		count      int
+		registered bool
+	}{
+		{TypeDashboard, 0, true},
+		{TypeJob, 1, false},
+		{TypeTable, 2, true},
+	}
+	for i, w := range want {
+		got := types[i]
+		if got.Name != w.name || got.Count != w.count || (got.Definition != nil) != w.registered {
+			t.Errorf("type %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+	if types[2].Definition.DisplayName != "Table" {
+		t.Errorf("expected display name from definition, got %q", types[2].Definition.DisplayName)
+	}
+}
@@ -0,0 +1,69 @@
+package openlineage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrInvalidEvent is returned for events missing the fields needed to map
+// them onto the graph.
+var ErrInvalidEvent = errors.New("invalid openlineage event")
+
+// Event types of a run.
+const (
+	EventStart    = "START"
+	EventRunning  = "RUNNING"
+	EventComplete = "COMPLETE"
+	EventAbort    = "ABORT"
+	EventFail     = "FAIL"
+	EventOther    = "OTHER"
+)
+
+// RunEvent is an OpenLineage run event, reduced to the fields Compass maps.
+// See https://openlineage.io/spec for the full schema.
+type RunEvent struct {
+	EventType string    `json:"eventType"`
+	EventTime time.Time `json:"eventTime"`
+	Run       Run       `json:"run"`
+	Job       Job       `json:"job"`
+	Inputs    []Dataset `json:"inputs"`
+	Outputs   []Dataset `json:"outputs"`
+	Producer  string    `json:"producer"`
+}
+
+// Run is one execution of a job.
+type Run struct {
+	RunID  string                 `json:"runId"`
+	Facets map[string]interface{} `json:"facets,omitempty"`
+}
+
+// Job is a process that consumes and produces datasets.
+type Job struct {
+	Namespace string                 `json:"namespace"`
+	Name      string                 `json:"name"`
+	Facets    map[string]interface{} `json:"facets,omitempty"`
+}
+
+// Dataset is a dataset read or written by a run. Namespace follows the
+// OpenLineage naming spec, e.g. "bigquery" or "kafka://broker:9092".
+type Dataset struct {
+	Namespace    string                 `json:"namespace"`
+	Name         string                 `json:"name"`
+	Facets       map[string]interface{} `json:"facets,omitempty"`
+	InputFacets  map[string]interface{} `json:"inputFacets,omitempty"`
+	OutputFacets map[string]interface{} `json:"outputFacets,omitempty"`
+}
+
+// Validate checks the fields the mapping relies on.
+func (e RunEvent) Validate() error {
+	if e.Job.Namespace == "" || e.Job.Name == "" {
+		return fmt.Errorf("%w: job namespace and name are required", ErrInvalidEvent)
+	}
+	for _, ds := range append(append([]Dataset{}, e.Inputs...), e.Outputs...) {
+		if ds.Namespace == "" || ds.Name == "" {
+			return fmt.Errorf("%w: dataset namespace and name are required", ErrInvalidEvent)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package openlineage
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/raystack/compass/core/entity"
+)
+
+// Edge types created from run events. Edges follow the data flow, input
+// dataset to job to output dataset, so downstream impact passes through the
+// jobs that move the data.
+const (
+	EdgeReads  = "reads"
+	EdgeWrites = "writes"
+)
+
+// PropertyKey holds the OpenLineage identity and facets of mapped entities.
+const PropertyKey = "openlineage"
+
+// Source is the source of the datasets events create, and of jobs whose
+// integration is not named.
+const Source = "openlineage"
+
+// volatileFacets are dataset facets that describe one run rather than the
+// dataset, such as its version or the rows a run wrote. Keeping them would
+// make every run a new version of the dataset.
+var volatileFacets = []string{
+	"version",
+	"lifecycleStateChange",
+	"outputStatistics",
+	"inputStatistics",
+	"dataQualityMetrics",
+	"dataQualityAssertions",
+}
+
+// streamSchemes are dataset namespace schemes whose datasets are topics.
+var streamSchemes = map[string]bool{
+	"kafka":   true,
+	"pulsar":  true,
+	"kinesis": true,
+	"pubsub":  true,
+}
+
+// JobURN is the URN of a job: urn:<integration>:<namespace>/<name>. The
+// integration comes from the jobType facet and defaults to "openlineage".
+func JobURN(job Job) string {
+	return "urn:" + jobIntegration(job) + ":" + job.Namespace + "/" + job.Name
+}
+
+// DatasetURN is the URN of a dataset: urn:<scheme>:<name> for namespaces
+// without an authority such as "bigquery", and urn:<scheme>:<authority>/<name>
+// for namespaces such as "postgres://db:5432".
+func DatasetURN(ds Dataset) string {
+	scheme, authority := splitNamespace(ds.Namespace)
+	if authority == "" {
+		return "urn:" + scheme + ":" + ds.Name
+	}
+	return "urn:" + scheme + ":" + authority + "/" + ds.Name
+}
+
+// MapJob maps the job of a run event to an entity. The run ID, event time
+// and run facets are left out, as they change on every run and would make
+// each one a new version of the job. Only the state a run ended in is kept,
+// under "run", so the job changes when a run fails after succeeding or the
+// other way round; events of runs still in progress carry no state.
+func MapJob(ev RunEvent) entity.Entity {
+	ol := map[string]interface{}{
+		"namespace": ev.Job.Namespace,
+		"name":      ev.Job.Name,
+	}
+	if facets := withoutFacets(ev.Job.Facets, "documentation"); len(facets) > 0 {
+		ol["facets"] = facets
+	}
+	props := map[string]interface{}{PropertyKey: ol}
+	if Terminal(ev.EventType) {
+		props["run"] = map[string]interface{}{"state": ev.EventType}
+	}
+	return entity.Entity{
+		URN:         JobURN(ev.Job),
+		Type:        entity.TypeJob,
+		Name:        ev.Job.Name,
+		Description: facetDescription(ev.Job.Facets),
+		Source:      jobIntegration(ev.Job),
+		Properties:  props,
+	}
+}
+
+// Terminal reports whether an event type ends a run.
+func Terminal(eventType string) bool {
+	switch eventType {
+	case EventComplete, EventFail, EventAbort:
+		return true
+	}
+	return false
+}
+
+// MapDataset maps a dataset to an entity of Source. The schema facet becomes
+// the "columns" property so that schema changes are detected as for any
+// table. Facets that describe a single run are dropped.
+func MapDataset(ds Dataset) entity.Entity {
+	scheme, _ := splitNamespace(ds.Namespace)
+	typ := entity.TypeTable
+	if streamSchemes[scheme] {
+		typ = entity.TypeTopic
+	}
+
+	props := map[string]interface{}{}
+	ol := map[string]interface{}{
+		"namespace": ds.Namespace,
+		"name":      ds.Name,
+	}
+	if facets := withoutFacets(ds.Facets, append([]string{"schema", "documentation"}, volatileFacets...)...); len(facets) > 0 {
+		ol["facets"] = facets
+	}
+	props[PropertyKey] = ol
+	if cols := schemaColumns(ds.Facets); cols != nil {
+		props["columns"] = cols
+	}
+
+	name := ds.Name
+	if i := strings.LastIndexAny(name, "./"); i >= 0 && i < len(name)-1 {
+		name = name[i+1:]
+	}
+	return entity.Entity{
+		URN:         DatasetURN(ds),
+		Type:        typ,
+		Name:        name,
+		Description: facetDescription(ds.Facets),
+		Source:      Source,
+		Properties:  props,
+	}
+}
+
+// MapEdges returns the reads and writes edges of a run event.
+func MapEdges(ev RunEvent) []entity.Edge {
+	job := JobURN(ev.Job)
+	source := jobIntegration(ev.Job)
+	var edges []entity.Edge
+	for _, ds := range ev.Inputs {
+		edges = append(edges, entity.Edge{SourceURN: DatasetURN(ds), TargetURN: job, Type: EdgeReads, Source: source})
+	}
+	for _, ds := range ev.Outputs {
+		edges = append(edges, entity.Edge{SourceURN: job, TargetURN: DatasetURN(ds), Type: EdgeWrites, Source: source})
+	}
+	return edges
+}
+
+func splitNamespace(ns string) (scheme, authority string) {
+	if i := strings.Index(ns, "://"); i >= 0 {
+		return strings.ToLower(ns[:i]), strings.TrimSuffix(ns[i+3:], "/")
+	}
+	return strings.ToLower(ns), ""
+}
+
+func jobIntegration(job Job) string {
+	if jt, ok := job.Facets["jobType"].(map[string]interface{}); ok {
+		if integration, ok := jt["integration"].(string); ok && integration != "" {
+			return strings.ToLower(integration)
+		}
+	}
+	return Source
+}
+
+func facetDescription(facets map[string]interface{}) string {
+	if doc, ok := facets["documentation"].(map[string]interface{}); ok {
+		desc, _ := doc["description"].(string)
+		return desc
+	}
+	return ""
+}
+
+// schemaColumns converts the fields of the schema facet to columns.
+func schemaColumns(facets map[string]interface{}) []interface{} {
+	schema, ok := facets["schema"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	fields, ok := schema["fields"].([]interface{})
+	if !ok {
+		return nil
+	}
+	cols := make([]interface{}, 0, len(fields))
+	for _, f := range fields {
+		field, ok := f.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		col := map[string]interface{}{"name": field["name"]}
+		for _, key := range []string{"type", "description"} {
+			if v, ok := field[key].(string); ok && v != "" {
+				col[key] = v
+			}
+		}
+		cols = append(cols, col)
+	}
+	return cols
+}
+
+func withoutFacets(facets map[string]interface{}, names ...string) map[string]interface{} {
+	drop := false
+	for _, name := range names {
+		if _, ok := facets[name]; ok {
+			drop = true
+			break
+		}
+	}
+	if !drop {
+		return facets
+	}
+	out := make(map[string]interface{}, len(facets))
+	for k, v := range facets {
+		if !slices.Contains(names, k) {
+			out[k] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,141 @@
+package openlineage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/raystack/compass/core/batch"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+)
+
+// EntityReader reads the current entities a run event maps to.
+type EntityReader interface {
+	GetByURN(ctx context.Context, ns *namespace.Namespace, urn string) (entity.Entity, error)
+}
+
+// Committer writes the entities and edges of a run event in one transaction.
+// Implemented by batch.Service.
+type Committer interface {
+	Commit(ctx context.Context, ns *namespace.Namespace, ops []batch.Operation) ([]batch.Result, error)
+}
+
+// Result is what an ingested run event was mapped to.
+type Result struct {
+	Job      entity.Entity   `json:"job"`
+	Datasets []entity.Entity `json:"datasets"`
+	Edges    []entity.Edge   `json:"edges"`
+}
+
+// Service maps OpenLineage run events onto the graph.
+type Service struct {
+	entities EntityReader
+	batches  Committer
+}
+
+func NewService(entities EntityReader, batches Committer) *Service {
+	return &Service{entities: entities, batches: batches}
+}
+
+// Ingest writes the job and datasets of a run event and the edges between
+// them, in one batch: either the whole event is written or none of it.
+// Datasets that already exist keep their name, description and source, which
+// usually come from a richer extractor; the event only adds its facets and
+// its columns, unless the entity has columns another source wrote. A job
+// keeps the state of its last finished run until another run finishes.
+func (s *Service) Ingest(ctx context.Context, ns *namespace.Namespace, ev RunEvent) (*Result, error) {
+	if err := ev.Validate(); err != nil {
+		return nil, err
+	}
+
+	job := MapJob(ev)
+	if !Terminal(ev.EventType) {
+		cur, err := s.entities.GetByURN(ctx, ns, job.URN)
+		switch {
+		case err == nil:
+			if run, ok := cur.Properties["run"]; ok {
+				job.Properties["run"] = run
+			}
+		case !errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("get job %s: %w", job.URN, err)
+		}
+	}
+	ops := []batch.Operation{{UpsertEntity: &job}}
+
+	var datasets []*entity.Entity
+	seen := map[string]bool{}
+	for _, ds := range append(append([]Dataset{}, ev.Inputs...), ev.Outputs...) {
+		ent := MapDataset(ds)
+		if seen[ent.URN] {
+			continue
+		}
+		seen[ent.URN] = true
+
+		cur, err := s.entities.GetByURN(ctx, ns, ent.URN)
+		switch {
+		case err == nil:
+			ent = mergeDataset(cur, ent)
+		case !errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("get dataset %s: %w", ent.URN, err)
+		}
+		datasets = append(datasets, &ent)
+		ops = append(ops, batch.Operation{UpsertEntity: &ent})
+	}
+
+	edges := MapEdges(ev)
+	for i := range edges {
+		ops = append(ops, batch.Operation{UpsertEdge: &edges[i]})
+	}
+
+	if _, err := s.batches.Commit(ctx, ns, ops); err != nil {
+		return nil, fmt.Errorf("write run event: %w", err)
+	}
+
+	res := &Result{Job: job, Edges: edges}
+	for _, ds := range datasets {
+		res.Datasets = append(res.Datasets, *ds)
+	}
+	return res, nil
+}
+
+// mergeDataset lays the event's view of a dataset over the current entity.
+func mergeDataset(cur, ev entity.Entity) entity.Entity {
+	props := make(map[string]interface{}, len(cur.Properties)+2)
+	for k, v := range cur.Properties {
+		props[k] = v
+	}
+	props[PropertyKey] = ev.Properties[PropertyKey]
+	if cols, ok := ev.Properties["columns"]; ok && columnsFromEvents(cur) {
+		props["columns"] = cols
+	}
+
+	merged := cur
+	merged.ID = ""
+	merged.PropertySources = nil
+	merged.Properties = props
+	merged.ChangedBy = ""
+	if merged.Description == "" {
+		merged.Description = ev.Description
+	}
+	return merged
+}
+
+// columnsFromEvents reports whether the columns of cur may be replaced by
+// those of an event: it has none, or it is a dataset events created and the
+// columns it has were written by events rather than by another source.
+func columnsFromEvents(cur entity.Entity) bool {
+	if _, ok := cur.Properties["columns"]; !ok {
+		return true
+	}
+	if cur.Source != Source {
+		return false
+	}
+	for src, values := range cur.PropertySources {
+		if v, ok := values["columns"]; ok && v.Applied && src != Source {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,285 @@
+package openlineage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/raystack/compass/core/batch"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+)
+
+type mockEntities struct {
+	byURN map[string]entity.Entity
+}
+
+func (m *mockEntities) GetByURN(_ context.Context, _ *namespace.Namespace, urn string) (entity.Entity, error) {
+	ent, ok := m.byURN[urn]
+	if !ok {
+		return entity.Entity{}, sql.ErrNoRows
+	}
+	return ent, nil
+}
+
+// mockBatches applies the operations of a batch to the mocks, or none of
+// them when err is set.
+type mockBatches struct {
+	entities *mockEntities
+	edges    []entity.Edge
+	commits  int
+	err      error
+}
+
+func (m *mockBatches) Commit(_ context.Context, _ *namespace.Namespace, ops []batch.Operation) ([]batch.Result, error) {
+	m.commits++
+	if m.err != nil {
+		return nil, m.err
+	}
+	results := make([]batch.Result, len(ops))
+	for i, op := range ops {
+		results[i] = batch.Result{Index: i, Action: op.Action()}
+		switch {
+		case op.UpsertEntity != nil:
+			op.UpsertEntity.ID = "id-" + op.UpsertEntity.URN
+			m.entities.byURN[op.UpsertEntity.URN] = *op.UpsertEntity
+		case op.UpsertEdge != nil:
+			m.edges = append(m.edges, *op.UpsertEdge)
+		}
+	}
+	return results, nil
+}
+
+func newTestService(byURN map[string]entity.Entity) (*Service, *mockEntities, *mockBatches) {
+	entities := &mockEntities{byURN: byURN}
+	batches := &mockBatches{entities: entities}
+	return NewService(entities, batches), entities, batches
+}
+
+const sampleEvent = `{
+  "eventType": "COMPLETE",
+  "eventTime": "2026-03-01T09:30:00Z",
+  "run": {"runId": "0176a8c2-fe01-7439-87e6-56a1a1b4029f"},
+  "job": {
+    "namespace": "analytics",
+    "name": "orders_daily",
+    "facets": {"jobType": {"integration": "AIRFLOW", "jobType": "TASK", "processingType": "BATCH"}}
+  },
+  "inputs": [
+    {"namespace": "postgres://db:5432", "name": "shop.public.orders"},
+    {"namespace": "kafka://broker:9092", "name": "order-events"}
+  ],
+  "outputs": [{
+    "namespace": "bigquery",
+    "name": "warehouse.analytics.orders",
+    "facets": {
+      "schema": {"fields": [{"name": "id", "type": "INT64"}, {"name": "total", "type": "NUMERIC", "description": "Order total"}]},
+      "documentation": {"description": "Daily orders"}
+    }
+  }],
+  "producer": "https://github.com/apache/airflow"
+}`
+
+func decodeEvent(t *testing.T) RunEvent {
+	t.Helper()
+	var ev RunEvent
+	if err := json.Unmarshal([]byte(sampleEvent), &ev); err != nil {
+		t.Fatal(err)
+	}
+	return ev
+}
+
+func TestDatasetURN(t *testing.T) {
+	tests := []struct {
+		ds   Dataset
+		want string
+	}{
+		{Dataset{Namespace: "bigquery", Name: "p.d.t"}, "urn:bigquery:p.d.t"},
+		{Dataset{Namespace: "postgres://db:5432", Name: "shop.public.orders"}, "urn:postgres:db:5432/shop.public.orders"},
+		{Dataset{Namespace: "Kafka://broker:9092/", Name: "events"}, "urn:kafka:broker:9092/events"},
+	}
+	for _, tt := range tests {
+		if got := DatasetURN(tt.ds); got != tt.want {
+			t.Errorf("DatasetURN(%+v) = %q, want %q", tt.ds, got, tt.want)
+		}
+	}
+}
+
+func TestService_Ingest(t *testing.T) {
+	ns := namespace.DefaultNamespace
+	svc, entities, batches := newTestService(map[string]entity.Entity{})
+
+	res, err := svc.Ingest(context.Background(), ns, decodeEvent(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job := entities.byURN["urn:airflow:analytics/orders_daily"]
+	if job.Type != entity.TypeJob || job.Source != "airflow" {
+		t.Errorf("unexpected job: %+v", job)
+	}
+	run, _ := job.Properties["run"].(map[string]interface{})
+	if run["state"] != EventComplete {
+		t.Errorf("expected run state %q, got %v", EventComplete, run["state"])
+	}
+
+	if got := entities.byURN["urn:kafka:broker:9092/order-events"].Type; got != entity.TypeTopic {
+		t.Errorf("expected kafka dataset to be a topic, got %q", got)
+	}
+	out := entities.byURN["urn:bigquery:warehouse.analytics.orders"]
+	if out.Type != entity.TypeTable || out.Name != "orders" || out.Description != "Daily orders" || out.Source != Source {
+		t.Errorf("unexpected output dataset: %+v", out)
+	}
+	cols, _ := out.Properties["columns"].([]interface{})
+	if len(cols) != 2 {
+		t.Fatalf("expected 2 columns, got %v", out.Properties["columns"])
+	}
+	if col := cols[1].(map[string]interface{}); col["name"] != "total" || col["description"] != "Order total" {
+		t.Errorf("unexpected column: %v", col)
+	}
+
+	if batches.commits != 1 {
+		t.Errorf("expected the event to be written in one batch, got %d", batches.commits)
+	}
+	if len(res.Datasets) != 3 || len(batches.edges) != 3 {
+		t.Fatalf("expected 3 datasets and 3 edges, got %d and %d", len(res.Datasets), len(batches.edges))
+	}
+	reads, writes := 0, 0
+	for _, e := range batches.edges {
+		switch e.Type {
+		case EdgeReads:
+			reads++
+			if e.TargetURN != job.URN {
+				t.Errorf("reads edge should point at the job: %+v", e)
+			}
+		case EdgeWrites:
+			writes++
+			if e.SourceURN != job.URN {
+				t.Errorf("writes edge should start at the job: %+v", e)
+			}
+		}
+	}
+	if reads != 2 || writes != 1 {
+		t.Errorf("expected 2 reads and 1 writes edges, got %d and %d", reads, writes)
+	}
+}
+
+func TestService_Ingest_KeepsExistingDataset(t *testing.T) {
+	ns := namespace.DefaultNamespace
+	urn := "urn:bigquery:warehouse.analytics.orders"
+	svc, entities, _ := newTestService(map[string]entity.Entity{
+		urn: {
+			ID: "v1", URN: urn, Type: entity.TypeTable, Name: "Orders", Source: "bigquery",
+			Description: "Curated orders",
+			Properties:  map[string]interface{}{"owner": "data-eng", "columns": []interface{}{map[string]interface{}{"name": "id"}}},
+		},
+	})
+
+	if _, err := svc.Ingest(context.Background(), ns, decodeEvent(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	got := entities.byURN[urn]
+	if got.Name != "Orders" || got.Description != "Curated orders" || got.Source != "bigquery" {
+		t.Errorf("existing fields should be kept: %+v", got)
+	}
+	if got.Properties["owner"] != "data-eng" {
+		t.Errorf("existing properties should be kept: %v", got.Properties)
+	}
+	if cols, _ := got.Properties["columns"].([]interface{}); len(cols) != 1 {
+		t.Errorf("existing columns should not be replaced: %v", got.Properties["columns"])
+	}
+	if _, ok := got.Properties[PropertyKey]; !ok {
+		t.Errorf("expected %q property to be added", PropertyKey)
+	}
+}
+
+func TestService_Ingest_UpdatesColumnsFromEvents(t *testing.T) {
+	ns := namespace.DefaultNamespace
+	urn := "urn:bigquery:warehouse.analytics.orders"
+	columns := []interface{}{map[string]interface{}{"name": "id"}}
+	svc, entities, _ := newTestService(map[string]entity.Entity{
+		urn: {
+			ID: "v1", URN: urn, Type: entity.TypeTable, Name: "orders", Source: Source,
+			Properties: map[string]interface{}{"columns": columns},
+			PropertySources: entity.PropertySources{
+				Source: {"columns": {Value: columns, Applied: true}},
+			},
+		},
+	})
+
+	if _, err := svc.Ingest(context.Background(), ns, decodeEvent(t)); err != nil {
+		t.Fatal(err)
+	}
+	if cols, _ := entities.byURN[urn].Properties["columns"].([]interface{}); len(cols) != 2 {
+		t.Errorf("expected the columns of an earlier event to be replaced, got %v", entities.byURN[urn].Properties["columns"])
+	}
+}
+
+func TestService_Ingest_WritesNothingOnFailure(t *testing.T) {
+	svc, entities, batches := newTestService(map[string]entity.Entity{})
+	batches.err = &batch.OpError{Index: 4, Action: batch.ActionUpsertEdge, Err: entity.ErrEdgeConstraint}
+
+	_, err := svc.Ingest(context.Background(), namespace.DefaultNamespace, decodeEvent(t))
+	if !errors.Is(err, entity.ErrEdgeConstraint) {
+		t.Errorf("expected ErrEdgeConstraint, got %v", err)
+	}
+	if len(entities.byURN) != 0 || len(batches.edges) != 0 {
+		t.Errorf("expected nothing to be written, got %d entities and %d edges", len(entities.byURN), len(batches.edges))
+	}
+}
+
+func TestService_Ingest_StableAcrossRuns(t *testing.T) {
+	ns := namespace.DefaultNamespace
+	svc, entities, _ := newTestService(map[string]entity.Entity{})
+	jobURN := "urn:airflow:analytics/orders_daily"
+	outURN := "urn:bigquery:warehouse.analytics.orders"
+
+	if _, err := svc.Ingest(context.Background(), ns, decodeEvent(t)); err != nil {
+		t.Fatal(err)
+	}
+	job, out := entities.byURN[jobURN], entities.byURN[outURN]
+
+	// The next run of the job: new run ID and time, and per-run facets.
+	for _, eventType := range []string{EventStart, EventComplete} {
+		ev := decodeEvent(t)
+		ev.EventType = eventType
+		ev.EventTime = ev.EventTime.Add(24 * time.Hour)
+		ev.Run = Run{RunID: "0176a8c2-fe01-7439-87e6-56a1a1b40300", Facets: map[string]interface{}{"nominalTime": map[string]interface{}{}}}
+		ev.Outputs[0].Facets["version"] = map[string]interface{}{"datasetVersion": "42"}
+		ev.Outputs[0].Facets["outputStatistics"] = map[string]interface{}{"rowCount": 1200}
+		if _, err := svc.Ingest(context.Background(), ns, ev); err != nil {
+			t.Fatal(err)
+		}
+		if got := entities.byURN[jobURN]; !reflect.DeepEqual(got.Properties, job.Properties) {
+			t.Errorf("%s: expected job properties to stay %v, got %v", eventType, job.Properties, got.Properties)
+		}
+		if got := entities.byURN[outURN]; !reflect.DeepEqual(got.Properties, out.Properties) {
+			t.Errorf("%s: expected dataset properties to stay %v, got %v", eventType, out.Properties, got.Properties)
+		}
+	}
+
+	ev := decodeEvent(t)
+	ev.EventType = EventFail
+	if _, err := svc.Ingest(context.Background(), ns, ev); err != nil {
+		t.Fatal(err)
+	}
+	if run, _ := entities.byURN[jobURN].Properties["run"].(map[string]interface{}); run["state"] != EventFail {
+		t.Errorf("expected the failed run to be recorded, got %v", run)
+	}
+}
+
+func TestService_Ingest_Invalid(t *testing.T) {
+	svc, _, _ := newTestService(map[string]entity.Entity{})
+	ev := decodeEvent(t)
+	ev.Outputs[0].Namespace = ""
+
+	_, err := svc.Ingest(context.Background(), namespace.DefaultNamespace, ev)
+	if !errors.Is(err, ErrInvalidEvent) {
+		t.Errorf("expected ErrInvalidEvent, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+// NamespaceLister lists the namespaces a janitor serves.
+type NamespaceLister interface {
+	List(ctx context.Context) ([]*namespace.Namespace, error)
+}
+
+// Janitor periodically enforces the retention policy of every namespace.
+type Janitor struct {
+	service    *Service
+	namespaces NamespaceLister
+	scope      func(context.Context, *namespace.Namespace) context.Context
+	interval   time.Duration
+	now        func() time.Time
+	wg         sync.WaitGroup
+	cancel     context.CancelFunc
+}
+
+// JanitorOption configures the janitor.
+type JanitorOption func(*Janitor)
+
+// WithInterval sets how often retention policies are enforced.
+func WithInterval(d time.Duration) JanitorOption {
+	return func(j *Janitor) {
+		if d > 0 {
+			j.interval = d
+		}
+	}
+}
+
+// WithNamespaceScope sets how a namespace is attached to the context of the
+// storage calls made on its behalf.
+func WithNamespaceScope(fn func(context.Context, *namespace.Namespace) context.Context) JanitorOption {
+	return func(j *Janitor) {
+		if fn != nil {
+			j.scope = fn
+		}
+	}
+}
+
+// NewJanitor creates a retention janitor.
+func NewJanitor(service *Service, namespaces NamespaceLister, opts ...JanitorOption) *Janitor {
+	j := &Janitor{
+		service:    service,
+		namespaces: namespaces,
+		scope:      func(ctx context.Context, _ *namespace.Namespace) context.Context { return ctx },
+		interval:   time.Hour,
+		now:        time.Now,
+	}
+	for _, opt := range opts {
+		opt(j)
+	}
+	return j
+}
+
+// Start runs the janitor in the background.
+func (j *Janitor) Start(ctx context.Context) {
+	ctx, j.cancel = context.WithCancel(ctx)
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+		for {
+			j.run(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	slog.Info("retention janitor started", "interval", j.interval)
+}
+
+// Stop signals the janitor to finish and waits for it.
+func (j *Janitor) Stop() {
+	if j.cancel != nil {
+		j.cancel()
+	}
+	j.wg.Wait()
+	slog.Info("retention janitor stopped")
+}
+
+// run enforces every namespace's policy once. A failing namespace is logged
+// and retried on the next run.
+func (j *Janitor) run(ctx context.Context) {
+	namespaces, err := j.namespaces.List(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "retention: list namespaces", "error", err)
+		return
+	}
+	for _, ns := range namespaces {
+		if ctx.Err() != nil {
+			return
+		}
+		res, err := j.service.Enforce(j.scope(ctx, ns), ns, j.now())
+		if err != nil {
+			slog.WarnContext(ctx, "retention enforcement failed", "namespace", ns.Name, "error", err)
+			continue
+		}
+		if !res.IsZero() {
+			slog.InfoContext(ctx, "retention enforced", "namespace", ns.Name,
+				"entity_versions", res.EntityVersions, "edge_versions", res.EdgeVersions,
+				"documents", res.Documents, "embeddings", res.Embeddings, "changes", res.Changes)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+// MetadataKey is the namespace metadata key holding the retention policy.
+const MetadataKey = "retention"
+
+// ErrNotConfirmed is returned for a purge whose confirmation does not name
+// the entity it would erase.
+var ErrNotConfirmed = errors.New("confirm must repeat the urn to purge")
+
+// Policy is a namespace's retention policy, read from its metadata:
+//
+//	"retention": {"closed_versions_days": 180, "deleted_days": 30}
+//
+// A zero setting keeps the data forever.
+type Policy struct {
+	// ClosedVersionsDays is how long a superseded entity or edge version is
+	// kept after a newer version replaced it. The latest version of an
+	// object is never pruned by this setting, so deleted objects stay
+	// restorable.
+	ClosedVersionsDays int
+	// DeletedDays is how long a deleted entity or edge is kept before it is
+	// hard-purged. Purging an entity also removes its documents, embeddings
+	// and change records.
+	DeletedDays int
+}
+
+// IsZero reports whether the policy keeps everything.
+func (p Policy) IsZero() bool {
+	return p.ClosedVersionsDays == 0 && p.DeletedDays == 0
+}
+
+// PolicyFromNamespace reads the retention policy from namespace metadata.
+func PolicyFromNamespace(ns *namespace.Namespace) (Policy, error) {
+	var p Policy
+	raw, ok := ns.Metadata[MetadataKey]
+	if !ok || raw == nil {
+		return p, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return p, fmt.Errorf("metadata.%s must be an object", MetadataKey)
+	}
+	var err error
+	if p.ClosedVersionsDays, err = days(m, "closed_versions_days"); err != nil {
+		return Policy{}, err
+	}
+	if p.DeletedDays, err = days(m, "deleted_days"); err != nil {
+		return Policy{}, err
+	}
+	return p, nil
+}
+
+// days reads a non-negative whole number of days. Metadata decoded from JSON
+// carries numbers as float64.
+func days(m map[string]interface{}, key string) (int, error) {
+	v, ok := m[key]
+	if !ok || v == nil {
+		return 0, nil
+	}
+	var n float64
+	switch v := v.(type) {
+	case float64:
+		n = v
+	case int:
+		n = float64(v)
+	case int64:
+		n = float64(v)
+	default:
+		return 0, fmt.Errorf("metadata.%s.%s must be a number of days", MetadataKey, key)
+	}
+	if n < 0 || n != float64(int(n)) {
+		return 0, fmt.Errorf("metadata.%s.%s must be a non-negative whole number of days", MetadataKey, key)
+	}
+	return int(n), nil
+}
+
+// Result counts the rows a purge removed.
+type Result struct {
+	EntityVersions int `json:"entity_versions"`
+	EdgeVersions   int `json:"edge_versions"`
+	Documents      int `json:"documents"`
+	Embeddings     int `json:"embeddings"`
+	Changes        int `json:"changes"`
+}
+
+// Add accumulates another result into r.
+func (r *Result) Add(o Result) {
+	r.EntityVersions += o.EntityVersions
+	r.EdgeVersions += o.EdgeVersions
+	r.Documents += o.Documents
+	r.Embeddings += o.Embeddings
+	r.Changes += o.Changes
+}
+
+// IsZero reports whether nothing was removed.
+func (r Result) IsZero() bool {
+	return r == (Result{})
+}
+
+// Repository defines the hard-delete operations retention relies on.
+type Repository interface {
+	// PurgeClosedVersions removes entity and edge versions that were
+	// superseded by a newer version before the cutoff.
+	PurgeClosedVersions(ctx context.Context, ns *namespace.Namespace, before time.Time) (Result, error)
+	// PurgeDeleted removes entities and edges whose latest version was
+	// closed before the cutoff, together with everything PurgeEntity
+	// removes for each purged entity. Entities merged into another entity
+	// are kept, so their URNs go on resolving through their aliases.
+	PurgeDeleted(ctx context.Context, ns *namespace.Namespace, before time.Time) (Result, error)
+	// PurgeEntity removes every version of an entity, its edges, documents,
+	// embeddings and change records, and those of the entities merged into
+	// it. A URN merged into another entity purges that entity.
+	PurgeEntity(ctx context.Context, ns *namespace.Namespace, urn string) (Result, error)
+	// ResolveURN returns the canonical URN of the entity urn names, which
+	// for a URN merged into another entity is that entity's.
+	ResolveURN(ctx context.Context, ns *namespace.Namespace, urn string) (string, error)
+}
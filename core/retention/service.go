@@ -0,0 +1,66 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/urn"
+)
+
+const day = 24 * time.Hour
+
+// Service applies retention policies and erases entities on request.
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Enforce applies the namespace's retention policy as of now.
+func (s *Service) Enforce(ctx context.Context, ns *namespace.Namespace, now time.Time) (Result, error) {
+	var total Result
+	policy, err := PolicyFromNamespace(ns)
+	if err != nil {
+		return total, err
+	}
+
+	if policy.DeletedDays > 0 {
+		res, err := s.repo.PurgeDeleted(ctx, ns, now.Add(-time.Duration(policy.DeletedDays)*day))
+		if err != nil {
+			return total, fmt.Errorf("purge deleted: %w", err)
+		}
+		total.Add(res)
+	}
+	if policy.ClosedVersionsDays > 0 {
+		res, err := s.repo.PurgeClosedVersions(ctx, ns, now.Add(-time.Duration(policy.ClosedVersionsDays)*day))
+		if err != nil {
+			return total, fmt.Errorf("purge closed versions: %w", err)
+		}
+		total.Add(res)
+	}
+	return total, nil
+}
+
+// PurgeEntity permanently erases an entity, current or deleted, with all of
+// its versions, edges, documents, embeddings and change records. It cannot be
+// undone, so confirm must repeat the URN of the entity that would be erased:
+// given a URN merged into another entity, that entity is erased, and confirm
+// must name it.
+func (s *Service) PurgeEntity(ctx context.Context, ns *namespace.Namespace, target, confirm string) (Result, error) {
+	target, err := s.repo.ResolveURN(ctx, ns, target)
+	if err != nil {
+		return Result{}, fmt.Errorf("resolve urn: %w", err)
+	}
+	if urn.Lookup(ns, confirm) != target {
+		return Result{}, fmt.Errorf("%w: %s", ErrNotConfirmed, target)
+	}
+	res, err := s.repo.PurgeEntity(ctx, ns, target)
+	if err != nil {
+		return Result{}, fmt.Errorf("purge entity: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,169 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/urn"
+)
+
+type mockRepo struct {
+	deletedBefore time.Time
+	closedBefore  time.Time
+	purged        []string
+	aliases       map[string]string
+}
+
+func (m *mockRepo) PurgeClosedVersions(_ context.Context, _ *namespace.Namespace, before time.Time) (Result, error) {
+	m.closedBefore = before
+	return Result{EntityVersions: 3, EdgeVersions: 1}, nil
+}
+
+func (m *mockRepo) PurgeDeleted(_ context.Context, _ *namespace.Namespace, before time.Time) (Result, error) {
+	m.deletedBefore = before
+	return Result{EntityVersions: 2, Documents: 1, Embeddings: 4}, nil
+}
+
+func (m *mockRepo) PurgeEntity(_ context.Context, _ *namespace.Namespace, urn string) (Result, error) {
+	m.purged = append(m.purged, urn)
+	return Result{EntityVersions: 1}, nil
+}
+
+func (m *mockRepo) ResolveURN(_ context.Context, ns *namespace.Namespace, u string) (string, error) {
+	u = urn.Lookup(ns, u)
+	if to, ok := m.aliases[u]; ok {
+		return to, nil
+	}
+	return u, nil
+}
+
+func nsWithRetention(retention interface{}) *namespace.Namespace {
+	return &namespace.Namespace{Name: "tenant", Metadata: map[string]interface{}{MetadataKey: retention}}
+}
+
+func TestPolicyFromNamespace(t *testing.T) {
+	tests := []struct {
+		name    string
+		ns      *namespace.Namespace
+		want    Policy
+		wantErr bool
+	}{
+		{"no metadata", namespace.DefaultNamespace, Policy{}, false},
+		{"json numbers", nsWithRetention(map[string]interface{}{"closed_versions_days": float64(180), "deleted_days": float64(30)}), Policy{ClosedVersionsDays: 180, DeletedDays: 30}, false},
+		{"partial", nsWithRetention(map[string]interface{}{"deleted_days": 7}), Policy{DeletedDays: 7}, false},
+		{"not an object", nsWithRetention("30d"), Policy{}, true},
+		{"negative", nsWithRetention(map[string]interface{}{"deleted_days": float64(-1)}), Policy{}, true},
+		{"fractional", nsWithRetention(map[string]interface{}{"deleted_days": 1.5}), Policy{}, true},
+		{"string", nsWithRetention(map[string]interface{}{"deleted_days": "30"}), Policy{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := PolicyFromNamespace(tt.ns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestService_Enforce(t *testing.T) {
+	repo := &mockRepo{}
+	svc := NewService(repo)
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	ns := nsWithRetention(map[string]interface{}{"closed_versions_days": float64(180), "deleted_days": float64(30)})
+
+	res, err := svc.Enforce(context.Background(), ns, now)
+	if err != nil {
+		t.Fatalf("Enforce failed: %v", err)
+	}
+	if want := now.AddDate(0, 0, -30); !repo.deletedBefore.Equal(want) {
+		t.Errorf("expected deleted cutoff %s, got %s", want, repo.deletedBefore)
+	}
+	if want := now.AddDate(0, 0, -180); !repo.closedBefore.Equal(want) {
+		t.Errorf("expected closed versions cutoff %s, got %s", want, repo.closedBefore)
+	}
+	want := Result{EntityVersions: 5, EdgeVersions: 1, Documents: 1, Embeddings: 4}
+	if res != want {
+		t.Errorf("expected %+v, got %+v", want, res)
+	}
+}
+
+func TestService_Enforce_NoPolicy(t *testing.T) {
+	repo := &mockRepo{}
+	res, err := NewService(repo).Enforce(context.Background(), namespace.DefaultNamespace, time.Now())
+	if err != nil {
+		t.Fatalf("Enforce failed: %v", err)
+	}
+	if !res.IsZero() || !repo.deletedBefore.IsZero() || !repo.closedBefore.IsZero() {
+		t.Errorf("expected nothing purged without a policy, got %+v", res)
+	}
+}
+
+type staticNamespaces []*namespace.Namespace
+
+func (s staticNamespaces) List(context.Context) ([]*namespace.Namespace, error) {
+	return s, nil
+}
+
+func TestJanitor_Run(t *testing.T) {
+	repo := &mockRepo{}
+	scoped := map[string]bool{}
+	j := NewJanitor(NewService(repo), staticNamespaces{
+		namespace.DefaultNamespace,
+		nsWithRetention(map[string]interface{}{"deleted_days": float64(30)}),
+		nsWithRetention("invalid"),
+	}, WithNamespaceScope(func(ctx context.Context, ns *namespace.Namespace) context.Context {
+		scoped[ns.Name] = true
+		return ctx
+	}))
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	j.now = func() time.Time { return now }
+
+	j.run(context.Background())
+
+	if want := now.AddDate(0, 0, -30); !repo.deletedBefore.Equal(want) {
+		t.Errorf("expected deleted cutoff %s, got %s", want, repo.deletedBefore)
+	}
+	if !repo.closedBefore.IsZero() {
+		t.Error("expected closed versions to be kept")
+	}
+	if !scoped["default"] || !scoped["tenant"] {
+		t.Errorf("expected every namespace to be scoped, got %v", scoped)
+	}
+}
+
+func TestService_PurgeEntity(t *testing.T) {
+	repo := &mockRepo{aliases: map[string]string{"urn:bigquery:old": "urn:bigquery:new"}}
+	svc := NewService(repo)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	if _, err := svc.PurgeEntity(ctx, ns, "urn:bigquery:new", "urn:bigquery:other"); !errors.Is(err, ErrNotConfirmed) {
+		t.Errorf("expected ErrNotConfirmed for another urn, got %v", err)
+	}
+	// A merged URN purges the entity it was merged into, which confirm must
+	// name.
+	if _, err := svc.PurgeEntity(ctx, ns, "urn:bigquery:old", "urn:bigquery:old"); !errors.Is(err, ErrNotConfirmed) {
+		t.Errorf("expected ErrNotConfirmed for the merged urn, got %v", err)
+	}
+	if len(repo.purged) != 0 {
+		t.Fatalf("expected nothing to be purged, got %v", repo.purged)
+	}
+
+	if _, err := svc.PurgeEntity(ctx, ns, "urn:bigquery:old", "urn:bigquery:new"); err != nil {
+		t.Fatalf("PurgeEntity failed: %v", err)
+	}
+	if _, err := svc.PurgeEntity(ctx, ns, " urn:BigQuery:NEW ", "urn:bigquery:new"); err != nil {
+		t.Fatalf("PurgeEntity failed: %v", err)
+	}
+	if want := []string{"urn:bigquery:new", "urn:bigquery:new"}; !reflect.DeepEqual(repo.purged, want) {
+		t.Errorf("expected %v, got %v", want, repo.purged)
+	}
+}
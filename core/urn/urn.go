@@ -0,0 +1,203 @@
+// Package urn parses, validates and canonicalises Compass URNs.
+//
+// A URN has the form urn:<source>:<scope>:<kind>:<id>, or the short form
+// urn:<source>:<id> most extractors write. A column URN appends #<column> to
+// the URN of its entity. Entities and edges are matched on the exact URN
+// text, so writes canonicalise URNs first: surrounding whitespace is
+// trimmed, the scheme, source and kind are lower-cased, and for sources whose
+// identifiers are case-insensitive so are the scope and id.
+package urn
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+// MetadataKey is the namespace metadata key holding the URN rules.
+const MetadataKey = "urn"
+
+// ErrInvalid is returned for a URN the namespace rules do not accept.
+var ErrInvalid = errors.New("invalid urn")
+
+// DefaultCaseInsensitive are the sources whose scope and id are folded to
+// lower case when a namespace does not set case_insensitive.
+var DefaultCaseInsensitive = []string{"bigquery", "redshift", "snowflake"}
+
+// segment matches a scope or kind of the long form. Anything else after the
+// source, such as the host:port of a Kafka broker, is read as part of the id.
+var segment = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// URN is a parsed URN. Scope and Kind are empty in the short form, and
+// Column is empty unless the URN names a column.
+type URN struct {
+	Source string
+	Scope  string
+	Kind   string
+	ID     string
+	Column string
+}
+
+// Parse splits a URN into its components, trimming the whitespace around
+// each of them.
+func Parse(s string) (URN, error) {
+	s = strings.TrimSpace(s)
+	scheme, rest, ok := strings.Cut(s, ":")
+	if !ok || !strings.EqualFold(strings.TrimSpace(scheme), "urn") {
+		return URN{}, fmt.Errorf("%w: %q does not start with urn:", ErrInvalid, s)
+	}
+
+	var u URN
+	if body, column, ok := strings.Cut(rest, "#"); ok {
+		rest, u.Column = body, strings.TrimSpace(column)
+		if u.Column == "" {
+			return URN{}, fmt.Errorf("%w: %q has an empty column", ErrInvalid, s)
+		}
+	}
+	parts := strings.SplitN(rest, ":", 4)
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	u.Source = parts[0]
+	if len(parts) == 4 && segment.MatchString(parts[1]) && segment.MatchString(parts[2]) {
+		u.Scope, u.Kind, u.ID = parts[1], parts[2], parts[3]
+	} else if len(parts) > 1 {
+		u.ID = strings.TrimSpace(strings.Join(parts[1:], ":"))
+	}
+	if u.Source == "" || u.ID == "" {
+		return URN{}, fmt.Errorf("%w: %q must have a source and an id", ErrInvalid, s)
+	}
+	return u, nil
+}
+
+// String formats the URN, in the long form when it has a scope and kind.
+func (u URN) String() string {
+	s := "urn:" + u.Source + ":"
+	if u.Scope != "" || u.Kind != "" {
+		s += u.Scope + ":" + u.Kind + ":"
+	}
+	s += u.ID
+	if u.Column != "" {
+		s += "#" + u.Column
+	}
+	return s
+}
+
+// Rules are a namespace's URN rules, read from its metadata:
+//
+//	"urn": {"sources": ["bigquery", "kafka"], "case_insensitive": ["bigquery"], "strict": true}
+type Rules struct {
+	// Sources lists the sources URNs may name; empty allows any. Setting it
+	// requires every URN to parse.
+	Sources []string
+	// CaseInsensitive lists the sources whose scope and id are folded to
+	// lower case. Column names keep their case.
+	CaseInsensitive []string
+	// Strict rejects identifiers that are not URNs. Otherwise they are only
+	// trimmed.
+	Strict bool
+}
+
+// RulesFromNamespace reads the URN rules from namespace metadata.
+func RulesFromNamespace(ns *namespace.Namespace) (Rules, error) {
+	r := Rules{CaseInsensitive: DefaultCaseInsensitive}
+	raw, ok := ns.Metadata[MetadataKey]
+	if !ok || raw == nil {
+		return r, nil
+	}
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		return Rules{}, fmt.Errorf("metadata.%s must be an object", MetadataKey)
+	}
+	var err error
+	if r.Sources, err = sources(m, "sources"); err != nil {
+		return Rules{}, err
+	}
+	if _, ok := m["case_insensitive"]; ok {
+		if r.CaseInsensitive, err = sources(m, "case_insensitive"); err != nil {
+			return Rules{}, err
+		}
+	}
+	if v, ok := m["strict"]; ok && v != nil {
+		if r.Strict, ok = v.(bool); !ok {
+			return Rules{}, fmt.Errorf("metadata.%s.strict must be a boolean", MetadataKey)
+		}
+	}
+	return r, nil
+}
+
+// sources reads a list of source names, lower-cased. Metadata decoded from
+// JSON carries lists as []interface{}.
+func sources(m map[string]interface{}, key string) ([]string, error) {
+	var items []interface{}
+	switch v := m[key].(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		items = v
+	case []string:
+		for _, s := range v {
+			items = append(items, s)
+		}
+	default:
+		return nil, fmt.Errorf("metadata.%s.%s must be a list of sources", MetadataKey, key)
+	}
+	out := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			return nil, fmt.Errorf("metadata.%s.%s must be a list of sources", MetadataKey, key)
+		}
+		out = append(out, strings.ToLower(strings.TrimSpace(s)))
+	}
+	return out, nil
+}
+
+// Canonical validates s against the rules and returns its canonical form.
+func (r Rules) Canonical(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", fmt.Errorf("%w: urn is empty", ErrInvalid)
+	}
+	if i := strings.IndexFunc(s, unicode.IsControl); i >= 0 {
+		return "", fmt.Errorf("%w: %q contains a control character", ErrInvalid, s)
+	}
+	u, err := Parse(s)
+	if err != nil {
+		if r.Strict || len(r.Sources) > 0 {
+			return "", err
+		}
+		return s, nil
+	}
+	u.Source, u.Kind = strings.ToLower(u.Source), strings.ToLower(u.Kind)
+	if len(r.Sources) > 0 && !slices.Contains(r.Sources, u.Source) {
+		return "", fmt.Errorf("%w: source %q of %s is not one of %s", ErrInvalid, u.Source, s, strings.Join(r.Sources, ", "))
+	}
+	if slices.Contains(r.CaseInsensitive, u.Source) {
+		u.Scope, u.ID = strings.ToLower(u.Scope), strings.ToLower(u.ID)
+	}
+	return u.String(), nil
+}
+
+// Canonical returns the canonical form of s under the rules of ns.
+func Canonical(ns *namespace.Namespace, s string) (string, error) {
+	r, err := RulesFromNamespace(ns)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrInvalid, err)
+	}
+	return r.Canonical(s)
+}
+
+// Lookup returns the canonical form of s under the rules of ns, or s itself
+// when it is not valid, so reads find what writes stored.
+func Lookup(ns *namespace.Namespace, s string) string {
+	if c, err := Canonical(ns, s); err == nil {
+		return c
+	}
+	return s
+}
@@ -0,0 +1,129 @@
+package urn
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+func nsWithRules(rules interface{}) *namespace.Namespace {
+	return &namespace.Namespace{Name: "tenant", Metadata: map[string]interface{}{MetadataKey: rules}}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    URN
+		wantErr bool
+	}{
+		{"urn:bigquery:proj.ds.t", URN{Source: "bigquery", ID: "proj.ds.t"}, false},
+		{"urn:bigquery:prod:table:proj.ds.t", URN{Source: "bigquery", Scope: "prod", Kind: "table", ID: "proj.ds.t"}, false},
+		{"urn:kafka:broker:9092/order-events", URN{Source: "kafka", ID: "broker:9092/order-events"}, false},
+		{" URN : bigquery : proj.ds.t #  id ", URN{Source: "bigquery", ID: "proj.ds.t", Column: "id"}, false},
+		{"urn:airflow:analytics/orders_daily", URN{Source: "airflow", ID: "analytics/orders_daily"}, false},
+		{"urn:t", URN{}, true},
+		{"urn::x", URN{}, true},
+		{"urn:bigquery:t#", URN{}, true},
+		{"bigquery:t", URN{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRulesFromNamespace(t *testing.T) {
+	tests := []struct {
+		name    string
+		ns      *namespace.Namespace
+		want    Rules
+		wantErr bool
+	}{
+		{"no metadata", namespace.DefaultNamespace, Rules{CaseInsensitive: DefaultCaseInsensitive}, false},
+		{"json lists", nsWithRules(map[string]interface{}{"sources": []interface{}{"BigQuery", "kafka"}, "strict": true}),
+			Rules{Sources: []string{"bigquery", "kafka"}, CaseInsensitive: DefaultCaseInsensitive, Strict: true}, false},
+		{"case sensitive", nsWithRules(map[string]interface{}{"case_insensitive": []interface{}{}}), Rules{CaseInsensitive: []string{}}, false},
+		{"not an object", nsWithRules("strict"), Rules{}, true},
+		{"not a list", nsWithRules(map[string]interface{}{"sources": "bigquery"}), Rules{}, true},
+		{"empty source", nsWithRules(map[string]interface{}{"sources": []interface{}{""}}), Rules{}, true},
+		{"strict string", nsWithRules(map[string]interface{}{"strict": "yes"}), Rules{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RulesFromNamespace(tt.ns)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Strict != tt.want.Strict || !slices.Equal(got.Sources, tt.want.Sources) || !slices.Equal(got.CaseInsensitive, tt.want.CaseInsensitive) {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRules_Canonical(t *testing.T) {
+	lax := Rules{CaseInsensitive: DefaultCaseInsensitive}
+	strict := Rules{Sources: []string{"bigquery", "kafka"}, CaseInsensitive: DefaultCaseInsensitive}
+	tests := []struct {
+		name    string
+		rules   Rules
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"folds case-insensitive source", lax, "urn:bigquery:Proj.DS.t", "urn:bigquery:proj.ds.t", false},
+		{"trims whitespace", lax, "  URN:BigQuery: proj.ds.t ", "urn:bigquery:proj.ds.t", false},
+		{"keeps case-sensitive id", lax, "urn:Kafka:Orders", "urn:kafka:Orders", false},
+		{"folds long form", lax, "urn:bigquery:Prod:TABLE:Proj.ds.t", "urn:bigquery:prod:table:proj.ds.t", false},
+		{"keeps column case", lax, "urn:bigquery:Proj.ds.t#CustomerID", "urn:bigquery:proj.ds.t#CustomerID", false},
+		{"keeps non-urn identifier", lax, " legacy-id ", "legacy-id", false},
+		{"empty", lax, "  ", "", true},
+		{"control character", lax, "urn:bigquery:a\nb", "", true},
+		{"unknown source", strict, "urn:mysql:db.t", "", true},
+		{"listed source", strict, "urn:Kafka:orders", "urn:kafka:orders", false},
+		{"not a urn under source rules", strict, "legacy-id", "", true},
+		{"not a urn when strict", Rules{Strict: true}, "legacy-id", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rules.Canonical(tt.in)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("expected ErrInvalid, got %q, %v", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Canonical failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+			if again, _ := tt.rules.Canonical(got); again != got {
+				t.Errorf("expected canonical form to be stable, got %q", again)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	ns := nsWithRules(map[string]interface{}{"sources": []interface{}{"bigquery"}})
+	if got := Lookup(ns, "urn:BigQuery:Proj.ds.t"); got != "urn:bigquery:proj.ds.t" {
+		t.Errorf("expected canonical urn, got %q", got)
+	}
+	if got := Lookup(ns, "urn:kafka:orders"); got != "urn:kafka:orders" {
+		t.Errorf("expected invalid urn to be looked up as given, got %q", got)
+	}
+}
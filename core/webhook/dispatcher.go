@@ -0,0 +1,371 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raystack/compass/core/change"
+	"github.com/raystack/compass/core/namespace"
+)
+
+// ChangeSource reads the change feed a dispatcher delivers from.
+type ChangeSource interface {
+	List(ctx context.Context, ns *namespace.Namespace, flt change.Filter) ([]change.Change, error)
+}
+
+// NamespaceLister lists the namespaces a dispatcher serves.
+type NamespaceLister interface {
+	List(ctx context.Context) ([]*namespace.Namespace, error)
+}
+
+// Payload is the JSON body of a delivery request.
+type Payload struct {
+	ID          string        `json:"id"`
+	Event       string        `json:"event"`
+	NamespaceID string        `json:"namespace_id"`
+	Change      change.Change `json:"change"`
+}
+
+// Dispatcher follows the change feed of every namespace and delivers matching
+// changes to its webhooks. Each webhook keeps a cursor into the feed, so
+// changes are delivered in order and at least once across restarts.
+type Dispatcher struct {
+	repo         Repository
+	changes      ChangeSource
+	namespaces   NamespaceLister
+	client       *http.Client
+	allowPrivate bool
+	scope        func(context.Context, *namespace.Namespace) context.Context
+	interval     time.Duration
+	maxAttempts  int
+	backoff      time.Duration
+	batchSize    int
+	wg           sync.WaitGroup
+	cancel       context.CancelFunc
+
+	// busy holds the webhooks being drained; inflight tracks their drains.
+	mu       sync.Mutex
+	busy     map[string]bool
+	inflight sync.WaitGroup
+}
+
+// Option configures the dispatcher.
+type Option func(*Dispatcher)
+
+// WithInterval sets how often the dispatcher checks for new changes.
+func WithInterval(d time.Duration) Option {
+	return func(dp *Dispatcher) {
+		if d > 0 {
+			dp.interval = d
+		}
+	}
+}
+
+// WithMaxAttempts sets how many times a delivery is attempted before it is
+// logged as failed.
+func WithMaxAttempts(n int) Option {
+	return func(dp *Dispatcher) {
+		if n > 0 {
+			dp.maxAttempts = n
+		}
+	}
+}
+
+// WithBackoff sets the wait before the first retry; it doubles on every
+// further retry.
+func WithBackoff(d time.Duration) Option {
+	return func(dp *Dispatcher) {
+		if d >= 0 {
+			dp.backoff = d
+		}
+	}
+}
+
+// WithHTTPClient sets the client deliveries are sent with, in place of the
+// default one that refuses private addresses.
+func WithHTTPClient(c *http.Client) Option {
+	return func(dp *Dispatcher) {
+		if c != nil {
+			dp.client = c
+		}
+	}
+}
+
+// WithPrivateNetworks lets the default client deliver to loopback, private
+// and link-local addresses.
+func WithPrivateNetworks(allow bool) Option {
+	return func(dp *Dispatcher) {
+		dp.allowPrivate = allow
+	}
+}
+
+// WithNamespaceScope sets how a namespace is attached to the context of the
+// storage calls made on its behalf.
+func WithNamespaceScope(fn func(context.Context, *namespace.Namespace) context.Context) Option {
+	return func(dp *Dispatcher) {
+		if fn != nil {
+			dp.scope = fn
+		}
+	}
+}
+
+// NewDispatcher creates a webhook dispatcher.
+func NewDispatcher(repo Repository, changes ChangeSource, namespaces NamespaceLister, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		repo:        repo,
+		changes:     changes,
+		namespaces:  namespaces,
+		scope:       func(ctx context.Context, _ *namespace.Namespace) context.Context { return ctx },
+		interval:    5 * time.Second,
+		maxAttempts: 5,
+		backoff:     time.Second,
+		batchSize:   100,
+		busy:        map[string]bool{},
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.client == nil {
+		d.client = newClient(d.allowPrivate)
+	}
+	return d
+}
+
+// Start runs the dispatch loop in the background.
+func (d *Dispatcher) Start(ctx context.Context) {
+	ctx, d.cancel = context.WithCancel(ctx)
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		ticker := time.NewTicker(d.interval)
+		defer ticker.Stop()
+		for {
+			d.dispatch(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	slog.Info("webhook dispatcher started", "interval", d.interval, "max_attempts", d.maxAttempts)
+}
+
+// Stop cancels in-flight deliveries and waits for the loop and every drain to
+// exit.
+func (d *Dispatcher) Stop() {
+	if d.cancel != nil {
+		d.cancel()
+	}
+	d.wg.Wait()
+	d.inflight.Wait()
+	slog.Info("webhook dispatcher stopped")
+}
+
+// dispatch starts a drain for every webhook of every namespace that is not
+// already being drained, and returns without waiting for them. Each webhook
+// runs on its own: a slow endpoint keeps its drain busy across ticks while the
+// others are served on every tick.
+func (d *Dispatcher) dispatch(ctx context.Context) {
+	namespaces, err := d.namespaces.List(ctx)
+	if err != nil {
+		slog.WarnContext(ctx, "webhook dispatch: list namespaces", "error", err)
+		return
+	}
+
+	for _, ns := range namespaces {
+		nsCtx := d.scope(ctx, ns)
+		webhooks, err := d.repo.List(nsCtx, ns)
+		if err != nil {
+			slog.WarnContext(ctx, "webhook dispatch: list webhooks", "namespace", ns.Name, "error", err)
+			continue
+		}
+		for _, wh := range webhooks {
+			if !d.claim(wh.ID) {
+				continue
+			}
+			d.inflight.Add(1)
+			go func(ns *namespace.Namespace, id string) {
+				defer d.inflight.Done()
+				defer d.release(id)
+				if err := d.serve(nsCtx, ns, id); err != nil && ctx.Err() == nil {
+					slog.WarnContext(ctx, "webhook dispatch failed", "webhook", id, "namespace", ns.Name, "error", err)
+				}
+			}(ns, wh.ID)
+		}
+	}
+}
+
+// claim marks a webhook as being drained. It reports false when a drain is
+// already running, so a webhook is never drained twice at once.
+func (d *Dispatcher) claim(id string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.busy[id] {
+		return false
+	}
+	d.busy[id] = true
+	return true
+}
+
+func (d *Dispatcher) release(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.busy, id)
+}
+
+// serve drains one webhook. It reloads the webhook first: the listed copy may
+// predate a drain that finished since, and the webhook may have been deleted.
+func (d *Dispatcher) serve(ctx context.Context, ns *namespace.Namespace, id string) error {
+	wh, err := d.repo.GetByID(ctx, ns, id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("get webhook: %w", err)
+	}
+	return d.drain(ctx, ns, wh)
+}
+
+// drain delivers every change after the webhook's cursor. The cursor moves
+// past a change once its delivery is logged, whether it succeeded or not, so
+// one unreachable endpoint cannot stall its own feed forever.
+func (d *Dispatcher) drain(ctx context.Context, ns *namespace.Namespace, wh Webhook) error {
+	for {
+		changes, err := d.changes.List(ctx, ns, change.Filter{SinceSeq: wh.LastSeq, Limit: d.batchSize})
+		if err != nil {
+			return fmt.Errorf("read change feed: %w", err)
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+
+		for _, c := range changes {
+			if !wh.Matches(c) {
+				continue
+			}
+			delivery, err := d.deliver(ctx, wh, c)
+			if err != nil {
+				// Cancelled mid-delivery: the cursor stays before c so it is
+				// delivered again on the next run.
+				return err
+			}
+			if err := d.repo.InsertDelivery(ctx, ns, delivery); err != nil {
+				return fmt.Errorf("log delivery: %w", err)
+			}
+			if err := d.advance(ctx, ns, &wh, c.Seq); err != nil {
+				return err
+			}
+		}
+		if err := d.advance(ctx, ns, &wh, changes[len(changes)-1].Seq); err != nil {
+			return err
+		}
+		if len(changes) < d.batchSize {
+			return nil
+		}
+	}
+}
+
+func (d *Dispatcher) advance(ctx context.Context, ns *namespace.Namespace, wh *Webhook, seq int64) error {
+	if seq <= wh.LastSeq {
+		return nil
+	}
+	if err := d.repo.UpdateCursor(ctx, ns, wh.ID, seq); err != nil {
+		return fmt.Errorf("update cursor: %w", err)
+	}
+	wh.LastSeq = seq
+	return nil
+}
+
+// deliver posts one change, retrying with exponential backoff. It only
+// returns an error when ctx is done before the outcome is known.
+func (d *Dispatcher) deliver(ctx context.Context, wh Webhook, c change.Change) (*Delivery, error) {
+	delivery := &Delivery{
+		ID:        uuid.NewString(),
+		WebhookID: wh.ID,
+		ChangeSeq: c.Seq,
+		Event:     EventName(c),
+	}
+	body, err := json.Marshal(Payload{ID: delivery.ID, Event: delivery.Event, NamespaceID: c.NamespaceID, Change: c})
+	if err != nil {
+		delivery.Status, delivery.Error = DeliveryFailed, err.Error()
+		return delivery, nil
+	}
+
+	wait := d.backoff
+	for delivery.Attempts < d.maxAttempts {
+		if delivery.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(wait):
+			}
+			wait *= 2
+		}
+		delivery.Attempts++
+
+		code, err := d.send(ctx, wh, delivery, body)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		delivery.ResponseCode = code
+		if err == nil {
+			delivery.Status, delivery.Error = DeliverySucceeded, ""
+			return delivery, nil
+		}
+		delivery.Status, delivery.Error = DeliveryFailed, err.Error()
+		if !retryable(code) {
+			break
+		}
+	}
+	return delivery, nil
+}
+
+func (d *Dispatcher) send(ctx context.Context, wh Webhook, delivery *Delivery, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "compass-webhooks")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(wh.Secret, ts, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed attempt is worth repeating. Transport
+// errors, timeouts, throttling and server errors are; other client errors
+// will not change on retry.
+func retryable(code int) bool {
+	switch {
+	case code == 0, code == http.StatusRequestTimeout, code == http.StatusTooManyRequests:
+		return true
+	case code >= 500:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,267 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/raystack/compass/core/change"
+	"github.com/raystack/compass/core/namespace"
+)
+
+type mockFeed struct {
+	changes []change.Change
+}
+
+func (m *mockFeed) List(_ context.Context, _ *namespace.Namespace, flt change.Filter) ([]change.Change, error) {
+	var result []change.Change
+	for _, c := range m.changes {
+		if c.Seq <= flt.SinceSeq {
+			continue
+		}
+		result = append(result, c)
+		if len(result) == flt.Limit {
+			break
+		}
+	}
+	return result, nil
+}
+
+type mockNamespaces struct{}
+
+func (mockNamespaces) List(context.Context) ([]*namespace.Namespace, error) {
+	return []*namespace.Namespace{namespace.DefaultNamespace}, nil
+}
+
+// receiver is a local stand-in for a webhook endpoint. It fails the first
+// failures requests and records the rest.
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	calls    int
+	payloads []Payload
+	headers  []http.Header
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.calls++
+	if rc.calls <= rc.failures {
+		w.WriteHeader(rc.status)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	var p Payload
+	_ = json.Unmarshal(body, &p)
+	rc.payloads = append(rc.payloads, p)
+	rc.headers = append(rc.headers, r.Header.Clone())
+
+	ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if !Verify("s3cret", ts, body, r.Header.Get(HeaderSignature)) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newTestService and newTestDispatcher allow private addresses, since the
+// test endpoints listen on loopback.
+func newTestService(repo *mockRepo) *Service {
+	svc := NewService(repo)
+	svc.WithPrivateNetworks(true)
+	return svc
+}
+
+func newTestDispatcher(repo *mockRepo, feed *mockFeed, opts ...Option) *Dispatcher {
+	opts = append([]Option{WithBackoff(time.Millisecond), WithMaxAttempts(3), WithPrivateNetworks(true)}, opts...)
+	return NewDispatcher(repo, feed, mockNamespaces{}, opts...)
+}
+
+// dispatchAndWait runs one dispatch pass and waits for its drains to finish.
+func dispatchAndWait(d *Dispatcher) {
+	d.dispatch(context.Background())
+	d.inflight.Wait()
+}
+
+func TestDispatcher_DeliversMatchingChanges(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	repo := newMockRepo()
+	wh, _ := newTestService(repo).Create(context.Background(), namespace.DefaultNamespace,
+		&Webhook{URL: srv.URL, Secret: "s3cret", Events: []string{"edge.deleted"}})
+
+	feed := &mockFeed{changes: []change.Change{
+		{Seq: 1, Kind: change.KindEntity, Op: change.OpUpdated, Key: "urn:a"},
+		{Seq: 2, Kind: change.KindEdge, Op: change.OpDeleted, Key: change.EdgeKey("urn:a", "lineage", "urn:b")},
+		{Seq: 3, Kind: change.KindEntity, Op: change.OpCreated, Key: "urn:c"},
+	}}
+	dispatchAndWait(newTestDispatcher(repo, feed))
+
+	if len(rc.payloads) != 1 || rc.payloads[0].Change.Seq != 2 || rc.payloads[0].Event != "edge.deleted" {
+		t.Fatalf("expected only the edge deletion, got %+v", rc.payloads)
+	}
+	h := rc.headers[0]
+	if h.Get(HeaderEvent) != "edge.deleted" || h.Get(HeaderDelivery) != rc.payloads[0].ID {
+		t.Errorf("unexpected headers %v", h)
+	}
+	if len(repo.deliveries) != 1 || repo.deliveries[0].Status != DeliverySucceeded || repo.deliveries[0].ResponseCode != http.StatusNoContent {
+		t.Errorf("expected one successful delivery, got %+v", repo.deliveries)
+	}
+	if got := repo.webhooks[wh.ID].LastSeq; got != 3 {
+		t.Errorf("expected cursor at 3, got %d", got)
+	}
+
+	// Nothing new: a second pass delivers nothing.
+	dispatchAndWait(newTestDispatcher(repo, feed))
+	if rc.calls != 1 {
+		t.Errorf("expected no redelivery, got %d calls", rc.calls)
+	}
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	rc := &receiver{failures: 2, status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	repo := newMockRepo()
+	_, _ = newTestService(repo).Create(context.Background(), namespace.DefaultNamespace,
+		&Webhook{URL: srv.URL, Secret: "s3cret"})
+	feed := &mockFeed{changes: []change.Change{{Seq: 1, Kind: change.KindEntity, Op: change.OpCreated, Key: "urn:a"}}}
+
+	dispatchAndWait(newTestDispatcher(repo, feed))
+
+	if len(repo.deliveries) != 1 {
+		t.Fatalf("expected one delivery, got %+v", repo.deliveries)
+	}
+	d := repo.deliveries[0]
+	if d.Status != DeliverySucceeded || d.Attempts != 3 {
+		t.Errorf("expected success on the third attempt, got %+v", d)
+	}
+}
+
+func TestDispatcher_LogsFailureAndMovesOn(t *testing.T) {
+	rc := &receiver{failures: 100, status: http.StatusInternalServerError}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	repo := newMockRepo()
+	wh, _ := newTestService(repo).Create(context.Background(), namespace.DefaultNamespace,
+		&Webhook{URL: srv.URL, Secret: "s3cret"})
+	feed := &mockFeed{changes: []change.Change{
+		{Seq: 1, Kind: change.KindEntity, Op: change.OpCreated, Key: "urn:a"},
+		{Seq: 2, Kind: change.KindEntity, Op: change.OpCreated, Key: "urn:b"},
+	}}
+
+	dispatchAndWait(newTestDispatcher(repo, feed))
+
+	if len(repo.deliveries) != 2 {
+		t.Fatalf("expected two deliveries, got %+v", repo.deliveries)
+	}
+	for _, d := range repo.deliveries {
+		if d.Status != DeliveryFailed || d.Attempts != 3 || d.ResponseCode != http.StatusInternalServerError || d.Error == "" {
+			t.Errorf("expected a logged failure after 3 attempts, got %+v", d)
+		}
+	}
+	if got := repo.webhooks[wh.ID].LastSeq; got != 2 {
+		t.Errorf("expected cursor past failed deliveries, got %d", got)
+	}
+}
+
+func TestDispatcher_DoesNotRetryClientErrors(t *testing.T) {
+	rc := &receiver{failures: 100, status: http.StatusGone}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	repo := newMockRepo()
+	_, _ = newTestService(repo).Create(context.Background(), namespace.DefaultNamespace,
+		&Webhook{URL: srv.URL, Secret: "s3cret"})
+	feed := &mockFeed{changes: []change.Change{{Seq: 1, Kind: change.KindEntity, Op: change.OpCreated, Key: "urn:a"}}}
+
+	dispatchAndWait(newTestDispatcher(repo, feed))
+
+	if len(repo.deliveries) != 1 || repo.deliveries[0].Attempts != 1 || repo.deliveries[0].Status != DeliveryFailed {
+		t.Errorf("expected a single failed attempt, got %+v", repo.deliveries)
+	}
+}
+
+func TestDispatcher_SlowWebhookDoesNotBlockOthers(t *testing.T) {
+	unblock := make(chan struct{})
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-unblock
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer slow.Close()
+	defer close(unblock)
+	rc := &receiver{}
+	fast := httptest.NewServer(rc)
+	defer fast.Close()
+
+	repo := newMockRepo()
+	svc := newTestService(repo)
+	_, _ = svc.Create(context.Background(), namespace.DefaultNamespace, &Webhook{URL: slow.URL, Secret: "s3cret"})
+	fastHook, _ := svc.Create(context.Background(), namespace.DefaultNamespace, &Webhook{URL: fast.URL, Secret: "s3cret"})
+
+	feed := &mockFeed{changes: []change.Change{
+		{Seq: 1, Kind: change.KindEntity, Op: change.OpCreated, Key: "urn:a"},
+		{Seq: 2, Kind: change.KindEntity, Op: change.OpCreated, Key: "urn:b"},
+	}}
+	d := newTestDispatcher(repo, feed)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		d.inflight.Wait()
+	}()
+
+	// Every pass serves the fast webhook, while the slow one stays on its
+	// first delivery and is not drained twice.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		d.dispatch(ctx)
+		wh, _ := repo.GetByID(ctx, namespace.DefaultNamespace, fastHook.ID)
+		if wh.LastSeq == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the fast webhook to reach seq 2 while the slow one blocks, got %d", wh.LastSeq)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if len(rc.payloads) != 2 {
+		t.Errorf("expected two deliveries to the fast webhook, got %+v", rc.payloads)
+	}
+}
+
+func TestDispatcher_RefusesPrivateAddresses(t *testing.T) {
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	// Registered while allowed, then delivered by a dispatcher that is not:
+	// the same happens to a public host name that resolves to loopback.
+	repo := newMockRepo()
+	_, _ = newTestService(repo).Create(context.Background(), namespace.DefaultNamespace,
+		&Webhook{URL: srv.URL, Secret: "s3cret"})
+	feed := &mockFeed{changes: []change.Change{{Seq: 1, Kind: change.KindEntity, Op: change.OpCreated, Key: "urn:a"}}}
+
+	dispatchAndWait(newTestDispatcher(repo, feed, WithPrivateNetworks(false)))
+
+	if rc.calls != 0 {
+		t.Errorf("expected no request to reach the endpoint, got %d", rc.calls)
+	}
+	if len(repo.deliveries) != 1 || repo.deliveries[0].Status != DeliveryFailed || !strings.Contains(repo.deliveries[0].Error, "private") {
+		t.Errorf("expected a failed delivery refused as private, got %+v", repo.deliveries)
+	}
+}
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range (RFC 6598), which is not
+// reachable from the internet either.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// privateAddr reports whether ip is an address a webhook must not reach:
+// loopback, private, link-local (which includes the cloud metadata endpoint
+// 169.254.169.254), shared, unspecified or multicast.
+func privateAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() || ip.IsMulticast() ||
+		ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// checkHost rejects webhook hosts that name a private address outright. Host
+// names are checked again at dial time, once they are resolved.
+func checkHost(host string) error {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: url must not point to a private address", ErrInvalid)
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && privateAddr(ip) {
+		return fmt.Errorf("%w: url must not point to a private address", ErrInvalid)
+	}
+	return nil
+}
+
+// denyPrivate is a dialer control that refuses connections to private
+// addresses. It runs after name resolution, so a host name that resolves (or
+// is rebound, or redirected) to a private address is refused as well.
+func denyPrivate(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if privateAddr(addrPort.Addr()) {
+		return fmt.Errorf("webhook address %s is private", addrPort.Addr())
+	}
+	return nil
+}
+
+// newClient returns the HTTP client deliveries are sent with. Unless
+// allowPrivate is set it cannot connect to private addresses.
+func newClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = denyPrivate
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// A proxy would dial the endpoint on our behalf, past the check.
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
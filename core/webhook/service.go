@@ -0,0 +1,151 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/raystack/compass/core/namespace"
+)
+
+const (
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 500
+)
+
+// Headers set on every delivery request.
+const (
+	HeaderSignature = "X-Compass-Signature"
+	HeaderTimestamp = "X-Compass-Timestamp"
+	HeaderEvent     = "X-Compass-Event"
+	HeaderDelivery  = "X-Compass-Delivery"
+)
+
+// Sign returns the signature header value for a delivery: the hex HMAC-SHA256
+// of "<timestamp>.<body>" keyed by the webhook secret, prefixed with "sha256=".
+// Including the timestamp lets receivers reject replayed deliveries.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is valid for the timestamp and body.
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// Service manages webhook registrations and their delivery logs.
+type Service struct {
+	repo         Repository
+	allowPrivate bool
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// WithPrivateNetworks lets webhooks point to loopback, private and link-local
+// addresses. Only enable it where every reachable internal service is trusted;
+// the dispatcher must be configured to match.
+func (s *Service) WithPrivateNetworks(allow bool) {
+	s.allowPrivate = allow
+}
+
+// Create registers a webhook. A secret is generated when none is given; it is
+// returned on the created webhook and never again.
+func (s *Service) Create(ctx context.Context, ns *namespace.Namespace, wh *Webhook) (*Webhook, error) {
+	if err := validate(wh, s.allowPrivate); err != nil {
+		return nil, err
+	}
+	if wh.Secret == "" {
+		secret, err := generateSecret()
+		if err != nil {
+			return nil, fmt.Errorf("generate secret: %w", err)
+		}
+		wh.Secret = secret
+	}
+
+	id, err := s.repo.Create(ctx, ns, wh)
+	if err != nil {
+		return nil, fmt.Errorf("create webhook: %w", err)
+	}
+	wh.ID = id
+	wh.NamespaceID = ns.ID.String()
+	return wh, nil
+}
+
+func (s *Service) GetByID(ctx context.Context, ns *namespace.Namespace, id string) (Webhook, error) {
+	wh, err := s.repo.GetByID(ctx, ns, id)
+	if err != nil {
+		return Webhook{}, err
+	}
+	wh.Secret = ""
+	return wh, nil
+}
+
+func (s *Service) List(ctx context.Context, ns *namespace.Namespace) ([]Webhook, error) {
+	webhooks, err := s.repo.List(ctx, ns)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (s *Service) Delete(ctx context.Context, ns *namespace.Namespace, id string) error {
+	return s.repo.Delete(ctx, ns, id)
+}
+
+// ListDeliveries returns a webhook's delivery log, newest first.
+func (s *Service) ListDeliveries(ctx context.Context, ns *namespace.Namespace, webhookID string, flt DeliveryFilter) ([]Delivery, error) {
+	if _, err := s.repo.GetByID(ctx, ns, webhookID); err != nil {
+		return nil, err
+	}
+	if flt.Limit <= 0 {
+		flt.Limit = defaultDeliveryLimit
+	}
+	if flt.Limit > maxDeliveryLimit {
+		flt.Limit = maxDeliveryLimit
+	}
+	deliveries, err := s.repo.ListDeliveries(ctx, ns, webhookID, flt)
+	if err != nil {
+		return nil, fmt.Errorf("list deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+func validate(wh *Webhook, allowPrivate bool) error {
+	u, err := url.Parse(wh.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalid)
+	}
+	if !allowPrivate {
+		if err := checkHost(u.Hostname()); err != nil {
+			return err
+		}
+	}
+	for _, e := range wh.Events {
+		if !validEvent(e) {
+			return fmt.Errorf("%w: event %q must be <entity|edge|document>.<created|updated|deleted|*> or schema.<additive|breaking|*>", ErrInvalid, e)
+		}
+	}
+	return nil
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,223 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/raystack/compass/core/change"
+	"github.com/raystack/compass/core/namespace"
+)
+
+type mockRepo struct {
+	mu         sync.Mutex
+	webhooks   map[string]*Webhook
+	deliveries []Delivery
+	head       int64
+}
+
+func newMockRepo() *mockRepo {
+	return &mockRepo{webhooks: map[string]*Webhook{}}
+}
+
+func (m *mockRepo) Create(_ context.Context, _ *namespace.Namespace, wh *Webhook) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored := *wh
+	stored.ID = uuid.NewString()
+	stored.LastSeq = m.head
+	m.webhooks[stored.ID] = &stored
+	return stored.ID, nil
+}
+
+func (m *mockRepo) GetByID(_ context.Context, _ *namespace.Namespace, id string) (Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	wh, ok := m.webhooks[id]
+	if !ok {
+		return Webhook{}, ErrNotFound
+	}
+	return *wh, nil
+}
+
+func (m *mockRepo) List(_ context.Context, _ *namespace.Namespace) ([]Webhook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []Webhook
+	for _, wh := range m.webhooks {
+		result = append(result, *wh)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+func (m *mockRepo) Delete(_ context.Context, _ *namespace.Namespace, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.webhooks[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.webhooks, id)
+	return nil
+}
+
+func (m *mockRepo) UpdateCursor(_ context.Context, _ *namespace.Namespace, id string, seq int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.webhooks[id].LastSeq = seq
+	return nil
+}
+
+func (m *mockRepo) InsertDelivery(_ context.Context, _ *namespace.Namespace, d *Delivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deliveries = append(m.deliveries, *d)
+	return nil
+}
+
+func (m *mockRepo) ListDeliveries(_ context.Context, _ *namespace.Namespace, webhookID string, flt DeliveryFilter) ([]Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var result []Delivery
+	for _, d := range m.deliveries {
+		if d.WebhookID == webhookID && (flt.Status == "" || d.Status == flt.Status) {
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+func TestService_Create(t *testing.T) {
+	repo := newMockRepo()
+	repo.head = 42
+	svc := NewService(repo)
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	wh, err := svc.Create(ctx, ns, &Webhook{URL: "https://ci.example.com/hook", Events: []string{"entity.updated", "edge.*", "schema.breaking"}})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if wh.ID == "" || len(wh.Secret) != 64 {
+		t.Errorf("expected an id and a generated secret, got %+v", wh)
+	}
+	if stored := repo.webhooks[wh.ID]; stored.LastSeq != 42 {
+		t.Errorf("expected cursor at feed head 42, got %d", stored.LastSeq)
+	}
+
+	got, err := svc.GetByID(ctx, ns, wh.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.Secret != "" {
+		t.Error("expected secret to be hidden after creation")
+	}
+}
+
+func TestService_CreateInvalid(t *testing.T) {
+	svc := NewService(newMockRepo())
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	for _, wh := range []*Webhook{
+		{URL: "ci.example.com/hook"},
+		{URL: "ftp://ci.example.com/hook"},
+		{URL: "https://ci.example.com/hook", Events: []string{"entity.renamed"}},
+		{URL: "https://ci.example.com/hook", Events: []string{"lineage"}},
+		{URL: "https://ci.example.com/hook", Events: []string{"schema.updated"}},
+	} {
+		if _, err := svc.Create(ctx, ns, wh); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%+v: expected ErrInvalid, got %v", wh, err)
+		}
+	}
+}
+
+func TestService_CreatePrivateAddress(t *testing.T) {
+	svc := NewService(newMockRepo())
+	ctx := context.Background()
+	ns := namespace.DefaultNamespace
+
+	urls := []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"http://10.0.0.5/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]:8080/hook",
+		"http://[::ffff:192.168.1.1]/hook",
+	}
+	for _, u := range urls {
+		if _, err := svc.Create(ctx, ns, &Webhook{URL: u}); !errors.Is(err, ErrInvalid) {
+			t.Errorf("%s: expected ErrInvalid, got %v", u, err)
+		}
+	}
+
+	svc.WithPrivateNetworks(true)
+	if _, err := svc.Create(ctx, ns, &Webhook{URL: urls[1]}); err != nil {
+		t.Errorf("expected private addresses to be allowed, got %v", err)
+	}
+}
+
+func TestService_ListDeliveries_UnknownWebhook(t *testing.T) {
+	svc := NewService(newMockRepo())
+	_, err := svc.ListDeliveries(context.Background(), namespace.DefaultNamespace, "missing", DeliveryFilter{})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestWebhook_Matches(t *testing.T) {
+	entityUpdated := change.Change{Kind: change.KindEntity, Op: change.OpUpdated, Key: "urn:bigquery:orders", Type: "table"}
+	edgeDeleted := change.Change{Kind: change.KindEdge, Op: change.OpDeleted, Key: change.EdgeKey("urn:kafka:events", "lineage", "urn:bigquery:orders"), Type: "lineage"}
+	docCreated := change.Change{Kind: change.KindDocument, Op: change.OpCreated, Key: "doc-1", Payload: map[string]interface{}{"entity_urn": "urn:bigquery:orders"}}
+	schemaBreaking := change.Change{Kind: change.KindSchema, Op: change.OpBreaking, Key: "urn:bigquery:orders", Type: "table"}
+
+	tests := []struct {
+		name string
+		wh   Webhook
+		c    change.Change
+		want bool
+	}{
+		{"no filters", Webhook{}, edgeDeleted, true},
+		{"exact event", Webhook{Events: []string{"entity.updated"}}, entityUpdated, true},
+		{"other event", Webhook{Events: []string{"entity.deleted"}}, entityUpdated, false},
+		{"kind wildcard", Webhook{Events: []string{"edge.*"}}, edgeDeleted, true},
+		{"entity type", Webhook{EntityTypes: []string{"table"}}, entityUpdated, true},
+		{"other entity type", Webhook{EntityTypes: []string{"topic"}}, entityUpdated, false},
+		{"entity type ignores edges", Webhook{EntityTypes: []string{"topic"}}, edgeDeleted, true},
+		{"prefix on entity", Webhook{URNPrefix: "urn:bigquery:"}, entityUpdated, true},
+		{"prefix on edge target", Webhook{URNPrefix: "urn:bigquery:"}, edgeDeleted, true},
+		{"prefix on document entity", Webhook{URNPrefix: "urn:bigquery:"}, docCreated, true},
+		{"prefix mismatch", Webhook{URNPrefix: "urn:postgres:"}, edgeDeleted, false},
+		{"schema event", Webhook{Events: []string{"schema.breaking"}, EntityTypes: []string{"table"}, URNPrefix: "urn:bigquery:"}, schemaBreaking, true},
+		{"schema entity type", Webhook{EntityTypes: []string{"topic"}}, schemaBreaking, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.wh.Matches(tt.c); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"entity.updated"}`)
+	sig := Sign("s3cret", 1700000000, body)
+	// echo -n '1700000000.{"event":"entity.updated"}' | openssl dgst -sha256 -hmac s3cret
+	want := "sha256=eb6687cccdc30c75a3f26762ab0853f2f1aacb5d9404e83b1a55af0677d1fd5f"
+	if sig != want {
+		t.Fatalf("expected %s, got %s", want, sig)
+	}
+	if !Verify("s3cret", 1700000000, body, sig) {
+		t.Error("expected signature to verify")
+	}
+	if Verify("s3cret", 1700000001, body, sig) {
+		t.Error("expected a different timestamp to fail verification")
+	}
+	if Verify("other", 1700000000, body, sig) {
+		t.Error("expected a different secret to fail verification")
+	}
+}
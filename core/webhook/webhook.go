@@ -0,0 +1,163 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/raystack/compass/core/change"
+	"github.com/raystack/compass/core/namespace"
+)
+
+var (
+	ErrNotFound = errors.New("webhook not found")
+	// ErrInvalid is returned when a webhook registration fails validation.
+	ErrInvalid = errors.New("invalid webhook")
+)
+
+// DeliveryStatus is the outcome of delivering one change to one webhook.
+type DeliveryStatus string
+
+const (
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Webhook is an HTTP endpoint registered by a namespace to receive changes.
+//
+// Events lists "kind.op" names such as "entity.updated", "edge.deleted" or
+// "schema.breaking"; "kind.*" subscribes to every op of a kind and an empty
+// list to everything. EntityTypes narrows entity and schema changes to the
+// given entity types. URNPrefix
+// narrows every change to those touching a URN with the prefix: the entity
+// itself, either end of an edge, or the entity a document is attached to.
+type Webhook struct {
+	ID          string    `json:"id"`
+	NamespaceID string    `json:"namespace_id"`
+	URL         string    `json:"url"`
+	Secret      string    `json:"secret,omitempty"`
+	Events      []string  `json:"events,omitempty"`
+	EntityTypes []string  `json:"entity_types,omitempty"`
+	URNPrefix   string    `json:"urn_prefix,omitempty"`
+	LastSeq     int64     `json:"last_seq"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Delivery is one entry of a webhook's delivery log.
+type Delivery struct {
+	ID           string         `json:"id"`
+	WebhookID    string         `json:"webhook_id"`
+	ChangeSeq    int64          `json:"change_seq"`
+	Event        string         `json:"event"`
+	Status       DeliveryStatus `json:"status"`
+	Attempts     int            `json:"attempts"`
+	ResponseCode int            `json:"response_code,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// DeliveryFilter for reading a webhook's delivery log.
+type DeliveryFilter struct {
+	Status DeliveryStatus
+	Limit  int
+}
+
+// Repository defines storage operations for webhooks and their deliveries.
+type Repository interface {
+	// Create stores the webhook with its cursor at the current head of the
+	// change feed, so it only receives changes made after registration.
+	Create(ctx context.Context, ns *namespace.Namespace, wh *Webhook) (string, error)
+	GetByID(ctx context.Context, ns *namespace.Namespace, id string) (Webhook, error)
+	List(ctx context.Context, ns *namespace.Namespace) ([]Webhook, error)
+	Delete(ctx context.Context, ns *namespace.Namespace, id string) error
+	UpdateCursor(ctx context.Context, ns *namespace.Namespace, id string, seq int64) error
+	InsertDelivery(ctx context.Context, ns *namespace.Namespace, d *Delivery) error
+	ListDeliveries(ctx context.Context, ns *namespace.Namespace, webhookID string, flt DeliveryFilter) ([]Delivery, error)
+}
+
+// EventName returns the "kind.op" event name of a change.
+func EventName(c change.Change) string {
+	return string(c.Kind) + "." + string(c.Op)
+}
+
+// Matches reports whether the webhook is subscribed to the change.
+func (w Webhook) Matches(c change.Change) bool {
+	if !w.subscribed(EventName(c)) {
+		return false
+	}
+	if len(w.EntityTypes) > 0 && (c.Kind == change.KindEntity || c.Kind == change.KindSchema) && !contains(w.EntityTypes, c.Type) {
+		return false
+	}
+	if w.URNPrefix != "" {
+		for _, urn := range changeURNs(c) {
+			if strings.HasPrefix(urn, w.URNPrefix) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func (w Webhook) subscribed(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	kind, _, _ := strings.Cut(event, ".")
+	for _, e := range w.Events {
+		if e == event || e == kind+".*" {
+			return true
+		}
+	}
+	return false
+}
+
+// changeURNs returns the entity URNs a change touches.
+func changeURNs(c change.Change) []string {
+	switch c.Kind {
+	case change.KindEntity, change.KindSchema:
+		return []string{c.Key}
+	case change.KindEdge:
+		parts := strings.SplitN(c.Key, "|", 3)
+		if len(parts) == 3 {
+			return []string{parts[0], parts[2]}
+		}
+	case change.KindDocument:
+		if urn, ok := c.Payload["entity_urn"].(string); ok {
+			return []string{urn}
+		}
+	}
+	return nil
+}
+
+// validEvent reports whether name is a "kind.op" or "kind.*" event name.
+func validEvent(name string) bool {
+	kind, op, ok := strings.Cut(name, ".")
+	if !ok {
+		return false
+	}
+	switch change.Kind(kind) {
+	case change.KindEntity, change.KindEdge, change.KindDocument:
+		switch change.Op(op) {
+		case change.OpCreated, change.OpUpdated, change.OpDeleted, "*":
+			return true
+		}
+	case change.KindSchema:
+		switch change.Op(op) {
+		case change.OpAdditive, change.OpBreaking, "*":
+			return true
+		}
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
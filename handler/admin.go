@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/raystack/compass/core/principal"
+)
+
+// AdminPolicy decides who may run administrative operations: purging,
+// merging and restoring entities. Admins are listed by principal subject.
+// With none listed every request may, so a server without configuration
+// stays usable; the server warns about that at startup.
+type AdminPolicy struct {
+	subjects map[string]bool
+}
+
+func NewAdminPolicy(subjects []string) *AdminPolicy {
+	p := &AdminPolicy{subjects: make(map[string]bool, len(subjects))}
+	for _, s := range subjects {
+		if s != "" {
+			p.subjects[s] = true
+		}
+	}
+	return p
+}
+
+// Open reports whether no admins are listed, so anyone may run
+// administrative operations.
+func (p *AdminPolicy) Open() bool {
+	return p == nil || len(p.subjects) == 0
+}
+
+// Require wraps an administrative route. Unless the policy is open, requests
+// without a principal are refused with 401 and those from anyone but an
+// admin with 403.
+func (p *AdminPolicy) Require(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if p.Open() {
+			next(w, r)
+			return
+		}
+		subject := principal.FromContext(r.Context()).Subject
+		if subject == "" {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "authentication required"})
+			return
+		}
+		if !p.subjects[subject] {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "admin access required"})
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/raystack/compass/core/batch"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/urn"
+	"github.com/raystack/compass/internal/middleware"
+)
+
+// BatchService defines the transactional batch operations served over HTTP.
+type BatchService interface {
+	Commit(ctx context.Context, ns *namespace.Namespace, ops []batch.Operation) ([]batch.Result, error)
+}
+
+// BatchHandler applies mixed batches of writes atomically.
+type BatchHandler struct {
+	service BatchService
+}
+
+func NewBatchHandler(service BatchService) *BatchHandler {
+	return &BatchHandler{service: service}
+}
+
+// RegisterRoutes registers batch HTTP routes on the mux. POST /v1/commit is
+// what a Commit RPC would serve; that RPC waits on a change to the
+// CompassService proto in raystack/proton.
+func (h *BatchHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /v1/commit", h.commit)
+}
+
+func (h *BatchHandler) commit(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	var req struct {
+		Operations []batch.Operation `json:"operations"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	// Property sources are derived on write, never taken from a client.
+	for _, op := range req.Operations {
+		if op.UpsertEntity != nil {
+			op.UpsertEntity.PropertySources = nil
+		}
+	}
+
+	results, err := h.service.Commit(r.Context(), ns, req.Operations)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, batch.ErrInvalid), errors.Is(err, entity.ErrEdgeConstraint), errors.Is(err, urn.ErrInvalid), errors.Is(err, entity.ErrInvalidPropertyPolicy):
+			status = http.StatusBadRequest
+		case errors.Is(err, sql.ErrNoRows):
+			status = http.StatusNotFound
+		}
+		body := map[string]interface{}{"error": err.Error()}
+		var opErr *batch.OpError
+		if errors.As(err, &opErr) {
+			body["index"] = opErr.Index
+		}
+		writeJSON(w, status, body)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": results})
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/internal/middleware"
+)
+
+const (
+	defaultBulkBatchSize = 500
+	maxBulkBatchSize     = 5000
+	maxBulkLineBytes     = 16 << 20
+)
+
+// BulkService defines the bulk write operations served over HTTP.
+type BulkService interface {
+	BulkUpsert(ctx context.Context, ns *namespace.Namespace, offset int, records []entity.BulkRecord) []entity.BulkResult
+	BulkCheck(ctx context.Context, ns *namespace.Namespace, offset int, records []entity.BulkRecord) []entity.BulkResult
+	StartSync(ns *namespace.Namespace, source, scope string) (*entity.Sync, error)
+}
+
+// BulkHandler ingests streams of entity and edge records.
+type BulkHandler struct {
+	service BulkService
+}
+
+func NewBulkHandler(service BulkService) *BulkHandler {
+	return &BulkHandler{service: service}
+}
+
+// RegisterRoutes registers bulk HTTP routes on the mux. A streamed request
+// body replaces the client-streaming BulkUpsert RPC, which the CompassService
+// proto in raystack/proton does not declare yet.
+func (h *BulkHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /v1/bulk", h.upsert)
+	mux.HandleFunc("POST /v1/sync", h.sync)
+}
+
+// upsert writes the streamed records. With dry_run=true, nothing is written
+// and each result reports what the write would have done.
+func (h *BulkHandler) upsert(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+	write := h.service.BulkUpsert
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dryRun {
+		write = h.service.BulkCheck
+	}
+	offset := 0
+	streamRecords(w, r, func(records []entity.BulkRecord) []entity.BulkResult {
+		results := write(r.Context(), ns, offset, records)
+		offset += len(records)
+		return results
+	})
+}
+
+// sync writes a complete snapshot of the source and scope given as query
+// parameters, then soft-deletes what the snapshot left out. A summary line
+// follows the per-record results.
+func (h *BulkHandler) sync(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+	q := r.URL.Query()
+	s, err := h.service.StartSync(ns, q.Get("source"), q.Get("scope"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	enc, ok := streamRecords(w, r, func(records []entity.BulkRecord) []entity.BulkResult {
+		return s.Write(r.Context(), records)
+	})
+	if !ok {
+		return
+	}
+	summary, err := s.Finish(r.Context())
+	line := map[string]interface{}{"summary": summary}
+	if err != nil {
+		if !errors.Is(err, entity.ErrSyncIncomplete) {
+			slog.WarnContext(r.Context(), "sync: prune snapshot", "source", summary.Source, "scope", summary.Scope, "error", err)
+		}
+		line["error"] = err.Error()
+	}
+	_ = enc.Encode(line)
+}
+
+// streamRecords reads newline-delimited records, each {"entity": {...}} or
+// {"edge": {...}}, and hands them to write in batches of batch_size. One
+// result per record is streamed back as newline-delimited JSON as each batch
+// commits, so clients can send more records than fit in memory. It reports
+// whether the whole request was read and answered.
+func streamRecords(w http.ResponseWriter, r *http.Request, write func([]entity.BulkRecord) []entity.BulkResult) (*json.Encoder, bool) {
+	batchSize := defaultBulkBatchSize
+	if v := r.URL.Query().Get("batch_size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxBulkBatchSize {
+			writeJSON(w, http.StatusBadRequest, map[string]string{
+				"error": fmt.Sprintf("batch_size must be between 1 and %d", maxBulkBatchSize),
+			})
+			return nil, false
+		}
+		batchSize = n
+	}
+
+	// Results are written while the request is still being read, and a
+	// large ingest outlives the server's timeouts.
+	rc := http.NewResponseController(w)
+	_ = rc.EnableFullDuplex()
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+
+	var (
+		records     []entity.BulkRecord
+		parseErrs   = map[int]error{}
+		offset      int
+		writeFailed bool
+	)
+	flush := func() {
+		results := write(records)
+		// Unparseable lines went in as empty records to keep their index.
+		for i, err := range parseErrs {
+			results[i].Error = "invalid json: " + err.Error()
+		}
+		offset += len(records)
+		records, parseErrs = records[:0], map[int]error{}
+		for _, res := range results {
+			if err := enc.Encode(res); err != nil {
+				writeFailed = true
+				return
+			}
+		}
+		_ = rc.Flush()
+	}
+
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxBulkLineBytes)
+	for !writeFailed && scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var rec entity.BulkRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			rec = entity.BulkRecord{}
+			parseErrs[len(records)] = err
+		}
+		// Property sources are derived on write, never taken from a client.
+		if rec.Entity != nil {
+			rec.Entity.PropertySources = nil
+		}
+		records = append(records, rec)
+		if len(records) >= batchSize {
+			flush()
+		}
+	}
+	if writeFailed {
+		return enc, false
+	}
+	if len(records) > 0 {
+		flush()
+	}
+	if err := scanner.Err(); err != nil {
+		slog.WarnContext(r.Context(), "bulk: read request", "error", err)
+		_ = enc.Encode(entity.BulkResult{Index: offset, Status: entity.UpsertFailed, Error: "read request: " + err.Error()})
+		return enc, false
+	}
+	return enc, !writeFailed
+}
@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/raystack/compass/core/change"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/internal/middleware"
+)
+
+// ChangeService defines the change feed operations served over HTTP.
+type ChangeService interface {
+	List(ctx context.Context, ns *namespace.Namespace, flt change.Filter) ([]change.Change, error)
+	Watch(ctx context.Context, ns *namespace.Namespace, flt change.Filter, fn func(change.Change) error) error
+}
+
+// ChangeHandler serves the change feed for polling and streaming consumers.
+type ChangeHandler struct {
+	service ChangeService
+}
+
+func NewChangeHandler(service ChangeService) *ChangeHandler {
+	return &ChangeHandler{service: service}
+}
+
+// RegisterRoutes registers change feed HTTP routes on the mux. They take
+// the place of the GetChanges and WatchChanges RPCs, which CompassService
+// lacks until its proto in raystack/proton declares them; the watch route
+// streams newline-delimited JSON where the RPC would stream messages.
+func (h *ChangeHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/changes", h.list)
+	mux.HandleFunc("GET /v1/changes/watch", h.watch)
+}
+
+func (h *ChangeHandler) list(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+	flt, err := parseChangeFilter(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	changes, err := h.service.List(r.Context(), ns, flt)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	// next_seq lets pollers resume without tracking the last record themselves.
+	next := flt.SinceSeq
+	if len(changes) > 0 {
+		next = changes[len(changes)-1].Seq
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": changes, "next_seq": next})
+}
+
+// watch streams change records as newline-delimited JSON until the client
+// disconnects. Clients resume after a disconnect with since_seq set to the
+// last seq they processed.
+func (h *ChangeHandler) watch(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+	flt, err := parseChangeFilter(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	// The stream is long-lived; lift the server's write timeout for it.
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	enc := json.NewEncoder(w)
+	err = h.service.Watch(r.Context(), ns, flt, func(c change.Change) error {
+		if err := enc.Encode(c); err != nil {
+			return err
+		}
+		return rc.Flush()
+	})
+	if err != nil && !errors.Is(err, context.Canceled) {
+		slog.WarnContext(r.Context(), "change feed watch ended", "error", err)
+	}
+}
+
+func parseChangeFilter(r *http.Request) (change.Filter, error) {
+	q := r.URL.Query()
+	var flt change.Filter
+	if v := q.Get("since_seq"); v != "" {
+		seq, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || seq < 0 {
+			return flt, errors.New("since_seq must be a non-negative integer")
+		}
+		flt.SinceSeq = seq
+	}
+	if v := q.Get("kinds"); v != "" {
+		for _, k := range strings.Split(v, ",") {
+			kind := change.Kind(strings.TrimSpace(k))
+			switch kind {
+			case change.KindEntity, change.KindEdge, change.KindDocument, change.KindSchema:
+				flt.Kinds = append(flt.Kinds, kind)
+			default:
+				return flt, errors.New("kinds must be a comma-separated list of entity, edge, document, schema")
+			}
+		}
+	}
+	if v := q.Get("types"); v != "" {
+		for _, t := range strings.Split(v, ",") {
+			flt.Types = append(flt.Types, strings.TrimSpace(t))
+		}
+	}
+	flt.Limit, _ = strconv.Atoi(q.Get("limit"))
+	return flt, nil
+}
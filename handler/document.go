@@ -3,7 +3,9 @@ package handler
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/raystack/compass/core/document"
 	"github.com/raystack/compass/core/namespace"
@@ -17,6 +19,7 @@ type DocumentService interface {
 	GetByEntityURN(ctx context.Context, ns *namespace.Namespace, entityURN string) ([]document.Document, error)
 	GetAll(ctx context.Context, ns *namespace.Namespace, filter document.Filter) ([]document.Document, error)
 	Delete(ctx context.Context, ns *namespace.Namespace, id string) error
+	Sync(ctx context.Context, ns *namespace.Namespace, source string, docs []document.Document, prune bool) (document.SyncSummary, error)
 }
 
 // DocumentHandler handles HTTP requests for document CRUD.
@@ -31,6 +34,7 @@ func NewDocumentHandler(service DocumentService) *DocumentHandler {
 // RegisterRoutes registers document HTTP routes on the mux.
 func (h *DocumentHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /v1/documents", h.upsert)
+	mux.HandleFunc("POST /v1/documents/sync", h.sync)
 	mux.HandleFunc("GET /v1/documents", h.list)
 	mux.HandleFunc("GET /v1/documents/{id}", h.get)
 	mux.HandleFunc("DELETE /v1/documents/{id}", h.delete)
@@ -69,6 +73,10 @@ func (h *DocumentHandler) upsert(w http.ResponseWriter, r *http.Request) {
 	}
 
 	id, err := h.service.Upsert(r.Context(), ns, doc)
+	if errors.Is(err, document.ErrInvalidFormat) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -77,6 +85,56 @@ func (h *DocumentHandler) upsert(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"id": id})
 }
 
+// sync makes the documents of a source match the posted set and, with prune,
+// deletes the documents of the source missing from it.
+func (h *DocumentHandler) sync(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	var req struct {
+		Source    string `json:"source"`
+		Prune     bool   `json:"prune"`
+		Documents []struct {
+			EntityURN  string                 `json:"entity_urn"`
+			Title      string                 `json:"title"`
+			Body       string                 `json:"body"`
+			Format     string                 `json:"format,omitempty"`
+			SourceID   string                 `json:"source_id"`
+			Properties map[string]interface{} `json:"properties,omitempty"`
+		} `json:"documents"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if req.Source == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "source is required"})
+		return
+	}
+
+	docs := make([]document.Document, len(req.Documents))
+	for i, d := range req.Documents {
+		docs[i] = document.Document{
+			EntityURN:  d.EntityURN,
+			Title:      d.Title,
+			Body:       d.Body,
+			Format:     d.Format,
+			SourceID:   d.SourceID,
+			Properties: d.Properties,
+		}
+	}
+
+	summary, err := h.service.Sync(r.Context(), ns, req.Source, docs, req.Prune)
+	if err != nil && !errors.Is(err, document.ErrSyncIncomplete) {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	res := map[string]interface{}{"summary": summary}
+	if err != nil {
+		res["error"] = err.Error()
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
 func (h *DocumentHandler) get(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	if id == "" {
@@ -100,6 +158,8 @@ func (h *DocumentHandler) list(w http.ResponseWriter, r *http.Request) {
 		EntityURN: r.URL.Query().Get("entity_urn"),
 		Source:    r.URL.Query().Get("source"),
 	}
+	filter.Size, _ = strconv.Atoi(r.URL.Query().Get("size"))
+	filter.Offset, _ = strconv.Atoi(r.URL.Query().Get("offset"))
 
 	docs, err := h.service.GetAll(r.Context(), ns, filter)
 	if err != nil {
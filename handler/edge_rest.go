@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/internal/middleware"
+)
+
+// EdgeLister lists the edges of a namespace page by page.
+type EdgeLister interface {
+	GetAll(ctx context.Context, ns *namespace.Namespace, filter entity.EdgeFilter, size, offset int) ([]entity.Edge, error)
+}
+
+// EdgeHandler serves edge HTTP routes that are not part of the RPC API.
+type EdgeHandler struct {
+	edges EdgeLister
+}
+
+func NewEdgeHandler(edges EdgeLister) *EdgeHandler {
+	return &EdgeHandler{edges: edges}
+}
+
+// RegisterRoutes registers edge HTTP routes on the mux.
+func (h *EdgeHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/edges", h.list)
+}
+
+// list returns current edges, or the edges live at as_of, ordered by source,
+// type and target so that pages are stable.
+func (h *EdgeHandler) list(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	q := r.URL.Query()
+	filter := entity.EdgeFilter{Current: true, AsOf: asOf}
+	if types := q.Get("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			filter.Types = append(filter.Types, strings.TrimSpace(t))
+		}
+	}
+	size, _ := strconv.Atoi(q.Get("size"))
+	offset, _ := strconv.Atoi(q.Get("offset"))
+
+	edges, err := h.edges.GetAll(r.Context(), ns, filter, size, offset)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": edges})
+}
@@ -2,12 +2,14 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"strings"
 
 	"connectrpc.com/connect"
 	"github.com/raystack/compass/internal/middleware"
 	"github.com/raystack/compass/core/entity"
 	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/urn"
 	compassv1beta1 "github.com/raystack/compass/gen/raystack/compass/v1beta1"
 	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
@@ -19,7 +21,7 @@ type EntityServiceV2 interface {
 	GetByURN(ctx context.Context, ns *namespace.Namespace, urn string) (entity.Entity, error)
 	GetByID(ctx context.Context, id string) (entity.Entity, error)
 	GetAll(ctx context.Context, ns *namespace.Namespace, flt entity.Filter) ([]entity.Entity, int, error)
-	GetTypes(ctx context.Context, ns *namespace.Namespace) (map[entity.Type]int, error)
+	GetEntityTypes(ctx context.Context, ns *namespace.Namespace) ([]entity.TypeSummary, error)
 	Delete(ctx context.Context, ns *namespace.Namespace, urn string) error
 	Search(ctx context.Context, cfg entity.SearchConfig) ([]entity.SearchResult, error)
 	Suggest(ctx context.Context, ns *namespace.Namespace, text string, limit int) ([]string, error)
@@ -95,6 +97,9 @@ func (server *Handler) UpsertEntity(ctx context.Context, req *connect.Request[co
 
 	id, err := server.entityService.Upsert(ctx, ns, ent)
 	if err != nil {
+		if errors.Is(err, entity.ErrTypeViolation) || errors.Is(err, urn.ErrInvalid) || errors.Is(err, entity.ErrInvalidPropertyPolicy) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
 		return nil, internalServerError(ctx, "error upserting entity", err)
 	}
 
@@ -162,14 +167,14 @@ func (server *Handler) SuggestEntities(ctx context.Context, req *connect.Request
 func (server *Handler) GetEntityTypes(ctx context.Context, _ *connect.Request[compassv1beta1.GetEntityTypesRequest]) (*connect.Response[compassv1beta1.GetEntityTypesResponse], error) {
 	ns := middleware.FetchNamespaceFromContext(ctx)
 
-	types, err := server.entityService.GetTypes(ctx, ns)
+	types, err := server.entityService.GetEntityTypes(ctx, ns)
 	if err != nil {
 		return nil, internalServerError(ctx, "error getting entity types", err)
 	}
 
 	data := make([]*compassv1beta1.Type, 0, len(types))
-	for t, count := range types {
-		data = append(data, &compassv1beta1.Type{Name: t.String(), Count: uint32(count)})
+	for _, t := range types {
+		data = append(data, &compassv1beta1.Type{Name: t.Name.String(), Count: uint32(t.Count)})
 	}
 	return connect.NewResponse(&compassv1beta1.GetEntityTypesResponse{Data: data}), nil
 }
@@ -226,8 +231,14 @@ func (server *Handler) UpsertEdge(ctx context.Context, req *connect.Request[comp
 	if req.Msg.GetProperties() != nil {
 		e.Properties = req.Msg.GetProperties().AsMap()
 	}
+	if err := entity.ValidateEdge(*e); err != nil {
+		return nil, connect.NewError(connect.CodeInvalidArgument, err)
+	}
 
 	if err := server.edgeService.Upsert(ctx, ns, e); err != nil {
+		if errors.Is(err, entity.ErrEdgeConstraint) || errors.Is(err, urn.ErrInvalid) {
+			return nil, connect.NewError(connect.CodeInvalidArgument, err)
+		}
 		return nil, internalServerError(ctx, "error upserting edge", err)
 	}
 	return connect.NewResponse(&compassv1beta1.UpsertEdgeResponse{Id: e.ID}), nil
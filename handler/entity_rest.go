@@ -0,0 +1,402 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/internal/middleware"
+)
+
+// EntityService defines the entity operations served over plain HTTP.
+// These complement the Connect RPCs for features not yet in the proto.
+type EntityService interface {
+	GetAll(ctx context.Context, ns *namespace.Namespace, flt entity.Filter) ([]entity.Entity, int, error)
+	GetByURN(ctx context.Context, ns *namespace.Namespace, urn string) (entity.Entity, error)
+	GetByURNAsOf(ctx context.Context, ns *namespace.Namespace, urn string, asOf time.Time) (entity.Entity, error)
+	GetHistory(ctx context.Context, ns *namespace.Namespace, urn string) ([]entity.Entity, error)
+	DiffEntity(ctx context.Context, ns *namespace.Namespace, urn string, from, to time.Time) (*entity.EntityDiff, error)
+	CheckSchemaChange(ctx context.Context, ns *namespace.Namespace, ent entity.Entity) (*entity.SchemaImpact, error)
+	GetContext(ctx context.Context, ns *namespace.Namespace, urn string, depth int) (*entity.ContextGraph, error)
+	GetContextAsOf(ctx context.Context, ns *namespace.Namespace, urn string, depth int, asOf time.Time) (*entity.ContextGraph, error)
+	GetImpact(ctx context.Context, ns *namespace.Namespace, urn string, depth int) ([]entity.Edge, error)
+	GetImpactAsOf(ctx context.Context, ns *namespace.Namespace, urn string, depth int, asOf time.Time) ([]entity.Edge, error)
+	GetImpactGraph(ctx context.Context, ns *namespace.Namespace, urn string, depth int) (*entity.ContextGraph, error)
+	GetImpactGraphAsOf(ctx context.Context, ns *namespace.Namespace, urn string, depth int, asOf time.Time) (*entity.ContextGraph, error)
+	RestoreEntity(ctx context.Context, ns *namespace.Namespace, urn string) (entity.Entity, []entity.Edge, error)
+	MergeEntities(ctx context.Context, ns *namespace.Namespace, from, into string) (entity.MergeResult, error)
+	GetPropertyProvenance(ctx context.Context, ns *namespace.Namespace, urn string) ([]entity.PropertyProvenance, error)
+}
+
+// EntityHandler handles HTTP requests for entity features outside the Connect API.
+type EntityHandler struct {
+	service EntityService
+	admins  *AdminPolicy
+}
+
+func NewEntityHandler(service EntityService, admins *AdminPolicy) *EntityHandler {
+	return &EntityHandler{service: service, admins: admins}
+}
+
+// RegisterRoutes registers entity HTTP routes on the mux.
+// Read routes accept an optional as_of (RFC 3339) query parameter to
+// reconstruct the graph as it stood at that moment. The context and impact
+// routes accept an optional format (dot, mermaid or graphml) to render the
+// subgraph instead of returning JSON; the GetEntityContext and
+// GetEntityImpact RPCs have no format field until their requests in the
+// raystack/proton proto gain one. Restoring and merging entities are
+// restricted to admins.
+func (h *EntityHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /v1/entities", h.list)
+	mux.HandleFunc("POST /v1/entities/schema-check", h.schemaCheck)
+	mux.HandleFunc("GET /v1/entities/{urn}", h.get)
+	// Stands in for a GetEntityHistory RPC, which needs a change to the
+	// CompassService proto in raystack/proton.
+	mux.HandleFunc("GET /v1/entities/{urn}/history", h.history)
+	mux.HandleFunc("GET /v1/entities/{urn}/properties", h.properties)
+	mux.HandleFunc("GET /v1/entities/{urn}/diff", h.diff)
+	mux.HandleFunc("GET /v1/entities/{urn}/context", h.contextGraph)
+	mux.HandleFunc("GET /v1/entities/{urn}/impact", h.impact)
+	mux.HandleFunc("POST /v1/entities/{urn}/restore", h.admins.Require(h.restore))
+	mux.HandleFunc("POST /v1/entities/{urn}/merge", h.admins.Require(h.merge))
+}
+
+func (h *EntityHandler) list(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	q := r.URL.Query()
+	flt := entity.Filter{
+		Source: q.Get("source"),
+		Query:  q.Get("q"),
+		AsOf:   asOf,
+	}
+	if types := q.Get("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			flt.Types = append(flt.Types, entity.Type(strings.TrimSpace(t)))
+		}
+	}
+	flt.Size, _ = strconv.Atoi(q.Get("size"))
+	flt.Offset, _ = strconv.Atoi(q.Get("offset"))
+
+	entities, total, err := h.service.GetAll(r.Context(), ns, flt)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": entities, "total": total})
+}
+
+func (h *EntityHandler) get(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+	urn := r.PathValue("urn")
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var ent entity.Entity
+	if asOf != nil {
+		ent, err = h.service.GetByURNAsOf(r.Context(), ns, urn, *asOf)
+	} else {
+		ent, err = h.service.GetByURN(r.Context(), ns, urn)
+	}
+	if err != nil {
+		writeEntityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ent)
+}
+
+// properties lists the properties of an entity with the sources that
+// contributed each value.
+func (h *EntityHandler) properties(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	props, err := h.service.GetPropertyProvenance(r.Context(), ns, r.PathValue("urn"))
+	if err != nil {
+		writeEntityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": props})
+}
+
+func (h *EntityHandler) history(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+	urn := r.PathValue("urn")
+	if urn == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "urn is required"})
+		return
+	}
+
+	versions, err := h.service.GetHistory(r.Context(), ns, urn)
+	if err != nil {
+		writeEntityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": versions})
+}
+
+func (h *EntityHandler) diff(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+	urn := r.PathValue("urn")
+
+	// Missing bounds are passed as zero times: current version vs. its predecessor.
+	var from, to time.Time
+	if t, err := parseTimeParam(r, "from"); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	} else if t != nil {
+		from = *t
+	}
+	if t, err := parseTimeParam(r, "to"); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	} else if t != nil {
+		to = *t
+	}
+
+	d, err := h.service.DiffEntity(r.Context(), ns, urn, from, to)
+	if err != nil {
+		if errors.Is(err, entity.ErrVersionNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+			return
+		}
+		writeEntityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, d)
+}
+
+// schemaCheck classifies the schema change an upsert of the posted entity
+// would make and lists the downstream entities a breaking change reaches.
+func (h *EntityHandler) schemaCheck(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	var req struct {
+		URN        string                 `json:"urn"`
+		Type       string                 `json:"type"`
+		Name       string                 `json:"name"`
+		Properties map[string]interface{} `json:"properties,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if req.URN == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "urn is required"})
+		return
+	}
+
+	impact, err := h.service.CheckSchemaChange(r.Context(), ns, entity.Entity{
+		URN:        req.URN,
+		Type:       entity.Type(req.Type),
+		Name:       req.Name,
+		Properties: req.Properties,
+	})
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, impact)
+}
+
+func (h *EntityHandler) contextGraph(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+	urn := r.PathValue("urn")
+	depth, _ := strconv.Atoi(r.URL.Query().Get("depth"))
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	format, err := parseGraphFormat(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	var cg *entity.ContextGraph
+	if asOf != nil {
+		cg, err = h.service.GetContextAsOf(r.Context(), ns, urn, depth, *asOf)
+	} else {
+		cg, err = h.service.GetContext(r.Context(), ns, urn, depth)
+	}
+	if err != nil {
+		writeEntityError(w, err)
+		return
+	}
+
+	if format != "" {
+		writeGraph(w, cg, format)
+		return
+	}
+	writeJSON(w, http.StatusOK, cg)
+}
+
+// impact serves the downstream impact of an entity. With a column query
+// parameter it serves the impact of that column of the entity.
+func (h *EntityHandler) impact(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+	urn := r.PathValue("urn")
+	if column := r.URL.Query().Get("column"); column != "" {
+		urn = entity.ColumnURN(urn, column)
+	}
+	depth, _ := strconv.Atoi(r.URL.Query().Get("depth"))
+	asOf, err := parseAsOf(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	format, err := parseGraphFormat(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	// A rendered impact needs the entities the edges reach for its labels.
+	if format != "" {
+		var cg *entity.ContextGraph
+		if asOf != nil {
+			cg, err = h.service.GetImpactGraphAsOf(r.Context(), ns, urn, depth, *asOf)
+		} else {
+			cg, err = h.service.GetImpactGraph(r.Context(), ns, urn, depth)
+		}
+		if err != nil {
+			writeEntityError(w, err)
+			return
+		}
+		writeGraph(w, cg, format)
+		return
+	}
+
+	var edges []entity.Edge
+	if asOf != nil {
+		edges, err = h.service.GetImpactAsOf(r.Context(), ns, urn, depth, *asOf)
+	} else {
+		edges, err = h.service.GetImpact(r.Context(), ns, urn, depth)
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": edges})
+}
+
+// restore brings back a deleted entity and the edges deleted with it.
+func (h *EntityHandler) restore(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+	urn := r.PathValue("urn")
+
+	ent, edges, err := h.service.RestoreEntity(r.Context(), ns, urn)
+	if err != nil {
+		if errors.Is(err, entity.ErrNotDeleted) {
+			writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+			return
+		}
+		writeEntityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"entity": ent, "edges": edges})
+}
+
+// merge merges the entity into the entity named by into, leaving its URN as
+// an alias of that entity.
+func (h *EntityHandler) merge(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	var req struct {
+		Into string `json:"into"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+	if req.Into == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "into is required"})
+		return
+	}
+
+	res, err := h.service.MergeEntities(r.Context(), ns, r.PathValue("urn"), req.Into)
+	if err != nil {
+		if errors.Is(err, entity.ErrInvalidMerge) || errors.Is(err, entity.ErrEdgeConstraint) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeEntityError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
+
+// parseGraphFormat reads the optional format query parameter. JSON, the
+// default, is returned as an empty format.
+func parseGraphFormat(r *http.Request) (entity.GraphFormat, error) {
+	raw := r.URL.Query().Get("format")
+	if raw == "" || strings.EqualFold(raw, "json") {
+		return "", nil
+	}
+	return entity.ParseGraphFormat(raw)
+}
+
+// writeGraph writes the subgraph rendered in format.
+func writeGraph(w http.ResponseWriter, cg *entity.ContextGraph, format entity.GraphFormat) {
+	var buf bytes.Buffer
+	if err := cg.Render(&buf, format); err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// parseAsOf reads the optional as_of query parameter.
+func parseAsOf(r *http.Request) (*time.Time, error) {
+	return parseTimeParam(r, "as_of")
+}
+
+// parseTimeParam reads an optional RFC 3339 query parameter.
+func parseTimeParam(r *http.Request, name string) (*time.Time, error) {
+	raw := r.URL.Query().Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+	}
+	return &t, nil
+}
+
+// writeEntityError maps a missing entity to 404 and anything else to 500.
+func writeEntityError(w http.ResponseWriter, err error) {
+	if errors.Is(err, sql.ErrNoRows) {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "entity not found"})
+		return
+	}
+	writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/internal/middleware"
+)
+
+// TypeService defines entity type registry operations for the handler.
+type TypeService interface {
+	RegisterType(ctx context.Context, ns *namespace.Namespace, def *entity.TypeDefinition) error
+	GetType(ctx context.Context, ns *namespace.Namespace, t entity.Type) (entity.TypeDefinition, error)
+	ListTypes(ctx context.Context, ns *namespace.Namespace) ([]entity.TypeDefinition, error)
+	DeleteType(ctx context.Context, ns *namespace.Namespace, t entity.Type) error
+	GetEntityTypes(ctx context.Context, ns *namespace.Namespace) ([]entity.TypeSummary, error)
+	RegisterEdgeType(ctx context.Context, ns *namespace.Namespace, def *entity.EdgeTypeDefinition) error
+	GetEdgeType(ctx context.Context, ns *namespace.Namespace, edgeType string) (entity.EdgeTypeDefinition, error)
+	ListEdgeTypes(ctx context.Context, ns *namespace.Namespace) ([]entity.EdgeTypeDefinition, error)
+	DeleteEdgeType(ctx context.Context, ns *namespace.Namespace, edgeType string) error
+}
+
+// TypeHandler handles HTTP requests for entity type definitions and edge type
+// declarations.
+type TypeHandler struct {
+	service TypeService
+}
+
+func NewTypeHandler(service TypeService) *TypeHandler {
+	return &TypeHandler{service: service}
+}
+
+// RegisterRoutes registers entity and edge type HTTP routes on the mux.
+// /v1/types serves the registered definitions; /v1/entity-types lists every
+// type in use or registered, with its entity count and definition.
+// /v1/edge-types serves the edge type declarations.
+func (h *TypeHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /v1/types", h.register)
+	mux.HandleFunc("GET /v1/types", h.list)
+	mux.HandleFunc("GET /v1/types/{type}", h.get)
+	mux.HandleFunc("DELETE /v1/types/{type}", h.delete)
+	mux.HandleFunc("GET /v1/entity-types", h.entityTypes)
+	mux.HandleFunc("POST /v1/edge-types", h.registerEdgeType)
+	mux.HandleFunc("GET /v1/edge-types", h.listEdgeTypes)
+	mux.HandleFunc("GET /v1/edge-types/{type}", h.getEdgeType)
+	mux.HandleFunc("DELETE /v1/edge-types/{type}", h.deleteEdgeType)
+}
+
+func (h *TypeHandler) register(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	var req struct {
+		Type        string                 `json:"type"`
+		DisplayName string                 `json:"display_name,omitempty"`
+		Icon        string                 `json:"icon,omitempty"`
+		Schema      map[string]interface{} `json:"schema,omitempty"`
+		Required    []string               `json:"required,omitempty"`
+		Mode        string                 `json:"mode,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	def := &entity.TypeDefinition{
+		Type:        entity.Type(req.Type),
+		DisplayName: req.DisplayName,
+		Icon:        req.Icon,
+		Schema:      req.Schema,
+		Required:    req.Required,
+		Mode:        entity.TypeMode(req.Mode),
+	}
+	if err := h.service.RegisterType(r.Context(), ns, def); err != nil {
+		writeTypeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, def)
+}
+
+func (h *TypeHandler) list(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	defs, err := h.service.ListTypes(r.Context(), ns)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": defs})
+}
+
+func (h *TypeHandler) get(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	def, err := h.service.GetType(r.Context(), ns, entity.Type(r.PathValue("type")))
+	if err != nil {
+		writeTypeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, def)
+}
+
+func (h *TypeHandler) delete(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	if err := h.service.DeleteType(r.Context(), ns, entity.Type(r.PathValue("type"))); err != nil {
+		writeTypeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (h *TypeHandler) entityTypes(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	types, err := h.service.GetEntityTypes(r.Context(), ns)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": types})
+}
+
+func (h *TypeHandler) registerEdgeType(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	var req struct {
+		Type        string   `json:"type"`
+		SourceTypes []string `json:"source_types,omitempty"`
+		TargetTypes []string `json:"target_types,omitempty"`
+		Lineage     bool     `json:"lineage"`
+		Inverse     string   `json:"inverse,omitempty"`
+		Cardinality string   `json:"cardinality,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	def := &entity.EdgeTypeDefinition{
+		Type:        req.Type,
+		Lineage:     req.Lineage,
+		Inverse:     req.Inverse,
+		Cardinality: entity.Cardinality(req.Cardinality),
+	}
+	for _, t := range req.SourceTypes {
+		def.SourceTypes = append(def.SourceTypes, entity.Type(t))
+	}
+	for _, t := range req.TargetTypes {
+		def.TargetTypes = append(def.TargetTypes, entity.Type(t))
+	}
+	if err := h.service.RegisterEdgeType(r.Context(), ns, def); err != nil {
+		writeTypeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, def)
+}
+
+func (h *TypeHandler) listEdgeTypes(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	defs, err := h.service.ListEdgeTypes(r.Context(), ns)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": defs})
+}
+
+func (h *TypeHandler) getEdgeType(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	def, err := h.service.GetEdgeType(r.Context(), ns, r.PathValue("type"))
+	if err != nil {
+		writeTypeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, def)
+}
+
+func (h *TypeHandler) deleteEdgeType(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	if err := h.service.DeleteEdgeType(r.Context(), ns, r.PathValue("type")); err != nil {
+		writeTypeError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func writeTypeError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, entity.ErrTypeNotFound), errors.Is(err, entity.ErrEdgeTypeNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, entity.ErrInvalidTypeDefinition), errors.Is(err, entity.ErrInvalidEdgeTypeDefinition):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
@@ -0,0 +1,58 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/raystack/compass/core/batch"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/openlineage"
+	"github.com/raystack/compass/core/urn"
+	"github.com/raystack/compass/internal/middleware"
+)
+
+// LineageService defines the lineage ingestion operations served over HTTP.
+type LineageService interface {
+	Ingest(ctx context.Context, ns *namespace.Namespace, ev openlineage.RunEvent) (*openlineage.Result, error)
+}
+
+// LineageHandler receives lineage events from external producers.
+type LineageHandler struct {
+	service LineageService
+}
+
+func NewLineageHandler(service LineageService) *LineageHandler {
+	return &LineageHandler{service: service}
+}
+
+// RegisterRoutes registers lineage HTTP routes on the mux.
+func (h *LineageHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /v1/lineage/openlineage", h.openLineage)
+}
+
+// openLineage accepts an OpenLineage RunEvent, the body OpenLineage HTTP
+// transports send.
+func (h *LineageHandler) openLineage(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	var ev openlineage.RunEvent
+	if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	res, err := h.service.Ingest(r.Context(), ns, ev)
+	if err != nil {
+		if errors.Is(err, openlineage.ErrInvalidEvent) || errors.Is(err, batch.ErrInvalid) || errors.Is(err, entity.ErrEdgeConstraint) || errors.Is(err, urn.ErrInvalid) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
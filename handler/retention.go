@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/retention"
+	"github.com/raystack/compass/internal/middleware"
+)
+
+// RetentionService defines the erasure operations served over HTTP.
+type RetentionService interface {
+	PurgeEntity(ctx context.Context, ns *namespace.Namespace, urn, confirm string) (retention.Result, error)
+}
+
+// RetentionHandler serves administrative erasure of entities.
+type RetentionHandler struct {
+	service RetentionService
+	admins  *AdminPolicy
+}
+
+func NewRetentionHandler(service RetentionService, admins *AdminPolicy) *RetentionHandler {
+	return &RetentionHandler{service: service, admins: admins}
+}
+
+// RegisterRoutes registers retention HTTP routes on the mux. Purging is
+// restricted to admins.
+func (h *RetentionHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /v1/entities/{urn}/purge", h.admins.Require(h.purgeEntity))
+}
+
+// purgeEntity permanently erases an entity and everything attached to it.
+// Unlike delete it cannot be undone, so the caller must repeat the URN in
+// the confirm query parameter.
+func (h *RetentionHandler) purgeEntity(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+	res, err := h.service.PurgeEntity(r.Context(), ns, r.PathValue("urn"), r.URL.Query().Get("confirm"))
+	if err != nil {
+		if errors.Is(err, retention.ErrNotConfirmed) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, sql.ErrNoRows) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "entity not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, res)
+}
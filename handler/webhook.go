@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/webhook"
+	"github.com/raystack/compass/internal/middleware"
+)
+
+// WebhookService defines webhook operations for the handler.
+type WebhookService interface {
+	Create(ctx context.Context, ns *namespace.Namespace, wh *webhook.Webhook) (*webhook.Webhook, error)
+	GetByID(ctx context.Context, ns *namespace.Namespace, id string) (webhook.Webhook, error)
+	List(ctx context.Context, ns *namespace.Namespace) ([]webhook.Webhook, error)
+	Delete(ctx context.Context, ns *namespace.Namespace, id string) error
+	ListDeliveries(ctx context.Context, ns *namespace.Namespace, webhookID string, flt webhook.DeliveryFilter) ([]webhook.Delivery, error)
+}
+
+// WebhookHandler handles HTTP requests for webhook registrations and their
+// delivery logs.
+type WebhookHandler struct {
+	service WebhookService
+}
+
+func NewWebhookHandler(service WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+// RegisterRoutes registers webhook HTTP routes on the mux.
+func (h *WebhookHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /v1/webhooks", h.create)
+	mux.HandleFunc("GET /v1/webhooks", h.list)
+	mux.HandleFunc("GET /v1/webhooks/{id}", h.get)
+	mux.HandleFunc("DELETE /v1/webhooks/{id}", h.delete)
+	mux.HandleFunc("GET /v1/webhooks/{id}/deliveries", h.deliveries)
+}
+
+func (h *WebhookHandler) create(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	var req struct {
+		URL         string   `json:"url"`
+		Secret      string   `json:"secret,omitempty"`
+		Events      []string `json:"events,omitempty"`
+		EntityTypes []string `json:"entity_types,omitempty"`
+		URNPrefix   string   `json:"urn_prefix,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	wh, err := h.service.Create(r.Context(), ns, &webhook.Webhook{
+		URL:         req.URL,
+		Secret:      req.Secret,
+		Events:      req.Events,
+		EntityTypes: req.EntityTypes,
+		URNPrefix:   req.URNPrefix,
+	})
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, wh)
+}
+
+func (h *WebhookHandler) list(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	webhooks, err := h.service.List(r.Context(), ns)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": webhooks})
+}
+
+func (h *WebhookHandler) get(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	wh, err := h.service.GetByID(r.Context(), ns, r.PathValue("id"))
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, wh)
+}
+
+func (h *WebhookHandler) delete(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	if err := h.service.Delete(r.Context(), ns, r.PathValue("id")); err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+func (h *WebhookHandler) deliveries(w http.ResponseWriter, r *http.Request) {
+	ns := middleware.FetchNamespaceFromContext(r.Context())
+
+	flt := webhook.DeliveryFilter{Status: webhook.DeliveryStatus(r.URL.Query().Get("status"))}
+	switch flt.Status {
+	case "", webhook.DeliverySucceeded, webhook.DeliveryFailed:
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "status must be succeeded or failed"})
+		return
+	}
+	flt.Limit, _ = strconv.Atoi(r.URL.Query().Get("limit"))
+
+	deliveries, err := h.service.ListDeliveries(r.Context(), ns, r.PathValue("id"), flt)
+	if err != nil {
+		writeWebhookError(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"data": deliveries})
+}
+
+func writeWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrNotFound):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+	case errors.Is(err, webhook.ErrInvalid):
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+	default:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+	}
+}
@@ -17,6 +17,21 @@ type Config struct {
 	Service   ServerConfig     `mapstructure:"service"`
 	Client    client.Config    `mapstructure:"client"`
 	Embedding EmbeddingConfig  `mapstructure:"embedding"`
+	Columns   ColumnsConfig    `mapstructure:"columns"`
+	Webhooks  WebhooksConfig   `mapstructure:"webhooks"`
+}
+
+// ColumnsConfig configures column entities: the columns of entities of the
+// listed types become entities of their own, so lineage can link columns.
+type ColumnsConfig struct {
+	Enabled bool     `yaml:"enabled" mapstructure:"enabled" default:"false"`
+	Types   []string `yaml:"types" mapstructure:"types" default:"[table]"`
+}
+
+// WebhooksConfig configures webhook delivery. Webhooks may not point to
+// loopback, private or link-local addresses unless AllowPrivateNetworks is set.
+type WebhooksConfig struct {
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" mapstructure:"allow_private_networks" default:"false"`
 }
 
 // EmbeddingConfig configures the embedding pipeline.
@@ -60,5 +75,10 @@ type IdentityConfig struct {
 	HeaderKeyUserEmail  string `yaml:"headerkey_email" mapstructure:"headerkey_email" default:"Compass-User-Email"`
 	ProviderDefaultName string `yaml:"provider_default_name" mapstructure:"provider_default_name" default:""`
 
+	// Admins lists the principal subjects allowed to run administrative
+	// operations: purging, merging and restoring entities. When empty,
+	// anyone may.
+	Admins []string `yaml:"admins" mapstructure:"admins"`
+
 	NamespaceClaimKey string `yaml:"namespace_claim_key" mapstructure:"namespace_claim_key" default:"namespace_id"`
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	gomcp "github.com/mark3labs/mcp-go/mcp"
 	"github.com/raystack/compass/core/entity"
@@ -87,6 +88,46 @@ func (s *Server) handleImpact(ctx context.Context, req gomcp.CallToolRequest) (*
 	return gomcp.NewToolResultText(formatImpactAnalysis(urn, edges)), nil
 }
 
+func (s *Server) handleDiffEntity(ctx context.Context, req gomcp.CallToolRequest) (*gomcp.CallToolResult, error) {
+	if s.entityService == nil {
+		return gomcp.NewToolResultError("entity service not configured"), nil
+	}
+
+	urn := gomcp.ParseString(req, "urn", "")
+	if urn == "" {
+		return gomcp.NewToolResultError("'urn' parameter is required"), nil
+	}
+
+	from, err := parseTimeArg(req, "from")
+	if err != nil {
+		return gomcp.NewToolResultError(err.Error()), nil
+	}
+	to, err := parseTimeArg(req, "to")
+	if err != nil {
+		return gomcp.NewToolResultError(err.Error()), nil
+	}
+
+	d, err := s.entityService.DiffEntity(ctx, getNamespace(ctx), urn, from, to)
+	if err != nil {
+		return gomcp.NewToolResultError("diff failed: " + err.Error()), nil
+	}
+
+	return gomcp.NewToolResultText(formatEntityDiff(d)), nil
+}
+
+// parseTimeArg reads an optional RFC 3339 argument; absent yields the zero time.
+func parseTimeArg(req gomcp.CallToolRequest, name string) (time.Time, error) {
+	raw := gomcp.ParseString(req, name, "")
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("'%s' must be an RFC 3339 timestamp", name)
+	}
+	return t, nil
+}
+
 // Formatters
 
 func formatEntitySearchResults(results []entity.SearchResult) string {
@@ -161,3 +202,46 @@ func formatImpactAnalysis(urn string, edges []entity.Edge) string {
 	}
 	return b.String()
 }
+
+func formatEntityDiff(d *entity.EntityDiff) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Diff for %s\n", d.URN)
+	fmt.Fprintf(&b, "From %s to %s", d.From.ValidFrom.Format(time.RFC3339), d.To.ValidFrom.Format(time.RFC3339))
+	if d.To.ChangedBy != "" {
+		fmt.Fprintf(&b, " (changed by %s)", d.To.ChangedBy)
+	}
+	b.WriteString("\n")
+
+	if d.IsEmpty() {
+		b.WriteString("\nNo changes.\n")
+		return b.String()
+	}
+
+	if len(d.Changes) > 0 {
+		b.WriteString("\n### Fields\n")
+		for _, c := range d.Changes {
+			b.WriteString(formatFieldChange("", c))
+		}
+	}
+	if len(d.Columns) > 0 {
+		b.WriteString("\n### Columns\n")
+		for _, c := range d.Columns {
+			fmt.Fprintf(&b, "- %s: %s\n", c.Name, c.Kind)
+			for _, attr := range c.Changes {
+				b.WriteString(formatFieldChange("  ", attr))
+			}
+		}
+	}
+	return b.String()
+}
+
+func formatFieldChange(indent string, c entity.FieldChange) string {
+	switch c.Kind {
+	case entity.ChangeAdded:
+		return fmt.Sprintf("%s- %s: added %v\n", indent, c.Path, c.To)
+	case entity.ChangeRemoved:
+		return fmt.Sprintf("%s- %s: removed %v\n", indent, c.Path, c.From)
+	default:
+		return fmt.Sprintf("%s- %s: %v → %v\n", indent, c.Path, c.From, c.To)
+	}
+}
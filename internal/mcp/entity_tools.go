@@ -51,3 +51,19 @@ func impactAnalysisTool() mcp.Tool {
 		),
 	)
 }
+
+func diffEntityTool() mcp.Tool {
+	return mcp.NewTool("diff_entity",
+		mcp.WithDescription("Show exactly what changed between two versions of an entity: field, nested property, and column-level additions, removals and changes. Defaults to the latest change."),
+		mcp.WithString("urn",
+			mcp.Required(),
+			mcp.Description("URN of the entity"),
+		),
+		mcp.WithString("from",
+			mcp.Description("RFC 3339 timestamp selecting the older version (default: the version before 'to')"),
+		),
+		mcp.WithString("to",
+			mcp.Description("RFC 3339 timestamp selecting the newer version (default: current)"),
+		),
+	)
+}
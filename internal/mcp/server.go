@@ -3,6 +3,7 @@ package mcp
 import (
 	"context"
 	"net/http"
+	"time"
 
 	mcpserver "github.com/mark3labs/mcp-go/server"
 	"github.com/raystack/compass/core/document"
@@ -16,6 +17,7 @@ type EntityService interface {
 	Search(ctx context.Context, cfg entity.SearchConfig) ([]entity.SearchResult, error)
 	GetContext(ctx context.Context, ns *namespace.Namespace, urn string, depth int) (*entity.ContextGraph, error)
 	GetImpact(ctx context.Context, ns *namespace.Namespace, urn string, depth int) ([]entity.Edge, error)
+	DiffEntity(ctx context.Context, ns *namespace.Namespace, urn string, from, to time.Time) (*entity.EntityDiff, error)
 	AssembleContext(ctx context.Context, ns *namespace.Namespace, req entity.AssemblyRequest) (*entity.AssembledContext, error)
 }
 
@@ -48,6 +50,7 @@ func New(entitySvc EntityService, docSvc DocumentService) *Server {
 	mcpSrv.AddTool(searchEntitiesTool(), s.handleSearchEntities)
 	mcpSrv.AddTool(getContextTool(), s.handleGetContext)
 	mcpSrv.AddTool(impactAnalysisTool(), s.handleImpact)
+	mcpSrv.AddTool(diffEntityTool(), s.handleDiffEntity)
 	mcpSrv.AddTool(getDocumentsTool(), s.handleGetDocuments)
 	mcpSrv.AddTool(assembleContextTool(), s.handleAssembleContext)
 
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	gomcp "github.com/mark3labs/mcp-go/mcp"
 	"github.com/raystack/compass/core/document"
@@ -18,6 +19,7 @@ type mockEntityService struct {
 	searchFn          func(ctx context.Context, cfg entity.SearchConfig) ([]entity.SearchResult, error)
 	getContextFn      func(ctx context.Context, ns *namespace.Namespace, urn string, depth int) (*entity.ContextGraph, error)
 	getImpactFn       func(ctx context.Context, ns *namespace.Namespace, urn string, depth int) ([]entity.Edge, error)
+	diffEntityFn      func(ctx context.Context, ns *namespace.Namespace, urn string, from, to time.Time) (*entity.EntityDiff, error)
 	assembleContextFn func(ctx context.Context, ns *namespace.Namespace, req entity.AssemblyRequest) (*entity.AssembledContext, error)
 }
 
@@ -33,6 +35,10 @@ func (m *mockEntityService) GetImpact(ctx context.Context, ns *namespace.Namespa
 	return m.getImpactFn(ctx, ns, urn, depth)
 }
 
+func (m *mockEntityService) DiffEntity(ctx context.Context, ns *namespace.Namespace, urn string, from, to time.Time) (*entity.EntityDiff, error) {
+	return m.diffEntityFn(ctx, ns, urn, from, to)
+}
+
 func (m *mockEntityService) AssembleContext(ctx context.Context, ns *namespace.Namespace, req entity.AssemblyRequest) (*entity.AssembledContext, error) {
 	return m.assembleContextFn(ctx, ns, req)
 }
@@ -289,6 +295,88 @@ func TestHandleImpact_MissingURN(t *testing.T) {
 
 // --- Document handler tests ---
 
+func TestHandleDiffEntity(t *testing.T) {
+	from := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	svc := &mockEntityService{
+		diffEntityFn: func(_ context.Context, _ *namespace.Namespace, urn string, f, to time.Time) (*entity.EntityDiff, error) {
+			if urn != "urn:bq:orders" {
+				t.Errorf("expected urn 'urn:bq:orders', got %q", urn)
+			}
+			if !f.Equal(from) {
+				t.Errorf("expected from %v, got %v", from, f)
+			}
+			if !to.IsZero() {
+				t.Errorf("expected zero 'to' when omitted, got %v", to)
+			}
+			return &entity.EntityDiff{
+				URN:  urn,
+				From: entity.VersionRef{ValidFrom: from},
+				To:   entity.VersionRef{ValidFrom: from.Add(time.Hour), ChangedBy: "alice"},
+				Changes: []entity.FieldChange{
+					{Path: "properties.owner", Kind: entity.ChangeChanged, From: "payments", To: "checkout"},
+				},
+				Columns: []entity.ColumnChange{
+					{Name: "note", Kind: entity.ChangeRemoved},
+				},
+			}, nil
+		},
+	}
+	srv := newTestServer(svc, nil)
+
+	result, err := srv.handleDiffEntity(context.Background(), makeRequest(map[string]any{
+		"urn":  "urn:bq:orders",
+		"from": "2026-03-01T09:00:00Z",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("expected success, got error: %s", resultText(t, result))
+	}
+
+	text := resultText(t, result)
+	for _, want := range []string{"urn:bq:orders", "changed by alice", "properties.owner: payments → checkout", "note: removed"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("expected %q in output, got: %s", want, text)
+		}
+	}
+}
+
+func TestHandleDiffEntity_InvalidTime(t *testing.T) {
+	srv := newTestServer(&mockEntityService{}, nil)
+
+	result, err := srv.handleDiffEntity(context.Background(), makeRequest(map[string]any{
+		"urn":  "urn:bq:orders",
+		"from": "yesterday",
+	}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected error result for invalid timestamp")
+	}
+}
+
+func TestHandleDiffEntity_ServiceError(t *testing.T) {
+	svc := &mockEntityService{
+		diffEntityFn: func(_ context.Context, _ *namespace.Namespace, _ string, _, _ time.Time) (*entity.EntityDiff, error) {
+			return nil, entity.ErrVersionNotFound
+		},
+	}
+	srv := newTestServer(svc, nil)
+
+	result, err := srv.handleDiffEntity(context.Background(), makeRequest(map[string]any{"urn": "urn:bq:orders"}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.IsError {
+		t.Fatal("expected error result")
+	}
+	if !strings.Contains(resultText(t, result), "diff failed") {
+		t.Errorf("expected 'diff failed' in error text")
+	}
+}
+
 func TestHandleGetDocuments(t *testing.T) {
 	docSvc := &mockDocumentService{
 		getByEntityURNFn: func(_ context.Context, _ *namespace.Namespace, entityURN string) ([]document.Document, error) {
@@ -8,15 +8,21 @@ import (
 	"os"
 	"strings"
 
+	"github.com/raystack/compass/core/batch"
+	"github.com/raystack/compass/core/change"
 	"github.com/raystack/compass/core/document"
 	"github.com/raystack/compass/core/embedding"
 	"github.com/raystack/compass/core/entity"
 	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/openlineage"
 	"github.com/raystack/compass/core/pipeline"
 	"github.com/raystack/compass/core/principal"
+	"github.com/raystack/compass/core/retention"
+	"github.com/raystack/compass/core/webhook"
 	"github.com/raystack/compass/handler"
 	"github.com/raystack/compass/internal/config"
 	compassmcp "github.com/raystack/compass/internal/mcp"
+	"github.com/raystack/compass/internal/middleware"
 	"github.com/raystack/compass/internal/telemetry"
 	"github.com/raystack/compass/store"
 )
@@ -89,6 +95,20 @@ func Start(ctx context.Context, cfg *config.Config, version string) error {
 	}
 	entityService := entity.NewService(entityRepo, edgeRepo, entitySearchRepo)
 
+	// batched writes for bulk ingestion
+	bulkRepo, err := store.NewBulkRepository(pgClient)
+	if err != nil {
+		return fmt.Errorf("failed to create bulk repository: %w", err)
+	}
+	entityService.WithBulkRepository(bulkRepo)
+
+	// entity type registry, validated on write
+	typeRepo, err := store.NewTypeRepository(pgClient)
+	if err != nil {
+		return fmt.Errorf("failed to create type repository: %w", err)
+	}
+	entityService.WithTypeRegistry(typeRepo)
+
 	// init document system
 	docRepo, err := store.NewDocumentRepository(pgClient)
 	if err != nil {
@@ -99,6 +119,28 @@ func Start(ctx context.Context, cfg *config.Config, version string) error {
 	// wire document fetcher into entity service for context assembly
 	entityService.WithDocumentFetcher(&docFetcherAdapter{svc: docService})
 
+	// init transactional batches
+	batchRepo, err := store.NewBatchRepository(pgClient)
+	if err != nil {
+		return fmt.Errorf("failed to create batch repository: %w", err)
+	}
+	batchService := batch.NewService(batchRepo)
+	batchService.WithTypeChecker(entityService)
+
+	// expand table columns into column entities (optional)
+	if cfg.Columns.Enabled {
+		types := make([]entity.Type, len(cfg.Columns.Types))
+		for i, t := range cfg.Columns.Types {
+			types[i] = entity.Type(t)
+		}
+		entityService.WithColumnEntities(types...)
+		batchService.WithColumnExpander(entityService)
+		slog.Info("column entities enabled", "types", cfg.Columns.Types)
+	}
+
+	// init OpenLineage ingestion
+	lineageService := openlineage.NewService(entityService, batchService)
+
 	// init embedding pipeline (optional)
 	if cfg.Embedding.Enabled {
 		provider, err := initEmbeddingProvider(cfg.Embedding)
@@ -130,13 +172,53 @@ func Start(ctx context.Context, cfg *config.Config, version string) error {
 		// Wire pipeline into services
 		entityService.WithPipeline(p)
 		docService.WithPipeline(p)
+		batchService.WithPipeline(p)
 	}
 
+	// init change feed
+	changeRepo, err := store.NewChangeRepository(pgClient)
+	if err != nil {
+		return fmt.Errorf("failed to create change repository: %w", err)
+	}
+	changeService := change.NewService(changeRepo)
+
+	// report schema changes detected at ingest time through the change feed
+	entityService.WithSchemaChangeNotifier(schemaChangeRecorder{changes: changeRepo})
+
+	// init webhooks, delivered from the change feed
+	webhookRepo, err := store.NewWebhookRepository(pgClient)
+	if err != nil {
+		return fmt.Errorf("failed to create webhook repository: %w", err)
+	}
+	webhookService := webhook.NewService(webhookRepo)
+	webhookService.WithPrivateNetworks(cfg.Webhooks.AllowPrivateNetworks)
+	dispatcher := webhook.NewDispatcher(webhookRepo, changeService, namespaceService,
+		webhook.WithNamespaceScope(middleware.BuildContextWithNamespace),
+		webhook.WithPrivateNetworks(cfg.Webhooks.AllowPrivateNetworks),
+	)
+	dispatcher.Start(ctx)
+	defer dispatcher.Stop()
+
+	// init retention; policies live in namespace metadata
+	retentionRepo, err := store.NewRetentionRepository(pgClient)
+	if err != nil {
+		return fmt.Errorf("failed to create retention repository: %w", err)
+	}
+	retentionService := retention.NewService(retentionRepo)
+	janitor := retention.NewJanitor(retentionService, namespaceService,
+		retention.WithNamespaceScope(middleware.BuildContextWithNamespace),
+	)
+	janitor.Start(ctx)
+	defer janitor.Stop()
+
 	// init MCP server
 	mcpServer := compassmcp.New(entityService, docService)
 
-	// init document handler
-	docHandler := handler.NewDocumentHandler(docService)
+	// administrative operations are limited to the configured admins
+	admins := handler.NewAdminPolicy(cfg.Service.Identity.Admins)
+	if admins.Open() {
+		slog.Warn("no admins configured, anyone may purge, merge and restore entities; set service.identity.admins to restrict it")
+	}
 
 	return Serve(
 		ctx,
@@ -145,7 +227,16 @@ func Start(ctx context.Context, cfg *config.Config, version string) error {
 		namespaceService,
 		entityService,
 		edgeRepo,
-		docHandler,
+		handler.NewDocumentHandler(docService),
+		handler.NewEntityHandler(entityService, admins),
+		handler.NewTypeHandler(entityService),
+		handler.NewEdgeHandler(edgeRepo),
+		handler.NewBulkHandler(entityService),
+		handler.NewBatchHandler(batchService),
+		handler.NewLineageHandler(lineageService),
+		handler.NewChangeHandler(changeService),
+		handler.NewWebhookHandler(webhookService),
+		handler.NewRetentionHandler(retentionService, admins),
 	)
 }
 
@@ -229,6 +320,35 @@ func (a *docFetcherAdapter) GetDocumentsForEntity(ctx context.Context, ns *names
 	return result, nil
 }
 
+// schemaChangeRecorder logs schema changes detected on entity upsert and
+// appends them to the change feed as schema records, so feed readers and
+// webhooks see them.
+type schemaChangeRecorder struct {
+	changes *store.ChangeRepository
+}
+
+func (r schemaChangeRecorder) NotifySchemaChange(ctx context.Context, ns *namespace.Namespace, impact *entity.SchemaImpact) {
+	affected := make([]string, len(impact.Affected))
+	for i, a := range impact.Affected {
+		affected[i] = a.URN
+	}
+	level := slog.LevelInfo
+	if impact.Level == entity.SchemaBreaking {
+		level = slog.LevelWarn
+	}
+	slog.Log(ctx, level, "schema change detected",
+		"namespace", ns.Name,
+		"urn", impact.URN,
+		"level", impact.Level,
+		"changes", len(impact.Changes),
+		"affected", affected,
+	)
+
+	if err := r.changes.Record(ctx, ns, change.KindSchema, change.Op(impact.Level), impact.URN, string(impact.Type), impact); err != nil {
+		slog.ErrorContext(ctx, "record schema change", "urn", impact.URN, "error", err)
+	}
+}
+
 func initEmbeddingProvider(cfg config.EmbeddingConfig) (embedding.Provider, error) {
 	switch strings.ToLower(cfg.Provider) {
 	case "openai":
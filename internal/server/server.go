@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -28,6 +29,11 @@ import (
 	"golang.org/x/net/http2/h2c"
 )
 
+// RouteRegistrar registers plain HTTP routes alongside the Connect service.
+type RouteRegistrar interface {
+	RegisterRoutes(mux *http.ServeMux)
+}
+
 func Serve(
 	ctx context.Context,
 	cfg config.ServerConfig,
@@ -35,7 +41,7 @@ func Serve(
 	namespaceService handler.NamespaceService,
 	entityService handler.EntityServiceV2,
 	edgeService handler.EdgeServiceV2,
-	documentHandler *handler.DocumentHandler,
+	httpHandlers ...RouteRegistrar,
 ) error {
 	logger := slog.Default().With("component", "server")
 
@@ -82,10 +88,13 @@ func Serve(
 	mux.Handle(grpcreflect.NewHandlerV1(reflector))
 	mux.Handle(grpcreflect.NewHandlerV1Alpha(reflector))
 
-	// Document REST API
-	if documentHandler != nil {
-		documentHandler.RegisterRoutes(mux)
+	// REST API (documents, entity history, ...), wrapped to resolve
+	// namespace and principal from headers like the Connect interceptors
+	restMux := http.NewServeMux()
+	for _, h := range httpHandlers {
+		h.RegisterRoutes(restMux)
 	}
+	mux.Handle("/v1/", httpContextMiddleware(restMux, namespaceService, cfg))
 
 	// Health check endpoint
 	mux.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
@@ -96,7 +105,7 @@ func Serve(
 	// MCP server for AI agent tool access, wrapped with HTTP middleware
 	// to extract principal and namespace from headers
 	if mcpServer != nil {
-		mcpHandler := mcpHTTPMiddleware(mcpServer.Handler(), namespaceService, cfg)
+		mcpHandler := httpContextMiddleware(mcpServer.Handler(), namespaceService, cfg)
 		mux.Handle("/mcp", mcpHandler)
 		logger.InfoContext(ctx, "MCP server enabled at /mcp")
 	}
@@ -146,28 +155,33 @@ func Serve(
 	return nil
 }
 
-// mcpHTTPMiddleware wraps an HTTP handler with namespace and principal extraction
+// httpContextMiddleware wraps an HTTP handler with namespace and principal extraction
 // from headers, mirroring the Connect interceptor logic for plain HTTP.
-func mcpHTTPMiddleware(next http.Handler, nsSvc middleware.NamespaceService, cfg config.ServerConfig) http.Handler {
+func httpContextMiddleware(next http.Handler, nsSvc middleware.NamespaceService, cfg config.ServerConfig) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
 
-		// Extract namespace from header
+		// Extract namespace from header. A namespace that does not resolve
+		// is rejected rather than falling back to the default one, so a typo
+		// cannot read or write another tenant's data.
 		ns := namespace.DefaultNamespace
-		nsHeader := r.Header.Get(client.NamespaceHeaderKey)
-		if nsHeader != "" {
-			nsID, parseErr := uuid.Parse(strings.TrimSpace(nsHeader))
-			if parseErr != nil {
-				resolved, err := nsSvc.GetByName(ctx, strings.TrimSpace(nsHeader))
-				if err == nil {
-					ns = resolved
-				}
+		if nsHeader := strings.TrimSpace(r.Header.Get(client.NamespaceHeaderKey)); nsHeader != "" {
+			var resolved *namespace.Namespace
+			var err error
+			if nsID, parseErr := uuid.Parse(nsHeader); parseErr != nil {
+				resolved, err = nsSvc.GetByName(ctx, nsHeader)
 			} else {
-				resolved, err := nsSvc.GetByID(ctx, nsID)
-				if err == nil {
-					ns = resolved
+				resolved, err = nsSvc.GetByID(ctx, nsID)
+			}
+			if err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, namespace.ErrNotFound) {
+					status = http.StatusBadRequest
 				}
+				writeJSONError(w, status, err.Error())
+				return
 			}
+			ns = resolved
 		}
 		ctx = middleware.BuildContextWithNamespace(ctx, ns)
 
@@ -187,3 +201,10 @@ func mcpHTTPMiddleware(next http.Handler, nsSvc middleware.NamespaceService, cfg
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// writeJSONError writes an error in the shape the REST handlers use.
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,390 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/raystack/compass/core/backup"
+	"github.com/raystack/compass/core/document"
+	"github.com/raystack/compass/core/embedding"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+)
+
+// restoreBatchSize is the number of rows written per insert statement.
+const restoreBatchSize = 500
+
+// BackupRepository reads and writes the raw rows of a namespace, history
+// included, for backups.
+type BackupRepository struct {
+	client *Client
+}
+
+func NewBackupRepository(client *Client) (*BackupRepository, error) {
+	if client == nil {
+		return nil, errors.New("postgres client is nil")
+	}
+	return &BackupRepository{client: client}, nil
+}
+
+// Dump streams every row of the namespace to sink, ordered so that two dumps
+// of the same namespace are identical. All rows are read from one snapshot,
+// so writes committed during the dump do not leave the archive inconsistent.
+func (r *BackupRepository) Dump(ctx context.Context, ns *namespace.Namespace, sink backup.Sink, withEmbeddings bool) error {
+	return r.client.QueryFn(ctx, func(conn *sqlx.Conn) error {
+		tx, err := conn.BeginTxx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+		if err != nil {
+			return fmt.Errorf("starting snapshot: %w", err)
+		}
+		defer func() { _ = tx.Rollback() }()
+		return dumpTx(ctx, tx, ns, sink, withEmbeddings)
+	})
+}
+
+// dumpTx streams the rows of the namespace visible to tx to sink.
+func dumpTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, sink backup.Sink, withEmbeddings bool) error {
+	err := eachRow(ctx, tx,
+		fmt.Sprintf(`SELECT %s FROM entities WHERE namespace_id = $1 ORDER BY urn, valid_from`, entityColumns),
+		ns.ID, func(m entityModel) error { return sink.WriteEntity(m.toEntity()) })
+	if err != nil {
+		return fmt.Errorf("dump entities: %w", err)
+	}
+	err = eachRow(ctx, tx,
+		fmt.Sprintf(`SELECT %s FROM edges WHERE namespace_id = $1 ORDER BY source_urn, target_urn, type, valid_from`, edgeColumns),
+		ns.ID, func(m edgeModel) error { return sink.WriteEdge(toEdgeList([]edgeModel{m})[0]) })
+	if err != nil {
+		return fmt.Errorf("dump edges: %w", err)
+	}
+	err = eachRow(ctx, tx,
+		fmt.Sprintf(`SELECT %s FROM documents WHERE namespace_id = $1 ORDER BY entity_urn, created_at, id`, documentColumns),
+		ns.ID, func(m documentModel) error { return sink.WriteDocument(m.toDomain()) })
+	if err != nil {
+		return fmt.Errorf("dump documents: %w", err)
+	}
+	err = eachRow(ctx, tx,
+		`SELECT id, namespace_id, uuid, email, provider, type, name, subject, metadata, created_at, updated_at
+		 FROM principals WHERE namespace_id = $1 ORDER BY created_at, id`,
+		ns.ID, func(m PrincipalModel) error {
+			p := m.toPrincipal()
+			return sink.WritePrincipal(backup.Principal{
+				ID:        p.ID,
+				Type:      p.Type,
+				Name:      p.Name,
+				Subject:   p.Subject,
+				Metadata:  p.Metadata,
+				CreatedAt: p.CreatedAt,
+				UpdatedAt: p.UpdatedAt,
+			})
+		})
+	if err != nil {
+		return fmt.Errorf("dump principals: %w", err)
+	}
+	err = eachRow(ctx, tx,
+		fmt.Sprintf(`SELECT %s FROM entity_types WHERE namespace_id = $1 ORDER BY type`, typeColumns),
+		ns.ID, func(m typeModel) error { return sink.WriteEntityType(m.toTypeDefinition()) })
+	if err != nil {
+		return fmt.Errorf("dump entity types: %w", err)
+	}
+	err = eachRow(ctx, tx,
+		fmt.Sprintf(`SELECT %s FROM edge_types WHERE namespace_id = $1 ORDER BY type`, edgeTypeColumns),
+		ns.ID, func(m edgeTypeModel) error { return sink.WriteEdgeType(m.toEdgeTypeDefinition()) })
+	if err != nil {
+		return fmt.Errorf("dump edge types: %w", err)
+	}
+	err = eachRow(ctx, tx,
+		`SELECT alias, urn, created_at FROM entity_aliases WHERE namespace_id = $1 ORDER BY alias`,
+		ns.ID, func(m aliasModel) error {
+			return sink.WriteAlias(backup.Alias{Alias: m.Alias, URN: m.URN, CreatedAt: m.CreatedAt})
+		})
+	if err != nil {
+		return fmt.Errorf("dump aliases: %w", err)
+	}
+	if !withEmbeddings {
+		return nil
+	}
+	err = eachRow(ctx, tx,
+		`SELECT id, entity_urn, COALESCE(content_id::text, '') AS content_id,
+			COALESCE(content_type, 'entity') AS content_type, content, COALESCE(context, '') AS context,
+			embedding::text AS vector, position, COALESCE(heading, '') AS heading,
+			COALESCE(token_count, 0) AS token_count, created_at
+		 FROM embeddings WHERE namespace_id = $1 ORDER BY entity_urn, content_type, position, id`,
+		ns.ID, func(m embeddingDumpModel) error {
+			vec, err := parseVector(m.Vector)
+			if err != nil {
+				return fmt.Errorf("embedding %s: %w", m.ID, err)
+			}
+			return sink.WriteEmbedding(embedding.Embedding{
+				ID:          m.ID,
+				EntityURN:   m.EntityURN,
+				ContentID:   m.ContentID,
+				ContentType: m.ContentType,
+				Content:     m.Content,
+				Context:     m.Context,
+				Vector:      vec,
+				Position:    m.Position,
+				Heading:     m.Heading,
+				TokenCount:  m.TokenCount,
+				CreatedAt:   m.CreatedAt,
+			})
+		})
+	if err != nil {
+		return fmt.Errorf("dump embeddings: %w", err)
+	}
+	return nil
+}
+
+// Load writes every record of src to the namespace in one transaction. Rows
+// get new IDs; embeddings are re-pointed at the new IDs of the entities and
+// documents they were derived from.
+func (r *BackupRepository) Load(ctx context.Context, ns *namespace.Namespace, src backup.Source, withEmbeddings bool) (backup.Counts, error) {
+	var counts backup.Counts
+	err := runExclusiveTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		ids := map[string]string{}
+
+		rows := newRowBatch(ctx, tx, "entities", "id", "namespace_id", "urn", "type", "name", "description",
+			"properties", "property_sources", "source", "scope", "changed_by", "valid_from", "valid_to", "created_at", "updated_at")
+		err := src.Entities(func(e entity.Entity) error {
+			id := newRowID(ids, e.ID)
+			counts.Entities++
+			return rows.add(id, ns.ID, e.URN, e.Type, e.Name, e.Description, JSONMap(e.Properties),
+				propertySources(e.PropertySources), e.Source, e.Scope, nilIfEmpty(e.ChangedBy), e.ValidFrom, e.ValidTo, e.CreatedAt, e.UpdatedAt)
+		})
+		if err = rows.close(err); err != nil {
+			return fmt.Errorf("load entities: %w", err)
+		}
+
+		rows = newRowBatch(ctx, tx, "edges", "namespace_id", "source_urn", "target_urn", "type", "properties",
+			"valid_from", "valid_to", "source", "scope", "created_at")
+		err = src.Edges(func(e entity.Edge) error {
+			counts.Edges++
+			return rows.add(ns.ID, e.SourceURN, e.TargetURN, e.Type, JSONMap(e.Properties),
+				e.ValidFrom, e.ValidTo, e.Source, e.Scope, e.CreatedAt)
+		})
+		if err = rows.close(err); err != nil {
+			return fmt.Errorf("load edges: %w", err)
+		}
+
+		rows = newRowBatch(ctx, tx, "documents", "id", "namespace_id", "entity_urn", "title", "body", "format",
+			"source", "source_id", "properties", "created_at", "updated_at")
+		err = src.Documents(func(d document.Document) error {
+			id := newRowID(ids, d.ID)
+			counts.Documents++
+			return rows.add(id, ns.ID, d.EntityURN, d.Title, d.Body, d.Format,
+				d.Source, d.SourceID, JSONMap(d.Properties), d.CreatedAt, d.UpdatedAt)
+		})
+		if err = rows.close(err); err != nil {
+			return fmt.Errorf("load documents: %w", err)
+		}
+
+		// Principals may already exist in the target namespace, having logged
+		// in before the restore; those are kept as they are.
+		err = src.Principals(func(p backup.Principal) error {
+			var metadata []byte
+			if p.Metadata != nil {
+				var err error
+				if metadata, err = json.Marshal(p.Metadata); err != nil {
+					return err
+				}
+			}
+			counts.Principals++
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO principals (namespace_id, uuid, subject, name, type, metadata, created_at, updated_at)
+			 VALUES ($1, $2, $2, $3, $4, $5, $6, $7)
+			 ON CONFLICT (subject, namespace_id) WHERE subject IS NOT NULL AND subject != ''
+			 DO NOTHING`,
+				ns.ID, nilIfEmpty(p.Subject), nilIfEmpty(p.Name), p.Type, metadata, p.CreatedAt, p.UpdatedAt)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("load principals: %w", err)
+		}
+
+		rows = newRowBatch(ctx, tx, "entity_types", "namespace_id", "type", "display_name", "icon",
+			"schema", "required", "mode", "created_at", "updated_at")
+		err = src.EntityTypes(func(d entity.TypeDefinition) error {
+			counts.EntityTypes++
+			return rows.add(ns.ID, d.Type, d.DisplayName, d.Icon, JSONMap(d.Schema),
+				JSONStringList(d.Required), string(d.Mode), d.CreatedAt, d.UpdatedAt)
+		})
+		if err = rows.close(err); err != nil {
+			return fmt.Errorf("load entity types: %w", err)
+		}
+
+		rows = newRowBatch(ctx, tx, "edge_types", "namespace_id", "type", "source_types", "target_types",
+			"lineage", "inverse", "cardinality", "created_at", "updated_at")
+		err = src.EdgeTypes(func(d entity.EdgeTypeDefinition) error {
+			counts.EdgeTypes++
+			return rows.add(ns.ID, d.Type, typeList(d.SourceTypes), typeList(d.TargetTypes),
+				d.Lineage, d.Inverse, string(d.Cardinality), d.CreatedAt, d.UpdatedAt)
+		})
+		if err = rows.close(err); err != nil {
+			return fmt.Errorf("load edge types: %w", err)
+		}
+
+		rows = newRowBatch(ctx, tx, "entity_aliases", "namespace_id", "alias", "urn", "created_at")
+		err = src.Aliases(func(a backup.Alias) error {
+			counts.Aliases++
+			return rows.add(ns.ID, a.Alias, a.URN, a.CreatedAt)
+		})
+		if err = rows.close(err); err != nil {
+			return fmt.Errorf("load aliases: %w", err)
+		}
+
+		if !withEmbeddings {
+			return nil
+		}
+		rows = newRowBatch(ctx, tx, "embeddings", "namespace_id", "entity_urn", "content_id", "content_type",
+			"content", "context", "embedding", "position", "heading", "token_count", "created_at")
+		err = src.Embeddings(func(e embedding.Embedding) error {
+			counts.Embeddings++
+			return rows.add(ns.ID, e.EntityURN, nilIfEmpty(ids[e.ContentID]), e.ContentType,
+				e.Content, e.Context, vectorString(e.Vector), e.Position, e.Heading, e.TokenCount, e.CreatedAt)
+		})
+		if err = rows.close(err); err != nil {
+			return fmt.Errorf("load embeddings: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return backup.Counts{}, err
+	}
+	return counts, nil
+}
+
+// IsEmpty reports whether the namespace holds no entities, edges or
+// documents, current or historical, and no type definitions or aliases.
+func (r *BackupRepository) IsEmpty(ctx context.Context, ns *namespace.Namespace) (bool, error) {
+	var used bool
+	err := r.client.GetContext(ctx, &used,
+		`SELECT EXISTS (SELECT 1 FROM entities WHERE namespace_id = $1)
+		OR EXISTS (SELECT 1 FROM edges WHERE namespace_id = $1)
+		OR EXISTS (SELECT 1 FROM documents WHERE namespace_id = $1)
+		OR EXISTS (SELECT 1 FROM entity_types WHERE namespace_id = $1)
+		OR EXISTS (SELECT 1 FROM edge_types WHERE namespace_id = $1)
+		OR EXISTS (SELECT 1 FROM entity_aliases WHERE namespace_id = $1)`, ns.ID)
+	if err != nil {
+		return false, fmt.Errorf("check namespace is empty: %w", err)
+	}
+	return !used, nil
+}
+
+// eachRow scans every row of the query into a T and passes it to fn, without
+// holding the whole result in memory.
+func eachRow[T any](ctx context.Context, q sqlx.QueryerContext, query string, nsID uuid.UUID, fn func(T) error) error {
+	rows, err := q.QueryxContext(ctx, query, nsID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m T
+		if err := rows.StructScan(&m); err != nil {
+			return err
+		}
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// newRowID returns a new ID for an archived row, remembering the ID it
+// replaces.
+func newRowID(ids map[string]string, old string) string {
+	id := uuid.NewString()
+	if old != "" {
+		ids[old] = id
+	}
+	return id
+}
+
+// rowBatch inserts rows into a table in multi-row statements.
+type rowBatch struct {
+	ctx     context.Context
+	tx      *sqlx.Tx
+	table   string
+	columns []string
+	rows    [][]interface{}
+}
+
+func newRowBatch(ctx context.Context, tx *sqlx.Tx, table string, columns ...string) *rowBatch {
+	return &rowBatch{ctx: ctx, tx: tx, table: table, columns: columns}
+}
+
+func (b *rowBatch) add(values ...interface{}) error {
+	b.rows = append(b.rows, values)
+	if len(b.rows) < restoreBatchSize {
+		return nil
+	}
+	return b.flush()
+}
+
+// close writes the pending rows, unless err, the error that ended the
+// batch, is set.
+func (b *rowBatch) close(err error) error {
+	if err != nil {
+		return err
+	}
+	return b.flush()
+}
+
+func (b *rowBatch) flush() error {
+	if len(b.rows) == 0 {
+		return nil
+	}
+	builder := sq.Insert(b.table).Columns(b.columns...).PlaceholderFormat(sq.Dollar)
+	for _, values := range b.rows {
+		builder = builder.Values(values...)
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return fmt.Errorf("build insert %s: %w", b.table, err)
+	}
+	if _, err := b.tx.ExecContext(b.ctx, query, args...); err != nil {
+		return err
+	}
+	b.rows = b.rows[:0]
+	return nil
+}
+
+type aliasModel struct {
+	Alias     string    `db:"alias"`
+	URN       string    `db:"urn"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type embeddingDumpModel struct {
+	embeddingModel
+	Vector string `db:"vector"`
+}
+
+// parseVector parses the text form of a pgvector value, such as [1,2.5,3].
+func parseVector(s string) ([]float32, error) {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return nil, fmt.Errorf("invalid vector %q", s)
+	}
+	s = strings.TrimSpace(s[1 : len(s)-1])
+	if s == "" {
+		return nil, nil
+	}
+	parts := strings.Split(s, ",")
+	vec := make([]float32, len(parts))
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vector component %q: %w", p, err)
+		}
+		vec[i] = float32(f)
+	}
+	return vec, nil
+}
@@ -0,0 +1,91 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/raystack/compass/core/batch"
+	"github.com/raystack/compass/core/namespace"
+)
+
+// BatchRepository applies mixed batches of writes in one transaction.
+type BatchRepository struct {
+	client *Client
+}
+
+func NewBatchRepository(client *Client) (*BatchRepository, error) {
+	if client == nil {
+		return nil, errors.New("postgres client is nil")
+	}
+	return &BatchRepository{client: client}, nil
+}
+
+// Commit applies the operations in order. Each operation is written at its
+// own, increasing timestamp: two writes of one entity or edge would otherwise
+// collide on its version key, and restoring an entity deleted in the batch
+// would bring back edges that another operation deleted explicitly.
+func (r *BatchRepository) Commit(ctx context.Context, ns *namespace.Namespace, ops []batch.Operation) ([]batch.Result, error) {
+	results := make([]batch.Result, len(ops))
+	err := runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		// Lock the entities the batch writes up front, in order, rather than
+		// one by one as each operation reaches them.
+		var urns []string
+		for _, op := range ops {
+			switch op.Action() {
+			case batch.ActionUpsertEntity:
+				urns = append(urns, lookupURN(ns, op.UpsertEntity.URN))
+			case batch.ActionDeleteEntity:
+				urns = append(urns, lookupURN(ns, op.DeleteEntity.URN))
+			}
+		}
+		if err := lockEntitiesTx(ctx, tx, ns, urns...); err != nil {
+			return err
+		}
+
+		clock := batch.NewClock(time.Now)
+		for i, op := range ops {
+			now := clock.Next()
+			res := batch.Result{Index: i, Action: op.Action()}
+			var err error
+			switch res.Action {
+			case batch.ActionUpsertEntity:
+				res.ID, err = upsertEntityTx(ctx, tx, ns, op.UpsertEntity, now)
+				op.UpsertEntity.ID = res.ID
+			case batch.ActionDeleteEntity:
+				err = deleteEntityTx(ctx, tx, ns, op.DeleteEntity.URN, now)
+			case batch.ActionUpsertEdge:
+				if err = op.UpsertEdge.CanonicalizeURNs(ns); err != nil {
+					break
+				}
+				if err = resolveAliases(ctx, tx, ns, &op.UpsertEdge.SourceURN, &op.UpsertEdge.TargetURN); err != nil {
+					break
+				}
+				if err = checkEdgeTypeTx(ctx, tx, ns, op.UpsertEdge); err == nil {
+					err = upsertEdgeTx(ctx, tx, ns, op.UpsertEdge, now)
+				}
+				res.ID = op.UpsertEdge.ID
+			case batch.ActionDeleteEdge:
+				e := op.DeleteEdge
+				err = closeEdgesTx(ctx, tx, ns, now,
+					`namespace_id = $1 AND source_urn = $2 AND target_urn = $3 AND type = $4`,
+					ns.ID, lookupURN(ns, e.SourceURN), lookupURN(ns, e.TargetURN), e.Type)
+			case batch.ActionUpsertDocument:
+				res.ID, err = upsertDocumentTx(ctx, tx, ns, op.UpsertDocument, now)
+				op.UpsertDocument.ID = res.ID
+			default:
+				err = batch.ErrInvalid
+			}
+			if err != nil {
+				return &batch.OpError{Index: i, Action: res.Action, Err: err}
+			}
+			results[i] = res
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,534 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/raystack/compass/core/change"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+)
+
+// BulkRepository writes batches of entities and edges. Each batch takes one
+// transaction: the current versions are locked with a single query, changed
+// ones are closed with a single update, and new versions and their change
+// records are inserted with one multi-row statement each.
+type BulkRepository struct {
+	client *Client
+}
+
+func NewBulkRepository(client *Client) (*BulkRepository, error) {
+	if client == nil {
+		return nil, errors.New("postgres client is nil")
+	}
+	return &BulkRepository{client: client}, nil
+}
+
+// UpsertEntities writes the entities with the semantics of
+// EntityRepository.Upsert, or of EntityRepository.UpsertWithColumns for the
+// types in columns. Canonical URNs must be unique within the batch.
+func (r *BulkRepository) UpsertEntities(ctx context.Context, ns *namespace.Namespace, ents []*entity.Entity, columns map[entity.Type]bool) ([]entity.UpsertOutcome, error) {
+	if len(ents) == 0 {
+		return nil, nil
+	}
+	urns := make([]string, len(ents))
+	seen := make(map[string]bool, len(ents))
+	for i, ent := range ents {
+		if err := ent.CanonicalizeURN(ns); err != nil {
+			return nil, err
+		}
+		if seen[ent.URN] {
+			return nil, fmt.Errorf("duplicate urn %q in batch", ent.URN)
+		}
+		seen[ent.URN] = true
+		urns[i] = ent.URN
+	}
+
+	outcomes := make([]entity.UpsertOutcome, len(ents))
+	err := runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		if err := lockEntitiesTx(ctx, tx, ns, urns...); err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		query, args, err := sq.Select(entityColumns).From("entities").
+			Where(sq.Eq{"namespace_id": ns.ID, "urn": urns}).
+			Where("valid_to IS NULL").
+			Suffix("FOR UPDATE").
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("build existing entities query: %w", err)
+		}
+		var models []entityModel
+		if err := tx.SelectContext(ctx, &models, query, args...); err != nil {
+			return fmt.Errorf("check existing entities: %w", err)
+		}
+		existing := make(map[string]entityModel, len(models))
+		for _, m := range models {
+			existing[m.URN] = m
+		}
+
+		// Column entities follow the merged properties of every entity,
+		// written or unchanged.
+		writeColumns := func() error {
+			for _, ent := range ents {
+				if !columns[ent.Type] {
+					continue
+				}
+				if err := writeColumnsTx(ctx, tx, ns, *ent, now); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+		var closeIDs []string
+		var written []int
+		insert := sq.Insert("entities").
+			Columns("namespace_id", "urn", "type", "name", "description", "properties", "property_sources", "source", "scope", "changed_by", "valid_from", "created_at", "updated_at").
+			Suffix("RETURNING id, urn").
+			PlaceholderFormat(sq.Dollar)
+		for i, ent := range ents {
+			createdAt := now
+			var current *entity.Entity
+			cur, ok := existing[ent.URN]
+			if ok {
+				prev := cur.toEntity()
+				current = &prev
+			}
+			if err := ent.MergeProperties(ns, current, now); err != nil {
+				return err
+			}
+			if ok {
+				if sameEntityContent(*current, *ent) {
+					ent.ID = cur.ID
+					ent.CreatedAt = cur.CreatedAt
+					ent.UpdatedAt = cur.UpdatedAt
+					ent.ValidFrom = cur.ValidFrom
+					outcomes[i] = entity.UpsertOutcome{Status: entity.UpsertUnchanged}
+					continue
+				}
+				closeIDs = append(closeIDs, cur.ID)
+				createdAt = cur.CreatedAt
+				outcomes[i] = entity.UpsertOutcome{Status: entity.UpsertUpdated, Previous: current}
+			} else {
+				outcomes[i] = entity.UpsertOutcome{Status: entity.UpsertCreated}
+			}
+			insert = insert.Values(ns.ID, ent.URN, string(ent.Type), ent.Name, ent.Description,
+				JSONMap(ent.Properties), propertySources(ent.PropertySources), ent.Source, ent.Scope, nilIfEmpty(ent.ChangedBy), now, createdAt, now)
+			ent.CreatedAt = createdAt
+			written = append(written, i)
+		}
+		if len(written) == 0 {
+			return writeColumns()
+		}
+
+		if len(closeIDs) > 0 {
+			query, args, err := sq.Update("entities").Set("valid_to", now).
+				Where(sq.Eq{"id": closeIDs}).
+				PlaceholderFormat(sq.Dollar).
+				ToSql()
+			if err != nil {
+				return fmt.Errorf("build close entity versions: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+				return fmt.Errorf("close entity versions: %w", err)
+			}
+		}
+
+		query, args, err = insert.ToSql()
+		if err != nil {
+			return fmt.Errorf("build insert entities: %w", err)
+		}
+		var inserted []struct {
+			ID  string `db:"id"`
+			URN string `db:"urn"`
+		}
+		if err := tx.SelectContext(ctx, &inserted, query, args...); err != nil {
+			return fmt.Errorf("insert entities: %w", err)
+		}
+		ids := make(map[string]string, len(inserted))
+		for _, row := range inserted {
+			ids[row.URN] = row.ID
+		}
+
+		// Entities written with a merged URN take the URN back.
+		var created []string
+		for _, i := range written {
+			if outcomes[i].Status == entity.UpsertCreated {
+				created = append(created, ents[i].URN)
+			}
+		}
+		if len(created) > 0 {
+			if _, err := tx.ExecContext(ctx,
+				`DELETE FROM entity_aliases WHERE namespace_id = $1 AND alias = ANY($2)`, ns.ID, created); err != nil {
+				return fmt.Errorf("delete aliases: %w", err)
+			}
+		}
+
+		records := make([]changeRecord, len(written))
+		for j, i := range written {
+			ent := ents[i]
+			ent.ID = ids[ent.URN]
+			ent.NamespaceID = ns.ID.String()
+			ent.UpdatedAt = now
+			ent.ValidFrom = now
+			ent.ValidTo = nil
+			op := change.OpCreated
+			if outcomes[i].Status == entity.UpsertUpdated {
+				op = change.OpUpdated
+			}
+			records[j] = changeRecord{kind: change.KindEntity, op: op, key: ent.URN, typ: string(ent.Type), payload: *ent}
+		}
+		if err := recordChangesTx(ctx, tx, ns, records); err != nil {
+			return err
+		}
+		return writeColumns()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return outcomes, nil
+}
+
+// UpsertEdges writes the edges with the semantics of EdgeRepository.Upsert.
+// Canonical edge keys must be unique within the batch. Edges are checked
+// against their type declarations in the write transaction, and one that is
+// not allowed fails the batch with entity.ErrEdgeConstraint; edges of the
+// batch are not counted against each other, which the caller checks.
+func (r *BulkRepository) UpsertEdges(ctx context.Context, ns *namespace.Namespace, edges []*entity.Edge) ([]entity.UpsertStatus, error) {
+	if len(edges) == 0 {
+		return nil, nil
+	}
+	if err := r.resolveEdgeURNs(ctx, ns, edges); err != nil {
+		return nil, err
+	}
+	keys := make(sq.Or, len(edges))
+	seen := make(map[string]bool, len(edges))
+	for i, e := range edges {
+		k := change.EdgeKey(e.SourceURN, e.Type, e.TargetURN)
+		if seen[k] {
+			return nil, fmt.Errorf("duplicate edge %q in batch", k)
+		}
+		seen[k] = true
+		keys[i] = sq.Eq{"source_urn": e.SourceURN, "target_urn": e.TargetURN, "type": e.Type}
+	}
+
+	statuses := make([]entity.UpsertStatus, len(edges))
+	err := runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		locked := make([]entity.Edge, len(edges))
+		for i, e := range edges {
+			if err := checkEdgeTypeTx(ctx, tx, ns, e); err != nil {
+				return err
+			}
+			locked[i] = *e
+		}
+		if err := lockEdgesTx(ctx, tx, ns, locked...); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		query, args, err := sq.Select(edgeColumns).From("edges").
+			Where(sq.Eq{"namespace_id": ns.ID}).
+			Where("valid_to IS NULL").
+			Where(keys).
+			OrderBy("valid_from").
+			Suffix("FOR UPDATE").
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return fmt.Errorf("build existing edges query: %w", err)
+		}
+		var models []edgeModel
+		if err := tx.SelectContext(ctx, &models, query, args...); err != nil {
+			return fmt.Errorf("check existing edges: %w", err)
+		}
+		// Ordered by valid_from, so the latest current row wins; every
+		// current row of a changed edge is closed.
+		existing := make(map[string]edgeModel, len(models))
+		currentIDs := make(map[string][]string, len(models))
+		for _, m := range models {
+			k := change.EdgeKey(m.SourceURN, m.Type, m.TargetURN)
+			existing[k] = m
+			currentIDs[k] = append(currentIDs[k], m.ID)
+		}
+
+		var closeIDs []string
+		var written []int
+		insert := sq.Insert("edges").
+			Columns("namespace_id", "source_urn", "target_urn", "type", "properties", "source", "scope", "valid_from", "created_at").
+			Suffix("RETURNING id, source_urn, target_urn, type").
+			PlaceholderFormat(sq.Dollar)
+		for i, e := range edges {
+			k := change.EdgeKey(e.SourceURN, e.Type, e.TargetURN)
+			if cur, ok := existing[k]; ok {
+				if sameEdgeContent(cur, *e) {
+					e.ID = cur.ID
+					e.ValidFrom = cur.ValidFrom
+					e.CreatedAt = cur.CreatedAt
+					statuses[i] = entity.UpsertUnchanged
+					continue
+				}
+				closeIDs = append(closeIDs, currentIDs[k]...)
+				statuses[i] = entity.UpsertUpdated
+			} else {
+				statuses[i] = entity.UpsertCreated
+			}
+			insert = insert.Values(ns.ID, e.SourceURN, e.TargetURN, e.Type, JSONMap(e.Properties), e.Source, e.Scope, now, now)
+			written = append(written, i)
+		}
+		if len(written) == 0 {
+			return nil
+		}
+
+		if len(closeIDs) > 0 {
+			query, args, err := sq.Update("edges").Set("valid_to", now).
+				Where(sq.Eq{"id": closeIDs}).
+				PlaceholderFormat(sq.Dollar).
+				ToSql()
+			if err != nil {
+				return fmt.Errorf("build close edge versions: %w", err)
+			}
+			if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+				return fmt.Errorf("close edge versions: %w", err)
+			}
+		}
+
+		query, args, err = insert.ToSql()
+		if err != nil {
+			return fmt.Errorf("build insert edges: %w", err)
+		}
+		var inserted []edgeModel
+		if err := tx.SelectContext(ctx, &inserted, query, args...); err != nil {
+			return fmt.Errorf("insert edges: %w", err)
+		}
+		ids := make(map[string]string, len(inserted))
+		for _, row := range inserted {
+			ids[change.EdgeKey(row.SourceURN, row.Type, row.TargetURN)] = row.ID
+		}
+
+		records := make([]changeRecord, len(written))
+		for j, i := range written {
+			e := edges[i]
+			k := change.EdgeKey(e.SourceURN, e.Type, e.TargetURN)
+			e.ID = ids[k]
+			e.NamespaceID = ns.ID.String()
+			e.ValidFrom = now
+			e.ValidTo = nil
+			e.CreatedAt = now
+			op := change.OpCreated
+			if statuses[i] == entity.UpsertUpdated {
+				op = change.OpUpdated
+			}
+			records[j] = changeRecord{kind: change.KindEdge, op: op, key: k, typ: e.Type, payload: e}
+		}
+		return recordChangesTx(ctx, tx, ns, records)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+// CheckEntities reports what UpsertEntities would do with each entity,
+// without writing. URNs must be unique within the batch.
+func (r *BulkRepository) CheckEntities(ctx context.Context, ns *namespace.Namespace, ents []*entity.Entity) ([]entity.UpsertStatus, error) {
+	if len(ents) == 0 {
+		return nil, nil
+	}
+	urns := make([]string, len(ents))
+	for i, ent := range ents {
+		if err := ent.CanonicalizeURN(ns); err != nil {
+			return nil, err
+		}
+		urns[i] = ent.URN
+	}
+	query, args, err := sq.Select(entityColumns).From("entities").
+		Where(sq.Eq{"namespace_id": ns.ID, "urn": urns}).
+		Where("valid_to IS NULL").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build existing entities query: %w", err)
+	}
+	var models []entityModel
+	if err := r.client.SelectContext(ctx, &models, query, args...); err != nil {
+		return nil, fmt.Errorf("check existing entities: %w", err)
+	}
+	existing := make(map[string]entityModel, len(models))
+	for _, m := range models {
+		existing[m.URN] = m
+	}
+
+	now := time.Now().UTC()
+	statuses := make([]entity.UpsertStatus, len(ents))
+	for i, ent := range ents {
+		var current *entity.Entity
+		cur, ok := existing[ent.URN]
+		if ok {
+			prev := cur.toEntity()
+			current = &prev
+		}
+		if err := ent.MergeProperties(ns, current, now); err != nil {
+			return nil, err
+		}
+		switch {
+		case !ok:
+			statuses[i] = entity.UpsertCreated
+		case sameEntityContent(*current, *ent):
+			ent.ID = cur.ID
+			statuses[i] = entity.UpsertUnchanged
+		default:
+			statuses[i] = entity.UpsertUpdated
+		}
+	}
+	return statuses, nil
+}
+
+// CheckEdges reports what UpsertEdges would do with each edge, without
+// writing. Edge keys must be unique within the batch.
+func (r *BulkRepository) CheckEdges(ctx context.Context, ns *namespace.Namespace, edges []*entity.Edge) ([]entity.UpsertStatus, error) {
+	if len(edges) == 0 {
+		return nil, nil
+	}
+	if err := r.resolveEdgeURNs(ctx, ns, edges); err != nil {
+		return nil, err
+	}
+	keys := make(sq.Or, len(edges))
+	for i, e := range edges {
+		keys[i] = sq.Eq{"source_urn": e.SourceURN, "target_urn": e.TargetURN, "type": e.Type}
+	}
+	query, args, err := sq.Select(edgeColumns).From("edges").
+		Where(sq.Eq{"namespace_id": ns.ID}).
+		Where("valid_to IS NULL").
+		Where(keys).
+		OrderBy("valid_from").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build existing edges query: %w", err)
+	}
+	var models []edgeModel
+	if err := r.client.SelectContext(ctx, &models, query, args...); err != nil {
+		return nil, fmt.Errorf("check existing edges: %w", err)
+	}
+	existing := make(map[string]edgeModel, len(models))
+	for _, m := range models {
+		existing[change.EdgeKey(m.SourceURN, m.Type, m.TargetURN)] = m
+	}
+
+	statuses := make([]entity.UpsertStatus, len(edges))
+	for i, e := range edges {
+		cur, ok := existing[change.EdgeKey(e.SourceURN, e.Type, e.TargetURN)]
+		switch {
+		case !ok:
+			statuses[i] = entity.UpsertCreated
+		case sameEdgeContent(cur, *e):
+			e.ID = cur.ID
+			statuses[i] = entity.UpsertUnchanged
+		default:
+			statuses[i] = entity.UpsertUpdated
+		}
+	}
+	return statuses, nil
+}
+
+// resolveEdgeURNs canonicalises the endpoint URNs of the edges and replaces
+// the endpoints merged into another entity by that entity.
+func (r *BulkRepository) resolveEdgeURNs(ctx context.Context, ns *namespace.Namespace, edges []*entity.Edge) error {
+	urns := make([]*string, 0, 2*len(edges))
+	for _, e := range edges {
+		if err := e.CanonicalizeURNs(ns); err != nil {
+			return err
+		}
+		urns = append(urns, &e.SourceURN, &e.TargetURN)
+	}
+	return resolveAliases(ctx, r.client, ns, urns...)
+}
+
+// Prune soft-deletes, in one transaction, the current entities and edges of
+// the source and scope that are not kept. Deleting an entity also deletes
+// its edges and its column entities, as Delete does; those count towards the
+// pruned edges and entities when they belong to the source and scope.
+func (r *BulkRepository) Prune(ctx context.Context, ns *namespace.Namespace, source, scope string, keepEntities, keepEdges map[string]bool) (entity.PruneResult, error) {
+	var res entity.PruneResult
+	err := runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		now := time.Now().UTC()
+		var urns []string
+		if err := tx.SelectContext(ctx, &urns,
+			`SELECT urn FROM entities
+			 WHERE namespace_id = $1 AND source = $2 AND scope = $3 AND valid_to IS NULL
+			 ORDER BY urn`,
+			ns.ID, source, scope); err != nil {
+			return fmt.Errorf("list synced entities: %w", err)
+		}
+		deleted := make(map[string]bool)
+		for _, urn := range urns {
+			if keepEntities[urn] {
+				continue
+			}
+			if deleted[urn] {
+				res.Entities++
+				continue
+			}
+			if err := lockEntitiesTx(ctx, tx, ns, urn); err != nil {
+				return err
+			}
+			columns, err := currentColumnsTx(ctx, tx, ns, urn)
+			if err != nil {
+				return err
+			}
+			if err := deleteEntityTx(ctx, tx, ns, urn, now); err != nil {
+				return err
+			}
+			res.Entities++
+			for _, col := range columns {
+				if err := deleteEntityTx(ctx, tx, ns, col, now); err != nil && !errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("delete column %s: %w", col, err)
+				}
+				deleted[col] = true
+			}
+		}
+
+		var edges []edgeModel
+		if err := tx.SelectContext(ctx, &edges,
+			fmt.Sprintf(`SELECT %s FROM edges
+				WHERE namespace_id = $1 AND source = $2 AND scope = $3 AND valid_to IS NULL`, edgeColumns),
+			ns.ID, source, scope); err != nil {
+			return fmt.Errorf("list synced edges: %w", err)
+		}
+		var ids []string
+		for _, e := range edges {
+			if !keepEdges[change.EdgeKey(e.SourceURN, e.Type, e.TargetURN)] {
+				ids = append(ids, e.ID)
+			}
+		}
+		if len(ids) > 0 {
+			where, args, err := sq.Eq{"id": ids}.ToSql()
+			if err != nil {
+				return fmt.Errorf("build prune edges: %w", err)
+			}
+			where, err = sq.Dollar.ReplacePlaceholders(where)
+			if err != nil {
+				return fmt.Errorf("build prune edges: %w", err)
+			}
+			if err := closeEdgesTx(ctx, tx, ns, now, where, args...); err != nil {
+				return err
+			}
+		}
+
+		return tx.GetContext(ctx, &res.Edges,
+			`SELECT COUNT(*) FROM edges
+			 WHERE namespace_id = $1 AND source = $2 AND scope = $3 AND valid_to = $4`,
+			ns.ID, source, scope, now)
+	})
+	if err != nil {
+		return entity.PruneResult{}, err
+	}
+	return res, nil
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/raystack/compass/core/entity"
+)
+
+func TestBulkRepository_Prune_DeletesColumns(t *testing.T) {
+	ctx, client, ns := testNamespace(t)
+	entities, err := NewEntityRepository(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewBulkRepository(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table := &entity.Entity{
+		URN: "urn:bigquery:orders", Type: entity.TypeTable, Name: "orders", Source: "bigquery",
+		Properties: map[string]interface{}{"columns": []interface{}{map[string]interface{}{"name": "id"}}},
+	}
+	if _, err := repo.UpsertEntities(ctx, ns, []*entity.Entity{table}, map[entity.Type]bool{entity.TypeTable: true}); err != nil {
+		t.Fatal(err)
+	}
+	column := entity.ColumnURN(table.URN, "id")
+	if _, err := entities.GetByURN(ctx, ns, column); err != nil {
+		t.Fatalf("expected the column entity to be written: %v", err)
+	}
+
+	res, err := repo.Prune(ctx, ns, "bigquery", "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Entities != 2 {
+		t.Errorf("expected the table and its column pruned, got %d entities", res.Entities)
+	}
+	if _, err := entities.GetByURN(ctx, ns, column); err == nil {
+		t.Error("expected the column entity to be deleted with its table")
+	}
+}
@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/raystack/compass/core/change"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/principal"
+)
+
+type ChangeRepository struct {
+	client *Client
+}
+
+func NewChangeRepository(client *Client) (*ChangeRepository, error) {
+	if client == nil {
+		return nil, errors.New("postgres client is nil")
+	}
+	return &ChangeRepository{client: client}, nil
+}
+
+func (r *ChangeRepository) List(ctx context.Context, ns *namespace.Namespace, flt change.Filter) ([]change.Change, error) {
+	builder := sq.Select("seq", "namespace_id", "kind", "op", "key", "type", "payload",
+		"COALESCE(changed_by, '') AS changed_by", "created_at").
+		From("changes").
+		Where(sq.Eq{"namespace_id": ns.ID}).
+		Where(sq.Gt{"seq": flt.SinceSeq}).
+		OrderBy("seq").
+		Limit(uint64(flt.Limit)).
+		PlaceholderFormat(sq.Dollar)
+
+	if len(flt.Kinds) > 0 {
+		kinds := make([]string, len(flt.Kinds))
+		for i, k := range flt.Kinds {
+			kinds[i] = string(k)
+		}
+		builder = builder.Where(sq.Eq{"kind": kinds})
+	}
+	if len(flt.Types) > 0 {
+		builder = builder.Where(sq.Eq{"type": flt.Types})
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+
+	var models []changeModel
+	if err := r.client.SelectContext(ctx, &models, query, args...); err != nil {
+		return nil, fmt.Errorf("list changes: %w", err)
+	}
+	result := make([]change.Change, len(models))
+	for i, m := range models {
+		result[i] = m.toChange()
+	}
+	return result, nil
+}
+
+// Record appends a change record in a transaction of its own, for changes
+// found after the mutation committed, such as the schema impact of an upsert.
+func (r *ChangeRepository) Record(ctx context.Context, ns *namespace.Namespace, kind change.Kind, op change.Op, key, typ string, payload interface{}) error {
+	return runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		return recordChangeTx(ctx, tx, ns, kind, op, key, typ, payload)
+	})
+}
+
+// runWriteTx runs f in a write transaction of the namespace. Writers hold the
+// namespace's write lock shared, so they run concurrently, and guard the
+// entities and edges they write with lockEntitiesTx and lockEdgesTx. The
+// change records f makes are held back and appended when f returns, under
+// the change feed lock, which is held until commit: concurrent writers commit
+// their records in seq order, and a reader following seq never skips a
+// record that commits late, while writers only queue for the tail of their
+// transactions.
+func runWriteTx(ctx context.Context, client *Client, ns *namespace.Namespace, f func(tx *sqlx.Tx) error) error {
+	return runTx(ctx, client, ns, false, f)
+}
+
+// runExclusiveTx runs f as runWriteTx does, but holding the namespace's write
+// lock exclusively, for maintenance that rewrites or erases rows across many
+// objects: merges, purges and loading a backup.
+func runExclusiveTx(ctx context.Context, client *Client, ns *namespace.Namespace, f func(tx *sqlx.Tx) error) error {
+	return runTx(ctx, client, ns, true, f)
+}
+
+// pendingChanges holds the change records of each transaction run by runTx
+// until they are appended.
+var pendingChanges sync.Map // *sqlx.Tx -> *[]changeRecord
+
+func runTx(ctx context.Context, client *Client, ns *namespace.Namespace, exclusive bool, f func(tx *sqlx.Tx) error) error {
+	return client.RunWithinTx(ctx, func(tx *sqlx.Tx) error {
+		lock := `SELECT pg_advisory_xact_lock_shared(hashtext('writes:' || $1))`
+		if exclusive {
+			lock = `SELECT pg_advisory_xact_lock(hashtext('writes:' || $1))`
+		}
+		if _, err := tx.ExecContext(ctx, lock, ns.ID.String()); err != nil {
+			return fmt.Errorf("lock namespace writes: %w", err)
+		}
+		pending := &[]changeRecord{}
+		pendingChanges.Store(tx, pending)
+		defer pendingChanges.Delete(tx)
+		if err := f(tx); err != nil {
+			return err
+		}
+		return appendChangesTx(ctx, tx, ns, *pending)
+	})
+}
+
+// lockChangesTx takes the change feed lock of the namespace for the rest of
+// the transaction. Taking it again in the same transaction is a no-op.
+func lockChangesTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('changes:' || $1))`, ns.ID.String()); err != nil {
+		return fmt.Errorf("lock change feed: %w", err)
+	}
+	return nil
+}
+
+// recordChangeTx records a change within the mutation's transaction, which
+// must have been started with runWriteTx.
+func recordChangeTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, kind change.Kind, op change.Op, key, typ string, payload interface{}) error {
+	return recordChangesTx(ctx, tx, ns, []changeRecord{{kind: kind, op: op, key: key, typ: typ, payload: payload}})
+}
+
+// changeRecord is a change waiting to be appended by appendChangesTx.
+type changeRecord struct {
+	kind    change.Kind
+	op      change.Op
+	key     string
+	typ     string
+	payload interface{}
+}
+
+// recordChangesTx records changes, in order. Payloads are encoded at once,
+// so later edits of the recorded objects do not leak into the feed. In a
+// transaction started with runWriteTx the records are appended when it ends;
+// otherwise they are appended now.
+func recordChangesTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, records []changeRecord) error {
+	encoded := make([]changeRecord, len(records))
+	for i, rec := range records {
+		body, err := toPayload(rec.payload)
+		if err != nil {
+			return fmt.Errorf("encode change payload: %w", err)
+		}
+		rec.payload = body
+		encoded[i] = rec
+	}
+	if pending, ok := pendingChanges.Load(tx); ok {
+		records := pending.(*[]changeRecord)
+		*records = append(*records, encoded...)
+		return nil
+	}
+	return appendChangesTx(ctx, tx, ns, encoded)
+}
+
+// appendChangesTx takes the change feed lock and appends change records with
+// one statement.
+func appendChangesTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, records []changeRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	if err := lockChangesTx(ctx, tx, ns); err != nil {
+		return err
+	}
+
+	changedBy := nilIfEmpty(principal.FromContext(ctx).Subject)
+	builder := sq.Insert("changes").
+		Columns("namespace_id", "kind", "op", "key", "type", "payload", "changed_by").
+		PlaceholderFormat(sq.Dollar)
+	for _, rec := range records {
+		body, err := toPayload(rec.payload)
+		if err != nil {
+			return fmt.Errorf("encode change payload: %w", err)
+		}
+		builder = builder.Values(ns.ID, string(rec.kind), string(rec.op), rec.key, rec.typ, body, changedBy)
+	}
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return fmt.Errorf("build change records: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("record change: %w", err)
+	}
+	return nil
+}
+
+// toPayload converts a domain object to the JSON map stored with a change.
+func toPayload(v interface{}) (JSONMap, error) {
+	switch v := v.(type) {
+	case nil:
+		return nil, nil
+	case JSONMap:
+		return v, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m JSONMap
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+type changeModel struct {
+	Seq         int64     `db:"seq"`
+	NamespaceID string    `db:"namespace_id"`
+	Kind        string    `db:"kind"`
+	Op          string    `db:"op"`
+	Key         string    `db:"key"`
+	Type        string    `db:"type"`
+	Payload     JSONMap   `db:"payload"`
+	ChangedBy   string    `db:"changed_by"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+func (m changeModel) toChange() change.Change {
+	return change.Change{
+		Seq:         m.Seq,
+		NamespaceID: m.NamespaceID,
+		Kind:        change.Kind(m.Kind),
+		Op:          change.Op(m.Op),
+		Key:         m.Key,
+		Type:        m.Type,
+		Payload:     m.Payload,
+		ChangedBy:   m.ChangedBy,
+		CreatedAt:   m.CreatedAt,
+	}
+}
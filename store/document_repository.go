@@ -9,6 +9,7 @@ import (
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/raystack/compass/core/change"
 	"github.com/raystack/compass/core/document"
 	"github.com/raystack/compass/core/namespace"
 )
@@ -24,30 +25,63 @@ func NewDocumentRepository(client *Client) (*DocumentRepository, error) {
 	return &DocumentRepository{client: client}, nil
 }
 
-func (r *DocumentRepository) Upsert(ctx context.Context, ns *namespace.Namespace, doc *document.Document) (string, error) {
-	now := time.Now().UTC()
-	doc.UpdatedAt = now
+var documentColumns = `id, namespace_id, entity_urn, title, body, format,
+	COALESCE(source, '') AS source, COALESCE(source_id, '') AS source_id,
+	properties, created_at, updated_at`
 
+func (r *DocumentRepository) Upsert(ctx context.Context, ns *namespace.Namespace, doc *document.Document) (string, error) {
 	var id string
-	err := r.client.QueryFn(ctx, func(conn *sqlx.Conn) error {
-		return conn.QueryRowxContext(ctx,
-			`INSERT INTO documents (namespace_id, entity_urn, title, body, format, source, source_id, properties, created_at, updated_at)
-			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-			 ON CONFLICT (namespace_id, entity_urn, source, source_id)
-			 DO UPDATE SET title = EXCLUDED.title, body = EXCLUDED.body, format = EXCLUDED.format,
-			              properties = EXCLUDED.properties, updated_at = EXCLUDED.updated_at
-			 RETURNING id`,
-			ns.ID, doc.EntityURN, doc.Title, doc.Body, doc.Format,
-			doc.Source, nilIfEmpty(doc.SourceID), JSONMap(doc.Properties), now, now,
-		).Scan(&id)
+	err := runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		var err error
+		id, err = upsertDocumentTx(ctx, tx, ns, doc, time.Now().UTC())
+		return err
 	})
 	if err != nil {
 		return "", fmt.Errorf("upsert document: %w", err)
 	}
-	doc.CreatedAt = now
 	return id, nil
 }
 
+func upsertDocumentTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, doc *document.Document, now time.Time) (string, error) {
+	doc.EntityURN = lookupURN(ns, doc.EntityURN)
+	if err := resolveAliases(ctx, tx, ns, &doc.EntityURN); err != nil {
+		return "", err
+	}
+	doc.UpdatedAt = now
+
+	var res struct {
+		ID        string    `db:"id"`
+		CreatedAt time.Time `db:"created_at"`
+		Inserted  bool      `db:"inserted"`
+	}
+	err := tx.QueryRowxContext(ctx,
+		`INSERT INTO documents (namespace_id, entity_urn, title, body, format, source, source_id, properties, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 ON CONFLICT (namespace_id, entity_urn, source, source_id)
+		 DO UPDATE SET title = EXCLUDED.title, body = EXCLUDED.body, format = EXCLUDED.format,
+		              properties = EXCLUDED.properties, updated_at = EXCLUDED.updated_at
+		 RETURNING id, created_at, (xmax = 0) AS inserted`,
+		ns.ID, doc.EntityURN, doc.Title, doc.Body, doc.Format,
+		doc.Source, nilIfEmpty(doc.SourceID), JSONMap(doc.Properties), now, now,
+	).StructScan(&res)
+	if err != nil {
+		return "", err
+	}
+	doc.CreatedAt = res.CreatedAt
+
+	op := change.OpUpdated
+	if res.Inserted {
+		op = change.OpCreated
+	}
+	payload := *doc
+	payload.ID = res.ID
+	payload.NamespaceID = ns.ID.String()
+	if err := recordChangeTx(ctx, tx, ns, change.KindDocument, op, res.ID, "", payload); err != nil {
+		return "", err
+	}
+	return res.ID, nil
+}
+
 func (r *DocumentRepository) GetByID(ctx context.Context, id string) (document.Document, error) {
 	var m documentModel
 	err := r.client.GetContext(ctx, &m,
@@ -70,8 +104,8 @@ func (r *DocumentRepository) GetByEntityURN(ctx context.Context, ns *namespace.N
 		`SELECT id, namespace_id, entity_urn, title, body, format,
 				COALESCE(source, '') as source, COALESCE(source_id, '') as source_id,
 				properties, created_at, updated_at
-		 FROM documents WHERE namespace_id = $1 AND entity_urn = $2
-		 ORDER BY created_at`, ns.ID, entityURN)
+		 FROM documents WHERE namespace_id = $1 AND `+urnOrAlias("entity_urn", "$1", "$2")+`
+		 ORDER BY created_at`, ns.ID, lookupURN(ns, entityURN))
 	if err != nil {
 		return nil, fmt.Errorf("get documents by entity: %w", err)
 	}
@@ -84,11 +118,12 @@ func (r *DocumentRepository) GetAll(ctx context.Context, ns *namespace.Namespace
 		"properties", "created_at", "updated_at").
 		From("documents").
 		Where(sq.Eq{"namespace_id": ns.ID}).
-		OrderBy("created_at DESC").
+		OrderBy("created_at DESC", "id").
 		PlaceholderFormat(sq.Dollar)
 
 	if filter.EntityURN != "" {
-		builder = builder.Where(sq.Eq{"entity_urn": filter.EntityURN})
+		entityURN := lookupURN(ns, filter.EntityURN)
+		builder = builder.Where(sq.Expr(urnOrAlias("entity_urn", "?", "?"), entityURN, ns.ID, entityURN))
 	}
 	if filter.Source != "" {
 		builder = builder.Where(sq.Eq{"source": filter.Source})
@@ -113,22 +148,43 @@ func (r *DocumentRepository) GetAll(ctx context.Context, ns *namespace.Namespace
 }
 
 func (r *DocumentRepository) Delete(ctx context.Context, ns *namespace.Namespace, id string) error {
-	res, err := r.client.ExecContext(ctx,
-		`DELETE FROM documents WHERE namespace_id = $1 AND id = $2`, ns.ID, id)
+	var deleted int
+	err := runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		var err error
+		deleted, err = deleteDocumentsTx(ctx, tx, ns, `namespace_id = $1 AND id = $2`, ns.ID, id)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("delete document: %w", err)
 	}
-	rows, _ := res.RowsAffected()
-	if rows == 0 {
+	if deleted == 0 {
 		return sql.ErrNoRows
 	}
 	return nil
 }
 
 func (r *DocumentRepository) DeleteByEntityURN(ctx context.Context, ns *namespace.Namespace, entityURN string) error {
-	_, err := r.client.ExecContext(ctx,
-		`DELETE FROM documents WHERE namespace_id = $1 AND entity_urn = $2`, ns.ID, entityURN)
-	return err
+	return runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		_, err := deleteDocumentsTx(ctx, tx, ns, `namespace_id = $1 AND entity_urn = $2`, ns.ID, lookupURN(ns, entityURN))
+		return err
+	})
+}
+
+// deleteDocumentsTx deletes the documents matching where, records a deletion
+// for each, and returns how many were deleted.
+func deleteDocumentsTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, where string, args ...interface{}) (int, error) {
+	var models []documentModel
+	err := tx.SelectContext(ctx, &models,
+		fmt.Sprintf(`DELETE FROM documents WHERE %s RETURNING %s`, where, documentColumns), args...)
+	if err != nil {
+		return 0, err
+	}
+	for _, doc := range toDocuments(models) {
+		if err := recordChangeTx(ctx, tx, ns, change.KindDocument, change.OpDeleted, doc.ID, "", doc); err != nil {
+			return 0, err
+		}
+	}
+	return len(models), nil
 }
 
 type documentModel struct {
@@ -2,12 +2,14 @@ package store
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
-	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/jmoiron/sqlx"
+	"github.com/raystack/compass/core/change"
 	"github.com/raystack/compass/core/entity"
 	"github.com/raystack/compass/core/namespace"
 )
@@ -23,29 +25,104 @@ func NewEdgeRepository(client *Client) (*EdgeRepository, error) {
 	return &EdgeRepository{client: client}, nil
 }
 
-var edgeColumns = `id, namespace_id, source_urn, target_urn, type, properties, valid_from, valid_to, source, created_at`
+var edgeColumns = `id, namespace_id, source_urn, target_urn, type, properties, valid_from, valid_to, source, scope, created_at`
 
+// Upsert writes the edge as the current version. As with entities, a changed
+// edge closes the current version and inserts a new one; writing identical
+// content is a no-op. The endpoint URNs are canonicalised first, and an
+// endpoint merged into another entity is replaced by that entity. An edge its
+// type declaration does not allow fails with entity.ErrEdgeConstraint.
 func (r *EdgeRepository) Upsert(ctx context.Context, ns *namespace.Namespace, e *entity.Edge) error {
+	if err := e.CanonicalizeURNs(ns); err != nil {
+		return err
+	}
+	return runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		if err := resolveAliases(ctx, tx, ns, &e.SourceURN, &e.TargetURN); err != nil {
+			return err
+		}
+		if err := checkEdgeTypeTx(ctx, tx, ns, e); err != nil {
+			return err
+		}
+		return upsertEdgeTx(ctx, tx, ns, e, time.Now().UTC())
+	})
+}
+
+func upsertEdgeTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, e *entity.Edge, now time.Time) error {
+	if err := lockEdgesTx(ctx, tx, ns, *e); err != nil {
+		return err
+	}
+	var existing edgeModel
+	err := tx.GetContext(ctx, &existing,
+		fmt.Sprintf(`SELECT %s FROM edges
+			WHERE namespace_id = $1 AND source_urn = $2 AND target_urn = $3 AND type = $4 AND valid_to IS NULL
+			ORDER BY valid_from DESC LIMIT 1 FOR UPDATE`, edgeColumns),
+		ns.ID, e.SourceURN, e.TargetURN, e.Type)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("check existing edge: %w", err)
+	}
+
+	op := change.OpCreated
+	if existing.ID != "" {
+		if sameEdgeContent(existing, *e) {
+			e.ID = existing.ID
+			e.ValidFrom = existing.ValidFrom
+			e.CreatedAt = existing.CreatedAt
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE edges SET valid_to = $1
+			 WHERE namespace_id = $2 AND source_urn = $3 AND target_urn = $4 AND type = $5 AND valid_to IS NULL`,
+			now, ns.ID, e.SourceURN, e.TargetURN, e.Type); err != nil {
+			return fmt.Errorf("close edge version: %w", err)
+		}
+		op = change.OpUpdated
+	}
+
 	query, args, err := sq.Insert("edges").
-		Columns("namespace_id", "source_urn", "target_urn", "type", "properties", "source").
-		Values(ns.ID, e.SourceURN, e.TargetURN, e.Type, JSONMap(e.Properties), e.Source).
-		Suffix(`ON CONFLICT (namespace_id, source_urn, target_urn, type, valid_from)
-			DO UPDATE SET properties = EXCLUDED.properties, source = EXCLUDED.source`).
+		Columns("namespace_id", "source_urn", "target_urn", "type", "properties", "source", "scope", "valid_from", "created_at").
+		Values(ns.ID, e.SourceURN, e.TargetURN, e.Type, JSONMap(e.Properties), e.Source, e.Scope, now, now).
+		Suffix("RETURNING id").
 		PlaceholderFormat(sq.Dollar).
 		ToSql()
 	if err != nil {
 		return fmt.Errorf("build upsert edge: %w", err)
 	}
-	_, err = r.client.ExecContext(ctx, query, args...)
-	if err != nil {
+	if err := tx.QueryRowxContext(ctx, query, args...).Scan(&e.ID); err != nil {
 		return fmt.Errorf("upsert edge: %w", err)
 	}
-	return nil
+	e.NamespaceID = ns.ID.String()
+	e.ValidFrom = now
+	e.ValidTo = nil
+	e.CreatedAt = now
+
+	return recordChangeTx(ctx, tx, ns, change.KindEdge, op, change.EdgeKey(e.SourceURN, e.Type, e.TargetURN), e.Type, e)
+}
+
+// GetAll lists the edges of the namespace matching the filter, one page at a
+// time, in a stable order.
+func (r *EdgeRepository) GetAll(ctx context.Context, ns *namespace.Namespace, filter entity.EdgeFilter, size, offset int) ([]entity.Edge, error) {
+	if size <= 0 {
+		size = 50
+	}
+	builder := sq.Select(edgeColumns).From("edges").
+		Where(sq.Eq{"namespace_id": ns.ID}).
+		OrderBy("source_urn", "type", "target_urn", "valid_from").
+		Limit(uint64(size)).Offset(uint64(offset)).
+		PlaceholderFormat(sq.Dollar)
+	builder = applyEdgeFilter(builder, filter)
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+	return r.queryEdges(ctx, query, args...)
 }
 
 func (r *EdgeRepository) GetBySource(ctx context.Context, ns *namespace.Namespace, urn string, filter entity.EdgeFilter) ([]entity.Edge, error) {
+	urn = lookupURN(ns, urn)
 	builder := sq.Select(edgeColumns).From("edges").
-		Where(sq.Eq{"namespace_id": ns.ID, "source_urn": urn}).
+		Where(sq.Eq{"namespace_id": ns.ID}).
+		Where(sq.Expr(urnOrAlias("source_urn", "?", "?"), urn, ns.ID, urn)).
 		PlaceholderFormat(sq.Dollar)
 	builder = applyEdgeFilter(builder, filter)
 
@@ -57,8 +134,10 @@ func (r *EdgeRepository) GetBySource(ctx context.Context, ns *namespace.Namespac
 }
 
 func (r *EdgeRepository) GetByTarget(ctx context.Context, ns *namespace.Namespace, urn string, filter entity.EdgeFilter) ([]entity.Edge, error) {
+	urn = lookupURN(ns, urn)
 	builder := sq.Select(edgeColumns).From("edges").
-		Where(sq.Eq{"namespace_id": ns.ID, "target_urn": urn}).
+		Where(sq.Eq{"namespace_id": ns.ID}).
+		Where(sq.Expr(urnOrAlias("target_urn", "?", "?"), urn, ns.ID, urn)).
 		PlaceholderFormat(sq.Dollar)
 	builder = applyEdgeFilter(builder, filter)
 
@@ -69,15 +148,16 @@ func (r *EdgeRepository) GetByTarget(ctx context.Context, ns *namespace.Namespac
 	return r.queryEdges(ctx, query, args...)
 }
 
-func (r *EdgeRepository) GetDownstream(ctx context.Context, ns *namespace.Namespace, urn string, depth int) ([]entity.Edge, error) {
-	return r.traverse(ctx, ns, urn, depth, "downstream")
+func (r *EdgeRepository) GetDownstream(ctx context.Context, ns *namespace.Namespace, urn string, depth int, filter entity.EdgeFilter) ([]entity.Edge, error) {
+	return r.traverse(ctx, ns, urn, depth, "downstream", filter)
 }
 
-func (r *EdgeRepository) GetUpstream(ctx context.Context, ns *namespace.Namespace, urn string, depth int) ([]entity.Edge, error) {
-	return r.traverse(ctx, ns, urn, depth, "upstream")
+func (r *EdgeRepository) GetUpstream(ctx context.Context, ns *namespace.Namespace, urn string, depth int, filter entity.EdgeFilter) ([]entity.Edge, error) {
+	return r.traverse(ctx, ns, urn, depth, "upstream", filter)
 }
 
-func (r *EdgeRepository) GetBidirectional(ctx context.Context, ns *namespace.Namespace, urn string, depth int) ([]entity.Edge, error) {
+func (r *EdgeRepository) GetBidirectional(ctx context.Context, ns *namespace.Namespace, urn string, depth int, filter entity.EdgeFilter) ([]entity.Edge, error) {
+	urn = lookupURN(ns, urn)
 	if depth <= 0 {
 		depth = 1
 	}
@@ -86,11 +166,11 @@ func (r *EdgeRepository) GetBidirectional(ctx context.Context, ns *namespace.Nam
 		WITH RECURSIVE seed AS (
 			SELECT source_urn, target_urn, type, properties, target_urn AS frontier
 			FROM edges
-			WHERE namespace_id = $1 AND source_urn = $2 AND valid_to IS NULL
+			WHERE namespace_id = $1 AND ` + urnOrAlias("source_urn", "$1", "$2") + ` AND ` + edgeValidAt("", "$4") + ` AND ` + edgeTypeIn("", "$5", "$6") + `
 			UNION ALL
 			SELECT source_urn, target_urn, type, properties, source_urn AS frontier
 			FROM edges
-			WHERE namespace_id = $1 AND target_urn = $2 AND valid_to IS NULL
+			WHERE namespace_id = $1 AND ` + urnOrAlias("target_urn", "$1", "$2") + ` AND ` + edgeValidAt("", "$4") + ` AND ` + edgeTypeIn("", "$5", "$6") + `
 		),
 		graph(source_urn, target_urn, type, properties, depth, path, frontier) AS (
 			SELECT source_urn, target_urn, type, properties, 1, ARRAY[source_urn, target_urn], frontier
@@ -102,33 +182,62 @@ func (r *EdgeRepository) GetBidirectional(ctx context.Context, ns *namespace.Nam
 			FROM edges e
 			JOIN graph g ON e.source_urn = g.frontier OR e.target_urn = g.frontier
 			WHERE CASE WHEN e.source_urn = g.frontier THEN e.target_urn ELSE e.source_urn END <> ALL(g.path)
-				AND e.valid_to IS NULL AND g.depth < $3
+				AND ` + edgeValidAt("e.", "$4") + ` AND ` + edgeTypeIn("e.", "$5", "$6") + ` AND g.depth < $3
 		)
 		SELECT DISTINCT source_urn, target_urn, type, properties FROM graph
 		LIMIT 1000`
 
 	var models []edgeModel
-	if err := r.client.SelectContext(ctx, &models, query, ns.ID, urn, depth); err != nil {
+	if err := r.client.SelectContext(ctx, &models, query, ns.ID, urn, depth, filter.AsOf, edgeTypes(filter.Types), edgeTypes(filter.ExcludeTypes)); err != nil {
 		return nil, fmt.Errorf("traverse bidirectional: %w", err)
 	}
 	return toEdgeList(models), nil
 }
 
 func (r *EdgeRepository) Delete(ctx context.Context, ns *namespace.Namespace, sourceURN, targetURN, edgeType string) error {
-	_, err := r.client.ExecContext(ctx,
-		`UPDATE edges SET valid_to = now() WHERE namespace_id = $1 AND source_urn = $2 AND target_urn = $3 AND type = $4 AND valid_to IS NULL`,
-		ns.ID, sourceURN, targetURN, edgeType)
-	return err
+	return runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		return closeEdgesTx(ctx, tx, ns, time.Now().UTC(),
+			`namespace_id = $1 AND source_urn = $2 AND target_urn = $3 AND type = $4`,
+			ns.ID, lookupURN(ns, sourceURN), lookupURN(ns, targetURN), edgeType)
+	})
 }
 
 func (r *EdgeRepository) DeleteByURN(ctx context.Context, ns *namespace.Namespace, urn string) error {
-	_, err := r.client.ExecContext(ctx,
-		`UPDATE edges SET valid_to = now() WHERE namespace_id = $1 AND (source_urn = $2 OR target_urn = $2) AND valid_to IS NULL`,
-		ns.ID, urn)
-	return err
+	urn = lookupURN(ns, urn)
+	return runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		return closeEdgesTx(ctx, tx, ns, time.Now().UTC(), `namespace_id = $1 AND (source_urn = $2 OR target_urn = $2)`, ns.ID, urn)
+	})
 }
 
-func (r *EdgeRepository) traverse(ctx context.Context, ns *namespace.Namespace, urn string, depth int, direction string) ([]entity.Edge, error) {
+// closeEdgesTx ends, at now, the current versions of the edges matching
+// where and records a deletion for each.
+func closeEdgesTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, now time.Time, where string, args ...interface{}) error {
+	var current []edgeModel
+	if err := tx.SelectContext(ctx, &current,
+		fmt.Sprintf(`SELECT %s FROM edges WHERE %s AND valid_to IS NULL`, edgeColumns, where), args...); err != nil {
+		return fmt.Errorf("get deleted edges: %w", err)
+	}
+	if err := lockEdgesTx(ctx, tx, ns, toEdgeList(current)...); err != nil {
+		return err
+	}
+	var closed []edgeModel
+	err := tx.SelectContext(ctx, &closed,
+		fmt.Sprintf(`UPDATE edges SET valid_to = $%d WHERE %s AND valid_to IS NULL RETURNING %s`, len(args)+1, where, edgeColumns),
+		append(args, now)...)
+	if err != nil {
+		return fmt.Errorf("delete edges: %w", err)
+	}
+	for _, e := range toEdgeList(closed) {
+		if err := recordChangeTx(ctx, tx, ns, change.KindEdge, change.OpDeleted,
+			change.EdgeKey(e.SourceURN, e.Type, e.TargetURN), e.Type, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *EdgeRepository) traverse(ctx context.Context, ns *namespace.Namespace, urn string, depth int, direction string, filter entity.EdgeFilter) ([]entity.Edge, error) {
+	urn = lookupURN(ns, urn)
 	if depth <= 0 {
 		depth = 3
 	}
@@ -144,18 +253,19 @@ func (r *EdgeRepository) traverse(ctx context.Context, ns *namespace.Namespace,
 		WITH RECURSIVE graph(source_urn, target_urn, type, properties, depth, path) AS (
 			SELECT source_urn, target_urn, type, properties, 1, ARRAY[%s]
 			FROM edges
-			WHERE namespace_id = $1 AND %s = $2 AND valid_to IS NULL
+			WHERE namespace_id = $1 AND %s AND %s AND %s
 		UNION ALL
 			SELECT e.source_urn, e.target_urn, e.type, e.properties, g.depth + 1, g.path || e.%s
 			FROM edges e
 			JOIN graph g ON e.%s = g.%s
-			WHERE e.%s <> ALL(g.path) AND e.valid_to IS NULL AND g.depth < $3
+			WHERE e.%s <> ALL(g.path) AND %s AND %s AND g.depth < $3
 		)
 		SELECT DISTINCT source_urn, target_urn, type, properties FROM graph`,
-		seedCol, seedCol, seedCol, seedCol, joinCol, seedCol)
+		seedCol, urnOrAlias(seedCol, "$1", "$2"), edgeValidAt("", "$4"), edgeTypeIn("", "$5", "$6"), seedCol, seedCol, joinCol, seedCol,
+		edgeValidAt("e.", "$4"), edgeTypeIn("e.", "$5", "$6"))
 
 	var models []edgeModel
-	if err := r.client.SelectContext(ctx, &models, query, ns.ID, urn, depth); err != nil {
+	if err := r.client.SelectContext(ctx, &models, query, ns.ID, urn, depth, filter.AsOf, edgeTypes(filter.Types), edgeTypes(filter.ExcludeTypes)); err != nil {
 		return nil, fmt.Errorf("traverse %s: %w", direction, err)
 	}
 	return toEdgeList(models), nil
@@ -169,13 +279,43 @@ func (r *EdgeRepository) queryEdges(ctx context.Context, query string, args ...i
 	return toEdgeList(models), nil
 }
 
+// edgeValidAt is the raw SQL form of validAt for the recursive traversals.
+// param is a nullable timestamptz placeholder: NULL selects current edges,
+// otherwise edges that were live at that instant.
+func edgeValidAt(prefix, param string) string {
+	return fmt.Sprintf(`((%[2]s::timestamptz IS NULL AND %[1]svalid_to IS NULL) OR
+				(%[1]svalid_from <= %[2]s::timestamptz AND (%[1]svalid_to IS NULL OR %[1]svalid_to > %[2]s::timestamptz)))`,
+		prefix, param)
+}
+
+// edgeTypeIn is the raw SQL restricting the traversals to the edge types in
+// include and not in exclude, nullable text[] placeholders; NULL allows every
+// type.
+func edgeTypeIn(prefix, include, exclude string) string {
+	return fmt.Sprintf(`(%[2]s::text[] IS NULL OR %[1]stype = ANY(%[2]s::text[])) AND
+				(%[3]s::text[] IS NULL OR NOT %[1]stype = ANY(%[3]s::text[]))`, prefix, include, exclude)
+}
+
+// edgeTypes is the argument for edgeTypeIn: the types, or NULL.
+func edgeTypes(types []string) interface{} {
+	if len(types) == 0 {
+		return nil
+	}
+	return types
+}
+
 func applyEdgeFilter(builder sq.SelectBuilder, filter entity.EdgeFilter) sq.SelectBuilder {
-	if filter.Current {
+	if filter.AsOf != nil {
+		builder = builder.Where(validAt(filter.AsOf))
+	} else if filter.Current {
 		builder = builder.Where("valid_to IS NULL")
 	}
 	if len(filter.Types) > 0 {
 		builder = builder.Where(sq.Eq{"type": filter.Types})
 	}
+	if len(filter.ExcludeTypes) > 0 {
+		builder = builder.Where(sq.NotEq{"type": filter.ExcludeTypes})
+	}
 	return builder
 }
 
@@ -189,9 +329,16 @@ type edgeModel struct {
 	ValidFrom   time.Time  `db:"valid_from"`
 	ValidTo     *time.Time `db:"valid_to"`
 	Source      string     `db:"source"`
+	Scope       string     `db:"scope"`
 	CreatedAt   time.Time  `db:"created_at"`
 }
 
+// sameEdgeContent reports whether e carries the same content as the stored
+// version m.
+func sameEdgeContent(m edgeModel, e entity.Edge) bool {
+	return m.Source == e.Source && m.Scope == e.Scope && sameProperties(m.Properties, e.Properties)
+}
+
 func toEdgeList(models []edgeModel) []entity.Edge {
 	edges := make([]entity.Edge, len(models))
 	for i, m := range models {
@@ -205,10 +352,10 @@ func toEdgeList(models []edgeModel) []entity.Edge {
 			ValidFrom:   m.ValidFrom,
 			ValidTo:     m.ValidTo,
 			Source:      m.Source,
+			Scope:       m.Scope,
 			CreatedAt:   m.CreatedAt,
 		}
 	}
 	return edges
 }
 
-var _ = strings.Join // keep import
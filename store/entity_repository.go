@@ -1,16 +1,21 @@
 package store
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/jmoiron/sqlx"
+	"github.com/raystack/compass/core/change"
 	"github.com/raystack/compass/core/entity"
 	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/principal"
 )
 
 type EntityRepository struct {
@@ -24,52 +29,141 @@ func NewEntityRepository(client *Client) (*EntityRepository, error) {
 	return &EntityRepository{client: client}, nil
 }
 
-var entityColumns = `id, namespace_id, urn, type, name, description, properties, source,
-	valid_from, valid_to, created_at, updated_at`
+var entityColumns = `id, namespace_id, urn, type, name, description, properties, property_sources, source, scope,
+	COALESCE(changed_by, '') AS changed_by, valid_from, valid_to, created_at, updated_at`
 
+// Upsert writes a new version of the entity. If a current version exists and
+// differs, it is closed (valid_to set) and a new version is inserted with the
+// same timestamp as valid_from. Writing identical content is a no-op that
+// returns the ID of the current version. The URN is canonicalised first, and
+// the properties are merged with those other sources wrote for the current
+// version; ent carries the canonical URN and merged properties afterwards.
 func (r *EntityRepository) Upsert(ctx context.Context, ns *namespace.Namespace, ent *entity.Entity) (string, error) {
-	now := time.Now().UTC()
-	ent.UpdatedAt = now
+	var id string
+	err := runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		var err error
+		id, err = upsertEntityTx(ctx, tx, ns, ent, time.Now().UTC())
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
 
-	// Check if entity already exists
-	existing, err := r.GetByURN(ctx, ns, ent.URN)
+// UpsertWithColumns writes the entity as Upsert does, and its column entities
+// in the same transaction.
+func (r *EntityRepository) UpsertWithColumns(ctx context.Context, ns *namespace.Namespace, ent *entity.Entity) (string, error) {
+	var id string
+	err := runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		now := time.Now().UTC()
+		var err error
+		if id, err = upsertEntityTx(ctx, tx, ns, ent, now); err != nil {
+			return err
+		}
+		return writeColumnsTx(ctx, tx, ns, *ent, now)
+	})
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func upsertEntityTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, ent *entity.Entity, now time.Time) (string, error) {
+	if err := ent.CanonicalizeURN(ns); err != nil {
+		return "", err
+	}
+	if err := lockEntitiesTx(ctx, tx, ns, ent.URN); err != nil {
+		return "", err
+	}
+	var existing entityModel
+	err := tx.GetContext(ctx, &existing,
+		fmt.Sprintf(`SELECT %s FROM entities WHERE namespace_id = $1 AND urn = $2 AND valid_to IS NULL FOR UPDATE`, entityColumns),
+		ns.ID, ent.URN)
 	if err != nil && !errors.Is(err, sql.ErrNoRows) {
 		return "", fmt.Errorf("check existing entity: %w", err)
 	}
+	var current *entity.Entity
+	if existing.ID != "" {
+		cur := existing.toEntity()
+		current = &cur
+	}
+	if err := ent.MergeProperties(ns, current, now); err != nil {
+		return "", err
+	}
+	if existing.ID != "" && sameEntityContent(*current, *ent) {
+		ent.CreatedAt = existing.CreatedAt
+		ent.UpdatedAt = existing.UpdatedAt
+		ent.ValidFrom = existing.ValidFrom
+		return existing.ID, nil
+	}
+	return insertEntityVersionTx(ctx, tx, ns, ent, existing, now)
+}
+
+// restoreEntityTx writes ent, a closed version of an entity without a current
+// version, as its current version. Unlike upsertEntityTx it keeps the property
+// sources ent carries.
+func restoreEntityTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, ent *entity.Entity, now time.Time) (string, error) {
+	if err := ent.RestoreProperties(ns); err != nil {
+		return "", err
+	}
+	return insertEntityVersionTx(ctx, tx, ns, ent, entityModel{}, now)
+}
 
+// insertEntityVersionTx inserts ent as the current version, closing the
+// current version existing when there is one, and records the change.
+func insertEntityVersionTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, ent *entity.Entity, existing entityModel, now time.Time) (string, error) {
+	createdAt := now
 	if existing.ID != "" {
-		// Update: set valid_to on old row, insert new row
-		_, err := r.client.ExecContext(ctx,
-			`UPDATE entities SET updated_at = $1, properties = $2, name = $3, description = $4, source = $5, type = $6
-			 WHERE id = $7`,
-			now, JSONMap(ent.Properties), ent.Name, ent.Description, ent.Source, string(ent.Type), existing.ID)
-		if err != nil {
-			return "", fmt.Errorf("update entity: %w", err)
+		// Close the current version; the new one starts where it ends.
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE entities SET valid_to = $1 WHERE id = $2`, now, existing.ID); err != nil {
+			return "", fmt.Errorf("close entity version: %w", err)
+		}
+		createdAt = existing.CreatedAt
+	} else {
+		// An entity written with a merged URN takes the URN back.
+		if _, err := tx.ExecContext(ctx,
+			`DELETE FROM entity_aliases WHERE namespace_id = $1 AND alias = $2`, ns.ID, ent.URN); err != nil {
+			return "", fmt.Errorf("delete alias: %w", err)
 		}
-		return existing.ID, nil
 	}
 
-	// Insert new entity
 	var id string
-	err = r.client.QueryFn(ctx, func(conn *sqlx.Conn) error {
-		return conn.QueryRowxContext(ctx,
-			`INSERT INTO entities (namespace_id, urn, type, name, description, properties, source, valid_from, created_at, updated_at)
-			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
-			 RETURNING id`,
-			ns.ID, ent.URN, string(ent.Type), ent.Name, ent.Description,
-			JSONMap(ent.Properties), ent.Source, now, now, now,
-		).Scan(&id)
-	})
+	err := tx.QueryRowxContext(ctx,
+		`INSERT INTO entities (namespace_id, urn, type, name, description, properties, property_sources, source, scope, changed_by, valid_from, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		 RETURNING id`,
+		ns.ID, ent.URN, string(ent.Type), ent.Name, ent.Description,
+		JSONMap(ent.Properties), propertySources(ent.PropertySources), ent.Source, ent.Scope, nilIfEmpty(ent.ChangedBy), now, createdAt, now,
+	).Scan(&id)
 	if err != nil {
 		return "", fmt.Errorf("insert entity: %w", err)
 	}
-	ent.CreatedAt = now
+	ent.CreatedAt = createdAt
+	ent.UpdatedAt = now
 	ent.ValidFrom = now
+	ent.ValidTo = nil
+
+	op := change.OpCreated
+	if existing.ID != "" {
+		op = change.OpUpdated
+	}
+	payload := *ent
+	payload.ID = id
+	payload.NamespaceID = ns.ID.String()
+	if err := recordChangeTx(ctx, tx, ns, change.KindEntity, op, ent.URN, string(ent.Type), payload); err != nil {
+		return "", err
+	}
 	return id, nil
 }
 
+// GetByURN returns the current version of the entity. A URN merged into
+// another entity resolves to that entity.
 func (r *EntityRepository) GetByURN(ctx context.Context, ns *namespace.Namespace, urn string) (entity.Entity, error) {
-	q := fmt.Sprintf(`SELECT %s FROM entities WHERE namespace_id = $1 AND urn = $2 AND valid_to IS NULL LIMIT 1`, entityColumns)
+	urn = lookupURN(ns, urn)
+	q := fmt.Sprintf(`SELECT %s FROM entities WHERE namespace_id = $1 AND %s AND valid_to IS NULL LIMIT 1`,
+		entityColumns, urnOrAlias("urn", "$1", "$2"))
 	var m entityModel
 	if err := r.client.GetContext(ctx, &m, q, ns.ID, urn); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -80,8 +174,50 @@ func (r *EntityRepository) GetByURN(ctx context.Context, ns *namespace.Namespace
 	return m.toEntity(), nil
 }
 
+// GetByURNAsOf returns the version of the entity that was live at asOf. A
+// URN merged into another entity resolves to that entity from the merge on.
+func (r *EntityRepository) GetByURNAsOf(ctx context.Context, ns *namespace.Namespace, urn string, asOf time.Time) (entity.Entity, error) {
+	urn = lookupURN(ns, urn)
+	q := fmt.Sprintf(`SELECT %s FROM entities
+		WHERE namespace_id = $1 AND %s AND valid_from <= $3 AND (valid_to IS NULL OR valid_to > $3)
+		ORDER BY urn = $2 DESC, valid_from DESC LIMIT 1`, entityColumns, urnOrAlias("urn", "$1", "$2"))
+	var m entityModel
+	if err := r.client.GetContext(ctx, &m, q, ns.ID, urn, asOf); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.Entity{}, sql.ErrNoRows
+		}
+		return entity.Entity{}, fmt.Errorf("get entity by URN as of %s: %w", asOf.Format(time.RFC3339), err)
+	}
+	return m.toEntity(), nil
+}
+
+// GetHistory returns every version of the entity, newest first.
+func (r *EntityRepository) GetHistory(ctx context.Context, ns *namespace.Namespace, urn string) ([]entity.Entity, error) {
+	urn = lookupURN(ns, urn)
+	q := fmt.Sprintf(`SELECT %s FROM entities WHERE namespace_id = $1 AND urn = $2 ORDER BY valid_from DESC`, entityColumns)
+	var models []entityModel
+	if err := r.client.SelectContext(ctx, &models, q, ns.ID, urn); err != nil {
+		return nil, fmt.Errorf("get entity history: %w", err)
+	}
+	result := make([]entity.Entity, len(models))
+	for i, m := range models {
+		result[i] = m.toEntity()
+	}
+	return result, nil
+}
+
+// GetByID returns the current version of the entity one of whose versions
+// has the ID, so an ID handed out before the entity was updated, or before
+// it was merged into another entity, still finds it.
 func (r *EntityRepository) GetByID(ctx context.Context, id string) (entity.Entity, error) {
-	q := fmt.Sprintf(`SELECT %s FROM entities WHERE id = $1 AND valid_to IS NULL LIMIT 1`, entityColumns)
+	q := fmt.Sprintf(`SELECT %s FROM entities
+		WHERE valid_to IS NULL AND (namespace_id, urn) IN (
+		  SELECT v.namespace_id, v.urn FROM entities v WHERE v.id = $1
+		  UNION ALL
+		  SELECT a.namespace_id, a.urn FROM entities v
+		  JOIN entity_aliases a ON a.namespace_id = v.namespace_id AND a.alias = v.urn
+		  WHERE v.id = $1)
+		LIMIT 1`, entityColumns)
 	var m entityModel
 	if err := r.client.GetContext(ctx, &m, q, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -96,7 +232,7 @@ func (r *EntityRepository) GetAll(ctx context.Context, ns *namespace.Namespace,
 	builder := sq.Select(entityColumns).
 		From("entities").
 		Where(sq.Eq{"namespace_id": ns.ID}).
-		Where("valid_to IS NULL").
+		Where(validAt(flt.AsOf)).
 		PlaceholderFormat(sq.Dollar)
 
 	builder = applyEntityFilter(builder, flt)
@@ -106,7 +242,7 @@ func (r *EntityRepository) GetAll(ctx context.Context, ns *namespace.Namespace,
 		limit = 50
 	}
 	builder = builder.Limit(uint64(limit)).Offset(uint64(flt.Offset))
-	builder = builder.OrderBy("updated_at DESC")
+	builder = builder.OrderBy("updated_at DESC", "id")
 
 	query, args, err := builder.ToSql()
 	if err != nil {
@@ -129,7 +265,7 @@ func (r *EntityRepository) GetCount(ctx context.Context, ns *namespace.Namespace
 	builder := sq.Select("count(1)").
 		From("entities").
 		Where(sq.Eq{"namespace_id": ns.ID}).
-		Where("valid_to IS NULL").
+		Where(validAt(flt.AsOf)).
 		PlaceholderFormat(sq.Dollar)
 
 	builder = applyEntityFilter(builder, flt)
@@ -165,21 +301,421 @@ func (r *EntityRepository) GetTypes(ctx context.Context, ns *namespace.Namespace
 	return result, nil
 }
 
+// Delete soft-deletes the entity, its edges and its column entities. They all
+// share one valid_to; Restore relies on that to find the edges removed by the
+// same delete.
 func (r *EntityRepository) Delete(ctx context.Context, ns *namespace.Namespace, urn string) error {
-	// Soft delete: set valid_to
-	res, err := r.client.ExecContext(ctx,
-		`UPDATE entities SET valid_to = now(), updated_at = now() WHERE namespace_id = $1 AND urn = $2 AND valid_to IS NULL`,
-		ns.ID, urn)
+	urn = lookupURN(ns, urn)
+	return runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		if err := lockEntitiesTx(ctx, tx, ns, urn); err != nil {
+			return err
+		}
+		columns, err := currentColumnsTx(ctx, tx, ns, urn)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		if err := deleteEntityTx(ctx, tx, ns, urn, now); err != nil {
+			return err
+		}
+		for _, col := range columns {
+			if err := deleteEntityTx(ctx, tx, ns, col, now); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("delete column %s: %w", col, err)
+			}
+		}
+		return nil
+	})
+}
+
+func deleteEntityTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, urn string, now time.Time) error {
+	urn = lookupURN(ns, urn)
+	if err := lockEntitiesTx(ctx, tx, ns, urn); err != nil {
+		return err
+	}
+	var closed entityModel
+	err := tx.GetContext(ctx, &closed,
+		fmt.Sprintf(`UPDATE entities SET valid_to = $3, updated_at = $3
+			WHERE namespace_id = $1 AND urn = $2 AND valid_to IS NULL RETURNING %s`, entityColumns),
+		ns.ID, urn, now)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return sql.ErrNoRows
+		}
 		return fmt.Errorf("delete entity: %w", err)
 	}
-	n, _ := res.RowsAffected()
-	if n == 0 {
-		return sql.ErrNoRows
+	ent := closed.toEntity()
+	if err := recordChangeTx(ctx, tx, ns, change.KindEntity, change.OpDeleted, urn, string(ent.Type), ent); err != nil {
+		return err
+	}
+	return closeEdgesTx(ctx, tx, ns, now, `namespace_id = $1 AND (source_urn = $2 OR target_urn = $2)`, ns.ID, urn)
+}
+
+// currentColumnsTx returns the URNs of the current column entities of urn.
+func currentColumnsTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, urn string) ([]string, error) {
+	var columns []string
+	if err := tx.SelectContext(ctx, &columns,
+		`SELECT target_urn FROM edges WHERE namespace_id = $1 AND source_urn = $2 AND type = $3 AND valid_to IS NULL`,
+		ns.ID, urn, entity.EdgeHasColumn); err != nil {
+		return nil, fmt.Errorf("get columns of %s: %w", urn, err)
+	}
+	return columns, nil
+}
+
+// writeColumnsTx brings the column entities of the written entity ent in line
+// with its properties.columns; see entity.PlanColumns.
+func writeColumnsTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, ent entity.Entity, now time.Time) error {
+	current, err := currentColumnsTx(ctx, tx, ns, ent.URN)
+	if err != nil {
+		return err
+	}
+	exp := entity.PlanColumns(ent, current)
+	for i := range exp.Columns {
+		if _, err := upsertEntityTx(ctx, tx, ns, &exp.Columns[i], now); err != nil {
+			return fmt.Errorf("upsert column %s: %w", exp.Columns[i].URN, err)
+		}
+	}
+	for i := range exp.Edges {
+		if err := checkEdgeTypeTx(ctx, tx, ns, &exp.Edges[i]); err != nil {
+			return err
+		}
+		if err := upsertEdgeTx(ctx, tx, ns, &exp.Edges[i], now); err != nil {
+			return fmt.Errorf("upsert column edge %s: %w", exp.Edges[i].TargetURN, err)
+		}
+	}
+	for _, urn := range exp.Removed {
+		if err := deleteEntityTx(ctx, tx, ns, urn, now); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("delete column %s: %w", urn, err)
+		}
 	}
 	return nil
 }
 
+// Restore reinstates a deleted entity as a new version carrying the content
+// of its latest closed version, and does the same for every edge of the
+// entity closed at that version's valid_to that has not been re-created
+// since and that its type declaration still allows. Column entities whose
+// has_column edge was closed by the same delete are restored the same way
+// first, in the same transaction. Restored objects are recorded as created in
+// the change feed.
+func (r *EntityRepository) Restore(ctx context.Context, ns *namespace.Namespace, urn string) (entity.Entity, []entity.Edge, error) {
+	var (
+		ent   entity.Entity
+		edges []entity.Edge
+	)
+	urn = lookupURN(ns, urn)
+	err := runWriteTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		var err error
+		ent, edges, err = restoreTx(ctx, tx, ns, urn, time.Now().UTC(), true)
+		return err
+	})
+	if err != nil {
+		return entity.Entity{}, nil, err
+	}
+	return ent, edges, nil
+}
+
+// restoreTx restores one deleted entity and its edges, as Restore describes,
+// and with columns set its column entities first, so that the edges are
+// checked against current endpoints. Edges their type declaration no longer
+// allows stay deleted.
+func restoreTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, urn string, now time.Time, columns bool) (entity.Entity, []entity.Edge, error) {
+	if err := lockEntitiesTx(ctx, tx, ns, urn); err != nil {
+		return entity.Entity{}, nil, err
+	}
+	var current int
+	if err := tx.GetContext(ctx, &current,
+		`SELECT COUNT(*) FROM entities WHERE namespace_id = $1 AND urn = $2 AND valid_to IS NULL`,
+		ns.ID, urn); err != nil {
+		return entity.Entity{}, nil, fmt.Errorf("check current entity: %w", err)
+	}
+	if current > 0 {
+		return entity.Entity{}, nil, entity.ErrNotDeleted
+	}
+
+	var last entityModel
+	err := tx.GetContext(ctx, &last,
+		fmt.Sprintf(`SELECT %s FROM entities WHERE namespace_id = $1 AND urn = $2
+			ORDER BY valid_to DESC LIMIT 1 FOR UPDATE`, entityColumns),
+		ns.ID, urn)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.Entity{}, nil, sql.ErrNoRows
+		}
+		return entity.Entity{}, nil, fmt.Errorf("get deleted entity: %w", err)
+	}
+
+	ent := last.toEntity()
+	ent.ChangedBy = principal.FromContext(ctx).Subject
+	if ent.ID, err = restoreEntityTx(ctx, tx, ns, &ent, now); err != nil {
+		return entity.Entity{}, nil, err
+	}
+	// The entity is the same object coming back, not a new one.
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE entities SET created_at = $1 WHERE id = $2`, last.CreatedAt, ent.ID); err != nil {
+		return entity.Entity{}, nil, fmt.Errorf("restore entity created_at: %w", err)
+	}
+	ent.CreatedAt = last.CreatedAt
+	ent.NamespaceID = ns.ID.String()
+
+	var edges []entity.Edge
+	if columns {
+		var cols []string
+		if err := tx.SelectContext(ctx, &cols,
+			`SELECT target_urn FROM edges
+			 WHERE namespace_id = $1 AND source_urn = $2 AND type = $3 AND valid_to = $4`,
+			ns.ID, urn, entity.EdgeHasColumn, *last.ValidTo); err != nil {
+			return entity.Entity{}, nil, fmt.Errorf("get deleted columns: %w", err)
+		}
+		// A column brings back its has_column edge with its own lineage.
+		for _, col := range cols {
+			_, colEdges, err := restoreTx(ctx, tx, ns, col, now, false)
+			if err != nil && !errors.Is(err, entity.ErrNotDeleted) {
+				return entity.Entity{}, nil, fmt.Errorf("restore column %s: %w", col, err)
+			}
+			for _, e := range colEdges {
+				if e.SourceURN == urn || e.TargetURN == urn {
+					edges = append(edges, e)
+				}
+			}
+		}
+	}
+
+	var closed []edgeModel
+	err = tx.SelectContext(ctx, &closed,
+		fmt.Sprintf(`SELECT %s FROM edges e
+			WHERE namespace_id = $1 AND (source_urn = $2 OR target_urn = $2) AND valid_to = $3
+			  AND NOT EXISTS (
+			    SELECT 1 FROM edges c
+			    WHERE c.namespace_id = e.namespace_id AND c.source_urn = e.source_urn
+			      AND c.target_urn = e.target_urn AND c.type = e.type AND c.valid_to IS NULL)`, edgeColumns),
+		ns.ID, urn, *last.ValidTo)
+	if err != nil {
+		return entity.Entity{}, nil, fmt.Errorf("get deleted edges: %w", err)
+	}
+	for _, e := range toEdgeList(closed) {
+		if err := checkEdgeTypeTx(ctx, tx, ns, &e); err != nil {
+			if errors.Is(err, entity.ErrEdgeConstraint) {
+				continue
+			}
+			return entity.Entity{}, nil, err
+		}
+		if err := upsertEdgeTx(ctx, tx, ns, &e, now); err != nil {
+			return entity.Entity{}, nil, err
+		}
+		edges = append(edges, e)
+	}
+	return ent, edges, nil
+}
+
+// Merge merges the entity from into the entity into. The current edges of
+// from are re-pointed at into, except edges between the two, which are
+// dropped. Documents of from move to into, except those into already has a
+// document of the same source and source ID for, which are deleted. The
+// embeddings of the moved documents follow them and the entity embeddings of
+// from are deleted. from is then deleted and recorded as an alias of into,
+// as are the aliases that pointed at from. Each column entity of from is
+// merged the same way into the column of into of the same name, or deleted
+// when into has no such column, in the same transaction.
+func (r *EntityRepository) Merge(ctx context.Context, ns *namespace.Namespace, from, into string) (entity.MergeResult, error) {
+	from, into = lookupURN(ns, from), lookupURN(ns, into)
+	if from == into {
+		return entity.MergeResult{}, fmt.Errorf("%w: cannot merge %s into itself", entity.ErrInvalidMerge, from)
+	}
+	var res entity.MergeResult
+	err := runExclusiveTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		columns, err := currentColumnsTx(ctx, tx, ns, from)
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		if res, err = mergeEntityTx(ctx, tx, ns, from, into, now); err != nil {
+			return err
+		}
+
+		// Column lineage moves to the column of the same name; columns into
+		// does not have are deleted, as a column dropped from
+		// properties.columns is.
+		for _, col := range columns {
+			_, name, _ := entity.ParseColumnURN(col)
+			target := entity.ColumnURN(into, name)
+			typ, err := currentEntityTypeTx(ctx, tx, ns, target)
+			if err != nil {
+				return err
+			}
+			if typ == "" {
+				if err := deleteEntityTx(ctx, tx, ns, col, now); err != nil && !errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("delete column %s: %w", col, err)
+				}
+				continue
+			}
+			if _, err := mergeEntityTx(ctx, tx, ns, col, target, now); err != nil {
+				return fmt.Errorf("merge column %s into %s: %w", col, target, err)
+			}
+			res.Columns++
+		}
+		return nil
+	})
+	if err != nil {
+		return entity.MergeResult{}, err
+	}
+	return res, nil
+}
+
+// mergeEntityTx merges from into into, as Merge does, except that the
+// has_column edges of from are closed rather than moved.
+func mergeEntityTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, from, into string, now time.Time) (entity.MergeResult, error) {
+	res := entity.MergeResult{Alias: from}
+	var current []entityModel
+	if err := tx.SelectContext(ctx, &current,
+		fmt.Sprintf(`SELECT %s FROM entities WHERE namespace_id = $1 AND urn IN ($2, $3) AND valid_to IS NULL FOR UPDATE`, entityColumns),
+		ns.ID, from, into); err != nil {
+		return entity.MergeResult{}, fmt.Errorf("get merged entities: %w", err)
+	}
+	if len(current) != 2 {
+		return entity.MergeResult{}, sql.ErrNoRows
+	}
+	for _, m := range current {
+		if m.URN == into {
+			res.Entity = m.toEntity()
+		}
+	}
+
+	var edges []edgeModel
+	if err := tx.SelectContext(ctx, &edges,
+		fmt.Sprintf(`SELECT %s FROM edges
+			WHERE namespace_id = $1 AND (source_urn = $2 OR target_urn = $2) AND valid_to IS NULL`, edgeColumns),
+		ns.ID, from); err != nil {
+		return entity.MergeResult{}, fmt.Errorf("get merged edges: %w", err)
+	}
+
+	if err := deleteEntityTx(ctx, tx, ns, from, now); err != nil {
+		return entity.MergeResult{}, err
+	}
+	for _, e := range toEdgeList(edges) {
+		// The columns of from are merged on their own.
+		if e.Type == entity.EdgeHasColumn && e.SourceURN == from {
+			continue
+		}
+		if e.SourceURN == from {
+			e.SourceURN = into
+		}
+		if e.TargetURN == from {
+			e.TargetURN = into
+		}
+		if e.SourceURN == e.TargetURN {
+			continue
+		}
+		if err := checkEdgeTypeTx(ctx, tx, ns, &e); err != nil {
+			return entity.MergeResult{}, err
+		}
+		if err := upsertEdgeTx(ctx, tx, ns, &e, now); err != nil {
+			return entity.MergeResult{}, err
+		}
+		res.Edges++
+	}
+
+	const duplicate = `namespace_id = $1 AND entity_urn = $2 AND source_id IS NOT NULL AND EXISTS (
+		SELECT 1 FROM documents d
+		WHERE d.namespace_id = documents.namespace_id AND d.entity_urn = $3
+		  AND d.source = documents.source AND d.source_id = documents.source_id)`
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM embeddings WHERE namespace_id = $1 AND content_id IN (SELECT id FROM documents WHERE `+duplicate+`)`,
+		ns.ID, from, into); err != nil {
+		return entity.MergeResult{}, fmt.Errorf("delete duplicate document embeddings: %w", err)
+	}
+	if _, err := deleteDocumentsTx(ctx, tx, ns, duplicate, ns.ID, from, into); err != nil {
+		return entity.MergeResult{}, fmt.Errorf("delete duplicate documents: %w", err)
+	}
+	var moved []documentModel
+	if err := tx.SelectContext(ctx, &moved,
+		fmt.Sprintf(`UPDATE documents SET entity_urn = $3, updated_at = $4
+			WHERE namespace_id = $1 AND entity_urn = $2 RETURNING %s`, documentColumns),
+		ns.ID, from, into, now); err != nil {
+		return entity.MergeResult{}, fmt.Errorf("move documents: %w", err)
+	}
+	for _, doc := range toDocuments(moved) {
+		if err := recordChangeTx(ctx, tx, ns, change.KindDocument, change.OpUpdated, doc.ID, "", doc); err != nil {
+			return entity.MergeResult{}, err
+		}
+	}
+	res.Documents = len(moved)
+
+	var err error
+	if res.Embeddings, err = execCount(ctx, tx,
+		`UPDATE embeddings SET entity_urn = $3 WHERE namespace_id = $1 AND entity_urn = $2 AND content_type = 'document'`,
+		ns.ID, from, into); err != nil {
+		return entity.MergeResult{}, fmt.Errorf("move embeddings: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM embeddings WHERE namespace_id = $1 AND entity_urn = $2`, ns.ID, from); err != nil {
+		return entity.MergeResult{}, fmt.Errorf("delete entity embeddings: %w", err)
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE entity_aliases SET urn = $3 WHERE namespace_id = $1 AND urn = $2`, ns.ID, from, into); err != nil {
+		return entity.MergeResult{}, fmt.Errorf("move aliases: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO entity_aliases (namespace_id, alias, urn, created_at) VALUES ($1, $2, $3, $4)
+		 ON CONFLICT (namespace_id, alias) DO UPDATE SET urn = EXCLUDED.urn, created_at = EXCLUDED.created_at`,
+		ns.ID, from, into, now); err != nil {
+		return entity.MergeResult{}, fmt.Errorf("record alias: %w", err)
+	}
+	return res, nil
+}
+
+// sameEntityContent reports whether b carries the same user-visible content as a.
+// Properties are compared by their JSON encoding so numeric types coming from
+// requests (int, float64) compare equal to values decoded from JSONB. The
+// property sources of a version written before they were tracked are not
+// compared, so rewriting such an entity unchanged is still a no-op.
+func sameEntityContent(a, b entity.Entity) bool {
+	if a.Type != b.Type || a.Name != b.Name || a.Description != b.Description ||
+		a.Source != b.Source || a.Scope != b.Scope {
+		return false
+	}
+	if len(a.PropertySources) > 0 && !samePropertySources(a.PropertySources, b.PropertySources) {
+		return false
+	}
+	return sameProperties(a.Properties, b.Properties)
+}
+
+func samePropertySources(a, b entity.PropertySources) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	pa, errA := json.Marshal(a)
+	pb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return bytes.Equal(pa, pb)
+}
+
+// sameProperties compares two property maps by their JSON encoding.
+func sameProperties(a, b map[string]interface{}) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	pa, errA := json.Marshal(a)
+	pb, errB := json.Marshal(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return bytes.Equal(pa, pb)
+}
+
+// validAt selects rows that were live at asOf, or current rows when asOf is nil.
+func validAt(asOf *time.Time) sq.Sqlizer {
+	if asOf == nil {
+		return sq.Expr("valid_to IS NULL")
+	}
+	return sq.And{
+		sq.LtOrEq{"valid_from": *asOf},
+		sq.Or{sq.Eq{"valid_to": nil}, sq.Gt{"valid_to": *asOf}},
+	}
+}
+
 func applyEntityFilter(builder sq.SelectBuilder, flt entity.Filter) sq.SelectBuilder {
 	if len(flt.Types) > 0 {
 		types := make([]string, len(flt.Types))
@@ -202,35 +738,70 @@ func applyEntityFilter(builder sq.SelectBuilder, flt entity.Filter) sq.SelectBui
 
 // entityModel maps to the entities table.
 type entityModel struct {
-	ID          string     `db:"id"`
-	NamespaceID string     `db:"namespace_id"`
-	URN         string     `db:"urn"`
-	Type        string     `db:"type"`
-	Name        string     `db:"name"`
-	Description string     `db:"description"`
-	Properties  JSONMap    `db:"properties"`
-	Source      string     `db:"source"`
-	ValidFrom   time.Time  `db:"valid_from"`
-	ValidTo     *time.Time `db:"valid_to"`
-	CreatedAt   time.Time  `db:"created_at"`
-	UpdatedAt   time.Time  `db:"updated_at"`
+	ID          string          `db:"id"`
+	NamespaceID string          `db:"namespace_id"`
+	URN         string          `db:"urn"`
+	Type        string          `db:"type"`
+	Name        string          `db:"name"`
+	Description string          `db:"description"`
+	Properties  JSONMap         `db:"properties"`
+	Sources     propertySources `db:"property_sources"`
+	Source      string          `db:"source"`
+	Scope       string          `db:"scope"`
+	ChangedBy   string          `db:"changed_by"`
+	ValidFrom   time.Time       `db:"valid_from"`
+	ValidTo     *time.Time      `db:"valid_to"`
+	CreatedAt   time.Time       `db:"created_at"`
+	UpdatedAt   time.Time       `db:"updated_at"`
 }
 
 func (m entityModel) toEntity() entity.Entity {
 	return entity.Entity{
-		ID:          m.ID,
-		NamespaceID: m.NamespaceID,
-		URN:         m.URN,
-		Type:        entity.Type(m.Type),
-		Name:        m.Name,
-		Description: m.Description,
-		Properties:  m.Properties,
-		Source:      m.Source,
-		ValidFrom:   m.ValidFrom,
-		ValidTo:     m.ValidTo,
-		CreatedAt:   m.CreatedAt,
-		UpdatedAt:   m.UpdatedAt,
+		ID:              m.ID,
+		NamespaceID:     m.NamespaceID,
+		URN:             m.URN,
+		Type:            entity.Type(m.Type),
+		Name:            m.Name,
+		Description:     m.Description,
+		Properties:      m.Properties,
+		PropertySources: entity.PropertySources(m.Sources),
+		Source:          m.Source,
+		Scope:           m.Scope,
+		ChangedBy:       m.ChangedBy,
+		ValidFrom:       m.ValidFrom,
+		ValidTo:         m.ValidTo,
+		CreatedAt:       m.CreatedAt,
+		UpdatedAt:       m.UpdatedAt,
 	}
 }
 
+// propertySources stores entity.PropertySources as JSONB; a version without
+// tracked sources stores NULL.
+type propertySources entity.PropertySources
 
+func (p propertySources) Value() (driver.Value, error) {
+	if len(p) == 0 {
+		return nil, nil
+	}
+	ba, err := json.Marshal(entity.PropertySources(p))
+	return string(ba), err
+}
+
+func (p *propertySources) Scan(value interface{}) error {
+	var ba []byte
+	switch v := value.(type) {
+	case nil:
+		*p = nil
+		return nil
+	case []byte:
+		ba = v
+	case string:
+		ba = []byte(v)
+	default:
+		return fmt.Errorf("failed to unmarshal JSONB value: %v", value)
+	}
+	var t entity.PropertySources
+	err := json.Unmarshal(ba, &t)
+	*p = propertySources(t)
+	return err
+}
@@ -73,14 +73,20 @@ func (r *EntitySearchRepository) Suggest(ctx context.Context, ns *namespace.Name
 	return names, nil
 }
 
+// aliasTarget is the URN of the entity the search text was merged into, when
+// the text is a URN left as an alias by a merge, so searching for the old URN
+// finds the surviving entity first.
+const aliasTarget = `(SELECT a.urn FROM entity_aliases a WHERE a.namespace_id = entities.namespace_id AND a.alias = $2)`
+
 func (r *EntitySearchRepository) tsvectorSearch(ctx context.Context, nsID, text string, filters map[string][]string, limit, offset int) ([]entity.SearchResult, error) {
 	// Build the query with plainto_tsquery for robustness (handles unquoted input)
 	query := `SELECT id, urn, type, name, COALESCE(source, '') as source,
 			COALESCE(description, '') as description,
-			ts_rank(search_vector, plainto_tsquery('english', $2)) as rank
+			CASE WHEN urn = ` + aliasTarget + ` THEN 1
+				ELSE ts_rank(search_vector, plainto_tsquery('english', $2)) END as rank
 		FROM entities
 		WHERE namespace_id = $1 AND valid_to IS NULL
-			AND search_vector @@ plainto_tsquery('english', $2)`
+			AND (search_vector @@ plainto_tsquery('english', $2) OR urn = ` + aliasTarget + `)`
 
 	args := []interface{}{nsID, text}
 	argIdx := 3
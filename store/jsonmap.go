@@ -52,3 +52,34 @@ func (m *JSONMap) UnmarshalJSON(b []byte) error {
 	*m = JSONMap(t)
 	return err
 }
+
+// JSONStringList is a []string stored as a JSONB array.
+type JSONStringList []string
+
+func (l JSONStringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	ba, err := json.Marshal([]string(l))
+	return string(ba), err
+}
+
+func (l *JSONStringList) Scan(value interface{}) error {
+	if value == nil {
+		*l = nil
+		return nil
+	}
+	var ba []byte
+	switch v := value.(type) {
+	case []byte:
+		ba = v
+	case string:
+		ba = []byte(v)
+	default:
+		return errors.New(fmt.Sprint("failed to unmarshal JSONB value:", value))
+	}
+	var t []string
+	err := json.Unmarshal(ba, &t)
+	*l = JSONStringList(t)
+	return err
+}
@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/raystack/compass/core/change"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+)
+
+// Writers of a namespace run concurrently and serialise on the objects they
+// touch instead, with transaction-scoped advisory locks taken in this order:
+// entities by URN, the edge ends of lockEdgeEndTx, then edges by key, and
+// each before the row locks of the same object. Every writer takes its locks
+// of one kind in sorted order where it knows them up front; when two writers
+// still wait on each other, Postgres aborts one with a deadlock error.
+
+// lockEntitiesTx takes, until commit, the locks on the entities with the
+// URNs, so that no other writer reads or writes their current versions in
+// between.
+func lockEntitiesTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, urns ...string) error {
+	if err := lockKeysTx(ctx, tx, ns, "entities", urns); err != nil {
+		return fmt.Errorf("lock entities: %w", err)
+	}
+	return nil
+}
+
+// lockEdgesTx takes, until commit, the locks on the edges.
+func lockEdgesTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, edges ...entity.Edge) error {
+	keys := make([]string, len(edges))
+	for i, e := range edges {
+		keys[i] = change.EdgeKey(e.SourceURN, e.Type, e.TargetURN)
+	}
+	if err := lockKeysTx(ctx, tx, ns, "edges", keys); err != nil {
+		return fmt.Errorf("lock edges: %w", err)
+	}
+	return nil
+}
+
+// lockKeysTx takes the advisory locks on the keys of kind, in sorted order.
+func lockKeysTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, kind string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	keys = slices.Clone(keys)
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+	_, err := tx.ExecContext(ctx,
+		`SELECT pg_advisory_xact_lock(hashtext($1 || ':' || $2 || ':' || k))
+		 FROM unnest($3::text[]) WITH ORDINALITY AS t(k, i) ORDER BY i`,
+		kind, ns.ID.String(), keys)
+	return err
+}
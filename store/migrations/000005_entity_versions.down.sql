@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_entities_ns_urn_current;
@@ -0,0 +1,4 @@
+-- At most one current (valid_to IS NULL) version per entity URN.
+-- Upserts close the current row and insert a new version, so this
+-- guards against concurrent writers producing two open versions.
+CREATE UNIQUE INDEX idx_entities_ns_urn_current ON entities(namespace_id, urn) WHERE valid_to IS NULL;
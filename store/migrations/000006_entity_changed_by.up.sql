@@ -0,0 +1,2 @@
+-- Record which principal wrote each entity version.
+ALTER TABLE entities ADD COLUMN changed_by text;
@@ -0,0 +1,21 @@
+-- Transactional outbox of entity, edge and document mutations.
+-- Rows are written in the same transaction as the mutation, under a
+-- per-namespace advisory lock held until commit, so within a namespace
+-- seq values become visible in increasing order.
+CREATE TABLE changes (
+    seq          bigserial PRIMARY KEY,
+    namespace_id uuid NOT NULL REFERENCES namespaces(id) ON DELETE CASCADE,
+    kind         text NOT NULL,
+    op           text NOT NULL,
+    key          text NOT NULL,
+    type         text NOT NULL DEFAULT '',
+    payload      jsonb,
+    changed_by   text,
+    created_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_changes_ns_seq ON changes(namespace_id, seq);
+
+ALTER TABLE changes ENABLE ROW LEVEL SECURITY;
+CREATE POLICY changes_ns ON changes
+    USING (namespace_id = current_setting('app.current_tenant')::uuid);
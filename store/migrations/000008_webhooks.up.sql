@@ -0,0 +1,40 @@
+-- Outgoing webhooks. Each webhook follows the change feed of its namespace
+-- from last_seq, which the dispatcher advances as it delivers.
+CREATE TABLE webhooks (
+    id           uuid DEFAULT gen_random_uuid() PRIMARY KEY,
+    namespace_id uuid NOT NULL REFERENCES namespaces(id) ON DELETE CASCADE,
+    url          text NOT NULL,
+    secret       text NOT NULL,
+    events       jsonb NOT NULL DEFAULT '[]',
+    entity_types jsonb NOT NULL DEFAULT '[]',
+    urn_prefix   text NOT NULL DEFAULT '',
+    last_seq     bigint NOT NULL DEFAULT 0,
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    updated_at   timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_webhooks_namespace ON webhooks(namespace_id);
+
+ALTER TABLE webhooks ENABLE ROW LEVEL SECURITY;
+CREATE POLICY webhooks_ns ON webhooks
+    USING (namespace_id = current_setting('app.current_tenant')::uuid);
+
+-- One row per change delivered (or given up on) per webhook.
+CREATE TABLE webhook_deliveries (
+    id            uuid PRIMARY KEY,
+    namespace_id  uuid NOT NULL REFERENCES namespaces(id) ON DELETE CASCADE,
+    webhook_id    uuid NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    change_seq    bigint NOT NULL,
+    event         text NOT NULL,
+    status        text NOT NULL,
+    attempts      int NOT NULL,
+    response_code int,
+    error         text,
+    created_at    timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, created_at DESC);
+
+ALTER TABLE webhook_deliveries ENABLE ROW LEVEL SECURITY;
+CREATE POLICY webhook_deliveries_ns ON webhook_deliveries
+    USING (namespace_id = current_setting('app.current_tenant')::uuid);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_edges_sync;
+DROP INDEX IF EXISTS idx_entities_sync;
+ALTER TABLE edges DROP COLUMN IF EXISTS scope;
+ALTER TABLE entities DROP COLUMN IF EXISTS scope;
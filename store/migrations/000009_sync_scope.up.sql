@@ -0,0 +1,7 @@
+-- Scope narrows a source to the slice of it one extractor run owns, e.g.
+-- a project or an instance. Sync reconciles current rows by source and scope.
+ALTER TABLE entities ADD COLUMN scope text NOT NULL DEFAULT '';
+ALTER TABLE edges ADD COLUMN scope text NOT NULL DEFAULT '';
+
+CREATE INDEX idx_entities_sync ON entities(namespace_id, source, scope) WHERE valid_to IS NULL;
+CREATE INDEX idx_edges_sync ON edges(namespace_id, source, scope) WHERE valid_to IS NULL;
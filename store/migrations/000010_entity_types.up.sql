@@ -0,0 +1,19 @@
+-- Registered entity types. A definition gives a type a display name and an
+-- icon, and the JSON Schema and required properties its entities are
+-- validated against on write.
+CREATE TABLE entity_types (
+    namespace_id uuid NOT NULL REFERENCES namespaces(id) ON DELETE CASCADE,
+    type         text NOT NULL,
+    display_name text NOT NULL DEFAULT '',
+    icon         text NOT NULL DEFAULT '',
+    schema       jsonb,
+    required     jsonb NOT NULL DEFAULT '[]',
+    mode         text NOT NULL DEFAULT 'reject',
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    updated_at   timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (namespace_id, type)
+);
+
+ALTER TABLE entity_types ENABLE ROW LEVEL SECURITY;
+CREATE POLICY entity_types_ns ON entity_types
+    USING (namespace_id = current_setting('app.current_tenant')::uuid);
@@ -0,0 +1,19 @@
+-- Declared edge types. A declaration limits the entity types an edge may
+-- link and how many edges of the type an entity may have, and tells impact
+-- analysis whether the edge carries lineage.
+CREATE TABLE edge_types (
+    namespace_id uuid NOT NULL REFERENCES namespaces(id) ON DELETE CASCADE,
+    type         text NOT NULL,
+    source_types jsonb NOT NULL DEFAULT '[]',
+    target_types jsonb NOT NULL DEFAULT '[]',
+    lineage      boolean NOT NULL DEFAULT false,
+    inverse      text NOT NULL DEFAULT '',
+    cardinality  text NOT NULL DEFAULT 'many_to_many',
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    updated_at   timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (namespace_id, type)
+);
+
+ALTER TABLE edge_types ENABLE ROW LEVEL SECURITY;
+CREATE POLICY edge_types_ns ON edge_types
+    USING (namespace_id = current_setting('app.current_tenant')::uuid);
@@ -0,0 +1,16 @@
+-- URNs of entities merged into another entity. Reads by an alias resolve to
+-- the entity it was merged into, until an entity is written with the alias
+-- URN again.
+CREATE TABLE entity_aliases (
+    namespace_id uuid NOT NULL REFERENCES namespaces(id) ON DELETE CASCADE,
+    alias        text NOT NULL,
+    urn          text NOT NULL,
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY (namespace_id, alias)
+);
+
+CREATE INDEX idx_entity_aliases_urn ON entity_aliases(namespace_id, urn);
+
+ALTER TABLE entity_aliases ENABLE ROW LEVEL SECURITY;
+CREATE POLICY entity_aliases_ns ON entity_aliases
+    USING (namespace_id = current_setting('app.current_tenant')::uuid);
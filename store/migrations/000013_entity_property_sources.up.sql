@@ -0,0 +1,3 @@
+-- The properties each source wrote for an entity version, keyed by source.
+-- properties holds their merge under the namespace's property policy.
+ALTER TABLE entities ADD COLUMN property_sources jsonb;
@@ -0,0 +1,2 @@
+-- The spellings URNs were stored with before canonicalisation are not kept,
+-- so there is nothing to restore.
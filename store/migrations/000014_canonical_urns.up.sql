@@ -0,0 +1,238 @@
+-- URNs are canonicalised on write and on lookup (see core/urn), but rows
+-- written before that keep the spelling they were written with, which
+-- lookups no longer find. Rewrite every stored URN to its canonical form
+-- under its namespace's rules, merging the rows two spellings of one URN
+-- leave behind: the latest current version is kept and the others closed.
+
+-- urn_rules reads a namespace's URN rules from its metadata, as
+-- urn.RulesFromNamespace does. ok is false when they are malformed, in which
+-- case lookups do not canonicalise and neither does this migration.
+CREATE FUNCTION urn_rules(metadata jsonb, OUT ok boolean, OUT sources text[], OUT case_insensitive text[], OUT is_strict boolean)
+LANGUAGE plpgsql IMMUTABLE AS $$
+DECLARE
+    r jsonb := metadata->'urn';
+    key text;
+    item jsonb;
+    names text[];
+BEGIN
+    ok := true;
+    sources := '{}';
+    case_insensitive := ARRAY['bigquery', 'redshift', 'snowflake'];
+    is_strict := false;
+    IF r IS NULL OR jsonb_typeof(r) = 'null' THEN
+        RETURN;
+    END IF;
+    IF jsonb_typeof(r) <> 'object' THEN
+        ok := false;
+        RETURN;
+    END IF;
+    FOREACH key IN ARRAY ARRAY['sources', 'case_insensitive'] LOOP
+        CONTINUE WHEN NOT r ? key;
+        names := '{}';
+        IF jsonb_typeof(r->key) = 'array' THEN
+            FOR item IN SELECT jsonb_array_elements(r->key) LOOP
+                IF jsonb_typeof(item) <> 'string' OR btrim(item #>> '{}') = '' THEN
+                    ok := false;
+                    RETURN;
+                END IF;
+                names := names || lower(btrim(item #>> '{}'));
+            END LOOP;
+        ELSIF jsonb_typeof(r->key) <> 'null' THEN
+            ok := false;
+            RETURN;
+        END IF;
+        IF key = 'sources' THEN
+            sources := names;
+        ELSE
+            case_insensitive := names;
+        END IF;
+    END LOOP;
+    IF jsonb_typeof(r->'strict') = 'boolean' THEN
+        is_strict := (r->>'strict')::boolean;
+    ELSIF r ? 'strict' AND jsonb_typeof(r->'strict') <> 'null' THEN
+        ok := false;
+    END IF;
+END $$;
+
+-- canonical_urn mirrors urn.Lookup: it returns the canonical form of s, or s
+-- itself when the rules do not accept it.
+CREATE FUNCTION canonical_urn(s text, sources text[], case_insensitive text[], is_strict boolean)
+RETURNS text LANGUAGE plpgsql IMMUTABLE AS $$
+DECLARE
+    t text := regexp_replace(s, '^\s+|\s+$', '', 'g');
+    rest text;
+    col text := '';
+    parts text[];
+    src text;
+    scope text := '';
+    kind text := '';
+    id text := '';
+    result text;
+BEGIN
+    IF t = '' OR t ~ '[[:cntrl:]]' THEN
+        RETURN s;
+    END IF;
+    IF position(':' IN t) = 0 OR lower(regexp_replace(split_part(t, ':', 1), '^\s+|\s+$', '', 'g')) <> 'urn' THEN
+        RETURN CASE WHEN is_strict OR cardinality(sources) > 0 THEN s ELSE t END;
+    END IF;
+    rest := substr(t, position(':' IN t) + 1);
+    IF position('#' IN rest) > 0 THEN
+        col := regexp_replace(substr(rest, position('#' IN rest) + 1), '^\s+|\s+$', '', 'g');
+        rest := substr(rest, 1, position('#' IN rest) - 1);
+        IF col = '' THEN
+            RETURN CASE WHEN is_strict OR cardinality(sources) > 0 THEN s ELSE t END;
+        END IF;
+    END IF;
+    -- strings.SplitN(rest, ":", 4), each part trimmed.
+    parts := regexp_match(rest, '^([^:]*)(?::([^:]*)(?::([^:]*)(?::(.*))?)?)?$');
+    FOR i IN 1..4 LOOP
+        parts[i] := regexp_replace(parts[i], '^\s+|\s+$', '', 'g');
+    END LOOP;
+    src := parts[1];
+    IF parts[4] IS NOT NULL AND parts[2] ~ '^[A-Za-z0-9][A-Za-z0-9_.-]*$' AND parts[3] ~ '^[A-Za-z0-9][A-Za-z0-9_.-]*$' THEN
+        scope := parts[2];
+        kind := parts[3];
+        id := parts[4];
+    ELSIF parts[2] IS NOT NULL THEN
+        id := regexp_replace(concat_ws(':', parts[2], parts[3], parts[4]), '^\s+|\s+$', '', 'g');
+    END IF;
+    IF src = '' OR id = '' THEN
+        RETURN CASE WHEN is_strict OR cardinality(sources) > 0 THEN s ELSE t END;
+    END IF;
+    src := lower(src);
+    kind := lower(kind);
+    IF cardinality(sources) > 0 AND NOT src = ANY(sources) THEN
+        RETURN s;
+    END IF;
+    IF src = ANY(case_insensitive) THEN
+        scope := lower(scope);
+        id := lower(id);
+    END IF;
+    result := 'urn:' || src || ':';
+    IF scope <> '' OR kind <> '' THEN
+        result := result || scope || ':' || kind || ':';
+    END IF;
+    result := result || id;
+    IF col <> '' THEN
+        result := result || '#' || col;
+    END IF;
+    RETURN result;
+END $$;
+
+-- Every stored URN whose canonical form differs.
+CREATE TEMP TABLE urn_map AS
+WITH urns AS (
+    SELECT namespace_id, urn FROM entities
+    UNION SELECT namespace_id, source_urn FROM edges
+    UNION SELECT namespace_id, target_urn FROM edges
+    UNION SELECT namespace_id, entity_urn FROM documents
+    UNION SELECT namespace_id, entity_urn FROM embeddings
+    UNION SELECT namespace_id, alias FROM entity_aliases
+    UNION SELECT namespace_id, urn FROM entity_aliases
+)
+SELECT u.namespace_id, u.urn AS stored, canonical_urn(u.urn, r.sources, r.case_insensitive, r.is_strict) AS canonical
+  FROM urns u
+  JOIN namespaces n ON n.id = u.namespace_id
+ CROSS JOIN LATERAL urn_rules(n.metadata) r
+ WHERE r.ok;
+
+DELETE FROM urn_map WHERE stored = canonical;
+CREATE INDEX ON urn_map(namespace_id, stored);
+
+-- Entities: keep the latest current version per canonical URN, then drop
+-- versions two spellings started at the same instant, then rename.
+WITH ranked AS (
+    SELECT e.id, row_number() OVER (
+               PARTITION BY e.namespace_id, coalesce(m.canonical, e.urn)
+               ORDER BY e.valid_from DESC, e.updated_at DESC, e.id) AS rank
+      FROM entities e
+      LEFT JOIN urn_map m ON m.namespace_id = e.namespace_id AND m.stored = e.urn
+     WHERE e.valid_to IS NULL
+)
+UPDATE entities e SET valid_to = now()
+  FROM ranked WHERE e.id = ranked.id AND ranked.rank > 1;
+
+WITH ranked AS (
+    SELECT e.id, row_number() OVER (
+               PARTITION BY e.namespace_id, coalesce(m.canonical, e.urn), e.valid_from
+               ORDER BY e.valid_to IS NULL DESC, e.valid_to DESC, e.id) AS rank
+      FROM entities e
+      LEFT JOIN urn_map m ON m.namespace_id = e.namespace_id AND m.stored = e.urn
+)
+DELETE FROM entities e USING ranked WHERE e.id = ranked.id AND ranked.rank > 1;
+
+UPDATE entities e SET urn = m.canonical
+  FROM urn_map m WHERE m.namespace_id = e.namespace_id AND m.stored = e.urn;
+
+-- Edges: the same, per canonical source, target and type.
+WITH ranked AS (
+    SELECT e.id, row_number() OVER (
+               PARTITION BY e.namespace_id, coalesce(s.canonical, e.source_urn), coalesce(t.canonical, e.target_urn), e.type
+               ORDER BY e.valid_from DESC, e.created_at DESC, e.id) AS rank
+      FROM edges e
+      LEFT JOIN urn_map s ON s.namespace_id = e.namespace_id AND s.stored = e.source_urn
+      LEFT JOIN urn_map t ON t.namespace_id = e.namespace_id AND t.stored = e.target_urn
+     WHERE e.valid_to IS NULL
+)
+UPDATE edges e SET valid_to = now()
+  FROM ranked WHERE e.id = ranked.id AND ranked.rank > 1;
+
+WITH ranked AS (
+    SELECT e.id, row_number() OVER (
+               PARTITION BY e.namespace_id, coalesce(s.canonical, e.source_urn), coalesce(t.canonical, e.target_urn), e.type, e.valid_from
+               ORDER BY e.valid_to IS NULL DESC, e.valid_to DESC, e.id) AS rank
+      FROM edges e
+      LEFT JOIN urn_map s ON s.namespace_id = e.namespace_id AND s.stored = e.source_urn
+      LEFT JOIN urn_map t ON t.namespace_id = e.namespace_id AND t.stored = e.target_urn
+)
+DELETE FROM edges e USING ranked WHERE e.id = ranked.id AND ranked.rank > 1;
+
+UPDATE edges e SET source_urn = m.canonical
+  FROM urn_map m WHERE m.namespace_id = e.namespace_id AND m.stored = e.source_urn;
+UPDATE edges e SET target_urn = m.canonical
+  FROM urn_map m WHERE m.namespace_id = e.namespace_id AND m.stored = e.target_urn;
+
+-- Documents: keep the latest of those sharing a source and source id, with
+-- their embeddings.
+CREATE TEMP TABLE duplicate_documents AS
+SELECT id FROM (
+    SELECT d.id, row_number() OVER (
+               PARTITION BY d.namespace_id, coalesce(m.canonical, d.entity_urn), d.source, d.source_id
+               ORDER BY d.updated_at DESC, d.id) AS rank
+      FROM documents d
+      LEFT JOIN urn_map m ON m.namespace_id = d.namespace_id AND m.stored = d.entity_urn
+     WHERE d.source IS NOT NULL AND d.source_id IS NOT NULL
+) ranked WHERE rank > 1;
+
+DELETE FROM embeddings WHERE content_id IN (SELECT id FROM duplicate_documents);
+DELETE FROM documents WHERE id IN (SELECT id FROM duplicate_documents);
+
+UPDATE documents d SET entity_urn = m.canonical
+  FROM urn_map m WHERE m.namespace_id = d.namespace_id AND m.stored = d.entity_urn;
+UPDATE embeddings e SET entity_urn = m.canonical
+  FROM urn_map m WHERE m.namespace_id = e.namespace_id AND m.stored = e.entity_urn;
+
+-- Aliases: drop those that now name the entity they point at, and keep the
+-- latest of those sharing a canonical alias.
+UPDATE entity_aliases a SET urn = m.canonical
+  FROM urn_map m WHERE m.namespace_id = a.namespace_id AND m.stored = a.urn;
+
+WITH ranked AS (
+    SELECT a.namespace_id, a.alias, coalesce(m.canonical, a.alias) AS canonical, a.urn,
+           row_number() OVER (
+               PARTITION BY a.namespace_id, coalesce(m.canonical, a.alias)
+               ORDER BY a.created_at DESC, a.alias) AS rank
+      FROM entity_aliases a
+      LEFT JOIN urn_map m ON m.namespace_id = a.namespace_id AND m.stored = a.alias
+)
+DELETE FROM entity_aliases a USING ranked
+ WHERE a.namespace_id = ranked.namespace_id AND a.alias = ranked.alias
+   AND (ranked.rank > 1 OR ranked.canonical = ranked.urn);
+
+UPDATE entity_aliases a SET alias = m.canonical
+  FROM urn_map m WHERE m.namespace_id = a.namespace_id AND m.stored = a.alias;
+
+DROP TABLE duplicate_documents;
+DROP TABLE urn_map;
+DROP FUNCTION canonical_urn(text, text[], text[], boolean);
+DROP FUNCTION urn_rules(jsonb);
@@ -0,0 +1,243 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/raystack/compass/core/change"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/retention"
+)
+
+// RetentionRepository hard-deletes historical and deleted rows.
+type RetentionRepository struct {
+	client *Client
+}
+
+func NewRetentionRepository(client *Client) (*RetentionRepository, error) {
+	if client == nil {
+		return nil, errors.New("postgres client is nil")
+	}
+	return &RetentionRepository{client: client}, nil
+}
+
+// PurgeClosedVersions removes versions closed before the cutoff for which a
+// newer version of the same entity or edge exists. The latest version of
+// every object is kept, current or not.
+func (r *RetentionRepository) PurgeClosedVersions(ctx context.Context, ns *namespace.Namespace, before time.Time) (retention.Result, error) {
+	var res retention.Result
+	err := runExclusiveTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		var err error
+		res.EntityVersions, err = execCount(ctx, tx,
+			`DELETE FROM entities e
+			 WHERE e.namespace_id = $1 AND e.valid_to < $2
+			   AND EXISTS (
+			     SELECT 1 FROM entities n
+			     WHERE n.namespace_id = e.namespace_id AND n.urn = e.urn AND n.valid_from >= e.valid_to)`,
+			ns.ID, before)
+		if err != nil {
+			return fmt.Errorf("purge entity versions: %w", err)
+		}
+		res.EdgeVersions, err = execCount(ctx, tx,
+			`DELETE FROM edges e
+			 WHERE e.namespace_id = $1 AND e.valid_to < $2
+			   AND EXISTS (
+			     SELECT 1 FROM edges n
+			     WHERE n.namespace_id = e.namespace_id AND n.source_urn = e.source_urn
+			       AND n.target_urn = e.target_urn AND n.type = e.type AND n.valid_from >= e.valid_to)`,
+			ns.ID, before)
+		if err != nil {
+			return fmt.Errorf("purge edge versions: %w", err)
+		}
+		return nil
+	})
+	return res, err
+}
+
+// PurgeDeleted erases entities deleted before the cutoff, then edges whose
+// latest version was closed before the cutoff. Entities merged into another
+// entity are kept, with their aliases, so their URNs go on resolving to the
+// entity they were merged into; only PurgeEntity erases them.
+func (r *RetentionRepository) PurgeDeleted(ctx context.Context, ns *namespace.Namespace, before time.Time) (retention.Result, error) {
+	var res retention.Result
+	err := runExclusiveTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		var urns []string
+		err := tx.SelectContext(ctx, &urns,
+			`SELECT urn FROM entities e WHERE namespace_id = $1
+			   AND NOT EXISTS (SELECT 1 FROM entity_aliases a WHERE a.namespace_id = e.namespace_id AND a.alias = e.urn)
+			 GROUP BY urn
+			 HAVING bool_and(valid_to IS NOT NULL) AND max(valid_to) < $2`,
+			ns.ID, before)
+		if err != nil {
+			return fmt.Errorf("find deleted entities: %w", err)
+		}
+		for _, urn := range urns {
+			purged, err := purgeEntityTx(ctx, tx, ns, urn, false)
+			if err != nil {
+				return err
+			}
+			res.Add(purged)
+		}
+
+		edges, err := execCount(ctx, tx,
+			`DELETE FROM edges e
+			 WHERE e.namespace_id = $1
+			   AND (e.source_urn, e.target_urn, e.type) IN (
+			     SELECT source_urn, target_urn, type FROM edges
+			     WHERE namespace_id = $1
+			     GROUP BY source_urn, target_urn, type
+			     HAVING bool_and(valid_to IS NOT NULL) AND max(valid_to) < $2)`,
+			ns.ID, before)
+		if err != nil {
+			return fmt.Errorf("purge deleted edges: %w", err)
+		}
+		res.EdgeVersions += edges
+		return nil
+	})
+	return res, err
+}
+
+// ResolveURN returns the canonical URN of the entity urn names, following the
+// alias of a merged URN.
+func (r *RetentionRepository) ResolveURN(ctx context.Context, ns *namespace.Namespace, urn string) (string, error) {
+	urn = lookupURN(ns, urn)
+	if err := resolveAliases(ctx, r.client, ns, &urn); err != nil {
+		return "", err
+	}
+	return urn, nil
+}
+
+// PurgeEntity erases an entity and everything attached to it, its column
+// entities and the entities merged into it included. A URN merged into
+// another entity erases that entity. When any of the entities or their edges
+// is current, a payload-free deletion is recorded after the purge so that
+// change feed consumers drop their copies.
+func (r *RetentionRepository) PurgeEntity(ctx context.Context, ns *namespace.Namespace, urn string) (retention.Result, error) {
+	var res retention.Result
+	urn = lookupURN(ns, urn)
+	err := runExclusiveTx(ctx, r.client, ns, func(tx *sqlx.Tx) error {
+		if err := resolveAliases(ctx, tx, ns, &urn); err != nil {
+			return err
+		}
+		var merged []string
+		if err := tx.SelectContext(ctx, &merged,
+			`SELECT alias FROM entity_aliases WHERE namespace_id = $1 AND urn = $2`, ns.ID, urn); err != nil {
+			return fmt.Errorf("get merged entities: %w", err)
+		}
+
+		var current []struct {
+			URN  string `db:"urn"`
+			Type string `db:"type"`
+		}
+		if err := tx.SelectContext(ctx, &current,
+			`SELECT urn, type FROM entities WHERE namespace_id = $1 AND `+matchURN("urn")+` AND valid_to IS NULL`,
+			ns.ID, urn); err != nil {
+			return fmt.Errorf("get current entity: %w", err)
+		}
+		var currentEdges []edgeModel
+		if err := tx.SelectContext(ctx, &currentEdges,
+			fmt.Sprintf(`SELECT %s FROM edges
+				WHERE namespace_id = $1 AND (%s OR %s) AND valid_to IS NULL`, edgeColumns, matchURN("source_urn"), matchURN("target_urn")),
+			ns.ID, urn); err != nil {
+			return fmt.Errorf("get current edges: %w", err)
+		}
+
+		var err error
+		if res, err = purgeEntityTx(ctx, tx, ns, urn, true); err != nil {
+			return err
+		}
+		if res.IsZero() {
+			return sql.ErrNoRows
+		}
+		// Merged entities are deleted; their versions remain until purged.
+		for _, alias := range merged {
+			purged, err := purgeEntityTx(ctx, tx, ns, alias, true)
+			if err != nil {
+				return err
+			}
+			res.Add(purged)
+		}
+
+		for _, c := range current {
+			if err := recordChangeTx(ctx, tx, ns, change.KindEntity, change.OpDeleted, c.URN, c.Type, nil); err != nil {
+				return err
+			}
+		}
+		for _, e := range currentEdges {
+			if err := recordChangeTx(ctx, tx, ns, change.KindEdge, change.OpDeleted,
+				change.EdgeKey(e.SourceURN, e.Type, e.TargetURN), e.Type, nil); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return retention.Result{}, err
+	}
+	return res, nil
+}
+
+// matchURN is a condition on the URN column col that holds for the URN $2 and
+// for the URNs of its column entities, $2#<column>.
+func matchURN(col string) string {
+	return fmt.Sprintf(`(%[1]s = $2 OR left(%[1]s, length($2) + 1) = $2 || '#')`, col)
+}
+
+// purgeEntityTx hard-deletes every row that carries the data of the entity or
+// its column entities: their versions, edges, documents, embeddings, and the
+// change records describing any of them, and with aliases set their aliases.
+func purgeEntityTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, urn string, aliases bool) (retention.Result, error) {
+	var res retention.Result
+	steps := []struct {
+		name  string
+		count *int
+		query string
+	}{
+		{"entity versions", &res.EntityVersions,
+			`DELETE FROM entities WHERE namespace_id = $1 AND ` + matchURN("urn")},
+		{"edges", &res.EdgeVersions,
+			`DELETE FROM edges WHERE namespace_id = $1 AND (` + matchURN("source_urn") + ` OR ` + matchURN("target_urn") + `)`},
+		{"documents", &res.Documents,
+			`DELETE FROM documents WHERE namespace_id = $1 AND ` + matchURN("entity_urn")},
+		{"embeddings", &res.Embeddings,
+			`DELETE FROM embeddings WHERE namespace_id = $1 AND ` + matchURN("entity_urn")},
+		// Edge keys are "source|type|target"; match either end, which is the
+		// URN or one of its columns.
+		{"changes", &res.Changes,
+			`DELETE FROM changes WHERE namespace_id = $1 AND (
+			   (kind IN ('entity', 'schema') AND ` + matchURN("key") + `)
+			   OR (kind = 'edge' AND (
+			     left(key, length($2) + 1) IN ($2 || '|', $2 || '#')
+			     OR right(key, length($2) + 1) = '|' || $2
+			     OR strpos(key, '|' || $2 || '#') > 0))
+			   OR (kind = 'document' AND ` + matchURN("payload->>'entity_urn'") + `))`},
+	}
+	for _, step := range steps {
+		n, err := execCount(ctx, tx, step.query, ns.ID, urn)
+		if err != nil {
+			return retention.Result{}, fmt.Errorf("purge %s: %w", step.name, err)
+		}
+		*step.count = n
+	}
+	if !aliases {
+		return res, nil
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM entity_aliases WHERE namespace_id = $1 AND (`+matchURN("alias")+` OR `+matchURN("urn")+`)`, ns.ID, urn); err != nil {
+		return retention.Result{}, fmt.Errorf("purge aliases: %w", err)
+	}
+	return res, nil
+}
+
+func execCount(ctx context.Context, tx *sqlx.Tx, query string, args ...interface{}) (int, error) {
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := result.RowsAffected()
+	return int(n), err
+}
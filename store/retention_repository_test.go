@@ -0,0 +1,104 @@
+package store
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/internal/middleware"
+)
+
+// testNamespace connects to the Postgres named by the COMPASS_TEST_DB_*
+// variables, migrates it and creates a namespace to run the test in. The test
+// is skipped when COMPASS_TEST_DB_HOST is unset.
+func testNamespace(t *testing.T) (context.Context, *Client, *namespace.Namespace) {
+	t.Helper()
+	cfg := Config{
+		Host:     os.Getenv("COMPASS_TEST_DB_HOST"),
+		Port:     5432,
+		Name:     os.Getenv("COMPASS_TEST_DB_NAME"),
+		User:     os.Getenv("COMPASS_TEST_DB_USER"),
+		Password: os.Getenv("COMPASS_TEST_DB_PASSWORD"),
+	}
+	if cfg.Host == "" {
+		t.Skip("COMPASS_TEST_DB_HOST is not set")
+	}
+	if port := os.Getenv("COMPASS_TEST_DB_PORT"); port != "" {
+		var err error
+		if cfg.Port, err = strconv.Atoi(port); err != nil {
+			t.Fatalf("COMPASS_TEST_DB_PORT: %v", err)
+		}
+	}
+	client, err := NewClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	if _, err := client.Migrate(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	ns := &namespace.Namespace{ID: uuid.New(), State: namespace.SharedState, Metadata: map[string]interface{}{}}
+	ns.Name = "test-" + ns.ID.String()
+	if _, err := NewNamespaceRepository(client).Create(context.Background(), ns); err != nil {
+		t.Fatal(err)
+	}
+	return middleware.BuildContextWithNamespace(context.Background(), ns), client, ns
+}
+
+func TestRetentionRepository_PurgeDeleted_KeepsMergedURNs(t *testing.T) {
+	ctx, client, ns := testNamespace(t)
+	entities, err := NewEntityRepository(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo, err := NewRetentionRepository(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, u := range []string{"urn:kafka:old-orders", "urn:kafka:orders", "urn:kafka:scratch"} {
+		if _, err := entities.Upsert(ctx, ns, &entity.Entity{URN: u, Type: entity.TypeTopic, Name: u}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := entities.Merge(ctx, ns, "urn:kafka:old-orders", "urn:kafka:orders"); err != nil {
+		t.Fatal(err)
+	}
+	if err := entities.Delete(ctx, ns, "urn:kafka:scratch"); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := repo.PurgeDeleted(ctx, ns, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EntityVersions != 1 {
+		t.Errorf("expected the deleted entity's one version purged, got %d", res.EntityVersions)
+	}
+	if history, err := entities.GetHistory(ctx, ns, "urn:kafka:scratch"); err != nil || len(history) != 0 {
+		t.Errorf("expected the deleted entity to be purged, got %d versions (%v)", len(history), err)
+	}
+	if history, err := entities.GetHistory(ctx, ns, "urn:kafka:old-orders"); err != nil || len(history) == 0 {
+		t.Errorf("expected the merged entity's versions to be kept, got %d (%v)", len(history), err)
+	}
+	got, err := repo.ResolveURN(ctx, ns, "urn:kafka:old-orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "urn:kafka:orders" {
+		t.Errorf("expected the merged urn to resolve to urn:kafka:orders, got %s", got)
+	}
+	ent, err := entities.GetByURN(ctx, ns, "urn:kafka:old-orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ent.URN != "urn:kafka:orders" {
+		t.Errorf("expected a read of the merged urn to find urn:kafka:orders, got %s", ent.URN)
+	}
+}
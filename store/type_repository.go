@@ -0,0 +1,289 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/raystack/compass/core/entity"
+	"github.com/raystack/compass/core/namespace"
+)
+
+type TypeRepository struct {
+	client *Client
+}
+
+func NewTypeRepository(client *Client) (*TypeRepository, error) {
+	if client == nil {
+		return nil, errors.New("postgres client is nil")
+	}
+	return &TypeRepository{client: client}, nil
+}
+
+const typeColumns = `type, display_name, icon, schema, required, mode, created_at, updated_at`
+
+func (r *TypeRepository) UpsertType(ctx context.Context, ns *namespace.Namespace, def *entity.TypeDefinition) error {
+	var res struct {
+		CreatedAt time.Time `db:"created_at"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	err := r.client.GetContext(ctx, &res,
+		`INSERT INTO entity_types (namespace_id, type, display_name, icon, schema, required, mode)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (namespace_id, type) DO UPDATE SET
+		     display_name = EXCLUDED.display_name,
+		     icon = EXCLUDED.icon,
+		     schema = EXCLUDED.schema,
+		     required = EXCLUDED.required,
+		     mode = EXCLUDED.mode,
+		     updated_at = now()
+		 RETURNING created_at, updated_at`,
+		ns.ID, string(def.Type), def.DisplayName, def.Icon, JSONMap(def.Schema), JSONStringList(def.Required), string(def.Mode))
+	if err != nil {
+		return fmt.Errorf("upsert entity type: %w", err)
+	}
+	def.CreatedAt = res.CreatedAt
+	def.UpdatedAt = res.UpdatedAt
+	return nil
+}
+
+func (r *TypeRepository) GetType(ctx context.Context, ns *namespace.Namespace, t entity.Type) (entity.TypeDefinition, error) {
+	var m typeModel
+	err := r.client.GetContext(ctx, &m,
+		`SELECT `+typeColumns+` FROM entity_types WHERE namespace_id = $1 AND type = $2`, ns.ID, string(t))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.TypeDefinition{}, entity.ErrTypeNotFound
+		}
+		return entity.TypeDefinition{}, fmt.Errorf("get entity type: %w", err)
+	}
+	return m.toTypeDefinition(), nil
+}
+
+func (r *TypeRepository) ListTypes(ctx context.Context, ns *namespace.Namespace) ([]entity.TypeDefinition, error) {
+	var models []typeModel
+	err := r.client.SelectContext(ctx, &models,
+		`SELECT `+typeColumns+` FROM entity_types WHERE namespace_id = $1 ORDER BY type`, ns.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list entity types: %w", err)
+	}
+	result := make([]entity.TypeDefinition, len(models))
+	for i, m := range models {
+		result[i] = m.toTypeDefinition()
+	}
+	return result, nil
+}
+
+func (r *TypeRepository) DeleteType(ctx context.Context, ns *namespace.Namespace, t entity.Type) error {
+	res, err := r.client.ExecContext(ctx,
+		`DELETE FROM entity_types WHERE namespace_id = $1 AND type = $2`, ns.ID, string(t))
+	if err != nil {
+		return fmt.Errorf("delete entity type: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return entity.ErrTypeNotFound
+	}
+	return nil
+}
+
+type typeModel struct {
+	Type        string         `db:"type"`
+	DisplayName string         `db:"display_name"`
+	Icon        string         `db:"icon"`
+	Schema      JSONMap        `db:"schema"`
+	Required    JSONStringList `db:"required"`
+	Mode        string         `db:"mode"`
+	CreatedAt   time.Time      `db:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at"`
+}
+
+func (m typeModel) toTypeDefinition() entity.TypeDefinition {
+	return entity.TypeDefinition{
+		Type:        entity.Type(m.Type),
+		DisplayName: m.DisplayName,
+		Icon:        m.Icon,
+		Schema:      m.Schema,
+		Required:    m.Required,
+		Mode:        entity.TypeMode(m.Mode),
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
+
+const edgeTypeColumns = `type, source_types, target_types, lineage, inverse, cardinality, created_at, updated_at`
+
+func (r *TypeRepository) UpsertEdgeType(ctx context.Context, ns *namespace.Namespace, def *entity.EdgeTypeDefinition) error {
+	var res struct {
+		CreatedAt time.Time `db:"created_at"`
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	err := r.client.GetContext(ctx, &res,
+		`INSERT INTO edge_types (namespace_id, type, source_types, target_types, lineage, inverse, cardinality)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (namespace_id, type) DO UPDATE SET
+		     source_types = EXCLUDED.source_types,
+		     target_types = EXCLUDED.target_types,
+		     lineage = EXCLUDED.lineage,
+		     inverse = EXCLUDED.inverse,
+		     cardinality = EXCLUDED.cardinality,
+		     updated_at = now()
+		 RETURNING created_at, updated_at`,
+		ns.ID, def.Type, typeList(def.SourceTypes), typeList(def.TargetTypes), def.Lineage, def.Inverse, string(def.Cardinality))
+	if err != nil {
+		return fmt.Errorf("upsert edge type: %w", err)
+	}
+	def.CreatedAt = res.CreatedAt
+	def.UpdatedAt = res.UpdatedAt
+	return nil
+}
+
+func (r *TypeRepository) GetEdgeType(ctx context.Context, ns *namespace.Namespace, edgeType string) (entity.EdgeTypeDefinition, error) {
+	var m edgeTypeModel
+	err := r.client.GetContext(ctx, &m,
+		`SELECT `+edgeTypeColumns+` FROM edge_types WHERE namespace_id = $1 AND type = $2`, ns.ID, edgeType)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.EdgeTypeDefinition{}, entity.ErrEdgeTypeNotFound
+		}
+		return entity.EdgeTypeDefinition{}, fmt.Errorf("get edge type: %w", err)
+	}
+	return m.toEdgeTypeDefinition(), nil
+}
+
+func (r *TypeRepository) ListEdgeTypes(ctx context.Context, ns *namespace.Namespace) ([]entity.EdgeTypeDefinition, error) {
+	var models []edgeTypeModel
+	err := r.client.SelectContext(ctx, &models,
+		`SELECT `+edgeTypeColumns+` FROM edge_types WHERE namespace_id = $1 ORDER BY type`, ns.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list edge types: %w", err)
+	}
+	result := make([]entity.EdgeTypeDefinition, len(models))
+	for i, m := range models {
+		result[i] = m.toEdgeTypeDefinition()
+	}
+	return result, nil
+}
+
+func (r *TypeRepository) DeleteEdgeType(ctx context.Context, ns *namespace.Namespace, edgeType string) error {
+	res, err := r.client.ExecContext(ctx,
+		`DELETE FROM edge_types WHERE namespace_id = $1 AND type = $2`, ns.ID, edgeType)
+	if err != nil {
+		return fmt.Errorf("delete edge type: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return entity.ErrEdgeTypeNotFound
+	}
+	return nil
+}
+
+// checkEdgeTypeTx returns entity.ErrEdgeConstraint when the declaration of
+// the edge's type does not allow it. Undeclared edge types are not checked.
+func checkEdgeTypeTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, e *entity.Edge) error {
+	var m edgeTypeModel
+	err := tx.GetContext(ctx, &m,
+		`SELECT `+edgeTypeColumns+` FROM edge_types WHERE namespace_id = $1 AND type = $2`, ns.ID, e.Type)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("get edge type: %w", err)
+	}
+	def := m.toEdgeTypeDefinition()
+
+	var ends entity.EdgeEndpoints
+	if ends.SourceType, err = currentEntityTypeTx(ctx, tx, ns, e.SourceURN); err != nil {
+		return err
+	}
+	if ends.TargetType, err = currentEntityTypeTx(ctx, tx, ns, e.TargetURN); err != nil {
+		return err
+	}
+	if def.OneTarget() {
+		if err := lockEdgeEndTx(ctx, tx, ns, e.Type, "source", e.SourceURN); err != nil {
+			return err
+		}
+		if err := tx.GetContext(ctx, &ends.SourceEdges,
+			`SELECT count(*) FROM edges
+			 WHERE namespace_id = $1 AND type = $2 AND source_urn = $3 AND target_urn <> $4 AND valid_to IS NULL`,
+			ns.ID, e.Type, e.SourceURN, e.TargetURN); err != nil {
+			return fmt.Errorf("count edges from source: %w", err)
+		}
+	}
+	if def.OneSource() {
+		if err := lockEdgeEndTx(ctx, tx, ns, e.Type, "target", e.TargetURN); err != nil {
+			return err
+		}
+		if err := tx.GetContext(ctx, &ends.TargetEdges,
+			`SELECT count(*) FROM edges
+			 WHERE namespace_id = $1 AND type = $2 AND target_urn = $3 AND source_urn <> $4 AND valid_to IS NULL`,
+			ns.ID, e.Type, e.TargetURN, e.SourceURN); err != nil {
+			return fmt.Errorf("count edges into target: %w", err)
+		}
+	}
+	return def.CheckEdge(*e, ends)
+}
+
+// lockEdgeEndTx takes, until commit, the lock on the edges of type edgeType
+// at one end of urn, so that no other writer adds one between counting them
+// and writing the checked edge. It is taken before the locks on edges; see
+// lock.go for the order.
+func lockEdgeEndTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, edgeType, end, urn string) error {
+	if _, err := tx.ExecContext(ctx,
+		`SELECT pg_advisory_xact_lock(hashtext('edge_types:' || $1 || ':' || $2 || ':' || $3 || ':' || $4))`,
+		ns.ID.String(), edgeType, end, urn); err != nil {
+		return fmt.Errorf("lock %s edges of %s: %w", edgeType, urn, err)
+	}
+	return nil
+}
+
+// currentEntityTypeTx returns the type of the current entity urn, or "" when
+// there is none.
+func currentEntityTypeTx(ctx context.Context, tx *sqlx.Tx, ns *namespace.Namespace, urn string) (entity.Type, error) {
+	var t string
+	err := tx.GetContext(ctx, &t,
+		`SELECT type FROM entities WHERE namespace_id = $1 AND urn = $2 AND valid_to IS NULL
+		 ORDER BY valid_from DESC LIMIT 1`, ns.ID, urn)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("get entity type of %s: %w", urn, err)
+	}
+	return entity.Type(t), nil
+}
+
+func typeList(types []entity.Type) JSONStringList {
+	list := make(JSONStringList, len(types))
+	for i, t := range types {
+		list[i] = string(t)
+	}
+	return list
+}
+
+type edgeTypeModel struct {
+	Type        string         `db:"type"`
+	SourceTypes JSONStringList `db:"source_types"`
+	TargetTypes JSONStringList `db:"target_types"`
+	Lineage     bool           `db:"lineage"`
+	Inverse     string         `db:"inverse"`
+	Cardinality string         `db:"cardinality"`
+	CreatedAt   time.Time      `db:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at"`
+}
+
+func (m edgeTypeModel) toEdgeTypeDefinition() entity.EdgeTypeDefinition {
+	def := entity.EdgeTypeDefinition{
+		Type:        m.Type,
+		Lineage:     m.Lineage,
+		Inverse:     m.Inverse,
+		Cardinality: entity.Cardinality(m.Cardinality),
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+	for _, t := range m.SourceTypes {
+		def.SourceTypes = append(def.SourceTypes, entity.Type(t))
+	}
+	for _, t := range m.TargetTypes {
+		def.TargetTypes = append(def.TargetTypes, entity.Type(t))
+	}
+	return def
+}
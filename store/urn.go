@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/urn"
+)
+
+// lookupURN returns the form of a URN that writes store, for queries by URN.
+func lookupURN(ns *namespace.Namespace, s string) string {
+	return urn.Lookup(ns, s)
+}
+
+// urnOrAlias is the raw SQL matching column against the URN in urnParam and,
+// when that URN is an alias left by a merge, against the URN of the entity it
+// was merged into.
+func urnOrAlias(column, nsParam, urnParam string) string {
+	return fmt.Sprintf(`%[1]s IN (%[3]s, (SELECT a.urn FROM entity_aliases a WHERE a.namespace_id = %[2]s AND a.alias = %[3]s))`,
+		column, nsParam, urnParam)
+}
+
+type selecter interface {
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+}
+
+// resolveAliases replaces each URN that is an alias left by a merge with the
+// URN of the entity it was merged into, so writes naming the old URN land on
+// the surviving entity.
+func resolveAliases(ctx context.Context, db selecter, ns *namespace.Namespace, urns ...*string) error {
+	list := make([]string, len(urns))
+	for i, u := range urns {
+		list[i] = *u
+	}
+	var rows []struct {
+		Alias string `db:"alias"`
+		URN   string `db:"urn"`
+	}
+	if err := db.SelectContext(ctx, &rows,
+		`SELECT alias, urn FROM entity_aliases WHERE namespace_id = $1 AND alias = ANY($2)`,
+		ns.ID, list); err != nil {
+		return fmt.Errorf("resolve aliases: %w", err)
+	}
+	resolved := make(map[string]string, len(rows))
+	for _, row := range rows {
+		resolved[row.Alias] = row.URN
+	}
+	for _, u := range urns {
+		if to, ok := resolved[*u]; ok {
+			*u = to
+		}
+	}
+	return nil
+}
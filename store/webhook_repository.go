@@ -0,0 +1,199 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/raystack/compass/core/namespace"
+	"github.com/raystack/compass/core/webhook"
+)
+
+type WebhookRepository struct {
+	client *Client
+}
+
+func NewWebhookRepository(client *Client) (*WebhookRepository, error) {
+	if client == nil {
+		return nil, errors.New("postgres client is nil")
+	}
+	return &WebhookRepository{client: client}, nil
+}
+
+const webhookColumns = `id, namespace_id, url, secret, events, entity_types, urn_prefix, last_seq, created_at, updated_at`
+
+func (r *WebhookRepository) Create(ctx context.Context, ns *namespace.Namespace, wh *webhook.Webhook) (string, error) {
+	var res struct {
+		ID        string    `db:"id"`
+		LastSeq   int64     `db:"last_seq"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+	// Start the cursor at the head of the feed so only later changes are sent.
+	err := r.client.GetContext(ctx, &res,
+		`INSERT INTO webhooks (namespace_id, url, secret, events, entity_types, urn_prefix, last_seq)
+		 VALUES ($1, $2, $3, $4, $5, $6,
+		         (SELECT COALESCE(MAX(seq), 0) FROM changes WHERE namespace_id = $1))
+		 RETURNING id, last_seq, created_at`,
+		ns.ID, wh.URL, wh.Secret, JSONStringList(wh.Events), JSONStringList(wh.EntityTypes), wh.URNPrefix)
+	if err != nil {
+		return "", fmt.Errorf("insert webhook: %w", err)
+	}
+	wh.LastSeq = res.LastSeq
+	wh.CreatedAt = res.CreatedAt
+	wh.UpdatedAt = res.CreatedAt
+	return res.ID, nil
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, ns *namespace.Namespace, id string) (webhook.Webhook, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return webhook.Webhook{}, webhook.ErrNotFound
+	}
+	var m webhookModel
+	err := r.client.GetContext(ctx, &m,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE namespace_id = $1 AND id = $2`, ns.ID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return webhook.Webhook{}, webhook.ErrNotFound
+		}
+		return webhook.Webhook{}, fmt.Errorf("get webhook: %w", err)
+	}
+	return m.toWebhook(), nil
+}
+
+func (r *WebhookRepository) List(ctx context.Context, ns *namespace.Namespace) ([]webhook.Webhook, error) {
+	var models []webhookModel
+	err := r.client.SelectContext(ctx, &models,
+		`SELECT `+webhookColumns+` FROM webhooks WHERE namespace_id = $1 ORDER BY created_at`, ns.ID)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	result := make([]webhook.Webhook, len(models))
+	for i, m := range models {
+		result[i] = m.toWebhook()
+	}
+	return result, nil
+}
+
+func (r *WebhookRepository) Delete(ctx context.Context, ns *namespace.Namespace, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return webhook.ErrNotFound
+	}
+	res, err := r.client.ExecContext(ctx,
+		`DELETE FROM webhooks WHERE namespace_id = $1 AND id = $2`, ns.ID, id)
+	if err != nil {
+		return fmt.Errorf("delete webhook: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return webhook.ErrNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) UpdateCursor(ctx context.Context, ns *namespace.Namespace, id string, seq int64) error {
+	_, err := r.client.ExecContext(ctx,
+		`UPDATE webhooks SET last_seq = $3, updated_at = now()
+		 WHERE namespace_id = $1 AND id = $2 AND last_seq < $3`, ns.ID, id, seq)
+	if err != nil {
+		return fmt.Errorf("update webhook cursor: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) InsertDelivery(ctx context.Context, ns *namespace.Namespace, d *webhook.Delivery) error {
+	err := r.client.GetContext(ctx, &d.CreatedAt,
+		`INSERT INTO webhook_deliveries (id, namespace_id, webhook_id, change_seq, event, status, attempts, response_code, error)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING created_at`,
+		d.ID, ns.ID, d.WebhookID, d.ChangeSeq, d.Event, string(d.Status), d.Attempts,
+		sql.NullInt64{Int64: int64(d.ResponseCode), Valid: d.ResponseCode != 0}, nilIfEmpty(d.Error))
+	if err != nil {
+		return fmt.Errorf("insert webhook delivery: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, ns *namespace.Namespace, webhookID string, flt webhook.DeliveryFilter) ([]webhook.Delivery, error) {
+	builder := sq.Select("id", "webhook_id", "change_seq", "event", "status", "attempts",
+		"COALESCE(response_code, 0) AS response_code", "COALESCE(error, '') AS error", "created_at").
+		From("webhook_deliveries").
+		Where(sq.Eq{"namespace_id": ns.ID, "webhook_id": webhookID}).
+		OrderBy("created_at DESC", "change_seq DESC").
+		Limit(uint64(flt.Limit)).
+		PlaceholderFormat(sq.Dollar)
+	if flt.Status != "" {
+		builder = builder.Where(sq.Eq{"status": string(flt.Status)})
+	}
+
+	query, args, err := builder.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("build query: %w", err)
+	}
+
+	var models []deliveryModel
+	if err := r.client.SelectContext(ctx, &models, query, args...); err != nil {
+		return nil, fmt.Errorf("list webhook deliveries: %w", err)
+	}
+	result := make([]webhook.Delivery, len(models))
+	for i, m := range models {
+		result[i] = m.toDelivery()
+	}
+	return result, nil
+}
+
+type webhookModel struct {
+	ID          string         `db:"id"`
+	NamespaceID string         `db:"namespace_id"`
+	URL         string         `db:"url"`
+	Secret      string         `db:"secret"`
+	Events      JSONStringList `db:"events"`
+	EntityTypes JSONStringList `db:"entity_types"`
+	URNPrefix   string         `db:"urn_prefix"`
+	LastSeq     int64          `db:"last_seq"`
+	CreatedAt   time.Time      `db:"created_at"`
+	UpdatedAt   time.Time      `db:"updated_at"`
+}
+
+func (m webhookModel) toWebhook() webhook.Webhook {
+	return webhook.Webhook{
+		ID:          m.ID,
+		NamespaceID: m.NamespaceID,
+		URL:         m.URL,
+		Secret:      m.Secret,
+		Events:      m.Events,
+		EntityTypes: m.EntityTypes,
+		URNPrefix:   m.URNPrefix,
+		LastSeq:     m.LastSeq,
+		CreatedAt:   m.CreatedAt,
+		UpdatedAt:   m.UpdatedAt,
+	}
+}
+
+type deliveryModel struct {
+	ID           string    `db:"id"`
+	WebhookID    string    `db:"webhook_id"`
+	ChangeSeq    int64     `db:"change_seq"`
+	Event        string    `db:"event"`
+	Status       string    `db:"status"`
+	Attempts     int       `db:"attempts"`
+	ResponseCode int       `db:"response_code"`
+	Error        string    `db:"error"`
+	CreatedAt    time.Time `db:"created_at"`
+}
+
+func (m deliveryModel) toDelivery() webhook.Delivery {
+	return webhook.Delivery{
+		ID:           m.ID,
+		WebhookID:    m.WebhookID,
+		ChangeSeq:    m.ChangeSeq,
+		Event:        m.Event,
+		Status:       webhook.DeliveryStatus(m.Status),
+		Attempts:     m.Attempts,
+		ResponseCode: m.ResponseCode,
+		Error:        m.Error,
+		CreatedAt:    m.CreatedAt,
+	}
+}